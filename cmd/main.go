@@ -1,19 +1,178 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/jellexet/golang-text-editor/pkg/editor"
 	"golang.org/x/sys/unix"
+	"io"
 	"log"
 	"os"
+	"runtime/debug"
+	"strings"
 )
 
+// version is set at build time with -ldflags "-X main.version=..."
+var version = ""
+
+// fileToOpen is a buffer to create at startup
+type fileToOpen struct {
+	name      string
+	content   string
+	line, col int
+	// background files are too large to read before the editor starts
+	background bool
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `usage: %s [options] [+line] [file[:line[:col]] ...]
+
+Opens each file in its own buffer; "-" reads a buffer from stdin.
+
+options:
+`, os.Args[0])
+	flag.PrintDefaults()
+}
+
 func main() {
+	status := run()
+	// Reported once the terminal is restored
+	if discarded := editor.DiscardedFiles(); len(discarded) > 0 && !quiet {
+		fmt.Fprintln(os.Stderr, "Quit without saving the changes to", strings.Join(discarded, ", "))
+	}
+	os.Exit(status)
+}
+
+// quiet leaves the exit status as the only report, for scripts
+var quiet bool
+
+// fail reports an error on stderr, unless quiet, and returns status
+func fail(status int, a ...any) int {
+	if !quiet {
+		fmt.Fprintln(os.Stderr, a...)
+	}
+	return status
+}
+
+// failUsage reports an error in the arguments followed by the usage,
+// unless quiet
+func failUsage(a ...any) int {
+	if !quiet {
+		fmt.Fprintln(os.Stderr, a...)
+		flag.Usage()
+	}
+	return editor.ExitUsage
+}
+
+// run starts the editor and returns its exit status once it quits, after
+// restoring the terminal
+func run() int {
+	readOnly := flag.Bool("readonly", false, "open the files read-only")
+	pager := flag.Bool("pager", false, "page through the files, or stdin, with the keys of less")
+	tabSize := flag.Int("tabsize", 0, "columns between tab stops (default 8)")
+	theme := flag.String("theme", "", "color theme: default, dark, light, high-contrast or mono")
+	configPath := flag.String("config", editor.DefaultConfigPath(), "config `file` to load")
+	logPath := flag.String("log", "", "append debug messages to `file`")
+	controlPath := flag.String("control", "", "accept JSON-RPC requests on a unix `socket` created at this path")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.BoolVar(&quiet, "quiet", false, "print no messages, report only through the exit status")
+	repairTerminal := flag.Bool("repair-terminal", false, "restore the terminal settings an editor that crashed left changed, and exit")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println("go-editor", buildVersion())
+		return editor.ExitOK
+	}
+
+	if *repairTerminal {
+		done, err := editor.RepairTerminal(int(os.Stdin.Fd()))
+		if err != nil {
+			return fail(editor.ExitError, "Cannot repair the terminal:", err)
+		}
+		if !quiet {
+			fmt.Println(done)
+		}
+		return editor.ExitOK
+	}
+
+	if *logPath != "" {
+		f, err := os.OpenFile(*logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fail(editor.ExitUsage, "Cannot open the log file:", err)
+		}
+		defer f.Close()
+		log.SetOutput(f)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	// The default config file is optional, one given explicitly is not
+	if err := editor.LoadConfig(*configPath); err != nil && (!os.IsNotExist(err) || isFlagSet("config")) {
+		return fail(editor.ExitUsage, err)
+	}
+	if err := editor.LoadPlugins(); err != nil {
+		return fail(editor.ExitUsage, err)
+	}
+	// Options given on the command line win over the config file
+	options := map[string]string{}
+	if isFlagSet("readonly") {
+		options["readonly"] = fmt.Sprint(*readOnly)
+	}
+	if isFlagSet("tabsize") {
+		options["tabsize"] = fmt.Sprint(*tabSize)
+	}
+	if isFlagSet("theme") {
+		options["theme"] = *theme
+	}
+	for name, value := range options {
+		if err := editor.Set(name, value); err != nil {
+			return failUsage(err)
+		}
+	}
+
+	args := flag.Args()
+	if *pager && len(args) == 0 {
+		// As $PAGER the text comes on stdin
+		if _, err := unix.IoctlGetTermios(int(os.Stdin.Fd()), unix.TCGETS); err == nil {
+			return fail(editor.ExitUsage, "--pager needs a file name or text on stdin")
+		}
+		args = []string{"-"}
+	}
+	files, err := filesToOpen(args)
+	if errors.Is(err, errCannotRead) {
+		return fail(editor.ExitReadError, err)
+	} else if err != nil {
+		return failUsage(err)
+	}
+
 	fd := int(os.Stdin.Fd())
+	// Keys are read from the terminal even when stdin is a pipe
+	for _, f := range files {
+		if f.name == "[stdin]" {
+			tty, err := os.Open("/dev/tty")
+			if err != nil {
+				return fail(editor.ExitError, "Cannot open the terminal:", err)
+			}
+			defer tty.Close()
+			fd = int(tty.Fd())
+			break
+		}
+	}
 
 	// Check if stdin is a terminal
 	if _, err := unix.IoctlGetTermios(fd, unix.TCGETS); err != nil {
-		log.Fatalln("Not a TTY. This editor requires a TTY to run.")
+		return fail(editor.ExitError, "Not a TTY. This editor requires a TTY to run.")
+	}
+
+	// Requests wait for the main loop, which starts once the buffers are open
+	if *controlPath != "" {
+		control, err := editor.ServeControl(*controlPath)
+		if err != nil {
+			return fail(editor.ExitError, "Cannot open the control socket:", err)
+		}
+		defer control.Close()
 	}
 
 	// Enable raw mode for terminal
@@ -21,26 +180,48 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	// Kept until the terminal is restored, for --repair-terminal to find
+	// if the editor is killed before
+	if err := editor.SaveTerminalState(fd, oldState); err != nil {
+		log.Printf("save terminal state: %v", err)
+	}
+	defer editor.RemoveTerminalState(fd)
 	// Enters an alternate screen buffer
 	fmt.Print("\x1b[?1049h")
 
 	// Printing this exits the alternate screen buffer
-	defer fmt.Print("\x1b[?1049h")
+	defer fmt.Print("\x1b[?1049l")
+	// Pasted text comes wrapped in markers, so it is inserted as typed
+	fmt.Print("\x1b[?2004h")
+	defer fmt.Print("\x1b[?2004l")
+	// Getting the focus back makes the editor check for files changed on disk
+	fmt.Print("\x1b[?1004h")
+	defer fmt.Print("\x1b[?1004l")
+	// The editor turns on mouse reporting with the mouse option
+	defer fmt.Print("\x1b[?1006l\x1b[?1000l")
+	// The editor sets the terminal title: save the current one, to restore it
+	fmt.Print("\x1b[22;0t")
+	defer fmt.Print("\x1b[23;0t")
 	defer editor.DisableRawMode(fd, oldState)
 
-	var initialContent string
-	var filename string
-	if len(os.Args) > 1 {
-		filename = os.Args[1]
-		contentBytes, err := os.ReadFile(filename)
-		// If file doesn't exist or errors, we'll just start with an empty buffer
-		if err == nil {
-			initialContent = string(contentBytes)
-		}
+	if first := files[0]; first.background {
+		editor.InitLoadingSession(fd, first.name, first.line, first.col)
 	} else {
-		filename = "[No Name]"
+		editor.InitSession(fd, first.name, first.content)
+		if first.line != 0 {
+			editor.GoToLine(first.line, first.col)
+		}
+	}
+	for _, f := range files[1:] {
+		if f.background {
+			editor.AddLoadingBuffer(f.name, f.line, f.col)
+		} else {
+			editor.AddBuffer(f.name, f.content, f.line, f.col)
+		}
+	}
+	if *pager {
+		editor.EnablePager()
 	}
-	editor.InitSession(fd, filename, initialContent)
 
 	// function to be passed as argument to ProcessKeypress()
 	// It defines what to do for each keypress
@@ -57,4 +238,79 @@ func main() {
 
 	// Start the main editor loop
 	editor.ProcessKeypress(fd, onKeypress)
+	return editor.ExitStatus()
+}
+
+// errCannotRead marks the errors of files that exist but could not be read,
+// as opposed to bad arguments
+var errCannotRead = errors.New("cannot read")
+
+// filesToOpen reads the file arguments. "+N" applies to the file after it,
+// "-" reads stdin, and a missing file starts as an empty buffer.
+func filesToOpen(args []string) ([]fileToOpen, error) {
+	if len(args) == 0 {
+		return []fileToOpen{{name: "[No Name]"}}, nil
+	}
+
+	var files []fileToOpen
+	for i := 0; i < len(args); i++ {
+		fileArgs := []string{args[i]}
+		if len(args[i]) > 0 && args[i][0] == '+' && i+1 < len(args) {
+			i++
+			fileArgs = append(fileArgs, args[i])
+		}
+
+		if fileArgs[len(fileArgs)-1] == "-" {
+			content, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return nil, fmt.Errorf("%w stdin: %v", errCannotRead, err)
+			}
+			files = append(files, fileToOpen{name: "[stdin]", content: string(content)})
+			continue
+		}
+
+		name, line, col, err := editor.ParseFileArgs(fileArgs)
+		if err != nil {
+			return nil, err
+		}
+		f := fileToOpen{name: name, line: line, col: col}
+		if editor.IsURL(name) {
+			if f.content, err = editor.FetchURL(name); err != nil {
+				return nil, fmt.Errorf("%w %s: %v", errCannotRead, name, err)
+			}
+		} else if editor.LoadsInBackground(name) {
+			f.background = true
+		} else if content, err := os.ReadFile(name); err == nil {
+			f.content = string(content)
+		} else if !os.IsNotExist(err) {
+			// A missing file starts as an empty buffer, one that can't be
+			// read would be overwritten by it
+			return nil, fmt.Errorf("%w: %v", errCannotRead, err)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// isFlagSet reports whether the flag called name was given on the command line
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// buildVersion returns the version set at build time, or the module
+// version go install recorded
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
 }
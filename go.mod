@@ -2,4 +2,9 @@ module github.com/jellexet/golang-text-editor
 
 go 1.25.1
 
-require golang.org/x/sys v0.36.0
+require (
+	github.com/tetratelabs/wazero v1.12.0
+	github.com/yuin/gopher-lua v1.1.2
+	golang.org/x/sys v0.44.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -2,6 +2,7 @@ package buffer
 
 import (
 	"fmt"
+	"math/bits"
 )
 
 // Rope data structure - a binary tree
@@ -10,19 +11,33 @@ type Rope struct {
 	right  *Rope
 	data   string
 	weight int
+	hash   uint64 // polynomial hash of the content, see Hash
+	power  uint64 // hashBase to the power of the content length
 }
 
 const (
 	maxLeafLength = 8 // maximum length of a leaf node
+
+	// Content hashes are polynomials in hashBase modulo the Mersenne prime
+	// hashModulus, so the hash of a concatenation follows from the hashes
+	// of its parts
+	hashModulus = 1<<61 - 1
+	hashBase    = 257
 )
 
 // NewRope creates a new rope from a string
 func NewRope(s string) *Rope {
 	if len(s) <= maxLeafLength {
-		return &Rope{
+		r := &Rope{
 			data:   s,
 			weight: len(s),
+			power:  1,
+		}
+		for i := 0; i < len(s); i++ {
+			r.hash = addMod(mulMod(r.hash, hashBase), uint64(s[i])+1)
+			r.power = mulMod(r.power, hashBase)
 		}
+		return r
 	}
 
 	// Split the string in the middle
@@ -34,6 +49,8 @@ func NewRope(s string) *Rope {
 		left:   left,
 		right:  right,
 		weight: left.Length(),
+		hash:   addMod(mulMod(left.hash, right.power), right.hash),
+		power:  mulMod(left.power, right.power),
 	}
 }
 
@@ -108,7 +125,36 @@ func Concat(r1, r2 *Rope) *Rope {
 		left:   r1,
 		right:  r2,
 		weight: r1.Length(), // weight is total length of left subtree
+		hash:   addMod(mulMod(r1.hash, r2.power), r2.hash),
+		power:  mulMod(r1.power, r2.power),
+	}
+}
+
+// Hash returns a hash of the content of the rope. Ropes with the same
+// content have the same hash whatever their shape, and the hash of every
+// node is computed when it is built, so this is constant time.
+func (r *Rope) Hash() uint64 {
+	if r == nil {
+		return 0
 	}
+	return r.hash
+}
+
+// mulMod multiplies a and b modulo hashModulus
+func mulMod(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	// As 2^61 is congruent to 1, hi*2^64 + lo is to hi*8 + lo>>61 + lo&hashModulus
+	sum := hi<<3 | lo>>61
+	return addMod(sum%hashModulus, lo&hashModulus)
+}
+
+// addMod adds a and b, both below hashModulus, modulo hashModulus
+func addMod(a, b uint64) uint64 {
+	sum := a + b
+	if sum >= hashModulus {
+		sum -= hashModulus
+	}
+	return sum
 }
 
 // Split splits the rope at the given index into two ropes
@@ -250,6 +296,18 @@ func (r *Rope) Print(indent string) {
 	r.right.Print(indent + "  R:")
 }
 
+// Depth returns the number of levels of the tree, 1 for a single leaf.
+// Edits deepen it; Rebalance brings it back to the logarithm of the length.
+func (r *Rope) Depth() int {
+	if r == nil {
+		return 0
+	}
+	if r.isLeaf() {
+		return 1
+	}
+	return 1 + max(r.left.Depth(), r.right.Depth())
+}
+
 // Rebalance optimizes the rope structure
 func (r *Rope) Rebalance() *Rope {
 	if r == nil {
@@ -1,6 +1,7 @@
 package buffer
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -98,6 +99,55 @@ func TestRopeInsertDeleteEdgeCases(t *testing.T) {
 	}
 }
 
+func TestRopeHash(t *testing.T) {
+	text := "the quick brown fox jumps over the lazy dog"
+	whole := NewRope(text)
+	built := NewRope("")
+	for i := len(text) - 1; i >= 0; i-- {
+		var err error
+		if built, err = built.Insert(0, text[i:i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if whole.Hash() != built.Hash() {
+		t.Errorf("ropes of the same content have hashes %x and %x", whole.Hash(), built.Hash())
+	}
+
+	edited, _ := whole.Insert(4, "very ")
+	if edited.Hash() == whole.Hash() {
+		t.Error("an insertion should change the hash")
+	}
+	undone, _ := edited.Delete(4, 9)
+	if undone.Hash() != whole.Hash() {
+		t.Error("undoing the insertion should restore the hash")
+	}
+	swapped := NewRope("the quick brown fox jumps over the lazy god")
+	if swapped.Hash() == whole.Hash() {
+		t.Error("swapping two characters should change the hash")
+	}
+	var empty *Rope
+	if empty.Hash() != NewRope("").Hash() {
+		t.Error("a nil rope and an empty one should hash alike")
+	}
+}
+
+func TestRopeDepth(t *testing.T) {
+	text := strings.Repeat("abcdefgh", 64)
+	if got := NewRope(text).Depth(); got != 7 {
+		t.Errorf("a rope of 64 leaves is %d deep, want 7", got)
+	}
+	built := NewRope("")
+	for i := 0; i < len(text); i += 8 {
+		built, _ = built.Insert(built.Length(), text[i:i+8])
+	}
+	if built.Depth() <= 7 {
+		t.Errorf("appending should deepen the rope, depth %d", built.Depth())
+	}
+	if got := built.Rebalance(); got.Depth() != 7 || got.String() != text {
+		t.Errorf("rebalanced to depth %d", got.Depth())
+	}
+}
+
 // Simple fuzz test.
 func FuzzRopeOps(f *testing.F) {
 	f.Add([]byte("hello"))
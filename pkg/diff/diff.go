@@ -0,0 +1,119 @@
+package diff
+
+// Hunk is a run of changed lines: a[OldStart:OldStart+OldLines] was replaced
+// by b[NewStart:NewStart+NewLines]. Starts are zero-based.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+}
+
+// Lines compares two slices of lines and returns the hunks that turn a into b,
+// using Myers' O(ND) shortest edit script algorithm
+func Lines(a, b []string) []Hunk {
+	// Common prefix and suffix never take part in an edit, trimming them
+	// keeps the quadratic part of the algorithm small for typical edits
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	hunks := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for i := range hunks {
+		hunks[i].OldStart += prefix
+		hunks[i].NewStart += prefix
+	}
+	return hunks
+}
+
+// myers finds the shortest edit script between a and b and groups it into hunks
+func myers(a, b []string) []Hunk {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	if n == 0 || m == 0 {
+		return []Hunk{{OldLines: n, NewLines: m}}
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k (where k = x - y).
+	// A copy is kept per edit distance d to walk the path back afterwards.
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // step down: insertion from b
+			} else {
+				x = v[offset+k-1] + 1 // step right: deletion from a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk back from (n, m), recording which lines of a and b are kept
+	keptA := make([]bool, n)
+	keptB := make([]bool, m)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			keptA[x] = true
+			keptB[y] = true
+		}
+		x, y = prevX, prevY
+	}
+
+	// Consecutive lines that are not kept on either side form a hunk
+	var hunks []Hunk
+	i, j := 0, 0
+	for i < n || j < m {
+		if i < n && j < m && keptA[i] && keptB[j] {
+			i++
+			j++
+			continue
+		}
+		h := Hunk{OldStart: i, NewStart: j}
+		for i < n && !keptA[i] {
+			i++
+		}
+		for j < m && !keptB[j] {
+			j++
+		}
+		h.OldLines = i - h.OldStart
+		h.NewLines = j - h.NewStart
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
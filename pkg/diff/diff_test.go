@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds b from a and the hunks, which must always give back b
+func apply(a, b []string, hunks []Hunk) []string {
+	var out []string
+	i := 0
+	for _, h := range hunks {
+		out = append(out, a[i:h.OldStart]...)
+		out = append(out, b[h.NewStart:h.NewStart+h.NewLines]...)
+		i = h.OldStart + h.OldLines
+	}
+	return append(out, a[i:]...)
+}
+
+func TestLines(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+		want []Hunk
+	}{
+		{"equal", "a b c", "a b c", nil},
+		{"insert", "a c", "a b c", []Hunk{{1, 0, 1, 1}}},
+		{"delete", "a b c", "a c", []Hunk{{1, 1, 1, 0}}},
+		{"replace", "a b c", "a x c", []Hunk{{1, 1, 1, 1}}},
+		{"two hunks", "a b c d e", "x b c d y", []Hunk{{0, 1, 0, 1}, {4, 1, 4, 1}}},
+		{"from empty", "", "a b", []Hunk{{0, 0, 0, 2}}},
+		{"to empty", "a b", "", []Hunk{{0, 2, 0, 0}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := strings.Fields(c.a), strings.Fields(c.b)
+			got := Lines(a, b)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %+v want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func FuzzLines(f *testing.F) {
+	f.Add("a b c d", "a c d e")
+	f.Add("", "x")
+
+	f.Fuzz(func(t *testing.T, x, y string) {
+		a, b := strings.Split(x, ""), strings.Split(y, "")
+		got := apply(a, b, Lines(a, b))
+		if strings.Join(got, "\x00") != strings.Join(b, "\x00") || len(got) != len(b) {
+			t.Fatalf("applying the hunks of %q -> %q gave %q", x, y, got)
+		}
+	})
+}
+
+func TestUnified(t *testing.T) {
+	// lines splits s the way files are compared: every line keeps its newline
+	lines := func(s string) []string {
+		l := strings.SplitAfter(s, "\n")
+		if l[len(l)-1] == "" {
+			l = l[:len(l)-1]
+		}
+		return l
+	}
+	a := lines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+
+	cases := []struct {
+		name string
+		b    string
+		want string
+	}{
+		{"equal", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", ""},
+		{"change in the middle", "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+			"--- a\n+++ b\n@@ -3,5 +3,5 @@\n 3\n 4\n-5\n+five\n 6\n 7\n"},
+		{"close changes share context", "one\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+			"--- a\n+++ b\n@@ -1,7 +1,7 @@\n-1\n+one\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n"},
+		{"distant changes", "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			"--- a\n+++ b\n@@ -1,3 +1,3 @@\n-1\n+one\n 2\n 3\n@@ -8,3 +8,3 @@\n 8\n 9\n-10\n+ten\n"},
+		{"missing newline", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10",
+			"--- a\n+++ b\n@@ -8,3 +8,3 @@\n 8\n 9\n-10\n+10\n\\ No newline at end of file\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Unified("a", "b", a, lines(c.b), 2); got != c.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+
+	if got := Unified("a", "b", nil, lines("x\n"), 3); got != "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n" {
+		t.Fatalf("diff from an empty file:\n%s", got)
+	}
+}
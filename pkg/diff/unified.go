@@ -0,0 +1,71 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unified formats the differences between a and b as a unified diff with
+// context lines of context around each change. The lines must keep their
+// trailing newline, so that a missing one at the end of a file shows.
+// It returns an empty string when a and b are equal.
+func Unified(oldName, newName string, a, b []string, context int) string {
+	hunks := Lines(a, b)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	for len(hunks) > 0 {
+		// Hunks closer than twice the context share their context lines
+		n := 1
+		for n < len(hunks) && hunks[n].OldStart-(hunks[n-1].OldStart+hunks[n-1].OldLines) <= 2*context {
+			n++
+		}
+		writeGroup(&buf, a, b, hunks[:n], context)
+		hunks = hunks[n:]
+	}
+	return buf.String()
+}
+
+// writeGroup writes one @@ section covering the hunks of group
+func writeGroup(buf *strings.Builder, a, b []string, group []Hunk, context int) {
+	first, last := group[0], group[len(group)-1]
+	oldStart := max(first.OldStart-context, 0)
+	oldEnd := min(last.OldStart+last.OldLines+context, len(a))
+	// Outside the hunks both sides are equal, so they share the offsets
+	newStart := first.NewStart - (first.OldStart - oldStart)
+	newEnd := last.NewStart + last.NewLines + (oldEnd - (last.OldStart + last.OldLines))
+
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(oldStart, oldEnd-oldStart), hunkRange(newStart, newEnd-newStart))
+	pos := oldStart
+	for _, h := range group {
+		writeLines(buf, " ", a[pos:h.OldStart])
+		writeLines(buf, "-", a[h.OldStart:h.OldStart+h.OldLines])
+		writeLines(buf, "+", b[h.NewStart:h.NewStart+h.NewLines])
+		pos = h.OldStart + h.OldLines
+	}
+	writeLines(buf, " ", a[pos:oldEnd])
+}
+
+// hunkRange formats a start and length the way diff does: an empty range
+// is addressed by the line before it
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func writeLines(buf *strings.Builder, prefix string, lines []string) {
+	for _, line := range lines {
+		buf.WriteString(prefix + line)
+		if !strings.HasSuffix(line, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
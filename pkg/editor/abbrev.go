@@ -0,0 +1,101 @@
+package editor
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// abbreviations maps words to the text they expand to when typed, set with
+// :abbrev and in the [abbreviations] section of the config file
+var abbreviations = map[string]string{}
+
+// abbreviationEscapes turns the escapes of an expansion into what they
+// stand for, for expansions of several lines
+var abbreviationEscapes = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\\`, `\`)
+
+// setAbbreviation makes word expand to expansion, in which \n and \t stand
+// for a line break and a tab
+func setAbbreviation(word, expansion string) error {
+	for i := 0; i < len(word); i++ {
+		if !isIdentifierByte(word[i]) {
+			return fmt.Errorf("abbreviation %q is not a word", word)
+		}
+	}
+	if word == "" || expansion == "" {
+		return fmt.Errorf("usage: abbrev word expansion")
+	}
+	abbreviations[word] = abbreviationEscapes.Replace(expansion)
+	return nil
+}
+
+// endsAbbreviation reports whether c, typed after a word, expands it: a
+// space or a punctuation character that can't be part of a word
+func endsAbbreviation(c byte) bool {
+	return c == ' ' || !isIdentifierByte(c) && (unicode.IsPunct(rune(c)) || unicode.IsSymbol(rune(c)))
+}
+
+// expandAbbreviation replaces the word before the cursor by its expansion,
+// when it is an abbreviation. The expansion is an undo step of its own, so
+// that Ctrl-Z gets the word back.
+func expandAbbreviation() {
+	if len(abbreviations) == 0 || session.rope == nil {
+		return
+	}
+	lineStart := getLineStartIndex(session.cursorRow)
+	before, err := session.rope.Substring(lineStart, session.cursorIdx)
+	if err != nil {
+		return
+	}
+	start := len(before)
+	for start > 0 && isIdentifierByte(before[start-1]) {
+		start--
+	}
+	if expansion, ok := abbreviations[before[start:]]; ok {
+		replaceText(lineStart+start, session.cursorIdx, expansion)
+	}
+}
+
+// handleAbbrev lists the abbreviations, shows one or sets one (:abbrev
+// [word [expansion]])
+func handleAbbrev(fd int, args string, callback func() byte) {
+	word, expansion, _ := strings.Cut(strings.TrimSpace(args), " ")
+	expansion = strings.TrimSpace(expansion)
+	switch {
+	case word == "":
+		if len(abbreviations) == 0 {
+			session.statusMessage = "No abbreviations"
+			return
+		}
+		var b strings.Builder
+		for _, word := range slices.Sorted(maps.Keys(abbreviations)) {
+			fmt.Fprintf(&b, "%-12s %s\n", word, strings.ReplaceAll(abbreviations[word], "\n", `\n`))
+		}
+		showScratch("[Abbreviations]", b.String())
+	case expansion == "":
+		if text, ok := abbreviations[word]; ok {
+			session.statusMessage = fmt.Sprintf("%s -> %s", word, strings.ReplaceAll(text, "\n", `\n`))
+		} else {
+			session.statusMessage = "No abbreviation " + word
+		}
+	default:
+		if err := setAbbreviation(word, expansion); err != nil {
+			session.statusMessage = "Abbrev: " + err.Error()
+			return
+		}
+		session.statusMessage = fmt.Sprintf("%s -> %s", word, expansion)
+	}
+}
+
+// handleUnabbrev removes an abbreviation (:unabbrev word)
+func handleUnabbrev(fd int, args string, callback func() byte) {
+	word := strings.TrimSpace(args)
+	if _, ok := abbreviations[word]; !ok {
+		session.statusMessage = "No abbreviation " + word
+		return
+	}
+	delete(abbreviations, word)
+	session.statusMessage = "Removed abbreviation " + word
+}
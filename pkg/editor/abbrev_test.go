@@ -0,0 +1,76 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// useAbbreviations gives the test abbreviations of its own
+func useAbbreviations(t *testing.T) {
+	old := abbreviations
+	abbreviations = map[string]string{}
+	t.Cleanup(func() { abbreviations = old })
+}
+
+func TestAbbreviationsExpand(t *testing.T) {
+	resetSessionForTest()
+	useAbbreviations(t)
+	runCommand(0, "abbrev teh the", nil)
+	runCommand(0, `ab sig Regards,\nAnn`, nil)
+
+	playKeys(t, "teh cat, tehx teh.\rsig\r")
+	if got := session.rope.String(); got != "the cat, tehx the.\nRegards,\nAnn\n" {
+		t.Fatalf("got %q", got)
+	}
+
+	// Ctrl-Z after the space takes it back, then the expansion
+	handleUndo()
+	handleUndo()
+	if got := session.rope.String(); got != "the cat, tehx the.\nsig" {
+		t.Errorf("undo left %q", got)
+	}
+}
+
+func TestAbbreviationLiteral(t *testing.T) {
+	resetSessionForTest()
+	useAbbreviations(t)
+	runCommand(0, "abbrev teh the", nil)
+
+	// Alt-Space types the space without expanding
+	playKeys(t, "teh\x1b teh ")
+	if got := session.rope.String(); got != "teh the " {
+		t.Errorf("got %q", got)
+	}
+
+	runCommand(0, "unabbrev teh", nil)
+	playKeys(t, "teh ")
+	if got := session.rope.String(); got != "teh the teh " {
+		t.Errorf("removed abbreviation still expanded: %q", got)
+	}
+}
+
+func TestAbbreviationsInConfig(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	useAbbreviations(t)
+	path := filepath.Join(t.TempDir(), "config")
+	config := "tabsize = 4\n[abbreviations]\nteh = the\nsig = -- \\nAnn\n"
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if abbreviations["teh"] != "the" || abbreviations["sig"] != "-- \nAnn" {
+		t.Errorf("got %q", abbreviations)
+	}
+
+	session.rope = buffer.NewRope("")
+	runCommand(0, "abbrev a-b c", nil)
+	if _, ok := abbreviations["a-b"]; ok || session.statusMessage == "" {
+		t.Errorf("only words can be abbreviations, status %q", session.statusMessage)
+	}
+}
@@ -0,0 +1,58 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// screenReader turns on the screen reader mode (:set screenreader on): the
+// screen changes as little as possible, so that a screen reader following
+// the terminal reads what changed rather than whole rows, and the status
+// bar only changes to announce something
+var screenReader bool
+
+// announcement is the last status message, kept on the status bar in
+// screen reader mode until the next one
+var announcement string
+
+// screenReaderStatus is the status bar in screen reader mode: the last
+// message, or the file name until there is one. The cursor position is
+// left out, as it would be read after every key.
+func screenReaderStatus() string {
+	if session.statusMessage != "" {
+		announcement = session.statusMessage
+		session.statusMessage = ""
+	}
+	if announcement != "" {
+		return announcement
+	}
+	return "File: " + statusFileName()
+}
+
+// handleSpeakLine announces the cursor line with its number, for a screen
+// reader to read it (:speak, Alt-L)
+func handleSpeakLine() {
+	row := max(session.cursorRow, 1)
+	start, end := lineBounds(lineStarts(), row-1)
+	line, _ := session.rope.Substring(start, end)
+	if strings.TrimSpace(line) == "" {
+		line = "blank"
+	}
+	session.statusMessage = fmt.Sprintf("Line %d: %s", row, line)
+}
+
+// changedSpan returns the first and last cells that differ between two rows
+// of the same width, and false when they are equal
+func changedSpan(shown, row []cell) (first, last int, changed bool) {
+	first, last = 0, len(row)-1
+	for first < len(row) && row[first] == shown[first] {
+		first++
+	}
+	if first == len(row) {
+		return 0, 0, false
+	}
+	for row[last] == shown[last] {
+		last--
+	}
+	return first, last, true
+}
@@ -0,0 +1,64 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func useScreenReader(t *testing.T) {
+	screenReader = true
+	t.Cleanup(func() { screenReader, announcement = false, "" })
+}
+
+func TestScreenReaderDrawsChangedCells(t *testing.T) {
+	useScreenReader(t)
+	invalidateFrame()
+	t.Cleanup(invalidateFrame)
+
+	frameUpdate("hello world\r\nsecond", 2, 20, 1, 1)
+	next := frameUpdate("hello there\r\nsecond", 2, 20, 1, 12)
+	if want := "\x1b[?25l\x1b[1;7Hthere\x1b[1;12H\x1b[?25h"; next != want {
+		t.Errorf("update %q, want %q", next, want)
+	}
+	// Erased cells are written as blanks
+	if next := frameUpdate("hello\r\nsecond", 2, 20, 1, 6); !strings.Contains(next, "\x1b[1;7H     \x1b") {
+		t.Errorf("erasing the end of a row gave %q", next)
+	}
+}
+
+func TestScreenReaderStatus(t *testing.T) {
+	resetSessionForTest()
+	useScreenReader(t)
+	session.filename = "notes.txt"
+
+	if got := screenReaderStatus(); got != "File: notes.txt" {
+		t.Errorf("status before any message %q", got)
+	}
+	session.statusMessage = "Saved"
+	screenReaderStatus()
+	// Moving the cursor changes nothing for the reader to read again
+	session.cursorRow, session.cursorCol = 5, 3
+	if got := screenReaderStatus(); got != "Saved" {
+		t.Errorf("the last message should stay, got %q", got)
+	}
+}
+
+func TestSpeakLine(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("first\n\nthird line")
+	session.cursorIdx = 8
+	updateCursorPosition()
+
+	runCommand(0, "speak", nil)
+	if session.statusMessage != "Line 3: third line" {
+		t.Errorf("spoke %q", session.statusMessage)
+	}
+	session.cursorIdx = 6
+	updateCursorPosition()
+	handleSpeakLine()
+	if session.statusMessage != "Line 2: blank" {
+		t.Errorf("spoke %q", session.statusMessage)
+	}
+}
@@ -0,0 +1,170 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// padRight pads s with fill up to width characters
+func padRight(s string, width int, fill string) string {
+	return s + strings.Repeat(fill, max(width-utf8.RuneCountInString(s), 0))
+}
+
+// alignFirst aligns the first delim of every line that has one, padding
+// the text before it with spaces
+func alignFirst(lines []string, delim string) []string {
+	width := 0
+	for _, line := range lines {
+		if before, _, ok := strings.Cut(line, delim); ok {
+			width = max(width, utf8.RuneCountInString(strings.TrimRight(before, " \t")))
+		}
+	}
+	aligned := make([]string, len(lines))
+	for i, line := range lines {
+		before, after, ok := strings.Cut(line, delim)
+		if !ok {
+			aligned[i] = line
+			continue
+		}
+		aligned[i] = padRight(strings.TrimRight(before, " \t"), width, " ") + " " + delim
+		if after = strings.TrimLeft(after, " \t"); after != "" {
+			aligned[i] += " " + after
+		}
+	}
+	return aligned
+}
+
+// isTableRule reports whether a table cell is a header separator like ":--"
+func isTableRule(cell string) bool {
+	return cell != "" && strings.Trim(cell, "-:") == ""
+}
+
+// tableRule returns a separator cell of width dashes, keeping the colons
+// that set the alignment of the column
+func tableRule(cell string, width int) string {
+	rule := strings.Repeat("-", width)
+	if strings.HasPrefix(cell, ":") {
+		rule = ":" + rule[1:]
+	}
+	if len(cell) > 1 && strings.HasSuffix(cell, ":") {
+		rule = rule[:width-1] + ":"
+	}
+	return rule
+}
+
+// alignColumns aligns every delim of the lines, like the columns of a
+// markdown table. Separator cells made of dashes stay made of dashes.
+func alignColumns(lines []string, delim string) []string {
+	var widths []int
+	rows := make([][]string, len(lines))
+	for i, line := range lines {
+		if !strings.Contains(line, delim) {
+			continue
+		}
+		rows[i] = strings.Split(line, delim)
+		// The first cell holds the indentation, the last what follows the row
+		for j := 1; j < len(rows[i])-1; j++ {
+			rows[i][j] = strings.TrimSpace(rows[i][j])
+			if j-1 == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j-1] = max(widths[j-1], utf8.RuneCountInString(rows[i][j]))
+		}
+	}
+
+	aligned := make([]string, len(lines))
+	for i, cells := range rows {
+		if cells == nil {
+			aligned[i] = lines[i]
+			continue
+		}
+		var b strings.Builder
+		b.WriteString(cells[0])
+		for j := 1; j < len(cells)-1; j++ {
+			b.WriteString(delim)
+			if isTableRule(cells[j]) {
+				b.WriteString(tableRule(cells[j], widths[j-1]+2))
+			} else {
+				b.WriteString(" " + padRight(cells[j], widths[j-1], " ") + " ")
+			}
+		}
+		b.WriteString(delim + cells[len(cells)-1])
+		aligned[i] = b.String()
+	}
+	return aligned
+}
+
+// alignRows returns the first and last (0-based) lines to align: those of
+// the selection, or the block of non-blank lines around the cursor
+func alignRows(lines []string) (first, last int) {
+	if start, end, ok := selection(); ok {
+		first, last = -1, 0
+		idx := 0
+		for i, line := range lines {
+			lineEnd := idx + len(line)
+			if first < 0 && start <= lineEnd {
+				first = i
+			}
+			// A selection ending at the start of a line doesn't include it
+			if idx < end {
+				last = i
+			}
+			idx = lineEnd + 1
+		}
+		return first, last
+	}
+	first, last = session.cursorRow-1, session.cursorRow-1
+	for first > 0 && strings.TrimSpace(lines[first-1]) != "" {
+		first--
+	}
+	for last < len(lines)-1 && strings.TrimSpace(lines[last+1]) != "" {
+		last++
+	}
+	return first, last
+}
+
+// handleAlign pads the selected lines, or the paragraph of the cursor, so
+// that a delimiter lines up (:align DELIM). "|" aligns every column of a
+// table, other delimiters only their first occurrence.
+func handleAlign(fd int, args string, callback func() byte) {
+	if args == "" {
+		session.statusMessage = "Usage: align DELIMITER (e.g. align =)"
+		return
+	}
+	if !editable() {
+		return
+	}
+	lines := getLines()
+	first, last := alignRows(lines)
+	block := lines[first : last+1]
+	var aligned []string
+	if args == "|" {
+		aligned = alignColumns(block, args)
+	} else {
+		aligned = alignFirst(block, args)
+	}
+
+	n := 0
+	for _, line := range block {
+		if strings.Contains(line, args) {
+			n++
+		}
+	}
+	if n == 0 {
+		session.statusMessage = "No line to align has " + args
+		return
+	}
+
+	start := getLineStartIndex(first + 1)
+	old := strings.Join(block, "\n")
+	text := strings.Join(aligned, "\n")
+	if text != old {
+		_, _, selected := selection()
+		replaceText(start, start+len(old), text)
+		if selected {
+			selectRange(start, start+len(text))
+		}
+	}
+	session.statusMessage = fmt.Sprintf("Aligned %d lines on %s", n, args)
+}
@@ -0,0 +1,59 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestAlignFirst(t *testing.T) {
+	got := alignFirst([]string{"\ta = 1", "\tlonger=2", "no delimiter", "\tb := x == y"}, "=")
+	want := []string{"\ta      = 1", "\tlonger = 2", "no delimiter", "\tb :    = x == y"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAlignColumns(t *testing.T) {
+	got := alignColumns([]string{"| Name | Size |", "|---|:-:|", "| editor.go | 12 |"}, "|")
+	want := []string{
+		"| Name      | Size |",
+		"|-----------|:----:|",
+		"| editor.go | 12   |",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHandleAlign(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("x = 1\nlong = 2\n\nother = 3\ny = 4\n")
+	session.cursorIdx = 0
+	updateCursorPosition()
+
+	// Without a selection the paragraph of the cursor is aligned
+	runCommand(0, "align =", nil)
+	want := "x    = 1\nlong = 2\n\nother = 3\ny = 4\n"
+	if got := session.rope.String(); got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if session.statusMessage != "Aligned 2 lines on =" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+
+	// The selection ends at the start of the line after y = 4
+	start := strings.Index(session.rope.String(), "other")
+	selectRange(start, session.rope.Length())
+	runCommand(0, "align =", nil)
+	want = "x    = 1\nlong = 2\n\nother = 3\ny     = 4\n"
+	if got := session.rope.String(); got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	runCommand(0, "align :", nil)
+	if session.statusMessage != "No line to align has :" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+}
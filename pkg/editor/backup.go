@@ -0,0 +1,137 @@
+package editor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Timestamped copies of the named buffers are written to backupDir every
+// backupInterval while they change, keeping the last backupKeep of each file
+var (
+	backupDir      = "" // empty turns backups off
+	backupInterval = time.Minute
+	backupKeep     = 10
+)
+
+// backupTimeFormat sorts backups of the same file by age
+const backupTimeFormat = "20060102-150405"
+
+// lastBackup is when the buffers were last checked for backups
+var lastBackup = time.Now()
+
+// expandHome replaces a leading "~" of path by the home directory
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/') {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + rest
+}
+
+// backupPrefix returns the start of the names of the backups of filename:
+// its absolute path with '%' for '/', so that files of different
+// directories don't mix
+func backupPrefix(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		abs = filename
+	}
+	return strings.ReplaceAll(abs, string(filepath.Separator), "%") + "."
+}
+
+// listBackups returns the backups of filename, oldest first
+func listBackups(filename string) ([]string, error) {
+	dir := expandHome(backupDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := backupPrefix(filename)
+	var backups []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) {
+			backups = append(backups, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// writeBackup writes the content of s to a new backup and removes the
+// oldest ones above backupKeep
+func writeBackup(s *Session, now time.Time) error {
+	dir := expandHome(backupDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, backupPrefix(s.filename)+now.Format(backupTimeFormat))
+	if err := os.WriteFile(name, []byte(s.rope.String()), 0600); err != nil {
+		return err
+	}
+	log.Printf("backed up %s to %s", s.filename, name)
+
+	backups, err := listBackups(s.filename)
+	if err != nil {
+		return err
+	}
+	for len(backups) > backupKeep {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// backupBuffers backs up the buffers that changed since their last backup,
+// once every backupInterval. It is one of the idle tasks, run while no
+// key comes.
+func backupBuffers(now time.Time) {
+	if backupDir == "" || now.Sub(lastBackup) < backupInterval {
+		return
+	}
+	lastBackup = now
+	for _, s := range buffers {
+		if s.rope == s.backupRope || isUnnamed(s.filename) {
+			continue
+		}
+		if err := writeBackup(s, now); err != nil {
+			session.statusMessage = fmt.Sprintf("Backup of %s failed: %v", s.filename, err)
+			continue
+		}
+		s.backupRope = s.rope
+	}
+}
+
+// handleBackups lists the backups of the buffer, newest first, in an
+// output buffer from which they can be opened with :e (:backups)
+func handleBackups(fd int, args string, callback func() byte) {
+	if backupDir == "" {
+		session.statusMessage = "Backups are off (:set backupdir DIR)"
+		return
+	}
+	backups, err := listBackups(session.filename)
+	if err != nil && !os.IsNotExist(err) {
+		session.statusMessage = fmt.Sprintf("Backups: %v", err)
+		return
+	}
+	if len(backups) == 0 {
+		session.statusMessage = "No backups of " + session.filename
+		return
+	}
+	var list strings.Builder
+	for i := len(backups) - 1; i >= 0; i-- {
+		list.WriteString(backups[i] + "\n")
+	}
+	showScratch("[Backups]", list.String())
+	session.statusMessage = "Open a backup with :e FILE, Ctrl-T goes back"
+}
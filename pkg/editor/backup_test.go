@@ -0,0 +1,75 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestBackupRotation(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	t.Chdir(t.TempDir())
+	runCommand(0, "set backupdir "+filepath.Join(t.TempDir(), "backups"), nil)
+	runCommand(0, "set backupkeep 2", nil)
+	session = newSession("notes.txt", "v0")
+	buffers = []*Session{session}
+	AddBuffer("other.txt", "unchanged", 0, 0)
+
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	lastBackup = start
+	for i, content := range []string{"v1", "v2", "v3"} {
+		session.rope = buffer.NewRope(content)
+		backupBuffers(start.Add(time.Duration(i+1) * backupInterval))
+	}
+	// Nothing changed since the last backup
+	backupBuffers(start.Add(10 * backupInterval))
+
+	backups, err := listBackups("notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected the last 2 backups, got %v", backups)
+	}
+	if !strings.HasSuffix(backups[1], ".20261016-090300") {
+		t.Errorf("unexpected backup name %s", backups[1])
+	}
+	for i, want := range []string{"v2", "v3"} {
+		if got, _ := os.ReadFile(backups[i]); string(got) != want {
+			t.Errorf("backup %d holds %q, want %q", i, got, want)
+		}
+	}
+	if other, _ := listBackups("other.txt"); len(other) != 0 {
+		t.Errorf("unchanged buffers are not backed up: %v", other)
+	}
+
+	runCommand(0, "backups", nil)
+	if session.filename != "[Backups]" || !strings.HasPrefix(session.rope.String(), backups[1]+"\n") {
+		t.Errorf("expected the newest backup first in %s, got %q", session.filename, session.rope.String())
+	}
+}
+
+func TestBackupsOff(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	backupDir = ""
+	session = newSession(filepath.Join(t.TempDir(), "a.txt"), "")
+	session.rope = buffer.NewRope("changed")
+	lastBackup = time.Time{}
+	backupBuffers(time.Now())
+	if session.backupRope == session.rope {
+		t.Error("no backup should be written when backupdir is empty")
+	}
+
+	if err := Set("backupinterval", "10ms"); err == nil {
+		t.Error("intervals under a second should be refused")
+	}
+	if err := Set("backupinterval", "5m"); err != nil || backupInterval != 5*time.Minute {
+		t.Errorf("backupinterval 5m: %v, %v", err, backupInterval)
+	}
+}
@@ -0,0 +1,159 @@
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// blameLine is what git blame knows about one line of the buffer
+type blameLine struct {
+	hash    string
+	author  string
+	time    time.Time
+	summary string
+}
+
+// blameCache holds the blame of one buffer content. Ropes are never
+// modified in place, so the rope pointer identifies the content it was run on.
+type blameCache struct {
+	rope  *buffer.Rope
+	lines []blameLine
+	err   error
+}
+
+// blameStatusEnabled adds the blame of the cursor line to the status bar
+var blameStatusEnabled bool
+
+// runBlame runs git blame on the buffer content, so unsaved lines show up
+// as not committed instead of shifting every line below them
+func runBlame(filename, content string) ([]blameLine, error) {
+	out, err := gitOutput(filepath.Dir(filename), []byte(content),
+		"blame", "--line-porcelain", "--contents", "-", "--", filepath.Base(filename))
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame([]byte(out)), nil
+}
+
+// parseBlame reads the output of git blame --line-porcelain, where every line
+// of the file is preceded by a "<hash> <orig> <final>" header and its metadata
+func parseBlame(out []byte) []blameLine {
+	var lines []blameLine
+	var current blameLine
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "\t") {
+			lines = append(lines, current)
+			current = blameLine{}
+			continue
+		}
+
+		key, value, _ := strings.Cut(text, " ")
+		switch key {
+		case "author":
+			current.author = value
+		case "author-time":
+			if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.time = time.Unix(secs, 0)
+			}
+		case "summary":
+			current.summary = value
+		default:
+			if current.hash == "" && len(key) == 40 {
+				current.hash = key[:8]
+			}
+		}
+	}
+	return lines
+}
+
+// firstLine returns s up to its first newline
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// cursorBlame returns the blame of the cursor line, rerunning git blame
+// only when the buffer changed since the last run
+func cursorBlame() (blameLine, error) {
+	if session.blame == nil || session.blame.rope != session.rope {
+		lines, err := runBlame(session.filename, session.rope.String())
+		session.blame = &blameCache{rope: session.rope, lines: lines, err: err}
+	}
+	if session.blame.err != nil {
+		return blameLine{}, session.blame.err
+	}
+	if session.cursorRow-1 >= len(session.blame.lines) {
+		return blameLine{}, fmt.Errorf("no blame for line %d", session.cursorRow)
+	}
+	return session.blame.lines[session.cursorRow-1], nil
+}
+
+// formatBlame renders a blame line the way the status bar shows it
+func formatBlame(b blameLine) string {
+	if strings.Trim(b.hash, "0") == "" {
+		return "Not committed yet"
+	}
+	return fmt.Sprintf("%s %s %s %s", b.hash, b.author, b.time.Format("2006-01-02"), b.summary)
+}
+
+// blameStatusSegment is the status bar text for the always-on blame. It
+// never runs git: it shows the last blame of the buffer, which refreshBlame
+// brings up to date while the user isn't typing.
+func blameStatusSegment() string {
+	c := session.blame
+	if c == nil || c.err != nil || session.cursorRow-1 >= len(c.lines) {
+		return ""
+	}
+	return formatBlame(c.lines[session.cursorRow-1])
+}
+
+// refreshBlame reruns git blame in the background when the status bar
+// shows the blame of the active buffer and it was edited since. Unlike
+// background, it keeps a result made stale by edits meanwhile: the status
+// bar shows it until the next run.
+func refreshBlame() {
+	s := session
+	if !blameStatusEnabled || s.largeFile || s.loading != nil || s.blaming || s.blame != nil && s.blame.rope == s.rope {
+		return
+	}
+	s.blaming = true
+	filename, rope := s.filename, s.rope
+	go func() {
+		lines, err := runBlame(filename, rope.String())
+		post(func() {
+			s.blaming = false
+			s.blame = &blameCache{rope: rope, lines: lines, err: err}
+		})
+	}()
+}
+
+// handleBlame shows who last changed the cursor line (:blame), or turns the
+// status bar segment on and off (:blame on, :blame off)
+func handleBlame(fd int, args string, callback func() byte) {
+	switch args {
+	case "on":
+		blameStatusEnabled = true
+		refreshBlame()
+		return
+	case "off":
+		blameStatusEnabled = false
+		return
+	}
+
+	b, err := cursorBlame()
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Blame: %v", err)
+		return
+	}
+	session.statusMessage = formatBlame(b)
+}
@@ -0,0 +1,112 @@
+package editor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestParseBlame(t *testing.T) {
+	out := strings.Join([]string{
+		"1234567890abcdef1234567890abcdef12345678 1 1 1",
+		"author Jane Doe",
+		"author-time 1700000000",
+		"summary Add the first line",
+		"filename a.txt",
+		"\tfirst",
+		"0000000000000000000000000000000000000000 2 2 1",
+		"author Not Committed Yet",
+		"summary Version of a.txt from -",
+		"\tsecond",
+	}, "\n")
+
+	lines := parseBlame([]byte(out))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines got %d", len(lines))
+	}
+	if lines[0].hash != "12345678" || lines[0].author != "Jane Doe" || lines[0].summary != "Add the first line" {
+		t.Fatalf("unexpected first line %+v", lines[0])
+	}
+	if got := formatBlame(lines[1]); got != "Not committed yet" {
+		t.Fatalf("uncommitted line shown as %q", got)
+	}
+}
+
+// gitTestRepo creates a repository holding one committed file
+func gitTestRepo(t *testing.T, name, content string) (dir, file string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir = t.TempDir()
+	file = filepath.Join(dir, name)
+	os.WriteFile(file, []byte(content), 0644)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", name},
+		{"-c", "user.name=Jane", "-c", "user.email=jane@example.com", "commit", "-q", "-m", "Initial"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	return dir, file
+}
+
+func TestCursorBlame_UsesGitAndCaches(t *testing.T) {
+	_, file := gitTestRepo(t, "a.txt", "one\ntwo\n")
+
+	resetSessionForTest()
+	session.filename = file
+	session.rope = buffer.NewRope("new\none\ntwo\n")
+	session.cursorIdx = 4 // second line, committed as the first
+	updateCursorPosition()
+
+	b, err := cursorBlame()
+	if err != nil {
+		t.Fatalf("blame: %v", err)
+	}
+	if b.author != "Jane" || b.summary != "Initial" {
+		t.Fatalf("unexpected blame %+v", b)
+	}
+	cached := session.blame
+	cursorBlame()
+	if session.blame != cached {
+		t.Fatalf("blame should be cached while the buffer is unchanged")
+	}
+}
+
+func TestBlameStatusRefreshesInBackground(t *testing.T) {
+	_, file := gitTestRepo(t, "a.txt", "one\ntwo\n")
+
+	resetSessionForTest()
+	session.filename = file
+	session.rope = buffer.NewRope("one\ntwo\n")
+	updateCursorPosition()
+	blameStatusEnabled = true
+	t.Cleanup(func() { blameStatusEnabled = false })
+
+	refreshBlame()
+	waitFor(t, func() bool { return !session.blaming })
+	if got := blameStatusSegment(); !strings.Contains(got, "Jane") {
+		t.Fatalf("status shows %q", got)
+	}
+
+	// An edit keeps the last blame on show, without running git
+	handleInsert("x")
+	if session.blaming || !strings.Contains(blameStatusSegment(), "Jane") {
+		t.Fatalf("the edit should show the last blame, status %q", blameStatusSegment())
+	}
+	pruneCaches()
+	refreshBlame()
+	if !session.blaming || session.blame == nil {
+		t.Fatal("expected the stale blame kept while git blame runs again")
+	}
+	waitFor(t, func() bool { return !session.blaming })
+	if session.blame.rope != session.rope {
+		t.Error("the blame was not brought up to date")
+	}
+}
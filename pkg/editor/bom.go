@@ -0,0 +1,30 @@
+package editor
+
+import "strings"
+
+// utf8BOM is the byte order mark some Windows programs put at the start of
+// UTF-8 files
+const utf8BOM = "\ufeff"
+
+// keepBOM writes the byte order mark back when saving a file that had one;
+// off, saving removes it (:set keepbom off)
+var keepBOM = true
+
+// stripBOM returns content without its byte order mark, and whether it had
+// one. The mark is kept out of the buffer, where it would show as a stray
+// character at the top of the file.
+func stripBOM(content string) (string, bool) {
+	if rest, ok := strings.CutPrefix(content, utf8BOM); ok {
+		return rest, true
+	}
+	return content, false
+}
+
+// fileContent returns what saving buffer s writes to its file: content,
+// with the byte order mark the file was read with when keepbom is on
+func fileContent(s *Session, content string) string {
+	if s.bom && keepBOM {
+		return utf8BOM + content
+	}
+	return content
+}
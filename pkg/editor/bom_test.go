@@ -0,0 +1,61 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestByteOrderMark(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	name := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(name, []byte(utf8BOM+"hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buffers = nil
+	if err := openBuffer(name); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := session.rope.String(); got != "hello\n" {
+		t.Fatalf("the buffer holds %q", got)
+	}
+	if got := statusFileName(); !strings.HasSuffix(got, " [BOM]") {
+		t.Errorf("status shows %q", got)
+	}
+
+	// Saving writes the mark back
+	replaceText(0, 0, "> ")
+	runCommand(0, "w", nil)
+	if got, _ := os.ReadFile(name); string(got) != utf8BOM+"> hello\n" {
+		t.Fatalf("saved %q", got)
+	}
+
+	// Unless keepbom is off, which drops it
+	runCommand(0, "set keepbom off", nil)
+	runCommand(0, "w", nil)
+	if got, _ := os.ReadFile(name); string(got) != "> hello\n" {
+		t.Fatalf("saved %q without keepbom", got)
+	}
+	if session.bom || strings.Contains(statusFileName(), "BOM") {
+		t.Error("the buffer should no longer have a byte order mark")
+	}
+}
+
+func TestStripBOM(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+		bom      bool
+	}{
+		{utf8BOM + "text", "text", true},
+		{"text", "text", false},
+		{"a" + utf8BOM, "a" + utf8BOM, false},
+		{"", "", false},
+	} {
+		if got, bom := stripBOM(tc.in); got != tc.want || bom != tc.bom {
+			t.Errorf("stripBOM(%q) = %q, %v", tc.in, got, bom)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package editor
+
+import "fmt"
+
+// AddBuffer opens another buffer holding content behind the active one,
+// with the cursor at a 1-indexed line and column when line isn't 0
+func AddBuffer(filename string, content string, line, col int) {
+	s := newSession(filename, content)
+	s.screenRows = session.screenRows
+	s.screenCols = session.screenCols
+	buffers = append(buffers, s)
+	fireHooks(eventBufOpen, s)
+	if line != 0 {
+		s.cursorIdx = lineColumnIndex(content, line, col)
+	}
+}
+
+// cycleBuffer makes the buffer delta places after the active one active
+func cycleBuffer(delta int) {
+	if len(buffers) < 2 {
+		session.statusMessage = "No other buffer"
+		return
+	}
+	next := (indexOfBuffer(session) + delta + len(buffers)) % len(buffers)
+	switchToBuffer(buffers[next])
+	session.statusMessage = fmt.Sprintf("Buffer %d/%d: %s", next+1, len(buffers), session.filename)
+}
+
+// indexOfBuffer returns the position of target in the buffer list, or -1
+func indexOfBuffer(target *Session) int {
+	for i, b := range buffers {
+		if b == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// handleBufferNext switches to the next open buffer (:bn)
+func handleBufferNext(fd int, args string, callback func() byte) {
+	cycleBuffer(1)
+}
+
+// handleBufferPrev switches to the previous open buffer (:bp)
+func handleBufferPrev(fd int, args string, callback func() byte) {
+	cycleBuffer(-1)
+}
+
+// handleBufferList picks an open buffer from a list (:buffers)
+func handleBufferList(fd int, args string, callback func() byte) {
+	items := make([]string, len(buffers))
+	for i, b := range buffers {
+		items[i] = b.filename
+	}
+	choice := fuzzyPickFromList(fd, "Buffer", items, callback)
+	if choice < 0 {
+		return
+	}
+	switchToBuffer(buffers[choice])
+}
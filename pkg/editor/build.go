@@ -0,0 +1,154 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// quickfixEntry is a location reported by a build command
+type quickfixEntry struct {
+	filename string
+	line     int // 1-indexed
+	col      int // 1-indexed, 0 when the tool reported none
+	message  string
+}
+
+// quickfixList holds the errors of the last build; quickfixIdx is the one
+// the cursor was last moved to
+var (
+	quickfixList []quickfixEntry
+	quickfixIdx  int
+)
+
+// buildCommand is the command run by :make, remembered once given as argument
+var buildCommand string
+
+// errorLinePattern matches "file:line: message" and "file:line:col: message",
+// the format of go build, gcc, clang, rustc (after "-->") and most linters
+var errorLinePattern = regexp.MustCompile(`^\s*(?:--> )?([^\s:][^:]*):(\d+)(?::(\d+))?:?\s*(.*)$`)
+
+// defaultBuildCommand guesses the build command of the working directory
+func defaultBuildCommand() string {
+	if _, err := os.Stat("go.mod"); err == nil {
+		return "go build ./..."
+	}
+	if _, err := os.Stat("Makefile"); err == nil {
+		return "make"
+	}
+	return ""
+}
+
+// parseErrors extracts the locations of compiler output, skipping lines that
+// don't point to an existing file
+func parseErrors(output string) []quickfixEntry {
+	var entries []quickfixEntry
+	for _, line := range strings.Split(output, "\n") {
+		m := errorLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		filename := filepath.Clean(m[1])
+		if _, err := os.Stat(filename); err != nil {
+			continue
+		}
+		lineNum, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		entries = append(entries, quickfixEntry{filename: filename, line: lineNum, col: col, message: m[4]})
+	}
+	return entries
+}
+
+// jumpToEntry opens the file of an error and moves the cursor to it
+func jumpToEntry(e quickfixEntry) error {
+	if err := openBuffer(e.filename); err != nil {
+		return err
+	}
+	GoToLine(max(e.line, 1), e.col)
+	return nil
+}
+
+// showQuickfixEntry jumps to entry i of the error list and describes it
+func showQuickfixEntry(i int) {
+	quickfixIdx = i
+	e := quickfixList[i]
+	if err := jumpToEntry(e); err != nil {
+		session.statusMessage = fmt.Sprintf("%s: %v", e.filename, err)
+		return
+	}
+	session.statusMessage = fmt.Sprintf("(%d/%d) %s", i+1, len(quickfixList), e.message)
+}
+
+// handleMake runs the build command (:make [command]), collects the errors
+// it prints and jumps to the first one
+func handleMake(fd int, args string, callback func() byte) {
+	if args != "" {
+		buildCommand = args
+	}
+	command := buildCommand
+	if command == "" {
+		command = defaultBuildCommand()
+	}
+	if command == "" {
+		session.statusMessage = "No build command: use make <command>"
+		return
+	}
+
+	session.statusMessage = "Running " + command + "..."
+	refreshScreen(fd)
+	result, err := runShellInterruptible(command, callback)
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("%s: %v", command, err)
+		return
+	}
+	if result.err != nil {
+		session.statusMessage = result.status(command)
+		return
+	}
+
+	quickfixList = parseErrors(result.stdout + result.stderr)
+	quickfixIdx = 0
+	if len(quickfixList) == 0 {
+		if result.exitCode == 0 {
+			session.statusMessage = command + ": build succeeded"
+		} else {
+			session.statusMessage = result.status(command)
+		}
+		return
+	}
+	pushJump()
+	showQuickfixEntry(0)
+}
+
+// handleNextError moves through the error list by delta (Alt-n, Alt-p)
+func handleNextError(delta int) {
+	if len(quickfixList) == 0 {
+		session.statusMessage = "No errors"
+		return
+	}
+	next := quickfixIdx + delta
+	if next < 0 || next >= len(quickfixList) {
+		session.statusMessage = "No more errors"
+		return
+	}
+	showQuickfixEntry(next)
+}
+
+// handleErrorList shows every error of the last build and jumps to the
+// one picked (:errors)
+func handleErrorList(fd int, args string, callback func() byte) {
+	if len(quickfixList) == 0 {
+		session.statusMessage = "No errors"
+		return
+	}
+	items := make([]string, len(quickfixList))
+	for i, e := range quickfixList {
+		items[i] = fmt.Sprintf("%s:%d:%d: %s", e.filename, e.line, e.col, e.message)
+	}
+	if i := pickFromList(fd, "Errors", items, quickfixIdx, callback); i >= 0 {
+		showQuickfixEntry(i)
+	}
+}
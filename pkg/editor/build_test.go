@@ -0,0 +1,52 @@
+package editor
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("main.go", []byte("package main\n"), 0644)
+
+	output := "# example\n./main.go:10:2: undefined: x\nmain.go:3: missing return\nother.go:1:1: no such file\n"
+	entries := parseErrors(output)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries got %+v", entries)
+	}
+	if e := entries[0]; e.filename != "main.go" || e.line != 10 || e.col != 2 || e.message != "undefined: x" {
+		t.Fatalf("unexpected first entry %+v", e)
+	}
+	if e := entries[1]; e.line != 3 || e.col != 0 {
+		t.Fatalf("unexpected second entry %+v", e)
+	}
+}
+
+func TestMake_JumpsThroughErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("SHELL", "/bin/sh")
+	os.WriteFile("a.txt", []byte("one\ntwo\nthree\n"), 0644)
+	resetSessionForTest()
+	buildCommand = ""
+	defer func() { buildCommand = "" }()
+
+	handleMake(0, `printf 'a.txt:2:3: first\na.txt:3:1: second\n'; exit 1`, nil)
+	if session.filename != "a.txt" || session.cursorRow != 2 || session.cursorCol != 3 {
+		t.Fatalf("expected a.txt:2:3 got %s:%d:%d", session.filename, session.cursorRow, session.cursorCol)
+	}
+
+	handleNextError(1) // Alt-n
+	if session.cursorRow != 3 || session.statusMessage != "(2/2) second" {
+		t.Fatalf("next error: row %d status %q", session.cursorRow, session.statusMessage)
+	}
+	handleNextError(1)
+	if session.statusMessage != "No more errors" {
+		t.Fatalf("expected end of list, got %q", session.statusMessage)
+	}
+
+	// The list picker jumps to the chosen entry
+	handleErrorList(0, "", makeCallback([]byte{Esc, '[', 'A', Return}))
+	if session.cursorRow != 2 {
+		t.Fatalf("picking the first error should move to row 2, got %d", session.cursorRow)
+	}
+}
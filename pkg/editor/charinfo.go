@@ -0,0 +1,72 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// runeAt returns the character of the active buffer that byte idx is part
+// of, and where it starts. The cursor moves by bytes, so it can be in the
+// middle of a multibyte character.
+func runeAt(idx int) (r rune, start, size int, ok bool) {
+	n := session.rope.Length()
+	if idx >= n {
+		return 0, 0, 0, false
+	}
+	from := max(idx-(utf8.UTFMax-1), 0)
+	text, err := session.rope.Substring(from, min(idx+utf8.UTFMax, n))
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if from+i+size > idx {
+			return r, from + i, size, true
+		}
+		i += size
+	}
+	return 0, 0, 0, false
+}
+
+// characterInfo describes the character r encoded as bytes: how it looks,
+// its code point in hex and decimal, its UTF-8 bytes and its name when the
+// digraph table knows it
+func characterInfo(r rune, bytes string) string {
+	glyph := string(r)
+	switch {
+	case r == utf8.RuneError && len(bytes) == 1:
+		return fmt.Sprintf("Invalid UTF-8 byte 0x%02X", bytes[0])
+	case r < 0x20:
+		glyph = "^" + string(r+'@')
+	case r == 0x7f:
+		glyph = "^?"
+	case !unicode.IsPrint(r):
+		glyph = fmt.Sprintf("<U+%04X>", r)
+	}
+
+	hexBytes := make([]string, len(bytes))
+	for i := range len(bytes) {
+		hexBytes[i] = fmt.Sprintf("%02X", bytes[i])
+	}
+	info := fmt.Sprintf("'%s' U+%04X, decimal %d, UTF-8 %s", glyph, r, r, strings.Join(hexBytes, " "))
+	for _, d := range digraphs {
+		if d.r == r {
+			return info + ", " + d.name + " (digraph " + d.keys + ")"
+		}
+	}
+	return info
+}
+
+// handleCharInfo shows the character under the cursor in the status bar
+// (:ga, :char)
+func handleCharInfo(fd int, args string, callback func() byte) {
+	r, start, size, ok := runeAt(session.cursorIdx)
+	if !ok {
+		session.statusMessage = "No character under the cursor"
+		return
+	}
+	bytes, _ := session.rope.Substring(start, start+size)
+	session.statusMessage = characterInfo(r, bytes)
+}
@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// BracketedPaste is the key reported when the terminal starts sending
+// pasted text, which it wraps in \x1b[200~ and \x1b[201~ once bracketed
+// paste mode (\x1b[?2004h) is on
+var BracketedPaste = Key{Special: KeyPaste}
+
+// pasteEnd ends the text of a bracketed paste
+const pasteEnd = "\x1b[201~"
+
+// replyTimeouts is how many read timeouts (of 100ms) to wait for before
+// giving up on the rest of a paste or a terminal reply
+const replyTimeouts = 10
+
+// readPaste reads the text of a bracketed paste up to its end marker. Line
+// ends, sent as \r by terminals, become \n.
+func readPaste(callback func() byte) string {
+	var text strings.Builder
+	for timeouts := 0; timeouts < replyTimeouts; {
+		b := callback()
+		if b == 0 {
+			timeouts++
+			continue
+		}
+		timeouts = 0
+		text.WriteByte(b)
+		if strings.HasSuffix(text.String(), pasteEnd) {
+			break
+		}
+	}
+	pasted := strings.TrimSuffix(text.String(), pasteEnd)
+	pasted = strings.ReplaceAll(pasted, "\r\n", "\n")
+	return strings.ReplaceAll(pasted, "\r", "\n")
+}
+
+// insertPaste inserts pasted text at the cursor as a single undo step,
+// in place of the selection when there is one
+func insertPaste(text string) {
+	if text == "" {
+		return
+	}
+	if start, end, ok := selection(); ok {
+		session.selecting = false
+		replaceText(start, end, text)
+		return
+	}
+	handleInsert(text)
+}
+
+// requestClipboard asks the terminal for the clipboard with OSC 52 and
+// reads its answer, \x1b]52;c;BASE64 ended by BEL or ST. Terminals that
+// don't allow reading the clipboard don't answer, or answer with no data.
+// Inside tmux or screen the request goes through to the outer terminal.
+func requestClipboard(callback func() byte) (string, error) {
+	fmt.Fprint(output, passthrough("\x1b]52;c;?\x07"))
+	var reply strings.Builder
+	for timeouts := 0; ; {
+		b := callback()
+		if b == 0 {
+			if timeouts++; timeouts == replyTimeouts {
+				return "", fmt.Errorf("the terminal did not answer")
+			}
+			continue
+		}
+		timeouts = 0
+		if b == 0x07 {
+			break
+		}
+		reply.WriteByte(b)
+		if strings.HasSuffix(reply.String(), "\x1b\\") {
+			break
+		}
+	}
+
+	data, ok := strings.CutPrefix(strings.TrimSuffix(reply.String(), "\x1b\\"), "\x1b]52;")
+	if _, payload, found := strings.Cut(data, ";"); ok && found {
+		if payload == "" || payload == "?" {
+			return "", fmt.Errorf("the terminal did not share the clipboard")
+		}
+		text, err := base64.StdEncoding.DecodeString(payload)
+		return string(text), err
+	}
+	return "", fmt.Errorf("unexpected answer %q", reply.String())
+}
+
+// handlePaste inserts the system clipboard, asked to the terminal with
+// OSC 52 (:paste, Alt-V). Where the terminal doesn't allow it, its own
+// paste key still works through bracketed paste.
+func handlePaste(fd int, args string, callback func() byte) {
+	if !editable() {
+		return
+	}
+	text, err := requestClipboard(callback)
+	if err != nil {
+		log.Printf("OSC 52 paste: %v", err)
+		session.statusMessage = fmt.Sprintf("Cannot read the clipboard: %v; use the paste key of the terminal", err)
+		return
+	}
+	insertPaste(strings.ReplaceAll(text, "\r\n", "\n"))
+	session.statusMessage = fmt.Sprintf("Pasted %d bytes", len(text))
+}
@@ -0,0 +1,88 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestBracketedPaste(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("ab")
+	session.cursorIdx = 1
+
+	keys := []byte("\x1b[200~line 1\r\n\tline 2\rx\x1b[201~")
+	ProcessKeypress(0, makeCallback(append(keys, CtrlQ, CtrlQ)))
+	if got := session.rope.String(); got != "aline 1\n\tline 2\nxb" {
+		t.Fatalf("paste left %q", got)
+	}
+	// The paste is undone in one step
+	handleUndo()
+	if got := session.rope.String(); got != "ab" {
+		t.Errorf("undo left %q", got)
+	}
+}
+
+func TestOSC52Paste(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("")
+
+	handlePaste(0, "", makeCallback([]byte("\x1b]52;c;aGVsbG8gd29ybGQ=\x1b\\")))
+	if got := session.rope.String(); got != "hello world" {
+		t.Fatalf("paste left %q, status %q", got, session.statusMessage)
+	}
+
+	handlePaste(0, "", makeCallback([]byte("\x1b]52;c;\x07")))
+	if !strings.Contains(session.statusMessage, "did not share") {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+	handlePaste(0, "", makeCallback(nil))
+	if !strings.Contains(session.statusMessage, "did not answer") || session.rope.String() != "hello world" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+}
+
+func TestPasteOverSelection(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("keep this old text")
+	selectRange(10, 13)
+
+	insertPaste("new")
+	if got := session.rope.String(); got != "keep this new text" {
+		t.Fatalf("paste left %q", got)
+	}
+	if _, _, ok := selection(); ok || session.cursorIdx != 13 {
+		t.Errorf("the cursor should end after the paste, at %d", session.cursorIdx)
+	}
+	// Replacing the selection is one undo step
+	handleUndo()
+	if got := session.rope.String(); got != "keep this old text" {
+		t.Errorf("undo left %q", got)
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("ab\ncd")
+	GoToLine(1, 2)
+
+	// Without a selection the line is copied below, the cursor following it
+	ProcessKeypress(0, makeCallback([]byte("\x1bd\x11\x11")))
+	if got := session.rope.String(); got != "ab\nab\ncd" {
+		t.Fatalf("duplicating the line left %q", got)
+	}
+	if session.cursorRow != 2 || session.cursorCol != 2 {
+		t.Errorf("cursor on %d:%d, want 2:2", session.cursorRow, session.cursorCol)
+	}
+
+	// A selection is copied after itself, and the copy selected
+	selectRange(6, 8)
+	runCommand(0, "duplicate", nil)
+	if got := session.rope.String(); got != "ab\nab\ncdcd" {
+		t.Fatalf("duplicating the selection left %q", got)
+	}
+	if start, end, ok := selection(); !ok || start != 8 || end != 10 {
+		t.Errorf("selected %d-%d %v, want the copy", start, end, ok)
+	}
+}
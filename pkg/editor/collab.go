@@ -0,0 +1,503 @@
+package editor
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// Collaborative editing (experimental): two editors share a buffer over
+// TCP. The host sends its buffer to the guest when it connects; then each
+// side sends its edits and cursor as lines of JSON. Edits made at the same
+// time are merged by operational transformation, two-party (Jupiter)
+// style: each side transforms what it receives against what it sent and
+// the other side hadn't seen yet, so both end with the same text.
+//
+// The guest first sends the token it was given, and the host only shares
+// the buffer when it is its own: a host given no token makes one up and
+// shows it. Until then the host reads no more than maxCollabAuth bytes
+// from the guest. Edits of the peer are refused like typed ones in a
+// read-only buffer or protected text, which ends the collaboration.
+
+// collabOp is an insertion or a deletion at a byte position
+type collabOp struct {
+	Pos int    `json:"pos"`
+	Del int    `json:"del,omitempty"` // bytes deleted
+	Ins string `json:"ins,omitempty"` // text inserted
+}
+
+// noop reports whether the op changes nothing, like an insertion that
+// landed in text deleted meanwhile
+func (op collabOp) noop() bool {
+	return op.Del == 0 && op.Ins == ""
+}
+
+// collabMessage is a line sent between the two editors
+type collabMessage struct {
+	Type string `json:"type"` // "auth", "hello", "edit" or "cursor"
+
+	// auth: the token of the guest
+	Token string `json:"token,omitempty"`
+
+	// hello: the buffer of the host
+	Name string `json:"name,omitempty"`
+	Text string `json:"text,omitempty"`
+
+	// edit and cursor: the state of the sender, as the number of edits it
+	// sent and received so far
+	Sent     int        `json:"sent"`
+	Received int        `json:"received"`
+	Ops      []collabOp `json:"ops,omitempty"`
+	Cursor   int        `json:"cursor"`
+}
+
+// collabEdit is an edit sent and not yet acknowledged by the peer
+type collabEdit struct {
+	sent int // value of Sent it was sent with
+	ops  []collabOp
+}
+
+// collabSession is a running collaboration
+type collabSession struct {
+	host     bool
+	token    string       // shared by both sides to let the guest in
+	authed   atomic.Bool  // set once the guest sent the token, read by the reader
+	listener net.Listener // host only, until the guest connects
+	conn     net.Conn
+	enc      *json.Encoder
+	buffer   *Session     // the shared buffer, nil until the guest got it
+	synced   *buffer.Rope // the text of buffer as last sent or received
+
+	sent, received int
+	pending        []collabEdit
+	peerCursor     int  // index of the cursor of the peer, -1 when unknown
+	closed         bool // set by end, after which messages are dropped
+}
+
+// collaboration is the running collaboration, or nil
+var collaboration *collabSession
+
+func init() {
+	addHook(eventTextChanged, func(s *Session) error {
+		if c := collaboration; c != nil && s == c.buffer {
+			c.sendChanges()
+		}
+		return nil
+	})
+	addHook(eventCursorMoved, func(s *Session) error {
+		if c := collaboration; c != nil && s == c.buffer {
+			c.sendCursor()
+		}
+		return nil
+	})
+}
+
+// defaultCollabAddr is where :collab host listens when given no address,
+// reachable only from this machine
+const defaultCollabAddr = "127.0.0.1:7700"
+
+// maxCollabAuth is how many bytes the guest may send before its token is
+// checked, plenty for the auth message
+const maxCollabAuth = 4096
+
+// errCollabAuthTooLong is read when the guest sends more than
+// maxCollabAuth bytes before its token is checked
+var errCollabAuthTooLong = errors.New("too much data before the token")
+
+// newCollabToken makes up a token for a host given none
+func newCollabToken() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// handleCollab shares the active buffer (:collab host [ADDR [TOKEN]]),
+// edits the buffer of another editor (:collab join HOST:PORT TOKEN) or
+// ends the collaboration (:collab off)
+func handleCollab(fd int, args string, callback func() byte) {
+	verb, rest, _ := strings.Cut(args, " ")
+	addr, token, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	token = strings.TrimSpace(token)
+	if verb == "off" {
+		if collaboration == nil {
+			session.statusMessage = "No collaboration"
+			return
+		}
+		collaboration.end("Collaboration ended")
+		return
+	}
+	if collaboration != nil {
+		session.statusMessage = "Already collaborating (:collab off to end)"
+		return
+	}
+	switch {
+	case verb == "host":
+		if addr == "" {
+			addr = defaultCollabAddr
+		}
+		if token == "" {
+			var err error
+			if token, err = newCollabToken(); err != nil {
+				session.statusMessage = fmt.Sprintf("Collab: %v", err)
+				return
+			}
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			session.statusMessage = fmt.Sprintf("Collab: %v", err)
+			return
+		}
+		c := &collabSession{host: true, token: token, listener: listener, buffer: session, peerCursor: -1}
+		collaboration = c
+		go c.accept()
+		session.statusMessage = fmt.Sprintf("Waiting for a collaborator on %s (:collab join %[1]s %s)", listener.Addr(), token)
+	case verb == "join" && addr != "" && token != "":
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			session.statusMessage = fmt.Sprintf("Collab: %v", err)
+			return
+		}
+		collaboration = &collabSession{token: token, peerCursor: -1}
+		// The guest reads what the host it chose sends, the whole buffer first
+		collaboration.authed.Store(true)
+		collaboration.connect(conn)
+		collaboration.send(collabMessage{Type: "auth", Token: token})
+		session.statusMessage = "Joining " + addr
+	default:
+		session.statusMessage = "Usage: collab host [ADDR [TOKEN]] | join HOST:PORT TOKEN | off"
+	}
+}
+
+// accept waits for the guest, which gets the buffer once it sent the token
+func (c *collabSession) accept() {
+	conn, err := c.listener.Accept()
+	c.listener.Close()
+	post(func() {
+		if c.closed {
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		if err != nil {
+			c.end(fmt.Sprintf("Collab: %v", err))
+			return
+		}
+		c.connect(conn)
+	})
+}
+
+// welcome sends the buffer to the guest if it knows the token
+func (c *collabSession) welcome(token string) {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.token)) != 1 {
+		c.end("Collab: wrong token from " + c.conn.RemoteAddr().String())
+		return
+	}
+	c.authed.Store(true)
+	c.synced = c.buffer.rope
+	c.send(collabMessage{Type: "hello", Name: c.buffer.filename, Text: c.synced.String()})
+	c.sendCursor()
+	session.statusMessage = "Collaborator joined from " + c.conn.RemoteAddr().String()
+}
+
+// connect starts talking to the peer on conn. What it sends is handled on
+// the main loop, in order.
+func (c *collabSession) connect(conn net.Conn) {
+	c.conn = conn
+	c.enc = json.NewEncoder(conn)
+	go func() {
+		dec := json.NewDecoder(&authLimitReader{r: conn, left: maxCollabAuth, authed: &c.authed})
+		for {
+			var msg collabMessage
+			if err := dec.Decode(&msg); err != nil {
+				post(func() {
+					if c.closed {
+						return
+					}
+					if errors.Is(err, errCollabAuthTooLong) {
+						c.end(fmt.Sprintf("Collab: %s sent %v", conn.RemoteAddr(), err))
+					} else {
+						c.end("Collaboration ended: the other editor left")
+					}
+				})
+				return
+			}
+			post(func() {
+				if !c.closed {
+					c.receive(msg)
+				}
+			})
+		}
+	}()
+}
+
+// authLimitReader reads at most left bytes of r until authed is set
+type authLimitReader struct {
+	r      io.Reader
+	left   int
+	authed *atomic.Bool
+}
+
+func (l *authLimitReader) Read(p []byte) (int, error) {
+	if l.authed.Load() {
+		return l.r.Read(p)
+	}
+	if l.left <= 0 {
+		return 0, errCollabAuthTooLong
+	}
+	n, err := l.r.Read(p[:min(len(p), l.left)])
+	l.left -= n
+	return n, err
+}
+
+// end closes the collaboration, leaving the shared buffer as it is
+func (c *collabSession) end(message string) {
+	c.closed = true
+	if c.listener != nil {
+		c.listener.Close()
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	if collaboration == c {
+		collaboration = nil
+	}
+	session.statusMessage = message
+}
+
+// send writes msg to the peer, ending the collaboration when it can't
+func (c *collabSession) send(msg collabMessage) {
+	if c.closed {
+		return
+	}
+	if err := c.enc.Encode(msg); err != nil {
+		c.end(fmt.Sprintf("Collaboration ended: %v", err))
+	}
+}
+
+// connected reports whether both sides have the buffer
+func (c *collabSession) connected() bool {
+	return c.enc != nil && c.synced != nil
+}
+
+// sendChanges sends what changed in the buffer since it was last synced
+func (c *collabSession) sendChanges() {
+	if !c.connected() || c.buffer.rope == c.synced {
+		return
+	}
+	ops := diffOps(c.synced.String(), c.buffer.rope.String())
+	c.synced = c.buffer.rope
+	if len(ops) == 0 {
+		return
+	}
+	c.pending = append(c.pending, collabEdit{c.sent, ops})
+	c.peerCursor = transformIndex(c.peerCursor, ops)
+	c.send(collabMessage{Type: "edit", Sent: c.sent, Received: c.received, Ops: ops})
+	c.sent++
+}
+
+// sendCursor tells the peer where the cursor is
+func (c *collabSession) sendCursor() {
+	if c.connected() {
+		c.send(collabMessage{Type: "cursor", Sent: c.sent, Received: c.received, Cursor: c.buffer.cursorIdx})
+	}
+}
+
+// receive handles a message of the peer
+func (c *collabSession) receive(msg collabMessage) {
+	if msg.Type == "auth" {
+		if c.host && c.synced == nil {
+			c.welcome(msg.Token)
+		}
+		return
+	}
+	if msg.Type == "hello" {
+		if c.host || c.buffer != nil {
+			return
+		}
+		c.buffer = newSession("[collab: "+msg.Name+"]", msg.Text)
+		c.synced = c.buffer.rope
+		buffers = append(buffers, c.buffer)
+		switchToBuffer(c.buffer)
+		session.statusMessage = "Editing " + msg.Name + " with its host"
+		return
+	}
+	if !c.connected() {
+		return
+	}
+
+	// Edits the peer has seen need no transforming anymore
+	for len(c.pending) > 0 && c.pending[0].sent < msg.Received {
+		c.pending = c.pending[1:]
+	}
+	switch msg.Type {
+	case "cursor":
+		idx := msg.Cursor
+		for _, p := range c.pending {
+			idx = transformIndex(idx, p.ops)
+		}
+		c.peerCursor = idx
+	case "edit":
+		// Local edits not sent yet would be taken for the peer's
+		c.sendChanges()
+		ops := msg.Ops
+		for i := range c.pending {
+			ops, c.pending[i].ops = transformOps(ops, c.pending[i].ops, !c.host)
+		}
+		if err := c.apply(ops); err != nil {
+			c.end(fmt.Sprintf("Collaboration ended: %v", err))
+			return
+		}
+		c.received++
+	}
+}
+
+// apply makes the edits of the peer on the shared buffer, which must be
+// editable there. They can't be undone, and clear the undo history, whose
+// positions they would shift.
+func (c *collabSession) apply(ops []collabOp) error {
+	active := session
+	session = c.buffer
+	defer func() { session = active }()
+	for _, op := range ops {
+		if !editableRange(op.Pos, op.Pos+op.Del) {
+			return fmt.Errorf("edit refused: %s", session.statusMessage)
+		}
+		removed, err := session.rope.Substring(op.Pos, op.Pos+op.Del)
+		if err != nil || !applyEdits([]textEdit{{position: op.Pos, removed: removed, inserted: op.Ins}}) {
+			return fmt.Errorf("edit out of the buffer at %d", op.Pos)
+		}
+	}
+	session.undoStack, session.redoStack = nil, nil
+	session.cursorIdx = transformIndex(session.cursorIdx, ops)
+	c.peerCursor = transformIndex(c.peerCursor, ops)
+	updateCursorPosition()
+	c.synced = session.rope
+	return nil
+}
+
+// collabHighlights marks the cursor of the peer in the shared buffer
+func collabHighlights() []highlight {
+	c := collaboration
+	if c == nil || c.buffer != session || c.peerCursor < 0 || !c.connected() {
+		return nil
+	}
+	return []highlight{{c.peerCursor, c.peerCursor + 1, currentTheme.peerCursor}}
+}
+
+// diffOps returns the ops that turn old into new: the part between their
+// common prefix and suffix, deleted then inserted
+func diffOps(old, new string) []collabOp {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	var ops []collabOp
+	if del := len(old) - prefix - suffix; del > 0 {
+		ops = append(ops, collabOp{Pos: prefix, Del: del})
+	}
+	if ins := new[prefix : len(new)-suffix]; ins != "" {
+		ops = append(ops, collabOp{Pos: prefix, Ins: ins})
+	}
+	return ops
+}
+
+// transformOps transforms a and b, made concurrently on the same text, so
+// that a applies after b and b after a, with the same result. Insertions
+// at the same place put those of a first when aFirst.
+func transformOps(a, b []collabOp, aFirst bool) (a2, b2 []collabOp) {
+	b2 = append([]collabOp(nil), b...)
+	for _, op := range a {
+		for j := range b2 {
+			op, b2[j] = transformOp(op, b2[j], aFirst)
+		}
+		if !op.noop() {
+			a2 = append(a2, op)
+		}
+	}
+	kept := b2[:0]
+	for _, op := range b2 {
+		if !op.noop() {
+			kept = append(kept, op)
+		}
+	}
+	return a2, kept
+}
+
+// transformOp transforms two concurrent ops. Text inserted in a deleted
+// span is deleted with it.
+func transformOp(a, b collabOp, aFirst bool) (collabOp, collabOp) {
+	switch {
+	case a.noop() || b.noop():
+		return a, b
+	case a.Ins != "" && b.Ins != "":
+		if a.Pos < b.Pos || a.Pos == b.Pos && aFirst {
+			b.Pos += len(a.Ins)
+		} else {
+			a.Pos += len(b.Ins)
+		}
+	case a.Ins != "":
+		switch {
+		case a.Pos <= b.Pos:
+			b.Pos += len(a.Ins)
+		case a.Pos >= b.Pos+b.Del:
+			a.Pos -= b.Del
+		default:
+			b.Del += len(a.Ins)
+			a = collabOp{}
+		}
+	case b.Ins != "":
+		b, a = transformOp(b, a, !aFirst)
+	default:
+		aStart, aEnd := deletedIndex(a.Pos, b), deletedIndex(a.Pos+a.Del, b)
+		bStart, bEnd := deletedIndex(b.Pos, a), deletedIndex(b.Pos+b.Del, a)
+		a = collabOp{Pos: aStart, Del: aEnd - aStart}
+		b = collabOp{Pos: bStart, Del: bEnd - bStart}
+	}
+	return a, b
+}
+
+// deletedIndex returns where idx is once the deletion del is made
+func deletedIndex(idx int, del collabOp) int {
+	switch {
+	case idx <= del.Pos:
+		return idx
+	case idx <= del.Pos+del.Del:
+		return del.Pos
+	}
+	return idx - del.Del
+}
+
+// transformIndex returns where idx is once ops are made, or -1 for -1.
+// Text inserted at idx goes after it.
+func transformIndex(idx int, ops []collabOp) int {
+	if idx < 0 {
+		return idx
+	}
+	for _, op := range ops {
+		if op.Ins != "" {
+			if op.Pos < idx {
+				idx += len(op.Ins)
+			}
+		} else {
+			idx = deletedIndex(idx, op)
+		}
+	}
+	return idx
+}
@@ -0,0 +1,184 @@
+package editor
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// applyOps returns text with ops made on it
+func applyOps(text string, ops []collabOp) string {
+	for _, op := range ops {
+		text = text[:op.Pos] + op.Ins + text[op.Pos+op.Del:]
+	}
+	return text
+}
+
+func TestTransformOpsConverge(t *testing.T) {
+	base := "hello brave new world"
+	ins := func(pos int, s string) collabOp { return collabOp{Pos: pos, Ins: s} }
+	del := func(pos, n int) collabOp { return collabOp{Pos: pos, Del: n} }
+	for _, tc := range []struct {
+		a, b []collabOp
+		want string
+	}{
+		{[]collabOp{ins(0, ">")}, []collabOp{ins(5, ",")}, ">hello, brave new world"},
+		{[]collabOp{ins(5, "A")}, []collabOp{ins(5, "B")}, "helloAB brave new world"},
+		{[]collabOp{del(6, 6)}, []collabOp{ins(21, "!")}, "hello new world!"},
+		{[]collabOp{del(6, 6)}, []collabOp{ins(8, "xx")}, "hello new world"},
+		{[]collabOp{del(0, 12)}, []collabOp{del(6, 10)}, "world"},
+		{[]collabOp{del(6, 4)}, []collabOp{del(0, 16)}, "world"},
+		{[]collabOp{del(6, 6), ins(6, "bold ")}, []collabOp{del(12, 4), ins(12, "old ")}, "hello bold old world"},
+	} {
+		a2, b2 := transformOps(tc.a, tc.b, true)
+		first, second := applyOps(applyOps(base, tc.b), a2), applyOps(applyOps(base, tc.a), b2)
+		if first != tc.want || second != tc.want {
+			t.Errorf("%v and %v gave %q and %q, want %q", tc.a, tc.b, first, second, tc.want)
+		}
+	}
+}
+
+func TestDiffOps(t *testing.T) {
+	for _, tc := range [][2]string{{"abc", "abc"}, {"abc", "aXc"}, {"abc", "abcd"}, {"aaa", "aa"}, {"", "new"}, {"old", ""}} {
+		if got := applyOps(tc[0], diffOps(tc[0], tc[1])); got != tc[1] {
+			t.Errorf("diff of %q and %q makes %q", tc[0], tc[1], got)
+		}
+	}
+	if ops := diffOps("abc", "abc"); ops != nil {
+		t.Errorf("no change should give no ops, got %v", ops)
+	}
+}
+
+// waitFor runs the functions posted to the main loop until done holds
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !done(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		runPosted()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCollaboration(t *testing.T) {
+	resetSessionForTest()
+	hostBuffer := newSession("notes.txt", "one\ntwo\n")
+	session = hostBuffer
+	buffers = []*Session{session}
+
+	hostConn, guestConn := net.Pipe()
+	host := &collabSession{host: true, token: "secret", buffer: hostBuffer, peerCursor: -1}
+	guest := &collabSession{token: "secret", peerCursor: -1}
+	guest.authed.Store(true)
+	guest.connect(guestConn)
+	host.connect(hostConn)
+	guest.send(collabMessage{Type: "auth", Token: guest.token})
+	waitFor(t, func() bool { return guest.buffer != nil })
+	guestBuffer := guest.buffer
+	if session != guestBuffer || guestBuffer.filename != "[collab: notes.txt]" || guestBuffer.rope.String() != "one\ntwo\n" {
+		t.Fatalf("guest buffer %q: %q", guestBuffer.filename, guestBuffer.rope.String())
+	}
+
+	// Both sides edit at once
+	session = hostBuffer
+	replaceText(0, 3, "ONE")
+	host.sendChanges()
+	session = guestBuffer
+	session.cursorIdx = 8
+	handleInsert("three\n")
+	guest.sendChanges()
+	guest.sendCursor()
+	waitFor(t, func() bool { return host.received == 1 && guest.received == 1 && host.peerCursor == 14 })
+
+	want := "ONE\ntwo\nthree\n"
+	if hostBuffer.rope.String() != want || guestBuffer.rope.String() != want {
+		t.Fatalf("host has %q, guest %q, want %q", hostBuffer.rope.String(), guestBuffer.rope.String(), want)
+	}
+	if len(hostBuffer.undoStack) != 0 {
+		t.Error("edits of the peer should clear the undo history")
+	}
+	session = hostBuffer
+	collaboration = host
+	t.Cleanup(func() { collaboration = nil })
+	if hl := collabHighlights(); len(hl) != 1 || hl[0].start != 14 {
+		t.Errorf("peer cursor highlights %+v", hl)
+	}
+
+	host.end("Collaboration ended")
+	waitFor(t, func() bool { return guest.closed })
+	if !strings.Contains(session.statusMessage, "left") {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
+
+func TestCollabWrongToken(t *testing.T) {
+	resetSessionForTest()
+	hostConn, guestConn := net.Pipe()
+	host := &collabSession{host: true, token: "secret", buffer: session, peerCursor: -1}
+	guest := &collabSession{peerCursor: -1}
+	guest.connect(guestConn)
+	host.connect(hostConn)
+	guest.send(collabMessage{Type: "auth", Token: "guess"})
+	waitFor(t, func() bool { return host.closed && guest.closed })
+	if guest.buffer != nil {
+		t.Error("a guest with the wrong token got the buffer")
+	}
+}
+
+func TestCollabAuthTooLong(t *testing.T) {
+	resetSessionForTest()
+	hostConn, guestConn := net.Pipe()
+	host := &collabSession{host: true, token: "secret", buffer: session, peerCursor: -1}
+	host.connect(hostConn)
+	go guestConn.Write([]byte(`{"type":"auth","token":"` + strings.Repeat("x", 2*maxCollabAuth)))
+	waitFor(t, func() bool { return host.closed })
+	if !strings.Contains(session.statusMessage, "too much data before the token") {
+		t.Errorf("status %q", session.statusMessage)
+	}
+	guestConn.Close()
+}
+
+func TestCollabProtectedText(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("notes.txt", "keep\nfree\n")
+	session.protect(0, 4)
+	host := &collabSession{host: true, token: "secret", buffer: session, peerCursor: -1, synced: session.rope}
+	if err := host.apply([]collabOp{{Pos: 5, Ins: "more "}}); err != nil {
+		t.Fatal(err)
+	}
+	err := host.apply([]collabOp{{Pos: 1, Del: 2}})
+	if err == nil || !strings.Contains(err.Error(), "is protected") || session.rope.String() != "keep\nmore free\n" {
+		t.Errorf("protected text edited: %v, %q", err, session.rope.String())
+	}
+	session.readOnly = true
+	if err := host.apply([]collabOp{{Pos: 5, Ins: "x"}}); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("read-only buffer edited: %v", err)
+	}
+}
+
+func TestCollabCommand(t *testing.T) {
+	resetSessionForTest()
+	runCommand(0, "collab", nil)
+	if !strings.HasPrefix(session.statusMessage, "Usage") {
+		t.Errorf("status %q", session.statusMessage)
+	}
+	runCommand(0, "collab join 127.0.0.1:7700", nil)
+	if collaboration != nil || !strings.HasPrefix(session.statusMessage, "Usage") {
+		t.Fatalf("joined without a token, status %q", session.statusMessage)
+	}
+	runCommand(0, "collab host 127.0.0.1:0", nil)
+	if collaboration == nil || !strings.HasPrefix(session.statusMessage, "Waiting for a collaborator on 127.0.0.1:") {
+		t.Fatalf("status %q", session.statusMessage)
+	}
+	// A host given no token makes one up
+	if token := collaboration.token; len(token) != 16 || !strings.HasSuffix(session.statusMessage, " "+token+")") {
+		t.Errorf("token %q, status %q", token, session.statusMessage)
+	}
+	runCommand(0, "collab off", nil)
+	if collaboration != nil || session.statusMessage != "Collaboration ended" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+	runPosted()
+}
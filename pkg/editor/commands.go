@@ -0,0 +1,152 @@
+package editor
+
+import (
+	"strings"
+)
+
+// commandFunc runs a command entered at the command prompt.
+// args holds whatever followed the command name.
+type commandFunc func(fd int, args string, callback func() byte)
+
+// commands maps the names accepted by the command prompt to their handlers.
+// It is filled in init so that handlers are free to run other commands.
+var commands map[string]commandFunc
+
+func init() {
+	commands = map[string]commandFunc{
+		"definition":    func(fd int, args string, callback func() byte) { handleGoToDefinition(fd, callback) },
+		"references":    handleFindReferences,
+		"back":          func(fd int, args string, callback func() byte) { jumpBack() },
+		"blame":         handleBlame,
+		"stage-hunk":    handleStageHunk,
+		"revert-hunk":   handleRevertHunk,
+		"spell":         handleSpellToggle,
+		"suggest":       handleSpellSuggest,
+		"spelladd":      handleSpellAdd,
+		"r":             handleReadCommand,
+		"make":          handleMake,
+		"errors":        handleErrorList,
+		"cnext":         func(fd int, args string, callback func() byte) { handleNextError(1) },
+		"cprev":         func(fd int, args string, callback func() byte) { handleNextError(-1) },
+		"tag":           handleTag,
+		"tags":          handleTagPicker,
+		"maketags":      handleMakeTags,
+		"format":        handleFormat,
+		"hex":           handleHex,
+		"table":         handleTable,
+		"scratch":       handleScratch,
+		"term":          handleTerminal,
+		"send":          handleSend,
+		"split":         handleSplit,
+		"vsplit":        handleVSplit,
+		"close":         handleCloseWindow,
+		"only":          handleOnlyWindow,
+		"qa":            handleQuitAll,
+		"cq":            handleQuitCanceled,
+		"wqa":           handleWriteQuitAll,
+		"encode":        handleEncode,
+		"decode":        handleDecode,
+		"largefile":     handleLargeFile,
+		"e":             handleEdit,
+		"edit":          handleEdit,
+		"goto":          handleGoTo,
+		"selectall":     func(fd int, args string, callback func() byte) { handleSelectAll() },
+		"duplicate":     func(fd int, args string, callback func() byte) { handleDuplicate() },
+		"files":         handleFilePicker,
+		"set":           handleSet,
+		"bn":            handleBufferNext,
+		"bp":            handleBufferPrev,
+		"buffers":       handleBufferList,
+		"diff":          handleDiffChanges,
+		"compare":       handleCompare,
+		"collab":        handleCollab,
+		"dget":          handleDiffGet,
+		"dput":          handleDiffPut,
+		"dnext":         handleDiffNext,
+		"dprev":         handleDiffPrev,
+		"conflict-next": handleConflictNext,
+		"conflict-prev": handleConflictPrev,
+		"ours":          handleConflictOurs,
+		"theirs":        handleConflictTheirs,
+		"both":          handleConflictBoth,
+		"zt":            handleScrollTop,
+		"zz":            handleScrollCenter,
+		"zb":            handleScrollBottom,
+		"s":             handleSubstitute,
+		"setlocal":      handleSetLocal,
+		"reload-config": handleReloadConfig,
+		"speak":         func(fd int, args string, callback func() byte) { handleSpeakLine() },
+		"backups":       handleBackups,
+		"w":             handleWrite,
+		"w!":            handleForceWrite,
+		"inc":           handleIncrement,
+		"dec":           handleDecrement,
+		"align":         handleAlign,
+		"json":          handleJSON,
+		"yaml":          handleYAML,
+		"protect":       handleProtect,
+		"paste":         handlePaste,
+		"unicode":       handleInsertUnicode,
+		"digraphs":      handleDigraphs,
+		"ga":            handleCharInfo,
+		"char":          handleCharInfo,
+		"unprotect":     handleUnprotect,
+		"suspend":       handleSuspend,
+		"abbrev":        handleAbbrev,
+		"ab":            handleAbbrev,
+		"unabbrev":      handleUnabbrev,
+		"stop":          handleSuspend,
+
+		// Strings and brackets around the cursor
+		"outer-start":     func(fd int, args string, callback func() byte) { handleEnclosingJump(false) },
+		"outer-end":       func(fd int, args string, callback func() byte) { handleEnclosingJump(true) },
+		"inside-brackets": func(fd int, args string, callback func() byte) { handleSelectInside("brackets") },
+		"inside-quotes":   func(fd int, args string, callback func() byte) { handleSelectInside("string") },
+
+		// Generated text
+		"date":      handleDate,
+		"time":      handleTime,
+		"uuid":      handleUUID,
+		"gen":       handleGenerate,
+		"generator": handleGenerator,
+
+		"stats":    handleStats,
+		"showkeys": handleShowKeys,
+		"lua":      handleLua,
+		"plugins":  handlePlugins,
+	}
+}
+
+// handleCommand prompts for a command (Ctrl-E) and runs it
+func handleCommand(fd int, callback func() byte) {
+	input, _ := readPrompt(":", commandHistory, callback)
+	input = strings.TrimSpace(strings.TrimPrefix(input, ":"))
+	if input == "" {
+		return
+	}
+	commandHistory.add(input)
+	runCommand(fd, input, callback)
+}
+
+// runCommand splits a command line into name and arguments and runs it.
+// A line starting with "!" is a shell command.
+func runCommand(fd int, line string, callback func() byte) {
+	traceCommand(line)
+	if shellCommand, ok := strings.CutPrefix(line, "!"); ok {
+		handleShellCommand(strings.TrimSpace(shellCommand), callback)
+		return
+	}
+	// "s/old/new/" needs no space after the command name
+	if rest, ok := strings.CutPrefix(line, "s"); ok && rest != "" && isSubstituteDelimiter(rest[0]) {
+		handleSubstitute(fd, rest, callback)
+		return
+	}
+
+	name, args, _ := strings.Cut(line, " ")
+	cmd, ok := commands[name]
+	if !ok {
+		session.statusMessage = "Unknown command: " + name
+		return
+	}
+	cmd(fd, strings.TrimSpace(args), callback)
+}
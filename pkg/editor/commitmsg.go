@@ -0,0 +1,165 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the lines of a commit message, past which they are highlighted
+const (
+	commitSummaryWidth = 50
+	commitBodyWidth    = 72
+)
+
+// commitScissors starts the diff git adds below the message with --verbose;
+// git ignores everything from there
+const commitScissors = "# ------------------------ >8 ------------------------"
+
+func init() {
+	addHook(eventBufOpen, func(s *Session) error {
+		switch filepath.Base(s.filename) {
+		case "COMMIT_EDITMSG", "MERGE_MSG":
+			if s.filetype == "" {
+				s.filetype = "gitcommit"
+			}
+		}
+		return nil
+	})
+	addHook(eventBufWritePre, func(s *Session) error {
+		if s.fileType() == "gitcommit" && commitMessage(s.rope.String()) == "" {
+			// Saving stays possible: an empty message is how a commit is aborted
+			s.statusMessage = "empty commit message, git will abort the commit"
+		}
+		return nil
+	})
+}
+
+// commitMessage returns the message git will keep: the text before the
+// scissors line without its comment lines, trimmed
+func commitMessage(text string) string {
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if line == commitScissors {
+			break
+		}
+		if !strings.HasPrefix(line, "#") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// commitHighlights colors the comment lines of a commit message and what
+// goes past 50 columns on the summary line or 72 on the others
+func commitHighlights(text string) []highlight {
+	var highlights []highlight
+	start, summarySeen := 0, false
+	for _, line := range strings.SplitAfter(text, "\n") {
+		content := strings.TrimSuffix(line, "\n")
+		if content == commitScissors {
+			// The diff below is ignored by git: show it all as a comment
+			return append(highlights, highlight{start, len(text), currentTheme.commitComment})
+		}
+		switch {
+		case strings.HasPrefix(content, "#"):
+			highlights = append(highlights, highlight{start, start + len(content), currentTheme.commitComment})
+		case !summarySeen && strings.TrimSpace(content) != "":
+			summarySeen = true
+			highlights = append(highlights, overflowHighlight(content, start, commitSummaryWidth)...)
+		default:
+			highlights = append(highlights, overflowHighlight(content, start, commitBodyWidth)...)
+		}
+		start += len(line)
+	}
+	return highlights
+}
+
+// overflowHighlight highlights what follows the first width characters of
+// line, which starts at start in the buffer
+func overflowHighlight(line string, start, width int) []highlight {
+	if utf8.RuneCountInString(line) <= width {
+		return nil
+	}
+	cut := byteOffsetOfRune(line, width)
+	return []highlight{{start + cut, start + len(line), currentTheme.commitOverflow}}
+}
+
+// commitDiffstat summarizes the changes listed in the comments of a commit
+// message: lines added and removed per file when git included the diff
+// (--verbose), otherwise the staged files with how they changed
+func commitDiffstat(text string) []string {
+	if _, diff, ok := strings.Cut(text, commitScissors+"\n"); ok {
+		return diffstat(diff)
+	}
+
+	var stat []string
+	staged := false
+	for _, line := range strings.Split(text, "\n") {
+		comment, ok := strings.CutPrefix(line, "#")
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(comment, " Changes to be committed:"):
+			staged = true
+		case strings.TrimSpace(comment) == "":
+			if len(stat) > 0 {
+				staged = false
+			}
+		case staged && strings.HasPrefix(comment, "\t"):
+			// "#\tmodified:   editor.go"
+			change, file, found := strings.Cut(strings.TrimSpace(comment), ":")
+			if found {
+				stat = append(stat, fmt.Sprintf("%-9s %s", change, strings.TrimSpace(file)))
+			}
+		}
+	}
+	return stat
+}
+
+// diffstat counts the added and removed lines of each file of a git diff
+func diffstat(diff string) []string {
+	var stat []string
+	file, added, removed := "", 0, 0
+	flush := func() {
+		if file != "" {
+			stat = append(stat, fmt.Sprintf("%s +%d -%d", file, added, removed))
+		}
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			file, added, removed = line[strings.LastIndex(line, " b/")+3:], 0, 0
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	flush()
+	return stat
+}
+
+// drawCommitPane draws the diffstat of a commit message in a box at the
+// top right of the screen, when it fits beside the 72 columns of the text
+func drawCommitPane(buf *strings.Builder, textRows int) {
+	stat := commitDiffstat(session.rope.String())
+	if len(stat) == 0 {
+		return
+	}
+	room := int(session.screenCols) - commitBodyWidth - 1
+	box := boxLines(strings.Join(stat, "\n"), "Changes", room-4, textRows-2)
+	width := utf8.RuneCountInString(box[0])
+	if width > room {
+		return
+	}
+	for i, line := range box {
+		fmt.Fprintf(buf, "\x1b[%d;%dH%s", i+1, int(session.screenCols)-width+1, line)
+	}
+	// The status bar is drawn from where the text view left off
+	fmt.Fprintf(buf, "\x1b[%d;1H", textRows+1)
+}
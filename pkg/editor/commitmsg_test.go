@@ -0,0 +1,83 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const commitTemplate = `Fix the thing that was broken in a way nobody noticed
+
+Body
+# Please enter the commit message for your changes.
+#
+# On branch master
+# Changes to be committed:
+#	modified:   editor.go
+#	new file:   commitmsg.go
+#
+# Changes not staged for commit:
+#	modified:   README.md
+#
+`
+
+func TestCommitMessageMode(t *testing.T) {
+	resetSessionForTest()
+	dir := t.TempDir()
+	session = newSession(filepath.Join(dir, ".git", "COMMIT_EDITMSG"), commitTemplate)
+	buffers = []*Session{session}
+	fireHooks(eventBufOpen, session)
+	if session.fileType() != "gitcommit" {
+		t.Fatalf("expected the gitcommit filetype, got %q", session.fileType())
+	}
+
+	highlights := commitHighlights(commitTemplate)
+	overflow := highlights[0]
+	if overflow.style != currentTheme.commitOverflow || commitTemplate[overflow.start:overflow.end] != "ced" {
+		t.Errorf("expected the summary past 50 columns highlighted, got %q", commitTemplate[overflow.start:overflow.end])
+	}
+	comments := 0
+	for _, h := range highlights {
+		if h.style == currentTheme.commitComment {
+			comments++
+		}
+	}
+	if comments != 10 {
+		t.Errorf("expected the 10 comment lines highlighted, got %d", comments)
+	}
+
+	want := []string{"modified  editor.go", "new file  commitmsg.go"}
+	if got := commitDiffstat(commitTemplate); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diffstat %q, want %q", got, want)
+	}
+}
+
+func TestCommitDiffstatVerbose(t *testing.T) {
+	text := "Summary\n" + commitScissors + "\n# Do not modify or remove the line above.\n" +
+		"diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n-old\n+new\n+more\n" +
+		"diff --git a/b.txt b/b.txt\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +0,0 @@\n-gone\n"
+	want := []string{"a.go +2 -1", "b.txt +0 -1"}
+	if got := commitDiffstat(text); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diffstat %q, want %q", got, want)
+	}
+	if got := commitMessage(text); got != "Summary" {
+		t.Errorf("the diff is not part of the message, got %q", got)
+	}
+}
+
+func TestCommitEmptyMessageWarning(t *testing.T) {
+	resetSessionForTest()
+	path := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	session = newSession(path, "\n# Please enter the commit message.\n")
+	buffers = []*Session{session}
+	fireHooks(eventBufOpen, session)
+
+	handleSave(0, nil)
+	if !strings.Contains(session.statusMessage, "empty commit message") {
+		t.Errorf("expected a warning, got %q", session.statusMessage)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the message should still be saved: %v", err)
+	}
+}
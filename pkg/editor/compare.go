@@ -0,0 +1,298 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jellexet/golang-text-editor/pkg/diff"
+)
+
+// compareView shows two buffers side by side with their differences
+type compareView struct {
+	left, right *Session
+	rowOffset   int // aligned rows scrolled off the top, shared by both sides
+}
+
+// compare is the active comparison, nil when none
+var compare *compareView
+
+// alignedRow is a screen row of the comparison: a line of each side, or -1
+// where the other side has lines this one lacks
+type alignedRow struct {
+	left, right int
+	changed     bool
+}
+
+// alignLines pairs the lines of a and b, padding the shorter side of every
+// change so that equal lines end up next to each other
+func alignLines(a, b []string) []alignedRow {
+	var rows []alignedRow
+	i, j := 0, 0
+	for _, h := range diff.Lines(a, b) {
+		for ; i < h.OldStart; i, j = i+1, j+1 {
+			rows = append(rows, alignedRow{left: i, right: j})
+		}
+		for k := 0; k < max(h.OldLines, h.NewLines); k++ {
+			row := alignedRow{left: -1, right: -1, changed: true}
+			if k < h.OldLines {
+				row.left = h.OldStart + k
+			}
+			if k < h.NewLines {
+				row.right = h.NewStart + k
+			}
+			rows = append(rows, row)
+		}
+		i, j = h.OldStart+h.OldLines, h.NewStart+h.NewLines
+	}
+	for ; i < len(a); i, j = i+1, j+1 {
+		rows = append(rows, alignedRow{left: i, right: j})
+	}
+	return rows
+}
+
+// sideLine returns the line of s shown in row, or -1
+func (r alignedRow) sideLine(s *Session) int {
+	if s == compare.left {
+		return r.left
+	}
+	return r.right
+}
+
+// shows reports whether s is one of the compared buffers
+func (c *compareView) shows(s *Session) bool {
+	return c != nil && (s == c.left || s == c.right)
+}
+
+// other returns the compared buffer that isn't active
+func (c *compareView) other() *Session {
+	if session == c.left {
+		return c.right
+	}
+	return c.left
+}
+
+// compareRows aligns the lines of both compared buffers
+func compareRows() (rows []alignedRow, left, right []string) {
+	left = strings.Split(compare.left.rope.String(), "\n")
+	right = strings.Split(compare.right.rope.String(), "\n")
+	return alignLines(left, right), left, right
+}
+
+// cursorAlignedRow returns the row showing the cursor line of the active buffer
+func cursorAlignedRow(rows []alignedRow) int {
+	for k, r := range rows {
+		if r.sideLine(session) == session.cursorRow-1 {
+			return k
+		}
+	}
+	return max(len(rows)-1, 0)
+}
+
+// compareCell draws line, tabs expanded, cut or padded to width columns
+func compareCell(line string, width int, style string) string {
+	line = renderLine(line, 0, nil)
+	end, cols := 0, 0
+	for end < len(line) && cols < width {
+		_, size := utf8.DecodeRuneInString(line[end:])
+		end += size
+		cols++
+	}
+	cell := line[:end] + strings.Repeat(" ", width-cols)
+	if style == "" {
+		return cell
+	}
+	return "\x1b[" + style + "m" + cell + "\x1b[m"
+}
+
+// drawCompareView draws both compared buffers side by side into buf and
+// returns the screen position of the cursor
+func drawCompareView(buf *strings.Builder, textRows int) (int, int) {
+	rows, left, right := compareRows()
+	textRows = max(textRows, 1)
+	cursor := cursorAlignedRow(rows)
+	if cursor < compare.rowOffset {
+		compare.rowOffset = cursor
+	}
+	if cursor >= compare.rowOffset+textRows {
+		compare.rowOffset = cursor - textRows + 1
+	}
+
+	width := max((int(session.screenCols)-1)/2, 1)
+	side := func(lines []string, n int, style string, changed bool) string {
+		if n < 0 {
+			return compareCell(strings.Repeat("-", width), width, currentTheme.diffFiller)
+		}
+		if !changed {
+			style = ""
+		}
+		return compareCell(lines[n], width, style)
+	}
+	for i := 0; i < textRows; i++ {
+		k := compare.rowOffset + i
+		if k < len(rows) {
+			r := rows[k]
+			buf.WriteString(side(left, r.left, currentTheme.diffOld, r.changed))
+			buf.WriteString("│")
+			buf.WriteString(side(right, r.right, currentTheme.diffNew, r.changed))
+		} else {
+			buf.WriteString("~")
+		}
+		buf.WriteString("\x1b[K")
+		buf.WriteString("\r\n")
+	}
+
+	lines := left
+	col := 1
+	if session == compare.right {
+		lines = right
+		col = width + 2
+	}
+	line := ""
+	if session.cursorRow-1 < len(lines) {
+		line = lines[session.cursorRow-1]
+	}
+	col += min(visualColumn(line, session.cursorCol-1), width-1)
+	return cursor - compare.rowOffset + 1, col
+}
+
+// handleCompare compares the active buffer with a file side by side
+// (:compare FILE), or ends the comparison (:compare off)
+func handleCompare(fd int, args string, callback func() byte) {
+	if args == "off" || args == "" {
+		if compare == nil {
+			session.statusMessage = "Usage: compare FILE"
+			return
+		}
+		compare = nil
+		session.statusMessage = "Comparison ended"
+		return
+	}
+
+	left := session
+	if err := openBuffer(args); err != nil {
+		session.statusMessage = fmt.Sprintf("Compare: %v", err)
+		return
+	}
+	if session == left {
+		session.statusMessage = "Compare: that is the active buffer"
+		return
+	}
+	compare = &compareView{left: left, right: session}
+	switchToBuffer(left)
+	session.statusMessage = "Alt-O: other side, :dnext/:dprev: changes, :dget/:dput: copy a change, :compare off"
+}
+
+// handleCompareSwitch moves the cursor to the other side of the comparison,
+// on the line next to the current one (Alt-O)
+func handleCompareSwitch() {
+	if !compare.shows(session) {
+		session.statusMessage = "No comparison (:compare FILE)"
+		return
+	}
+	rows, _, _ := compareRows()
+	k := cursorAlignedRow(rows)
+	other := compare.other()
+	line := -1
+	for ; k >= 0 && line < 0; k-- {
+		line = rows[k].sideLine(other)
+	}
+	col := session.cursorCol
+	switchToBuffer(other)
+	GoToLine(max(line, 0)+1, col)
+}
+
+// handleCompareNext moves the cursor to the start of the next (delta 1) or
+// previous (delta -1) change of the comparison (:dnext, :dprev)
+func handleCompareNext(delta int) {
+	if !compare.shows(session) {
+		session.statusMessage = "No comparison (:compare FILE)"
+		return
+	}
+	rows, _, _ := compareRows()
+	for k := cursorAlignedRow(rows) + delta; k >= 0 && k < len(rows); k += delta {
+		start := rows[k].changed && (k == 0 || !rows[k-1].changed)
+		if !start {
+			continue
+		}
+		// A change may have no lines on this side: stop next to it
+		line := rows[k].sideLine(session)
+		for j := k; line < 0 && j > 0; j-- {
+			line = rows[j-1].sideLine(session)
+		}
+		GoToLine(max(line, 0)+1, 1)
+		return
+	}
+	session.statusMessage = "No more changes"
+}
+
+// copyCompareHunk replaces the change under the cursor in dst with its
+// version in src; the change is found in the active buffer
+func copyCompareHunk(src, dst *Session) error {
+	if dst.readOnly {
+		return fmt.Errorf("%s is read-only", dst.filename)
+	}
+	other := splitLinesKeepEnds(compare.other().rope.String())
+	active := splitLinesKeepEnds(session.rope.String())
+	h, ok := hunkAtRow(diff.Lines(other, active), session.cursorRow-1)
+	if !ok {
+		return fmt.Errorf("no change under the cursor")
+	}
+
+	// Express the hunk as line ranges of src and dst
+	srcLines, srcStart, srcCount := active, h.NewStart, h.NewLines
+	dstLines, dstStart, dstCount := other, h.OldStart, h.OldLines
+	if src != session {
+		srcLines, srcStart, srcCount, dstLines, dstStart, dstCount =
+			dstLines, dstStart, dstCount, srcLines, srcStart, srcCount
+	}
+
+	start := len(strings.Join(dstLines[:dstStart], ""))
+	end := start + len(strings.Join(dstLines[dstStart:dstStart+dstCount], ""))
+	text := strings.Join(srcLines[srcStart:srcStart+srcCount], "")
+
+	// replaceText works on the active buffer
+	prev, cursor := session, session.cursorIdx
+	session = dst
+	replaceText(start, end, text)
+	session = prev
+	if dst == session {
+		session.cursorIdx = start
+	} else {
+		session.cursorIdx = cursor
+	}
+	updateCursorPosition()
+	return nil
+}
+
+// handleDiffGet takes the change under the cursor from the other side (:dget)
+func handleDiffGet(fd int, args string, callback func() byte) {
+	if !compare.shows(session) {
+		session.statusMessage = "No comparison (:compare FILE)"
+		return
+	}
+	if err := copyCompareHunk(compare.other(), session); err != nil {
+		session.statusMessage = fmt.Sprintf("Diff get: %v", err)
+	}
+}
+
+// handleDiffPut copies the change under the cursor to the other side (:dput)
+func handleDiffPut(fd int, args string, callback func() byte) {
+	if !compare.shows(session) {
+		session.statusMessage = "No comparison (:compare FILE)"
+		return
+	}
+	if err := copyCompareHunk(session, compare.other()); err != nil {
+		session.statusMessage = fmt.Sprintf("Diff put: %v", err)
+	}
+}
+
+// handleDiffNext jumps to the next change of the comparison (:dnext)
+func handleDiffNext(fd int, args string, callback func() byte) {
+	handleCompareNext(1)
+}
+
+// handleDiffPrev jumps to the previous change of the comparison (:dprev)
+func handleDiffPrev(fd int, args string, callback func() byte) {
+	handleCompareNext(-1)
+}
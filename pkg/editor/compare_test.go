@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAlignLines(t *testing.T) {
+	a := strings.Fields("a b c d")
+	b := strings.Fields("a x y c")
+	want := []alignedRow{
+		{0, 0, false},
+		{1, 1, true}, // b -> x
+		{-1, 2, true},
+		{2, 3, false},
+		{3, -1, true}, // d removed
+	}
+	if got := alignLines(a, b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+// compareFixture opens left.txt and compares it with right.txt
+func compareFixture(t *testing.T, left, right string) {
+	t.Helper()
+	resetSessionForTest()
+	t.Chdir(t.TempDir())
+	t.Cleanup(func() { compare = nil })
+	if err := os.WriteFile("right.txt", []byte(right), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session = newSession("left.txt", left)
+	session.screenRows, session.screenCols = 24, 41
+	buffers = []*Session{session}
+	runCommand(0, "compare right.txt", nil)
+	if !compare.shows(session) || session.filename != "left.txt" {
+		t.Fatalf("comparison not started: %s", session.statusMessage)
+	}
+}
+
+func TestCompareViewDraw(t *testing.T) {
+	compareFixture(t, "same\nold\nend\n", "same\nnew\nextra\nend\n")
+
+	var buf strings.Builder
+	row, col := drawCompareView(&buf, 5)
+	lines := strings.Split(buf.String(), "\x1b[K\r\n")
+	if !strings.HasPrefix(lines[0], "same                │same") {
+		t.Errorf("equal lines should sit side by side: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "\x1b[31mold") || !strings.Contains(lines[1], "\x1b[32mnew") {
+		t.Errorf("changed lines should be highlighted: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "----") {
+		t.Errorf("missing lines should be padded: %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], "end ") {
+		t.Errorf("lines after a change should be aligned again: %q", lines[3])
+	}
+	if row != 1 || col != 1 {
+		t.Errorf("cursor at %d,%d want 1,1", row, col)
+	}
+
+	// The other side: the cursor moves to the matching line in the right pane
+	session.cursorIdx = getLineStartIndex(3)
+	updateCursorPosition()
+	handleCompareSwitch()
+	if session.filename != "right.txt" || session.cursorRow != 4 {
+		t.Fatalf("expected right.txt line 4, got %s line %d", session.filename, session.cursorRow)
+	}
+	buf.Reset()
+	row, col = drawCompareView(&buf, 5)
+	if row != 4 || col != 22 {
+		t.Errorf("cursor at %d,%d want 4,22", row, col)
+	}
+}
+
+func TestCompareCopyHunks(t *testing.T) {
+	compareFixture(t, "a\nb\nc\nd\ne\n", "a\nB\nc\nd\n")
+
+	runCommand(0, "dnext", nil)
+	if session.cursorRow != 2 {
+		t.Fatalf("dnext should stop at line 2, got %d", session.cursorRow)
+	}
+	runCommand(0, "dget", nil)
+	if got := session.rope.String(); got != "a\nB\nc\nd\ne\n" {
+		t.Fatalf("after dget: %q", got)
+	}
+
+	runCommand(0, "dnext", nil)
+	if session.cursorRow != 5 {
+		t.Fatalf("dnext should stop at line 5, got %d", session.cursorRow)
+	}
+	runCommand(0, "dput", nil)
+	if got := compare.right.rope.String(); got != "a\nB\nc\nd\ne\n" {
+		t.Fatalf("after dput the right side is %q", got)
+	}
+	runCommand(0, "dnext", nil)
+	if session.statusMessage != "No more changes" {
+		t.Fatalf("unexpected status %q", session.statusMessage)
+	}
+
+	runCommand(0, "compare off", nil)
+	if compare != nil {
+		t.Fatal("compare off should end the comparison")
+	}
+}
@@ -0,0 +1,217 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// maxCompletions is the number of suggestions shown in the popup
+const maxCompletions = 10
+
+// isIdentifierByte reports whether b can be part of an identifier.
+// Bytes of multi-byte UTF-8 characters count as identifier bytes.
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b >= 0x80 || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// identifierPrefix returns the part of the identifier before the cursor
+func identifierPrefix() string {
+	text := session.rope.String()
+	start := session.cursorIdx
+	for start > 0 && isIdentifierByte(text[start-1]) {
+		start--
+	}
+	return text[start:session.cursorIdx]
+}
+
+// completionCandidates returns the identifiers of the open buffers that
+// start with prefix, best first. Words close to the cursor and frequent
+// words rank higher: the score is frequency * 10 / (10 + distance in lines),
+// with words only found in other buffers counted as 1000 lines away.
+func completionCandidates(prefix string) []string {
+	type stats struct {
+		count    int
+		distance int
+	}
+	words := map[string]*stats{}
+
+	for _, b := range buffers {
+		text := b.rope.String()
+		line := 1
+		for i := 0; i < len(text); {
+			if text[i] == '\n' {
+				line++
+			}
+			if !isIdentifierByte(text[i]) {
+				i++
+				continue
+			}
+			start := i
+			for i < len(text) && isIdentifierByte(text[i]) {
+				i++
+			}
+			word := text[start:i]
+			// Skip numbers, the prefix itself and the word being typed
+			if word == prefix || !strings.HasPrefix(word, prefix) || (word[0] >= '0' && word[0] <= '9') ||
+				(b == session && start <= session.cursorIdx && session.cursorIdx <= i) {
+				continue
+			}
+
+			distance := 1000
+			if b == session {
+				distance = abs(line - session.cursorRow)
+			}
+			if s, ok := words[word]; ok {
+				s.count++
+				s.distance = min(s.distance, distance)
+			} else {
+				words[word] = &stats{count: 1, distance: distance}
+			}
+		}
+	}
+
+	candidates := make([]string, 0, len(words))
+	for w := range words {
+		candidates = append(candidates, w)
+	}
+	score := func(w string) float64 {
+		s := words[w]
+		return float64(s.count) * 10 / float64(10+s.distance)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		si, sj := score(candidates[i]), score(candidates[j])
+		if si != sj {
+			return si > sj
+		}
+		return candidates[i] < candidates[j]
+	})
+	if len(candidates) > maxCompletions {
+		candidates = candidates[:maxCompletions]
+	}
+	return candidates
+}
+
+// handleComplete shows a popup of words completing the identifier before
+// the cursor (Ctrl-N), or of files and directories completing the path
+// before it, when there is one. Ctrl-N/Ctrl-P or the arrows select, Return
+// or Tab accept, typing keeps narrowing the list, and any other key closes
+// the popup and is handled as usual.
+func handleComplete(fd int, callback func() byte) {
+	if prefix := pathPrefix(); prefix != "" && len(pathCandidates(prefix)) > 0 {
+		completeWith(fd, callback, pathPrefix, pathCandidates, isPathByte)
+		return
+	}
+	completeWith(fd, callback, identifierPrefix, completionCandidates, isIdentifierByte)
+}
+
+// completeWith runs the completion popup for the text before the cursor
+// returned by prefixBefore, which typing bytes for which continues is true
+// extends
+func completeWith(fd int, callback func() byte, prefixBefore func() string, candidatesFor func(string) []string, continues func(byte) bool) {
+	var prefix string
+	popup := newListOverlay("", nil, 0, placeNearCursor)
+	// update lists the candidates for the text before the cursor, and
+	// reports whether there are any
+	update := func() bool {
+		prefix = prefixBefore()
+		if prefix == "" {
+			session.statusMessage = "Nothing to complete"
+			return false
+		}
+		popup.lines = candidatesFor(prefix)
+		popup.selected = 0
+		if len(popup.lines) == 0 {
+			session.statusMessage = fmt.Sprintf("No completions for %q", prefix)
+			return false
+		}
+		return true
+	}
+	if !update() {
+		return
+	}
+
+	popup.run(fd, callback, func(key Key) bool {
+		switch {
+		case key == ctrl('n'):
+			popup.selected = (popup.selected + 1) % len(popup.lines)
+		case key == ctrl('p'):
+			popup.selected = (popup.selected + len(popup.lines) - 1) % len(popup.lines)
+		case key == ReturnKey || key == TabKey:
+			handleInsert(popup.lines[popup.selected][len(prefix):])
+			return true
+		case key == EscKey:
+			return true
+		case key == BackspaceKey:
+			handleBackspace()
+			return !update()
+		default:
+			if c, ok := key.char(); ok && continues(c) {
+				handleInsert(string(c))
+				return !update()
+			}
+			pendingKey = key
+			return true
+		}
+		return false
+	})
+}
+
+// isPathByte reports whether b can be part of a file path typed in the text
+func isPathByte(b byte) bool {
+	return isIdentifierByte(b) || strings.IndexByte("/.-~+@%", b) >= 0
+}
+
+// pathPrefix returns the path before the cursor, or "" when the text
+// before it doesn't look like a path: it must hold a '/' or start with '~'
+func pathPrefix() string {
+	text := session.rope.String()
+	start := session.cursorIdx
+	for start > 0 && isPathByte(text[start-1]) {
+		start--
+	}
+	prefix := text[start:session.cursorIdx]
+	if !strings.Contains(prefix, "/") && !strings.HasPrefix(prefix, "~") {
+		return ""
+	}
+	return prefix
+}
+
+// pathCandidates returns the paths of the files and directories completing
+// prefix, relative to the working directory, directories ending with '/'.
+// Hidden entries are only listed once prefix names them with their dot.
+func pathCandidates(prefix string) []string {
+	if prefix == "~" {
+		return []string{"~/"}
+	}
+	dir, base := "", prefix
+	if i := strings.LastIndexByte(prefix, '/'); i >= 0 {
+		dir, base = prefix[:i+1], prefix[i+1:]
+	}
+	readDir := expandHome(dir)
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		}
+		if dir+name != prefix {
+			candidates = append(candidates, dir+name)
+		}
+		if len(candidates) == maxCompletions {
+			break
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,93 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestCompletionCandidates_RankedByProximityAndFrequency(t *testing.T) {
+	resetSessionForTest()
+	other := newSession("other.go", "counterFar counterFar counterFar counterFar")
+	buffers = append(buffers, other)
+
+	session.rope = buffer.NewRope("countAll\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\ncounter\nco")
+	session.cursorIdx = session.rope.Length()
+	updateCursorPosition()
+
+	got := completionCandidates("co")
+	want := []string{"counter", "countAll", "counterFar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestComplete_SelectAndAccept(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("alpha alps\nal")
+	session.cursorIdx = session.rope.Length()
+	updateCursorPosition()
+
+	// Ctrl-N moves to the second suggestion, Return accepts it
+	handleComplete(0, makeCallback([]byte{CtrlN, Return}))
+	if got := session.rope.String(); got != "alpha alps\nalps" {
+		t.Fatalf("unexpected buffer %q", got)
+	}
+}
+
+func TestComplete_OtherKeyIsHandedBack(t *testing.T) {
+	resetSessionForTest()
+	pendingKey = Key{}
+	session.rope = buffer.NewRope("alpha\nal")
+	session.cursorIdx = session.rope.Length()
+	updateCursorPosition()
+
+	handleComplete(0, makeCallback([]byte{'p', ' '}))
+	if got := session.rope.String(); got != "alpha\nalp" {
+		t.Fatalf("typing should narrow the completion, buffer is %q", got)
+	}
+	if pendingKey != (Key{Rune: ' '}) {
+		t.Fatalf("space should close the popup and be handed back, got %+v", pendingKey)
+	}
+	pendingKey = Key{}
+}
+
+func TestComplete_Paths(t *testing.T) {
+	resetSessionForTest()
+	dir := t.TempDir()
+	for _, name := range []string{"config.yaml", "configs/app.conf", ".hidden"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := pathCandidates(dir + "/con"); !reflect.DeepEqual(got, []string{dir + "/config.yaml", dir + "/configs/"}) {
+		t.Errorf("got %q", got)
+	}
+	if got := pathCandidates(dir + "/."); !reflect.DeepEqual(got, []string{dir + "/.hidden"}) {
+		t.Errorf("hidden files should only come when asked for, got %q", got)
+	}
+
+	// The second candidate is the directory, where typing continues
+	session.rope = buffer.NewRope("include " + dir + "/con")
+	session.cursorIdx = session.rope.Length()
+	updateCursorPosition()
+	handleComplete(0, makeCallback([]byte{CtrlN, Tab}))
+	handleComplete(0, makeCallback([]byte{Return}))
+	if got := session.rope.String(); got != "include "+dir+"/configs/app.conf" {
+		t.Fatalf("unexpected buffer %q", got)
+	}
+
+	// Words that aren't paths complete from the buffers
+	session.rope = buffer.NewRope("alpha al")
+	session.cursorIdx = session.rope.Length()
+	updateCursorPosition()
+	handleComplete(0, makeCallback([]byte{Return}))
+	if got := session.rope.String(); got != "alpha alpha" {
+		t.Errorf("unexpected buffer %q", got)
+	}
+}
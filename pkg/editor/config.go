@@ -0,0 +1,523 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is an option that can be changed from the config file,
+// the command line or the :set command
+type setting struct {
+	get func() string
+	set func(value string) error
+
+	// Options that can differ between buffers (:setlocal) have these too.
+	// An option with only these is always local to its buffer.
+	getLocal func(s *Session) string
+	setLocal func(s *Session, value string) error
+}
+
+// settings holds every option by name
+var settings = map[string]setting{
+	"tabsize": {
+		get: func() string { return strconv.Itoa(tabSize) },
+		set: func(value string) error {
+			n, err := parseTabSize(value)
+			if err == nil {
+				tabSize = n
+			}
+			return err
+		},
+		getLocal: func(s *Session) string { return strconv.Itoa(s.tabWidth()) },
+		setLocal: func(s *Session, value string) error {
+			n, err := parseTabSize(value)
+			if err == nil {
+				s.tabSize = n
+			}
+			return err
+		},
+	},
+	"theme": {
+		get: func() string {
+			for name, t := range themes {
+				if t == currentTheme {
+					return name
+				}
+			}
+			return ""
+		},
+		set: setTheme,
+	},
+	"readonly": {
+		get: func() string { return strconv.FormatBool(session.readOnly) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			if err != nil {
+				return err
+			}
+			// Buffers opened later follow the setting of the one it was changed in
+			session.readOnly = b
+			openReadOnly = b
+			return nil
+		},
+		getLocal: func(s *Session) string { return strconv.FormatBool(s.readOnly) },
+		setLocal: func(s *Session, value string) error {
+			b, err := parseBool(value)
+			if err == nil {
+				s.readOnly = b
+			}
+			return err
+		},
+	},
+	"expandtab": {
+		get: func() string { return strconv.FormatBool(expandTab) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			if err != nil {
+				return err
+			}
+			// Like readonly, it applies to the active buffer and those opened later
+			session.expandTab = b
+			expandTab = b
+			return nil
+		},
+		getLocal: func(s *Session) string { return strconv.FormatBool(s.expandTab) },
+		setLocal: func(s *Session, value string) error {
+			b, err := parseBool(value)
+			if err == nil {
+				s.expandTab = b
+			}
+			return err
+		},
+	},
+	"formatonsave": {
+		get: func() string { return strconv.FormatBool(formatOnSave) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			formatOnSave = b && err == nil
+			return err
+		},
+	},
+	"scrolloff": {
+		get: func() string { return strconv.Itoa(scrollOff) },
+		set: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("scrolloff must be a number of lines")
+			}
+			scrollOff = n
+			return nil
+		},
+	},
+	"keytimeout": {
+		get: func() string { return keyTimeout.String() },
+		set: func(value string) error {
+			// A bare number is in milliseconds
+			if _, err := strconv.Atoi(value); err == nil {
+				value += "ms"
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d < readTimeout || d > 10*time.Second {
+				return fmt.Errorf("expected a duration between 100ms and 10s, like 300ms")
+			}
+			keyTimeout = d
+			return nil
+		},
+	},
+	"minimap": {
+		get: func() string { return strconv.FormatBool(minimapEnabled) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			minimapEnabled = b && err == nil
+			return err
+		},
+	},
+	"keyhints": {
+		get: func() string { return strconv.FormatBool(keyHintsEnabled) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			keyHintsEnabled = b && err == nil
+			return err
+		},
+	},
+	"backupdir": {
+		get: func() string { return backupDir },
+		set: func(value string) error {
+			if value == "off" {
+				value = ""
+			}
+			backupDir = value
+			return nil
+		},
+	},
+	"dateformat": {
+		get: func() string { return dateFormat },
+		set: func(value string) error {
+			if value == "" {
+				return fmt.Errorf("empty format")
+			}
+			dateFormat = value
+			return nil
+		},
+	},
+	"timeformat": {
+		get: func() string { return timeFormat },
+		set: func(value string) error {
+			if value == "" {
+				return fmt.Errorf("empty format")
+			}
+			timeFormat = value
+			return nil
+		},
+	},
+	"templatedir": {
+		get: func() string { return templateDir },
+		set: func(value string) error {
+			if value == "off" {
+				value = ""
+			}
+			templateDir = value
+			return nil
+		},
+	},
+	"plugindir": {
+		get: func() string { return pluginDir },
+		set: func(value string) error {
+			if value == "off" {
+				value = ""
+			}
+			pluginDir = value
+			return nil
+		},
+	},
+	"backupinterval": {
+		get: func() string { return backupInterval.String() },
+		set: func(value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < time.Second {
+				return fmt.Errorf("expected a duration of at least 1s, like 30s or 5m")
+			}
+			backupInterval = d
+			return nil
+		},
+	},
+	"backupkeep": {
+		get: func() string { return strconv.Itoa(backupKeep) },
+		set: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return fmt.Errorf("backupkeep must be a positive number")
+			}
+			backupKeep = n
+			return nil
+		},
+	},
+	"screenreader": {
+		get: func() string { return strconv.FormatBool(screenReader) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			screenReader = b && err == nil
+			return err
+		},
+	},
+	"perfstats": {
+		get: func() string { return strconv.FormatBool(perfStats) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			perfStats = b && err == nil
+			return err
+		},
+	},
+	"keepbom": {
+		get: func() string { return strconv.FormatBool(keepBOM) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			if err != nil {
+				return err
+			}
+			keepBOM = b
+			return nil
+		},
+	},
+	"subword": {
+		get: func() string { return strconv.FormatBool(subwordMotion) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			subwordMotion = b && err == nil
+			return err
+		},
+	},
+	"scrollbar": {
+		get: func() string { return strconv.FormatBool(scrollbarEnabled) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			scrollbarEnabled = b && err == nil
+			return err
+		},
+	},
+	"mouse": {
+		get: func() string { return strconv.FormatBool(mouseEnabled) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			mouseEnabled = b && err == nil
+			return err
+		},
+	},
+	"scrollbind": {
+		get: func() string { return strconv.FormatBool(scrollBind) },
+		set: func(value string) error {
+			b, err := parseBool(value)
+			scrollBind = b && err == nil
+			return err
+		},
+	},
+	"statusline": {
+		get: func() string { return strings.Join(statusLine, ",") },
+		set: setStatusLine,
+	},
+	"largefile": {
+		get: func() string { return strconv.Itoa(largeFileThreshold) },
+		set: func(value string) error {
+			n, err := parseSize(value)
+			if err == nil {
+				largeFileThreshold = n
+			}
+			return err
+		},
+	},
+}
+
+// parseTabSize checks the value of the tabsize option
+func parseTabSize(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > 16 {
+		return 0, fmt.Errorf("tab size must be between 1 and 16")
+	}
+	return n, nil
+}
+
+// fileType returns the type of the buffer, which decides its formatter,
+// language server and spell checked regions: the filetype option when set,
+// the file extension otherwise ("go" for main.go)
+func (s *Session) fileType() string {
+	if s.filetype != "" {
+		return s.filetype
+	}
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(s.filename), "."))
+}
+
+// value returns the option as it applies to the active buffer
+func (st setting) value() string {
+	if st.getLocal != nil {
+		return st.getLocal(session)
+	}
+	return st.get()
+}
+
+// parseBool accepts the usual ways of writing a yes/no value
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "on", "yes", "1":
+		return true, nil
+	case "false", "off", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not on or off", value)
+}
+
+// Set changes the option called name
+func Set(name, value string) error {
+	s, ok := settings[name]
+	if !ok {
+		return fmt.Errorf("unknown option %q", name)
+	}
+	set := s.set
+	if set == nil {
+		set = func(value string) error { return s.setLocal(session, value) }
+	}
+	if err := set(strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// SetLocal changes the option called name for the active buffer only
+func SetLocal(name, value string) error {
+	s, ok := settings[name]
+	if !ok {
+		return fmt.Errorf("unknown option %q", name)
+	}
+	if s.setLocal == nil {
+		return fmt.Errorf("%s is the same for every buffer, use set", name)
+	}
+	if err := s.setLocal(session, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// DefaultConfigPath returns where the config file is looked for when none
+// is given on the command line
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "goedit", "config")
+}
+
+// configPath is the config file last loaded, watched for changes
+var configPath string
+
+// configDisk is the state on disk of the config file when it was loaded
+var configDisk diskState
+
+// configCheckedAt is when the config file was last looked at
+var configCheckedAt time.Time
+
+// LoadConfig applies the "name = value" lines of a config file.
+// Lines after a "[filetype]" header set local options of the buffers of
+// that filetype instead, lines after "[abbreviations]" abbreviations,
+// lines after "[generators]" the generators of :gen and lines after
+// "[plugins]" the capabilities of WASM plugins.
+// Blank lines and lines starting with '#' are ignored.
+// The file is watched: changes to it are applied while editing.
+func LoadConfig(path string) error {
+	configPath, configDisk = path, statFile(path)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	fileType := ""
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if header, ok := strings.CutPrefix(line, "["); ok && strings.HasSuffix(header, "]") {
+			fileType = strings.ToLower(strings.TrimPrefix(strings.TrimSuffix(header, "]"), "."))
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			return fmt.Errorf("%s:%d: expected name = value", path, lineNo)
+		}
+		var err error
+		if fileType == "abbreviations" {
+			err = setAbbreviation(strings.TrimSpace(name), strings.TrimSpace(value))
+		} else if fileType == "generators" {
+			err = setGenerator(strings.TrimSpace(name), strings.TrimSpace(value))
+		} else if fileType == "plugins" {
+			err = setPluginGrant(strings.TrimSpace(name), value)
+		} else if fileType != "" {
+			err = setProfileOption(fileType, strings.TrimSpace(name), value)
+		} else {
+			err = Set(strings.TrimSpace(name), value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// reloadChangedConfig applies the config file again when it changed on
+// disk, looking at it at most once a second. It is called by the main loop
+// between keys and reports whether the file was reloaded.
+func reloadChangedConfig(now time.Time) bool {
+	if configPath == "" || now.Sub(configCheckedAt) < time.Second {
+		return false
+	}
+	configCheckedAt = now
+	disk := statFile(configPath)
+	if disk == configDisk {
+		return false
+	}
+	configDisk = disk
+	// A file removed leaves the options as they are
+	if disk == (diskState{}) {
+		return false
+	}
+	reloadConfig()
+	return true
+}
+
+// reloadConfig applies the config file again, reporting parse errors in the
+// status line. Options no longer in the file keep their current value.
+func reloadConfig() {
+	if err := LoadConfig(configPath); err != nil {
+		session.statusMessage = fmt.Sprintf("Config: %v", err)
+		return
+	}
+	session.statusMessage = "Reloaded " + displayPath(configPath)
+}
+
+// handleReloadConfig applies the config file again (:reload-config)
+func handleReloadConfig(fd int, args string, callback func() byte) {
+	if configPath == "" {
+		session.statusMessage = "No config file"
+		return
+	}
+	reloadConfig()
+}
+
+// handleSet shows every option, shows one, or changes one (:set [name [value]])
+func handleSet(fd int, args string, callback func() byte) {
+	setOptions(args, false)
+}
+
+// handleSetLocal shows the options that can differ between buffers, shows
+// one, or changes one for the active buffer (:setlocal [name [value]])
+func handleSetLocal(fd int, args string, callback func() byte) {
+	setOptions(args, true)
+}
+
+// setOptions runs :set, or :setlocal when local is true
+func setOptions(args string, local bool) {
+	name, value, _ := strings.Cut(args, " ")
+	if name == "" {
+		names := make([]string, 0, len(settings))
+		for n, s := range settings {
+			if !local || s.getLocal != nil {
+				names = append(names, n+"="+s.value())
+			}
+		}
+		sort.Strings(names)
+		session.statusMessage = strings.Join(names, " ")
+		return
+	}
+	// "name=value" works as well as "name value"
+	if n, v, found := strings.Cut(name, "="); found {
+		name, value = n, v
+	}
+
+	command, set := "Set", Set
+	if local {
+		command, set = "Setlocal", SetLocal
+	}
+	if strings.TrimSpace(value) == "" {
+		s, ok := settings[name]
+		if !ok {
+			session.statusMessage = fmt.Sprintf("Unknown option %q", name)
+			return
+		}
+		session.statusMessage = name + "=" + s.value()
+		return
+	}
+	if err := set(name, value); err != nil {
+		session.statusMessage = fmt.Sprintf("%s: %v", command, err)
+		return
+	}
+	session.statusMessage = name + "=" + settings[name].value()
+}
@@ -0,0 +1,217 @@
+package editor
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// saveSettings restores the options changed by a test
+func saveSettings(t *testing.T) {
+	oldTab, oldTheme, oldRO, oldFormat := tabSize, currentTheme, openReadOnly, formatOnSave
+	oldMinimap, oldScrollOff, oldExpand, oldHints := minimapEnabled, scrollOff, expandTab, keyHintsEnabled
+	oldKeyTimeout, oldPerf, oldBind := keyTimeout, perfStats, scrollBind
+	oldScrollbar, oldMouse, oldTemplates, oldSubword := scrollbarEnabled, mouseEnabled, templateDir, subwordMotion
+	oldKeepBOM, oldDate, oldTime := keepBOM, dateFormat, timeFormat
+	oldBackupDir, oldInterval, oldKeep := backupDir, backupInterval, backupKeep
+	oldStatusLine, oldConfig, oldConfigDisk := statusLine, configPath, configDisk
+	oldProfiles := map[string]map[string]string{}
+	for fileType, profile := range fileTypeProfiles {
+		oldProfiles[fileType] = maps.Clone(profile)
+	}
+	t.Cleanup(func() {
+		tabSize, currentTheme, openReadOnly, formatOnSave = oldTab, oldTheme, oldRO, oldFormat
+		minimapEnabled, scrollOff, expandTab, keyHintsEnabled = oldMinimap, oldScrollOff, oldExpand, oldHints
+		keyTimeout, perfStats, scrollBind = oldKeyTimeout, oldPerf, oldBind
+		scrollbarEnabled, mouseEnabled, templateDir, subwordMotion = oldScrollbar, oldMouse, oldTemplates, oldSubword
+		keepBOM, dateFormat, timeFormat = oldKeepBOM, oldDate, oldTime
+		backupDir, backupInterval, backupKeep = oldBackupDir, oldInterval, oldKeep
+		statusLine, configPath, configDisk = oldStatusLine, oldConfig, oldConfigDisk
+		fileTypeProfiles = oldProfiles
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	path := filepath.Join(t.TempDir(), "config")
+	config := "# editor settings\n\ntabsize = 4\ntheme=dark\nformatonsave = off\n"
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if tabSize != 4 || currentTheme != themes["dark"] || formatOnSave {
+		t.Errorf("config not applied: tabsize %d, theme %+v, formatonsave %v", tabSize, currentTheme, formatOnSave)
+	}
+
+	if err := os.WriteFile(path, []byte("tabsize = 4\ncolour = red\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("expected an error pointing at line 2, got %v", err)
+	}
+}
+
+func TestReloadChangedConfig(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("tabsize = 4\n"), 0644)
+	if err := LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	now := configCheckedAt.Add(time.Minute)
+	if reloadChangedConfig(now) {
+		t.Fatal("an unchanged config should not be reloaded")
+	}
+
+	os.WriteFile(path, []byte("tabsize = 2\ntheme = light\n"), 0644)
+	if reloadChangedConfig(now.Add(time.Millisecond)) {
+		t.Error("the config should be looked at once a second at most")
+	}
+	if !reloadChangedConfig(now.Add(time.Second)) {
+		t.Fatal("a changed config should be reloaded")
+	}
+	if tabSize != 2 || currentTheme != themes["light"] || !strings.HasPrefix(session.statusMessage, "Reloaded") {
+		t.Errorf("config not applied: tabsize %d, status %q", tabSize, session.statusMessage)
+	}
+
+	// Errors show up in the status line
+	os.WriteFile(path, []byte("tabsize = 3\ntheme = neon\n"), 0644)
+	runCommand(0, "reload-config", nil)
+	if tabSize != 3 || !strings.Contains(session.statusMessage, ":2:") {
+		t.Errorf("tabsize %d, status %q: want the error of line 2", tabSize, session.statusMessage)
+	}
+}
+
+func TestSetValidatesValues(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	for name, value := range map[string]string{"tabsize": "0", "theme": "neon", "readonly": "maybe", "nosuch": "1"} {
+		if err := Set(name, value); err == nil {
+			t.Errorf("Set(%q, %q) should fail", name, value)
+		}
+	}
+}
+
+func TestHandleSet(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+
+	runCommand(0, "set tabsize 2", nil)
+	if tabSize != 2 || session.statusMessage != "tabsize=2" {
+		t.Errorf("tabsize %d, status %q", tabSize, session.statusMessage)
+	}
+	runCommand(0, "set theme=light", nil)
+	if session.statusMessage != "theme=light" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+	runCommand(0, "set", nil)
+	if !strings.Contains(session.statusMessage, "tabsize=2") || !strings.Contains(session.statusMessage, "theme=light") {
+		t.Errorf("listing misses options: %q", session.statusMessage)
+	}
+}
+
+func TestSetLocal(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	session = newSession("main.go", "\tx")
+	buffers = []*Session{session}
+	AddBuffer("notes", "\tx", 0, 0)
+
+	runCommand(0, "setlocal tabsize=2", nil)
+	runCommand(0, "setlocal readonly on", nil)
+	if session.statusMessage != "readonly=true" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+	if got := visualColumn("\tx", 1); got != 2 {
+		t.Errorf("expected a tab of 2 columns in this buffer, got %d", got)
+	}
+	if openReadOnly {
+		t.Error("setlocal readonly should not apply to buffers opened later")
+	}
+
+	runCommand(0, "set tabsize 4", nil)
+	if got := visualColumn("\tx", 1); got != 2 {
+		t.Errorf("the local tab size should win over the global one, got %d", got)
+	}
+	runCommand(0, "bn", nil)
+	if got := visualColumn("\tx", 1); got != 4 || session.readOnly {
+		t.Errorf("other buffers follow the global options: tab %d, read-only %v", got, session.readOnly)
+	}
+
+	// The filetype comes from the extension unless set
+	if session.fileType() != "" || buffers[0].fileType() != "go" {
+		t.Errorf("detected filetypes %q and %q", session.fileType(), buffers[0].fileType())
+	}
+	runCommand(0, "setlocal filetype=.MD", nil)
+	if session.statusMessage != "filetype=md" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+
+	runCommand(0, "setlocal theme=dark", nil)
+	if !strings.Contains(session.statusMessage, "same for every buffer") {
+		t.Errorf("theme is global only, status %q", session.statusMessage)
+	}
+	runCommand(0, "setlocal", nil)
+	if strings.Contains(session.statusMessage, "theme") || !strings.Contains(session.statusMessage, "filetype=md") {
+		t.Errorf("setlocal should list the local options: %q", session.statusMessage)
+	}
+}
+
+func TestReadOnlyBuffer(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	path := filepath.Join(t.TempDir(), "ro.txt")
+	session = newSession(path, "keep")
+	buffers = []*Session{session}
+
+	runCommand(0, "set readonly on", nil)
+	handleInsert("x")
+	handleBackspace()
+	replaceText(0, 1, "K")
+	handleSave(0, nil)
+	if got := session.rope.String(); got != "keep" {
+		t.Errorf("read-only buffer changed to %q", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("read-only buffer should not be saved")
+	}
+	if !strings.Contains(session.statusMessage, "read-only") {
+		t.Errorf("status %q should explain the refusal", session.statusMessage)
+	}
+	if other := newSession("other.txt", ""); !other.readOnly {
+		t.Error("buffers opened after setting readonly should be read-only")
+	}
+
+	runCommand(0, "set readonly off", nil)
+	handleInsert("!")
+	if got := session.rope.String(); got != "!keep" {
+		t.Errorf("edit after readonly off gave %q", got)
+	}
+}
+
+func TestCycleBuffers(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("a.txt", "a")
+	buffers = []*Session{session}
+	AddBuffer("b.txt", "b\nbb\n", 2, 2)
+	AddBuffer("c.txt", "c", 0, 0)
+
+	runCommand(0, "bn", nil)
+	if session.filename != "b.txt" || session.cursorRow != 2 || session.cursorCol != 2 {
+		t.Fatalf("expected b.txt at 2:2, got %s at %d:%d", session.filename, session.cursorRow, session.cursorCol)
+	}
+	runCommand(0, "bp", nil)
+	runCommand(0, "bp", nil)
+	if session.filename != "c.txt" {
+		t.Fatalf("bp from the first buffer should wrap to the last, got %s", session.filename)
+	}
+}
@@ -0,0 +1,58 @@
+package editor
+
+import "fmt"
+
+// answer is the reply to a question asked with confirm
+type answer int
+
+const (
+	answerCancel answer = iota // Esc: neither, stop what asked
+	answerYes
+	answerNo
+	answerAll  // yes, and to the questions that would follow
+	answerNone // no, and to the questions that would follow
+)
+
+// confirm asks question on the status bar and returns the answer, a single
+// key rather than text typed and entered: y or n, Esc to cancel, and when
+// all is set, for questions asked about several things in a row, a for yes
+// to all and Ctrl-Q for no to all. The question tells the keys.
+func confirm(question string, all bool, callback func() byte) answer {
+	choices := []Key{{Rune: 'y'}, {Rune: 'Y'}, {Rune: 'n'}, {Rune: 'N'}, EscKey}
+	if all {
+		choices = append(choices, Key{Rune: 'a'}, Key{Rune: 'A'}, ctrl('q'))
+	}
+	switch readChoice(question, choices, callback) {
+	case Key{Rune: 'y'}, Key{Rune: 'Y'}:
+		return answerYes
+	case Key{Rune: 'n'}, Key{Rune: 'N'}:
+		return answerNo
+	case Key{Rune: 'a'}, Key{Rune: 'A'}:
+		return answerAll
+	case ctrl('q'):
+		return answerNone
+	}
+	return answerCancel
+}
+
+// readChoice draws prompt on the status bar and waits for one of the keys
+// of choices, which it returns
+func readChoice(prompt string, choices []Key, callback func() byte) Key {
+	drawStatusPrompt(prompt)
+	for {
+		key := editorReadKeypress(callback)
+		for _, c := range choices {
+			if key == c {
+				return key
+			}
+		}
+	}
+}
+
+// drawStatusPrompt draws prompt on the status bar, with the cursor after it
+func drawStatusPrompt(prompt string) {
+	statusRow := int(session.screenRows) - statusRows() + 1
+	fmt.Fprintf(output, "\x1b[%d;1H\x1b[%sm%s\x1b[K\x1b[m\x1b[%d;%dH\x1b[?25h",
+		statusRow, currentTheme.statusBar, prompt, statusRow, len(prompt)+1)
+	invalidateFrame()
+}
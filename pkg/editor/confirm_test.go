@@ -0,0 +1,25 @@
+package editor
+
+import "testing"
+
+func TestConfirm(t *testing.T) {
+	resetSessionForTest()
+	for _, tc := range []struct {
+		keys []byte
+		all  bool
+		want answer
+	}{
+		{[]byte("y"), false, answerYes},
+		{[]byte("N"), false, answerNo},
+		{[]byte{Esc}, false, answerCancel},
+		// Keys other than the answers are ignored
+		{[]byte("xy"), false, answerYes},
+		{[]byte("an"), false, answerNo},
+		{[]byte("a"), true, answerAll},
+		{[]byte{CtrlQ}, true, answerNone},
+	} {
+		if got := confirm("Sure?", tc.all, makeCallback(tc.keys)); got != tc.want {
+			t.Errorf("confirm with %q, all %v = %v, want %v", tc.keys, tc.all, got, tc.want)
+		}
+	}
+}
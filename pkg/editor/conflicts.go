@@ -0,0 +1,182 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// conflict is a merge conflict of the buffer, as byte ranges. The base
+// section only exists in the diff3 style, otherwise baseStart == baseEnd.
+type conflict struct {
+	start, end             int // the whole conflict, markers included
+	oursStart, oursEnd     int
+	baseStart, baseEnd     int
+	theirsStart, theirsEnd int
+}
+
+func init() {
+	addHook(eventBufOpen, func(s *Session) error {
+		if n := len(findConflicts(s.rope.String())); n > 0 {
+			s.statusMessage = fmt.Sprintf("%d merge conflicts (:conflict-next, :ours, :theirs, :both)", n)
+		}
+		return nil
+	})
+}
+
+// findConflicts returns the conflicts marked in text by git, in order.
+// Markers are only recognized at the start of a line.
+func findConflicts(text string) []conflict {
+	if !strings.Contains(text, "<<<<<<<") {
+		return nil
+	}
+
+	var conflicts []conflict
+	var c conflict
+	state := 0 // 0 outside, 1 in ours, 2 in base, 3 in theirs
+	for pos := 0; pos < len(text); {
+		lineEnd := strings.IndexByte(text[pos:], '\n')
+		next := len(text)
+		if lineEnd >= 0 {
+			next = pos + lineEnd + 1
+		}
+		line := text[pos:next]
+
+		switch {
+		case strings.HasPrefix(line, "<<<<<<<"):
+			c = conflict{start: pos, oursStart: next}
+			state = 1
+		case state == 1 && strings.HasPrefix(line, "|||||||"):
+			c.oursEnd = pos
+			c.baseStart = next
+			state = 2
+		case (state == 1 || state == 2) && strings.HasPrefix(line, "======="):
+			if state == 1 {
+				c.oursEnd = pos
+				c.baseStart, c.baseEnd = pos, pos
+			} else {
+				c.baseEnd = pos
+			}
+			c.theirsStart = next
+			state = 3
+		case state == 3 && strings.HasPrefix(line, ">>>>>>>"):
+			c.theirsEnd = pos
+			c.end = next
+			conflicts = append(conflicts, c)
+			state = 0
+		}
+		pos = next
+	}
+	return conflicts
+}
+
+// conflictHighlights colors the sections and markers of every conflict
+func conflictHighlights(text string) []highlight {
+	var highlights []highlight
+	for _, c := range findConflicts(text) {
+		sections := []highlight{
+			{c.start, c.oursStart, currentTheme.conflictMarker},
+			{c.oursStart, c.oursEnd, currentTheme.conflictOurs},
+			{c.oursEnd, c.baseStart, currentTheme.conflictMarker},
+			{c.baseStart, c.baseEnd, currentTheme.conflictBase},
+			{c.baseEnd, c.theirsStart, currentTheme.conflictMarker},
+			{c.theirsStart, c.theirsEnd, currentTheme.conflictTheirs},
+			{c.theirsEnd, c.end, currentTheme.conflictMarker},
+		}
+		for _, h := range sections {
+			if h.start < h.end {
+				highlights = append(highlights, h)
+			}
+		}
+	}
+	return highlights
+}
+
+// conflictAtCursor returns the conflict containing the cursor
+func conflictAtCursor() (conflict, bool) {
+	for _, c := range findConflicts(session.rope.String()) {
+		if session.cursorIdx >= c.start && session.cursorIdx < c.end {
+			return c, true
+		}
+	}
+	return conflict{}, false
+}
+
+// nextConflict moves the cursor to the start of the next (delta 1) or
+// previous (delta -1) conflict, wrapping around the buffer
+func nextConflict(delta int) {
+	conflicts := findConflicts(session.rope.String())
+	if len(conflicts) == 0 {
+		session.statusMessage = "No merge conflicts"
+		return
+	}
+
+	i := 0
+	if delta > 0 {
+		for i < len(conflicts) && conflicts[i].start <= session.cursorIdx {
+			i++
+		}
+		i %= len(conflicts)
+	} else {
+		i = len(conflicts) - 1
+		for i >= 0 && conflicts[i].start >= session.cursorIdx {
+			i--
+		}
+		i = (i + len(conflicts)) % len(conflicts)
+	}
+	session.cursorIdx = conflicts[i].start
+	updateCursorPosition()
+	session.statusMessage = fmt.Sprintf("Conflict %d/%d", i+1, len(conflicts))
+}
+
+// resolveConflict replaces the conflict under the cursor with the sections
+// of it that pick returns
+func resolveConflict(pick func(text string, c conflict) string) {
+	if !editable() {
+		return
+	}
+	c, ok := conflictAtCursor()
+	if !ok {
+		session.statusMessage = "No merge conflict under the cursor"
+		return
+	}
+	text := session.rope.String()
+	replaceText(c.start, c.end, pick(text, c))
+	session.cursorIdx = c.start
+	updateCursorPosition()
+	if n := len(findConflicts(session.rope.String())); n > 0 {
+		session.statusMessage = fmt.Sprintf("%d merge conflicts left", n)
+	} else {
+		session.statusMessage = "All merge conflicts resolved"
+	}
+}
+
+// handleConflictNext jumps to the next merge conflict (:conflict-next)
+func handleConflictNext(fd int, args string, callback func() byte) {
+	nextConflict(1)
+}
+
+// handleConflictPrev jumps to the previous merge conflict (:conflict-prev)
+func handleConflictPrev(fd int, args string, callback func() byte) {
+	nextConflict(-1)
+}
+
+// handleConflictOurs keeps our side of the conflict under the cursor (:ours)
+func handleConflictOurs(fd int, args string, callback func() byte) {
+	resolveConflict(func(text string, c conflict) string {
+		return text[c.oursStart:c.oursEnd]
+	})
+}
+
+// handleConflictTheirs keeps their side of the conflict under the cursor (:theirs)
+func handleConflictTheirs(fd int, args string, callback func() byte) {
+	resolveConflict(func(text string, c conflict) string {
+		return text[c.theirsStart:c.theirsEnd]
+	})
+}
+
+// handleConflictBoth keeps our side followed by theirs (:both)
+func handleConflictBoth(fd int, args string, callback func() byte) {
+	resolveConflict(func(text string, c conflict) string {
+		return text[c.oursStart:c.oursEnd] + text[c.theirsStart:c.theirsEnd]
+	})
+}
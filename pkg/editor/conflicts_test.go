@@ -0,0 +1,106 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+const conflictText = `start
+<<<<<<< HEAD
+mine
+=======
+yours
+>>>>>>> feature
+middle
+<<<<<<< HEAD
+a
+||||||| base
+o
+=======
+b
+>>>>>>> feature
+end
+`
+
+func TestFindConflicts(t *testing.T) {
+	conflicts := findConflicts(conflictText)
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts got %d", len(conflicts))
+	}
+	c := conflicts[0]
+	if got := conflictText[c.oursStart:c.oursEnd]; got != "mine\n" {
+		t.Errorf("ours = %q", got)
+	}
+	if got := conflictText[c.theirsStart:c.theirsEnd]; got != "yours\n" {
+		t.Errorf("theirs = %q", got)
+	}
+	if c.baseStart != c.baseEnd {
+		t.Errorf("a two-way conflict has no base")
+	}
+	c = conflicts[1]
+	if got := conflictText[c.baseStart:c.baseEnd]; got != "o\n" {
+		t.Errorf("base = %q", got)
+	}
+	if got := conflictText[c.start:c.end]; got[len(got)-len(">>>>>>> feature\n"):] != ">>>>>>> feature\n" {
+		t.Errorf("the conflict should end after its closing marker: %q", got)
+	}
+
+	if got := findConflicts("<<<<<<< HEAD\nunterminated\n"); got != nil {
+		t.Errorf("an unterminated conflict is not a conflict: %+v", got)
+	}
+}
+
+func TestConflictHighlights(t *testing.T) {
+	resetSessionForTest()
+	var ours, theirs, markers int
+	for _, h := range conflictHighlights(conflictText) {
+		switch h.style {
+		case currentTheme.conflictOurs:
+			ours++
+		case currentTheme.conflictTheirs:
+			theirs++
+		case currentTheme.conflictMarker:
+			markers++
+		}
+	}
+	if ours != 2 || theirs != 2 || markers != 7 {
+		t.Fatalf("got %d ours, %d theirs and %d marker highlights", ours, theirs, markers)
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope(conflictText)
+
+	runCommand(0, "conflict-next", nil)
+	if session.cursorRow != 2 {
+		t.Fatalf("expected the first conflict on line 2, got %d", session.cursorRow)
+	}
+	runCommand(0, "theirs", nil)
+	runCommand(0, "conflict-next", nil)
+	if session.cursorRow != 4 {
+		t.Fatalf("expected the second conflict on line 4, got %d", session.cursorRow)
+	}
+	runCommand(0, "both", nil)
+	want := "start\nyours\nmiddle\na\nb\nend\n"
+	if got := session.rope.String(); got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if session.statusMessage != "All merge conflicts resolved" {
+		t.Fatalf("unexpected status %q", session.statusMessage)
+	}
+
+	for len(session.undoStack) > 0 {
+		handleUndo()
+	}
+	if session.rope.String() != conflictText {
+		t.Fatal("resolving conflicts should be undoable")
+	}
+	session.cursorIdx = 0
+	runCommand(0, "conflict-prev", nil)
+	runCommand(0, "ours", nil)
+	if got := session.rope.String(); got[len(got)-len("middle\na\nend\n"):] != "middle\na\nend\n" {
+		t.Fatalf("conflict-prev should wrap to the last conflict, got %q", got)
+	}
+}
@@ -0,0 +1,274 @@
+package editor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// controlRequest is a JSON-RPC 2.0 request read from the control socket.
+// Without an id it is a notification, which gets no answer.
+type controlRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// controlResponse answers a controlRequest with its result or error
+type controlResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *controlError   `json:"error,omitempty"`
+}
+
+type controlError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcFailed         = -32000 // the method ran and failed
+)
+
+// maxControlRequest bounds the length of a request line
+const maxControlRequest = 64 << 20
+
+// bufferState is what the control methods return: the active buffer after
+// they ran, with its text only for getBuffer
+type bufferState struct {
+	Path     string  `json:"path"`
+	Line     int     `json:"line"`
+	Col      int     `json:"col"`
+	Modified bool    `json:"modified"`
+	Text     *string `json:"text,omitempty"`
+}
+
+// controlMethods are the methods of the control socket, by name. They run
+// on the main loop, like key presses, and act on the active buffer.
+var controlMethods = map[string]func(params json.RawMessage) (any, error){
+	"openFile":   controlOpenFile,
+	"insertText": controlInsertText,
+	"getBuffer":  controlGetBuffer,
+	"save":       controlSave,
+	"moveCursor": controlMoveCursor,
+}
+
+// errInvalidParams marks the errors of params that don't fit the method
+var errInvalidParams = errors.New("invalid params")
+
+// ServeControl listens on a unix socket at path for JSON-RPC 2.0 requests,
+// one per line, that drive the editor like the keyboard does. Only the
+// user can connect. Closing the returned listener removes the socket.
+func ServeControl(path string) (io.Closer, error) {
+	// The socket is created with the permissions the umask leaves, so it
+	// is never open to others, even for a moment
+	mask := unix.Umask(0177)
+	listener, err := net.Listen("unix", path)
+	unix.Umask(mask)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveControlConn(conn)
+		}
+	}()
+	return listener, nil
+}
+
+// serveControlConn answers the requests of one client in order. Each one
+// waits for the main loop, so it is delayed while a prompt is open.
+func serveControlConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, maxControlRequest)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		done := make(chan []byte, 1)
+		post(func() { done <- handleControlRequest(line) })
+		if reply := <-done; reply != nil {
+			if _, err := conn.Write(append(reply, '\n')); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleControlRequest runs the request in line and returns the encoded
+// response, or nil for a notification
+func handleControlRequest(line []byte) []byte {
+	var req controlRequest
+	resp := controlResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = &controlError{rpcParseError, err.Error()}
+	} else {
+		if req.ID != nil {
+			resp.ID = req.ID
+		}
+		method, ok := controlMethods[req.Method]
+		if !ok {
+			resp.Error = &controlError{rpcMethodNotFound, "unknown method " + req.Method}
+		} else if result, err := method(req.Params); errors.Is(err, errInvalidParams) {
+			resp.Error = &controlError{rpcInvalidParams, err.Error()}
+		} else if err != nil {
+			resp.Error = &controlError{rpcFailed, err.Error()}
+		} else {
+			resp.Result = result
+		}
+		if req.ID == nil {
+			return nil
+		}
+	}
+	reply, _ := json.Marshal(resp)
+	return reply
+}
+
+// decodeParams reads params into v, which is left as is when there are none
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidParams, err)
+	}
+	return nil
+}
+
+// activeBufferState returns the state of the active buffer
+func activeBufferState() bufferState {
+	return bufferState{
+		Path:     session.filename,
+		Line:     session.cursorRow,
+		Col:      session.cursorCol,
+		Modified: session.modified(),
+	}
+}
+
+// statusError returns the status message left by a refused action as an
+// error, or what when there is none
+func statusError(what string) error {
+	if session.statusMessage != "" {
+		return errors.New(session.statusMessage)
+	}
+	return errors.New(what)
+}
+
+// declineKeys answers the prompts of an action run from the control socket
+// with Esc, as there is nobody to answer them
+func declineKeys() func() byte {
+	esc := false
+	return func() byte {
+		if esc = !esc; esc {
+			return Esc
+		}
+		return 0
+	}
+}
+
+// controlOpenFile opens a file, or switches to its buffer, with the cursor
+// at an optional line and column: {"path": "main.go", "line": 3, "col": 1}
+func controlOpenFile(params json.RawMessage) (any, error) {
+	var p struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Col  int    `json:"col"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Path == "" {
+		return nil, fmt.Errorf("%w: no path given", errInvalidParams)
+	}
+	pushJump()
+	if err := openBuffer(p.Path); err != nil {
+		jumpList = jumpList[:len(jumpList)-1]
+		return nil, err
+	}
+	if session.loading != nil {
+		session.loading.line, session.loading.col = p.Line, p.Col
+	} else if p.Line != 0 {
+		GoToLine(p.Line, p.Col)
+	}
+	return activeBufferState(), nil
+}
+
+// controlInsertText inserts text at the cursor, as typed: {"text": "hi"}
+func controlInsertText(params json.RawMessage) (any, error) {
+	var p struct {
+		Text string `json:"text"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	rope := session.rope
+	handleInsert(p.Text)
+	if p.Text != "" && session.rope == rope {
+		return nil, statusError("text not inserted")
+	}
+	return activeBufferState(), nil
+}
+
+// controlGetBuffer returns the active buffer with its text
+func controlGetBuffer(params json.RawMessage) (any, error) {
+	state := activeBufferState()
+	text := session.rope.String()
+	state.Text = &text
+	return state, nil
+}
+
+// controlSave saves the active buffer, under a new path when one is given:
+// {"path": "copy.txt"}. Prompts, like the offer to save with sudo, are
+// declined.
+func controlSave(params json.RawMessage) (any, error) {
+	var p struct {
+		Path string `json:"path"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	filename, readOnly := session.filename, session.readOnly
+	if p.Path != "" {
+		session.filename = p.Path
+		session.readOnly = false
+	} else if isUnnamed(session.filename) {
+		return nil, fmt.Errorf("%w: the buffer has no file name, give a path", errInvalidParams)
+	}
+	if !saveBuffer(-1, declineKeys()) {
+		// The buffer keeps its file, which a save to a new path left alone
+		session.filename, session.readOnly = filename, readOnly
+		return nil, statusError("not saved")
+	}
+	return activeBufferState(), nil
+}
+
+// controlMoveCursor moves the cursor to a 1-indexed line and column,
+// clamped to the buffer: {"line": 10, "col": 4}
+func controlMoveCursor(params json.RawMessage) (any, error) {
+	var p struct {
+		Line int `json:"line"`
+		Col  int `json:"col"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Line < 1 {
+		return nil, fmt.Errorf("%w: line must be 1 or more", errInvalidParams)
+	}
+	GoToLine(p.Line, p.Col)
+	return activeBufferState(), nil
+}
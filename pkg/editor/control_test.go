@@ -0,0 +1,128 @@
+package editor
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// controlCall runs one request as the main loop would and decodes the reply
+func controlCall(t *testing.T, request string) controlResponse {
+	t.Helper()
+	var resp controlResponse
+	if err := json.Unmarshal(handleControlRequest([]byte(request)), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestControlMethods(t *testing.T) {
+	resetSessionForTest()
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("one\ntwo\n"), 0644)
+
+	resp := controlCall(t, `{"jsonrpc":"2.0","id":1,"method":"openFile","params":{"path":"`+path+`","line":2,"col":4}}`)
+	if resp.Error != nil || session.filename != path || session.cursorRow != 2 || session.cursorCol != 4 {
+		t.Fatalf("openFile: %+v, cursor %d:%d", resp.Error, session.cursorRow, session.cursorCol)
+	}
+	controlCall(t, `{"jsonrpc":"2.0","id":2,"method":"moveCursor","params":{"line":1,"col":1}}`)
+	controlCall(t, `{"jsonrpc":"2.0","id":3,"method":"insertText","params":{"text":"zero\n"}}`)
+
+	resp = controlCall(t, `{"jsonrpc":"2.0","id":"b","method":"getBuffer"}`)
+	var state bufferState
+	result, _ := json.Marshal(resp.Result)
+	json.Unmarshal(result, &state)
+	if string(resp.ID) != `"b"` || state.Path != path || state.Line != 2 || state.Col != 1 || !state.Modified ||
+		state.Text == nil || *state.Text != "zero\none\ntwo\n" {
+		t.Errorf("getBuffer %s: %s", resp.ID, result)
+	}
+
+	if resp = controlCall(t, `{"jsonrpc":"2.0","id":4,"method":"save"}`); resp.Error != nil {
+		t.Fatalf("save: %+v", resp.Error)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "zero\none\ntwo\n" {
+		t.Errorf("saved %q", got)
+	}
+
+	// A save to a path that can't be written leaves the buffer on its file
+	session.readOnly = true
+	missing := filepath.Join(filepath.Dir(path), "missing", "copy.txt")
+	if resp = controlCall(t, `{"jsonrpc":"2.0","id":5,"method":"save","params":{"path":"`+missing+`"}}`); resp.Error == nil {
+		t.Fatal("saving under a missing directory should fail")
+	}
+	if session.filename != path || !session.readOnly {
+		t.Errorf("after a failed save the buffer is %q, read-only %v", session.filename, session.readOnly)
+	}
+}
+
+func TestControlErrors(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("[No Name]", "")
+	buffers = []*Session{session}
+
+	for request, code := range map[string]int{
+		`{"jsonrpc":"2.0","id":1,"method":"quit"}`:                                 rpcMethodNotFound,
+		`{"jsonrpc":"2.0","id":1,"method":"moveCursor","params":{"line":"x"}}`:     rpcInvalidParams,
+		`{"jsonrpc":"2.0","id":1,"method":"save"}`:                                 rpcInvalidParams,
+		`{"jsonrpc":"2.0","id":1,"method":"openFile","params":{"path":"/nope/x"}}`: rpcFailed,
+		`{"jsonrpc":`: rpcParseError,
+	} {
+		if resp := controlCall(t, request); resp.Error == nil || resp.Error.Code != code {
+			t.Errorf("%s: got %+v, want code %d", request, resp.Error, code)
+		}
+	}
+
+	session.readOnly = true
+	resp := controlCall(t, `{"jsonrpc":"2.0","id":1,"method":"insertText","params":{"text":"x"}}`)
+	if resp.Error == nil || !strings.Contains(resp.Error.Message, "read-only") {
+		t.Errorf("insert in a read-only buffer: %+v", resp.Error)
+	}
+
+	// Notifications get no answer
+	if reply := handleControlRequest([]byte(`{"jsonrpc":"2.0","method":"moveCursor","params":{"line":1}}`)); reply != nil {
+		t.Errorf("notification answered with %s", reply)
+	}
+}
+
+func TestServeControl(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("a.txt", "abc")
+	buffers = []*Session{session}
+	path := filepath.Join(t.TempDir(), "ctl")
+	control, err := ServeControl(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer control.Close()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("socket %v, %v: want it for the user only", info, err)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(`{"jsonrpc":"2.0","id":7,"method":"getBuffer"}` + "\n"))
+	replies := make(chan string)
+	go func() {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		replies <- line
+	}()
+	// The request runs when the main loop gets to it
+	var reply string
+	for reply == "" {
+		runPosted()
+		select {
+		case reply = <-replies:
+		default:
+		}
+	}
+	if !strings.HasPrefix(reply, `{"jsonrpc":"2.0","id":7,"result":{"path":"a.txt"`) || !strings.Contains(reply, `"text":"abc"`) {
+		t.Errorf("reply %q", reply)
+	}
+}
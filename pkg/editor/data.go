@@ -0,0 +1,150 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// dataErrorMark is the line of the last JSON or YAML syntax error, shown
+// until the buffer changes
+var dataErrorMark struct {
+	rope       *buffer.Rope
+	start, end int
+}
+
+// handleJSON pretty-prints the selection, or the buffer, as JSON (:json),
+// minifies it (:json min) or only checks it (:json check)
+func handleJSON(fd int, args string, callback func() byte) {
+	handleData("JSON", args, func(text string, indent string) (string, error) {
+		// Indent would copy the space after the value, so it gets the value alone
+		leading := len(text) - len(strings.TrimLeft(text, " \t\r\n"))
+		var out bytes.Buffer
+		var err error
+		if args == "min" {
+			err = json.Compact(&out, []byte(text))
+		} else {
+			err = json.Indent(&out, []byte(strings.TrimSpace(text)), "", indent)
+		}
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			offset := int(max(syntaxErr.Offset-1, 0))
+			if args != "min" {
+				offset += leading
+			}
+			offset = min(offset, len(text))
+			return "", &dataError{strings.Count(text[:offset], "\n") + 1, syntaxErr.Error()}
+		}
+		return out.String(), err
+	})
+}
+
+// handleYAML re-indents the selection, or the buffer, as YAML in block
+// style (:yaml), writes it on one line in flow style (:yaml min) or only
+// checks it (:yaml check). Comments are kept, so text with comments can't
+// be minified.
+func handleYAML(fd int, args string, callback func() byte) {
+	handleData("YAML", args, func(text string, indent string) (string, error) {
+		node, err := parseYAML(text)
+		if err != nil {
+			return "", err
+		}
+		if node == nil {
+			// Nothing but comments
+			return strings.TrimSuffix(text, "\n"), nil
+		}
+		if args == "check" {
+			return "", nil
+		}
+		comments := yamlCommentLines(text)
+		if args == "min" {
+			if comments > 0 {
+				return "", errors.New("comments can't be kept on one line, remove them first")
+			}
+			return yamlFlow(node)
+		}
+		out, err := formatYAML(node, len(indent))
+		if err != nil {
+			return "", err
+		}
+		if yamlCommentLines(out) < comments {
+			return "", errors.New("formatting would lose comments, text left as is")
+		}
+		return strings.TrimSuffix(out, "\n"), nil
+	})
+}
+
+// handleData runs format, which returns the formatted text, on the
+// selection or the whole buffer. A syntax error moves the cursor to its
+// line, which is highlighted until the next edit.
+func handleData(kind, args string, format func(text, indent string) (string, error)) {
+	if args != "" && args != "min" && args != "check" {
+		session.statusMessage = fmt.Sprintf("Usage: %s [min|check]", strings.ToLower(kind))
+		return
+	}
+	start, end, selected := selection()
+	if !selected {
+		start, end = 0, session.rope.Length()
+	}
+	text, _ := session.rope.Substring(start, end)
+	indent := "\t"
+	if session.expandTab {
+		indent = strings.Repeat(" ", session.tabWidth())
+	} else if kind == "YAML" {
+		// YAML can't be indented with tabs
+		indent = "  "
+	}
+
+	formatted, err := format(text, indent)
+	var syntaxErr *dataError
+	if errors.As(err, &syntaxErr) {
+		row := syntaxErr.line - 1 + strings.Count(session.rope.String()[:start], "\n")
+		lineStart, lineEnd := lineBounds(lineStarts(), row)
+		dataErrorMark.rope, dataErrorMark.start, dataErrorMark.end = session.rope, lineStart, max(lineEnd, lineStart+1)
+		session.selecting = false
+		session.cursorIdx = lineStart
+		updateCursorPosition()
+		session.statusMessage = fmt.Sprintf("%s: line %d: %s", kind, row+1, syntaxErr.msg)
+		return
+	}
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("%s: %v", kind, err)
+		return
+	}
+	if args == "check" {
+		session.statusMessage = "Valid " + kind
+		return
+	}
+
+	if !selected || strings.HasSuffix(text, "\n") {
+		formatted += "\n"
+	}
+	if formatted == text {
+		session.statusMessage = kind + " already formatted"
+		return
+	}
+	if selected {
+		replaceText(start, end, formatted)
+		selectRange(start, start+len(formatted))
+	} else {
+		applyTextPreservingCursor(formatted)
+	}
+	if args == "min" {
+		session.statusMessage = "Minified " + kind
+	} else {
+		session.statusMessage = "Formatted " + kind
+	}
+}
+
+// dataErrorHighlights marks the line of the last syntax error, until the
+// buffer changes
+func dataErrorHighlights() []highlight {
+	if dataErrorMark.rope != session.rope {
+		return nil
+	}
+	return []highlight{{dataErrorMark.start, dataErrorMark.end, currentTheme.parseError}}
+}
@@ -0,0 +1,91 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHandleJSON(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("config.json", `{"name": "web", "ports": [80, 443]}`)
+	buffers = []*Session{session}
+
+	runCommand(0, "json", nil)
+	want := "{\n\t\"name\": \"web\",\n\t\"ports\": [\n\t\t80,\n\t\t443\n\t]\n}\n"
+	if got := session.rope.String(); got != want || session.statusMessage != "Formatted JSON" {
+		t.Fatalf("formatted %q, status %q", got, session.statusMessage)
+	}
+	runCommand(0, "json min", nil)
+	if got := session.rope.String(); got != "{\"name\":\"web\",\"ports\":[80,443]}\n" {
+		t.Errorf("minified %q", got)
+	}
+	handleUndo()
+	if got := session.rope.String(); got != want {
+		t.Errorf("minifying should be one undo step, got %q", got)
+	}
+
+	// Only the selection changes
+	session = newSession("notes.md", "data:\n[1,\n 2]\nend\n")
+	buffers = []*Session{session}
+	selectRange(6, 14)
+	runCommand(0, "json min", nil)
+	if got := session.rope.String(); got != "data:\n[1,2]\nend\n" {
+		t.Errorf("selection minified to %q", got)
+	}
+}
+
+func TestJSONSyntaxError(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("config.json", "{\n  \"a\": 1,\n  \"b\": 2,\n}\n")
+	buffers = []*Session{session}
+
+	runCommand(0, "json check", nil)
+	if session.cursorRow != 4 || !strings.HasPrefix(session.statusMessage, "JSON: line 4: invalid character '}'") {
+		t.Errorf("cursor on line %d, status %q", session.cursorRow, session.statusMessage)
+	}
+	if hl := dataErrorHighlights(); len(hl) != 1 || hl[0].start != 22 || hl[0].end != 23 {
+		t.Errorf("error highlights %+v", hl)
+	}
+	replaceText(20, 21, "")
+	if hl := dataErrorHighlights(); hl != nil {
+		t.Errorf("the mark should go with the next edit, got %+v", hl)
+	}
+	runCommand(0, "json check", nil)
+	if session.statusMessage != "Valid JSON" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
+
+func TestHandleYAML(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("app.yaml", "# app\nname:    web\nports: [80, 443]\n")
+	buffers = []*Session{session}
+
+	runCommand(0, "yaml", nil)
+	if got := session.rope.String(); got != "# app\nname: web\nports:\n  - 80\n  - 443\n" {
+		t.Errorf("formatted %q", got)
+	}
+	if session.statusMessage != "Formatted YAML" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+	// Comments can't be kept on one line
+	runCommand(0, "yaml min", nil)
+	if got := session.rope.String(); !strings.HasPrefix(got, "# app\n") || !strings.Contains(session.statusMessage, "comments") {
+		t.Errorf("minified %q with its comments, status %q", got, session.statusMessage)
+	}
+	deleteText(0, len("# app\n"))
+	runCommand(0, "yaml min", nil)
+	if got := session.rope.String(); got != "{name: web, ports: [80, 443]}\n" {
+		t.Errorf("minified %q", got)
+	}
+
+	session = newSession("app.yaml", "a: 1\nb:\n  c: 2\n   d: 3\n")
+	buffers = []*Session{session}
+	runCommand(0, "yaml", nil)
+	if session.cursorRow != 4 || session.statusMessage != "YAML: line 4: mapping values are not allowed in this context" {
+		t.Errorf("cursor on line %d, status %q", session.cursorRow, session.statusMessage)
+	}
+	if got := session.rope.String(); got != "a: 1\nb:\n  c: 2\n   d: 3\n" {
+		t.Errorf("a broken document should be left alone, got %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+	"github.com/jellexet/golang-text-editor/pkg/diff"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// showScratch shows text in the buffer called name, reusing it
+// if it is already open, and records the jump so Ctrl-T returns
+func showScratch(name, text string) {
+	pushJump()
+	scratch := findBuffer(name)
+	if scratch == nil {
+		scratch = newSession(name, text)
+		scratch.scratch = true
+		buffers = append(buffers, scratch)
+	} else {
+		scratch.rope = buffer.NewRope(text)
+		scratch.cursorIdx = 0
+		scratch.rowOffset = 0
+	}
+	switchToBuffer(scratch)
+	updateCursorPosition()
+}
+
+// handleDiffChanges shows the changes made to the buffer since it was last
+// saved, as a unified diff against the file on disk (:diff)
+func handleDiffChanges(fd int, args string, callback func() byte) {
+	if isUnnamed(session.filename) {
+		session.statusMessage = "Diff: the buffer has no file on disk"
+		return
+	}
+	onDisk, err := os.ReadFile(session.filename)
+	if err != nil && !os.IsNotExist(err) {
+		session.statusMessage = fmt.Sprintf("Diff: %v", err)
+		return
+	}
+
+	// The byte order mark is not part of the buffer, nor a change
+	diskText, _ := stripBOM(string(onDisk))
+	name := session.filename
+	text := diff.Unified(name+" (on disk)", name+" (buffer)",
+		splitLinesKeepEnds(diskText), splitLinesKeepEnds(session.rope.String()), diffContext)
+	if text == "" {
+		session.statusMessage = "No unsaved changes"
+		return
+	}
+	showScratch("[Diff: "+name+"]", text)
+	session.readOnly = true
+	session.statusMessage = "Unsaved changes of " + name + " (Ctrl-T to go back)"
+}
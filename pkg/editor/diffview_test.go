@@ -0,0 +1,41 @@
+package editor
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiffChanges(t *testing.T) {
+	resetSessionForTest()
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("a.txt", []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session = newSession("a.txt", "one\ntwo\nthree\n")
+	buffers = []*Session{session}
+
+	runCommand(0, "diff", nil)
+	if session.statusMessage != "No unsaved changes" {
+		t.Fatalf("unexpected status %q", session.statusMessage)
+	}
+
+	session.cursorIdx = 4
+	handleInsert("2 ")
+	runCommand(0, "diff", nil)
+	want := "--- a.txt (on disk)\n+++ a.txt (buffer)\n@@ -1,3 +1,3 @@\n one\n-two\n+2 two\n three\n"
+	if session.filename != "[Diff: a.txt]" || session.rope.String() != want {
+		t.Fatalf("got buffer %s:\n%s", session.filename, session.rope.String())
+	}
+	if !session.readOnly {
+		t.Error("the diff buffer should be read-only")
+	}
+
+	// Going back and diffing again reuses the scratch buffer
+	jumpBack()
+	handleInsert("!")
+	runCommand(0, "diff", nil)
+	if len(buffers) != 2 || !strings.Contains(session.rope.String(), "+2 !two") {
+		t.Fatalf("expected the refreshed diff in the same buffer, %d buffers:\n%s", len(buffers), session.rope.String())
+	}
+}
@@ -1,18 +1,33 @@
 package editor
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jellexet/golang-text-editor/pkg/buffer"
 	"golang.org/x/sys/unix"
+	"io"
+	"io/fs"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
-// Action represents an editing action for undo/redo
+// textEdit replaces the text removed at position in the rope by inserted.
+// An insertion removes nothing and a deletion inserts nothing.
+type textEdit struct {
+	position int
+	removed  string
+	inserted string
+}
+
+// Action represents an editing action for undo/redo: edits made in order,
+// each at a position in the text left by the ones before, undone and
+// redone in one step
 type Action struct {
-	actionType string // "insert" or "delete"
-	position   int    // position in rope
-	content    string // content that was inserted or deleted
+	edits  []textEdit
+	cursor int // cursor before the action, where undoing it puts it back
 }
 
 // Session contains the information to display the text, undo-redo and edit the text
@@ -23,15 +38,52 @@ type Session struct {
 	cursorIdx       int // linear index in the rope
 	cursorRow       int // 1-indexed row (screen position)
 	cursorCol       int // 1-indexed column (screen position)
+	goalCol         int // column up and down aim for, 0-indexed, while the cursor is at goalIdx
+	goalIdx         int
+	rowOffset       int // number of lines scrolled off the top of the screen
 	screenRows      uint16
 	screenCols      uint16
 	filename        string // Name of the file being edited
 	statusMessage   string // For showing messages like "Not found"
 	lastSearchQuery string // For "find next"
+	lspVersion      int    // version last sent to the language server, 0 if never opened
+	blame           *blameCache
+	blaming         bool             // git blame runs in the background for the status bar
+	seenRope        *buffer.Rope     // content when change events last fired
+	seenCursorIdx   int              // cursor when change events last fired
+	hex             *hexState        // non-nil while the buffer is shown as hex
+	table           *tableState      // non-nil while the buffer is shown as an aligned table
+	largeFile       bool             // expensive features are off for this buffer
+	lineIndex       *lineIndex       // line starts of the rope
+	readOnly        bool             // edits and saving are refused
+	selecting       bool             // text between selAnchor and the cursor is selected
+	selAnchor       int              // where the selection started
+	tabSize         int              // tab width of this buffer, 0 to follow the global option
+	filetype        string           // overrides the type given by the file extension
+	expandTab       bool             // Tab inserts spaces
+	backupRope      *buffer.Rope     // content when last backed up
+	protected       []protectedRange // read-only spans of the text
+	loading         *fileLoad        // non-nil while the file is read in the background
+	scratch         bool             // not backed by a file: only :w FILE saves it
+	repl            string           // interpreter :send starts, the shell when empty
+	savedRope       *buffer.Rope     // content when last loaded or saved
+	disk            diskState        // the file when last loaded or saved
+	bom             bool             // the file starts with a UTF-8 byte order mark, kept out of the rope
 }
 
-// The session global variable
-var session Session
+// pendingKey is a key already read by a popup that closed because of it,
+// to be handled by the main loop as if it was just pressed
+var pendingKey Key
+
+// The session global variable, always pointing at the active buffer
+var session = &Session{}
+
+// buffers holds every open buffer, including the active one
+var buffers []*Session
+
+// output is where the screen is drawn: the terminal, or an emulated one in
+// tests
+var output io.Writer = os.Stdout
 
 // EnableRawMode sets the terminal into raw mode
 func EnableRawMode(fd int) (*unix.Termios, error) {
@@ -66,29 +118,36 @@ func DisableRawMode(fd int, prevState *unix.Termios) error {
 
 // Control character constants
 const (
+	CtrlB byte = 0x02
+	CtrlC byte = 0x03
+	CtrlD byte = 0x04
+	CtrlE byte = 0x05
 	CtrlF byte = 0x06
+	CtrlG byte = 0x07
+	CtrlK byte = 0x0B
+	CtrlL byte = 0x0C
 	CtrlN byte = 0x0E
+	CtrlP byte = 0x10
 	CtrlQ byte = 0x11
 	CtrlR byte = 0x12
 	CtrlS byte = 0x13
+	CtrlT byte = 0x14
+	CtrlU byte = 0x15
+	CtrlV byte = 0x16
+	CtrlW byte = 0x17
 	CtrlZ byte = 0x1A
 	Esc   byte = 0x1B
+
+	CtrlRightBracket byte = 0x1D
 )
 
 // Special character constants
 const (
+	Tab       byte = 0x09
 	Return    byte = 0x0D
 	Backspace byte = 0x7F
 )
 
-// Arrow key constants
-const (
-	ArrowUp    = 1000
-	ArrowDown  = 1001
-	ArrowLeft  = 1002
-	ArrowRight = 1003
-)
-
 // Screen clearing constants
 const (
 	Line        rune = '0'
@@ -98,17 +157,109 @@ const (
 
 // Initialize session with rope and screen dimensions
 func InitSession(fd int, filename string, initialContent string) {
-	session.rope = buffer.NewRope(initialContent)
-	session.filename = filename
-	session.cursorIdx = 0
-	session.cursorRow = 1
-	session.cursorCol = 1
-	session.undoStack = []Action{}
-	session.redoStack = []Action{}
+	session = newSession(filename, initialContent)
+	buffers = []*Session{session}
 	rows, cols := getWindowSize(fd)
 	session.screenRows = rows
 	session.screenCols = cols
 	updateCursorPosition()
+	fireHooks(eventBufOpen, session)
+}
+
+// newSession creates the state for a buffer holding the given content
+func newSession(filename string, content string) *Session {
+	content, bom := stripBOM(content)
+	rope := buffer.NewRope(content)
+	s := &Session{
+		rope:       rope,
+		seenRope:   rope,
+		backupRope: rope,
+		savedRope:  rope,
+		filename:   filename,
+		bom:        bom,
+		cursorRow:  1,
+		cursorCol:  1,
+		undoStack:  []Action{},
+		redoStack:  []Action{},
+	}
+	// Downloaded buffers can only be saved under a local name
+	s.readOnly = openReadOnly || IsURL(filename)
+	s.expandTab = expandTab
+	if len(content) >= largeFileThreshold {
+		s.largeFile = true
+		s.statusMessage = largeFileNotice
+	} else if hasLongLine(content) {
+		s.largeFile = true
+		s.statusMessage = longLineNotice
+	}
+	return s
+}
+
+// openBuffer makes the buffer for filename active, loading it from disk
+// if it isn't open yet
+func openBuffer(filename string) error {
+	target := findBuffer(filename)
+	if target == nil {
+		if !IsURL(filename) && LoadsInBackground(filename) {
+			target = newLoadingSession(filename, 0, 0)
+			buffers = append(buffers, target)
+			switchToBuffer(target)
+			return nil
+		}
+		var content string
+		if IsURL(filename) {
+			var err error
+			if content, err = FetchURL(filename); err != nil {
+				return err
+			}
+		} else {
+			contentBytes, err := os.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			content = string(contentBytes)
+		}
+		log.Printf("opened %s (%d bytes)", filename, len(content))
+		target = newSession(filename, content)
+		buffers = append(buffers, target)
+		switchToBuffer(target)
+		fireHooks(eventBufOpen, target)
+		return nil
+	}
+	switchToBuffer(target)
+	return nil
+}
+
+// findBuffer returns the open buffer for filename, or nil
+func findBuffer(filename string) *Session {
+	want, err := filepath.Abs(filename)
+	if err != nil {
+		want = filename
+	}
+	for _, b := range buffers {
+		have, err := filepath.Abs(b.filename)
+		if err != nil {
+			have = b.filename
+		}
+		if have == want {
+			return b
+		}
+	}
+	return nil
+}
+
+// switchToBuffer makes target the active buffer, keeping the screen size
+func switchToBuffer(target *Session) {
+	if target == session {
+		return
+	}
+	target.screenRows = session.screenRows
+	target.screenCols = session.screenCols
+	session = target
+	if len(windows) > 0 {
+		windows[activeWindow].buf = target
+	}
+	updateCursorPosition()
 }
 
 // ProcessKeypress handles keyboard input and updates editor state
@@ -118,59 +269,176 @@ func ProcessKeypress(fd int, callback func() (key byte)) {
 	refreshScreen(fd)
 
 	for {
-		key := editorReadKeypress(callback)
+		key := pendingKey
+		pendingKey = Key{}
+		keyTraceBytes = keyTraceBytes[:0]
+		if key == (Key{}) {
+			key = readTracedKey(callback)
+		}
+		readAt := time.Now()
 
-		if key == 0 {
+		if key == (Key{}) {
+			// Results of background work show up without waiting for a key
+			if runPosted() || runIdleTask(readAt) || session.loading != nil || frameStale || statusOutdated(time.Now()) || reloadChangedConfig(time.Now()) {
+				fireChangeEvents()
+				drawFrame(fd, time.Now())
+			}
 			continue
 		}
+		runPosted()
+		noteActivity(readAt)
+		traceKey(key)
 
-		// Handle arrow keys
-		if key >= 1000 {
-			switch key {
-			case ArrowUp:
-				editorMoveCursor(ArrowUp)
-			case ArrowDown:
-				editorMoveCursor(ArrowDown)
-			case ArrowLeft:
-				editorMoveCursor(ArrowLeft)
-			case ArrowRight:
-				editorMoveCursor(ArrowRight)
+		if session.loading != nil && key == EscKey {
+			cancelLoading()
+		}
+
+		if pagerMode {
+			if key.Rune == 'q' || key.Rune == 'Q' || key == ctrl('q') {
+				quitEditor()
+				return
 			}
-			refreshScreen(fd)
+			handlePagerKey(fd, key, callback)
+			fireChangeEvents()
+			keyHandled(readAt)
+			requestRefresh(fd, time.Now())
 			continue
 		}
 
-		// Handle control characters
-		controlChar := byte(key)
-		switch controlChar {
-		case CtrlQ:
-			ClearScreen(Screen)
-			MoveCursorTopLeft()
-			return
-		case CtrlF:
-			handleSearch(fd, callback)
-			refreshScreen(fd)
-		case CtrlR:
-			handleRedo()
-			refreshScreen(fd)
-		case CtrlS:
-			handleSave(callback)
-			refreshScreen(fd)
-		case CtrlZ:
-			handleUndo()
-			refreshScreen(fd)
-		case Backspace:
-			handleBackspace()
-			refreshScreen(fd)
-		case Return:
-			handleInsert("\n")
-			refreshScreen(fd)
-		default:
-			if isRegularCharacter(controlChar) {
-				handleInsert(string(controlChar))
-				refreshScreen(fd)
+		if termPane != nil && termPane.focused && key != alt('t') {
+			handleTerminalKey(key, callback)
+			keyHandled(readAt)
+			requestRefresh(fd, time.Now())
+			continue
+		}
+
+		if clearsSelection(key) {
+			session.selecting = false
+		}
+
+		if bound, ok := pluginKeys[key]; ok {
+			bound.run(fd, callback)
+		} else if session.hex != nil && handleHexKey(key) {
+			// The hex view used the key
+		} else if session.table != nil && session.hex == nil && handleTableKey(key) {
+			// The table view used the key
+		} else {
+			switch key {
+			case ArrowUp, ArrowDown, ArrowLeft, ArrowRight:
+				editorMoveCursor(key)
+			case HomeKey:
+				handleHome()
+			case EndKey:
+				handleEnd()
+			case CtrlArrowLeft:
+				handleWordLeft()
+			case CtrlArrowRight:
+				handleWordRight()
+			case CtrlHome:
+				handleBufferStart()
+			case CtrlEnd:
+				handleBufferEnd()
+			case ctrl('a'):
+				handleSelectAll()
+			case PageUpKey:
+				movePage(-1)
+			case PageDownKey:
+				movePage(1)
+			case DeleteKey:
+				handleDeleteForward()
+			case ShiftArrowUp, ShiftArrowDown, ShiftArrowLeft, ShiftArrowRight:
+				extendSelection(Key{Special: key.Special})
+			case alt('n'):
+				handleNextError(1)
+			case alt('p'):
+				handleNextError(-1)
+			case alt('o'):
+				handleCompareSwitch()
+			case alt('d'):
+				handleDuplicate()
+			case alt('('):
+				handleEnclosingJump(false)
+			case alt(')'):
+				handleEnclosingJump(true)
+			case alt('b'):
+				handleSelectInside("brackets")
+			case alt('q'):
+				handleSelectInside("string")
+			case alt('a'):
+				incrementNumber("", 1)
+			case alt('x'):
+				incrementNumber("", -1)
+			case alt('v'):
+				handlePaste(fd, "", callback)
+			case alt('l'):
+				handleSpeakLine()
+			case alt('t'):
+				toggleTerminalFocus()
+			case alt('w'):
+				handleNextWindow()
+			case BracketedPaste:
+				insertPaste(readPaste(callback))
+			case FocusIn:
+				checkDiskChanges(callback)
+			case MouseEvent:
+				handleMouse(fd, callback)
+			case ctrl('q'):
+				handleQuitAll(fd, "", callback)
+			case ctrl('n'):
+				handleComplete(fd, callback)
+			case ctrl('e'):
+				handleCommand(fd, callback)
+			case ctrl('f'):
+				handleSearch(fd, callback)
+			case ctrl('g'):
+				handleHover(fd, callback)
+			case ctrl('l'):
+				scrollCursorTo("center")
+			case ctrl(']'):
+				handleGoToDefinition(fd, callback)
+			case ctrl('t'):
+				jumpBack()
+			case ctrl('r'):
+				handleRedo()
+			case ctrl('s'):
+				handleSave(fd, callback)
+			case ctrl('z'):
+				handleUndo()
+			case BackspaceKey:
+				handleBackspace()
+			case ctrl('w'):
+				handleDeleteWordBackward()
+			case ctrl('k'):
+				handleDeleteToLineEnd()
+			case ctrl('v'):
+				handleInsertUnicode(fd, "", callback)
+			case TabKey:
+				handleTab()
+			case ReturnKey:
+				expandAbbreviation()
+				handleInsert("\n")
+			default:
+				if c, ok := key.char(); ok {
+					if endsAbbreviation(c) {
+						expandAbbreviation()
+					}
+					handleInsert(string(c))
+				} else if c, ok := (Key{Rune: key.Rune}).char(); ok && key.Mod == ModAlt && endsAbbreviation(c) {
+					// Alt with the key ending an abbreviation types it as it is
+					handleInsert(string(c))
+				}
 			}
 		}
+
+		if quitRequested {
+			quitRequested = false
+			quitEditor()
+			return
+		}
+
+		fireChangeEvents()
+		keyHandled(readAt)
+		requestRefresh(fd, time.Now())
 	}
 }
 
@@ -180,6 +448,30 @@ func isRegularCharacter(c byte) bool {
 	return c >= 32 && c < 127
 }
 
+// readTimeout is how long reading waits for input before the callback
+// returns 0: VTIME, set to 1 decisecond in raw mode
+const readTimeout = 100 * time.Millisecond
+
+// keyTimeout is how long to wait for each byte of an escape sequence before
+// taking the bytes received as keys typed on their own, like Esc. Slow
+// links, which split sequences, need more (:set keytimeout 300ms), as
+// tmux and screen get by default.
+var keyTimeout = defaultKeyTimeout()
+
+// sequenceReader returns a reader of the bytes following the start of an
+// escape sequence, which waits up to keyTimeout for each of them
+func sequenceReader(callback func() byte) func() byte {
+	tries := max(int((keyTimeout+readTimeout-1)/readTimeout), 1)
+	return func() byte {
+		for range tries {
+			if b := callback(); b != 0 {
+				return b
+			}
+		}
+		return 0
+	}
+}
+
 // editorReadKeypress reads a key from stdin, handling multi-byte ANSI escape sequences.
 // This is necessary because special keys, like the arrow keys,
 // are not sent as a single byte.
@@ -188,6 +480,7 @@ func isRegularCharacter(c byte) bool {
 //   - Arrow Up    is sent as 3 bytes: \x1b [ A
 //   - Arrow Down  is sent as 3 bytes: \x1b [ B
 //   - ...and so on.
+//   - Shift+Arrow Up is sent as 6 bytes: \x1b [ 1 ; 2 A
 //
 // This function reads the first byte. If it's '\x1b', it uses the
 // non-blocking callback (which respects the VMIN/VTIME timeout) to
@@ -195,168 +488,240 @@ func isRegularCharacter(c byte) bool {
 //
 // This allows it to distinguish between a user just pressing the 'Esc' key
 // (where the subsequent reads will time out) and a user pressing an arrow
-// key (where the sequence is read successfully).
-func editorReadKeypress(callback func() byte) int {
+// key (where the sequence is read successfully). An Esc followed by anything
+// other than '[' or 'O' is reported as Alt plus that key. A timeout returns
+// the zero Key.
+func editorReadKeypress(callback func() byte) Key {
 	firstByte := callback()
 
-	// If we time out (firstByte == 0), just return 0
+	// If we time out (firstByte == 0), there is no key
 	if firstByte == 0 {
-		return 0
+		return Key{}
+	}
+
+	// Characters outside ASCII come in several bytes
+	if firstByte >= 0x80 {
+		return Key{Rune: readRune(firstByte, sequenceReader(callback))}
 	}
 
 	// If it's not an escape sequence, return the key
 	if firstByte != Esc {
-		return int(firstByte)
+		return keyOfByte(firstByte)
 	}
 
 	// It's an escape key. Try to read the next two bytes.
-	// These will also time out if no byte is available.
-	secondByte := callback()
+	// These will also time out if no byte comes within keyTimeout.
+	next := sequenceReader(callback)
+	secondByte := next()
 	if secondByte == 0 {
-		return int(Esc) // Just an Esc key was pressed
+		return EscKey // Just an Esc key was pressed
 	}
-
-	thirdByte := callback()
-	if thirdByte == 0 {
-		return int(Esc) // Incomplete sequence, treat as Esc
+	if secondByte != '[' && secondByte != 'O' {
+		// Alt+key
+		key := keyOfByte(secondByte)
+		key.Mod |= ModAlt
+		return key
 	}
 
-	// Check for \x1b[... sequences
-	if secondByte == '[' {
-		switch thirdByte {
-		case 'A':
-			return ArrowUp
-		case 'B':
-			return ArrowDown
-		case 'C':
-			return ArrowRight
-		case 'D':
-			return ArrowLeft
+	// Special keys send \x1b[ or \x1bO sequences, which vary between
+	// terminals: they are looked up among those of $TERM
+	seq, complete := readSequence(secondByte, next)
+	if !complete {
+		if seq == "\x1bO" {
+			return alt('O')
 		}
+		return EscKey // Incomplete sequence, treat as Esc
+	}
+	if key, ok := decodeSequence(seq); ok {
+		return key
 	}
 
 	// If it's not a recognized sequence, just return Esc
-	return int(Esc)
+	return EscKey
 }
 
-// editorMoveCursor moves the cursor based on arrow key
-func editorMoveCursor(arrowKey int) {
-	lines := getLines()
-	currentLine := ""
-	if session.cursorRow > 0 && session.cursorRow <= len(lines) {
-		currentLine = lines[session.cursorRow-1]
+// editorMoveCursor moves the cursor based on arrow key, working on the line
+// index. Up and down keep the column when the line is long enough, and
+// come back to it past shorter lines as long as the cursor moves only up
+// and down.
+func editorMoveCursor(arrowKey Key) {
+	starts := lineStarts()
+	row := session.cursorRow - 1
+	col := session.cursorCol - 1
+	if session.goalIdx == session.cursorIdx {
+		col = session.goalCol
 	}
 
-	// Terminals are 1-indexed, so the minimum row or coulmn is 1.
 	switch arrowKey {
 	case ArrowLeft:
-		if session.cursorCol > 1 {
-			session.cursorCol--
-			session.cursorIdx--
-		} else if session.cursorRow > 1 {
-			// Move to end of previous line
-			session.cursorRow--
-			prevLine := lines[session.cursorRow-1]
-			session.cursorCol = len(prevLine) + 1
-			session.cursorIdx--
-		}
-
+		session.cursorIdx = max(session.cursorIdx-1, 0)
 	case ArrowRight:
-		if session.cursorCol <= len(currentLine) {
-			session.cursorCol++
-			session.cursorIdx++
-		} else if session.cursorRow < len(lines) {
-			// Move to start of next line
-			session.cursorRow++
-			session.cursorCol = 1
-			session.cursorIdx++
-		}
-
-	case ArrowUp:
-		if session.cursorRow > 1 {
-			session.cursorRow--
-			// Adjust column if new line is shorter
-			prevLine := lines[session.cursorRow-1]
-			if session.cursorCol > len(prevLine)+1 {
-				session.cursorCol = len(prevLine) + 1
-			}
-			// Recalculate cursorIdx
-			session.cursorIdx = getLineStartIndex(session.cursorRow) + session.cursorCol - 1
-		}
-
-	case ArrowDown:
-		if session.cursorRow < len(lines) {
-			session.cursorRow++
-			// Adjust column if new line is shorter
-			if session.cursorRow <= len(lines) {
-				nextLine := lines[session.cursorRow-1]
-				if session.cursorCol > len(nextLine)+1 {
-					session.cursorCol = len(nextLine) + 1
-				}
-			}
-			// Recalculate cursorIdx
-			session.cursorIdx = getLineStartIndex(session.cursorRow) + session.cursorCol - 1
+		session.cursorIdx = min(session.cursorIdx+1, session.rope.Length())
+	case ArrowUp, ArrowDown:
+		if arrowKey == ArrowUp {
+			row--
+		} else {
+			row++
+		}
+		if row < 0 || row >= len(starts) {
+			return
 		}
+		start, end := lineBounds(starts, row)
+		session.cursorIdx = start + min(col, end-start)
+		session.goalCol, session.goalIdx = col, session.cursorIdx
 	}
+	session.cursorRow, session.cursorCol = indexPosition(session.cursorIdx)
+}
 
-	// Bounds check
-	if session.cursorIdx < 0 {
-		session.cursorIdx = 0
+// movePage moves the cursor a screen up, or down when dir is 1, in the
+// same column (Page Up and Page Down)
+func movePage(dir int) {
+	arrow := ArrowDown
+	if dir < 0 {
+		arrow = ArrowUp
 	}
-	if session.cursorIdx > session.rope.Length() {
-		session.cursorIdx = session.rope.Length()
+	for range max(int(session.screenRows)-statusRows(), 1) {
+		editorMoveCursor(arrow)
 	}
 }
 
 // handleInsert inserts a character at cursor position
 func handleInsert(s string) {
-	if session.rope == nil || session.rope.Length() == 0 {
-		session.rope = buffer.NewRope(s)
-	} else {
-		newRope, err := session.rope.Insert(session.cursorIdx, s)
-		if err == nil {
-			// Record action for undo
-			action := Action{
-				actionType: "insert",
-				position:   session.cursorIdx,
-				content:    s,
-			}
-			session.undoStack = append(session.undoStack, action)
-			session.redoStack = []Action{} // Clear redo stack on new action
-
-			session.rope = newRope
-		}
+	if !editableRange(session.cursorIdx, session.cursorIdx) {
+		return
+	}
+	if session.rope == nil {
+		session.rope = buffer.NewRope("")
+	}
+	if !editText(textEdit{position: session.cursorIdx, inserted: s}) {
+		return
 	}
 
 	session.cursorIdx += len(s)
 	updateCursorPosition()
 }
 
+// replaceText replaces the bytes in [start, end) with s, recording it for
+// undo as one step, and leaves the cursor after s
+func replaceText(start, end int, s string) {
+	if !editableRange(start, end) {
+		return
+	}
+	deleted, err := session.rope.Substring(start, end)
+	if err != nil || !editText(textEdit{position: start, removed: deleted, inserted: s}) {
+		return
+	}
+	session.cursorIdx = start + len(s)
+	updateCursorPosition()
+}
+
+// deleteText deletes the bytes in [start, end), recording it for undo,
+// and leaves the cursor at start
+func deleteText(start, end int) {
+	if start >= end || !editableRange(start, end) {
+		return
+	}
+	deleted, err := session.rope.Substring(start, end)
+	if err != nil || !editText(textEdit{position: start, removed: deleted}) {
+		return
+	}
+	session.cursorIdx = start
+	updateCursorPosition()
+}
+
+// openReadOnly makes buffers opened from now on read-only
+var openReadOnly bool
+
+// editable reports whether the active buffer may be changed, telling the
+// user why not when it may not
+func editable() bool {
+	if session.loading != nil {
+		session.statusMessage = "Buffer is still loading (Esc to cancel)"
+		return false
+	}
+	if session.readOnly {
+		session.statusMessage = "Buffer is read-only (:set readonly off to allow edits)"
+		return false
+	}
+	return true
+}
+
+// editText makes edits on the active buffer and records them for undo as
+// one action. The cursor is left where it was.
+func editText(edits ...textEdit) bool {
+	cursor := session.cursorIdx
+	if !applyEdits(edits) {
+		return false
+	}
+	pushUndo(Action{edits: edits, cursor: cursor})
+	return true
+}
+
+// applyEdits makes edits in order on the active buffer, all or none, and
+// moves the line index, the protected ranges and the cursors of the other
+// windows on the buffer with the text around them
+func applyEdits(edits []textEdit) bool {
+	rope := session.rope
+	for _, e := range edits {
+		var err error
+		if e.removed != "" {
+			if rope, err = rope.Delete(e.position, e.position+len(e.removed)); err != nil {
+				return false
+			}
+		}
+		if e.inserted != "" {
+			if rope, err = rope.Insert(e.position, e.inserted); err != nil {
+				return false
+			}
+		}
+	}
+	editLineIndex(session.rope, rope, edits)
+	session.rope = rope
+	for _, e := range edits {
+		shiftProtected(e.position, len(e.removed), len(e.inserted))
+		shiftWindows(e.position, len(e.removed), len(e.inserted))
+	}
+	return true
+}
+
+// undoEdits returns the edits that undo the action: the inverse of its
+// edits, in reverse order
+func (a Action) undoEdits() []textEdit {
+	edits := make([]textEdit, len(a.edits))
+	for i, e := range a.edits {
+		edits[len(a.edits)-1-i] = textEdit{position: e.position, removed: e.inserted, inserted: e.removed}
+	}
+	return edits
+}
+
+// pushUndo records new actions for undo, which makes the undone ones
+// impossible to redo. Large files only keep the most recent actions.
+func pushUndo(actions ...Action) {
+	session.undoStack = append(session.undoStack, actions...)
+	session.redoStack = []Action{}
+	if session.largeFile && len(session.undoStack) > largeFileUndoLimit {
+		kept := session.undoStack[len(session.undoStack)-largeFileUndoLimit:]
+		session.undoStack = append([]Action(nil), kept...)
+	}
+}
+
 // handleBackspace deletes character before cursor
 func handleBackspace() {
-	if session.cursorIdx > 0 {
+	if !editable() {
+		return
+	}
+	if session.cursorIdx > 0 && editableRange(session.cursorIdx-1, session.cursorIdx) {
 		// Get the character being deleted for undo
 		deletedChar, _ := session.rope.Index(session.cursorIdx - 1)
 
-		newRope, err := session.rope.Delete(session.cursorIdx-1, session.cursorIdx)
-		if err == nil {
-			// Record action for undo
-			action := Action{
-				actionType: "delete",
-				position:   session.cursorIdx - 1,
-				content:    string(deletedChar),
-			}
-			session.undoStack = append(session.undoStack, action)
-			session.redoStack = []Action{} // Clear redo stack
-
-			session.rope = newRope
+		if editText(textEdit{position: session.cursorIdx - 1, removed: string(deletedChar)}) {
 			session.cursorIdx--
 			updateCursorPosition()
 		}
 	}
 }
 
-// handleUndo undoes the last action
+// handleUndo undoes the last action and puts the cursor back where it was
 func handleUndo() {
 	if len(session.undoStack) == 0 {
 		return
@@ -364,23 +729,15 @@ func handleUndo() {
 
 	// Pop last action
 	action := session.undoStack[len(session.undoStack)-1]
+	edits := action.undoEdits()
+	if protectedChange(edits) {
+		return
+	}
 	session.undoStack = session.undoStack[:len(session.undoStack)-1]
 
 	// Perform reverse operation
-	if action.actionType == "insert" {
-		// Undo insert by deleting
-		newRope, err := session.rope.Delete(action.position, action.position+len(action.content))
-		if err == nil {
-			session.rope = newRope
-			session.cursorIdx = action.position
-		}
-	} else if action.actionType == "delete" {
-		// Undo delete by inserting
-		newRope, err := session.rope.Insert(action.position, action.content)
-		if err == nil {
-			session.rope = newRope
-			session.cursorIdx = action.position + len(action.content)
-		}
+	if applyEdits(edits) {
+		session.cursorIdx = min(action.cursor, session.rope.Length())
 	}
 
 	// Add to redo stack
@@ -388,7 +745,8 @@ func handleUndo() {
 	updateCursorPosition()
 }
 
-// handleRedo redoes the last undone action
+// handleRedo redoes the last undone action, leaving the cursor after its
+// last edit
 func handleRedo() {
 	if len(session.redoStack) == 0 {
 		return
@@ -396,20 +754,16 @@ func handleRedo() {
 
 	// Pop last undone action
 	action := session.redoStack[len(session.redoStack)-1]
+	if protectedChange(action.edits) {
+		return
+	}
 	session.redoStack = session.redoStack[:len(session.redoStack)-1]
 
 	// Perform the action again
-	if action.actionType == "insert" {
-		newRope, err := session.rope.Insert(action.position, action.content)
-		if err == nil {
-			session.rope = newRope
-			session.cursorIdx = action.position + len(action.content)
-		}
-	} else if action.actionType == "delete" {
-		newRope, err := session.rope.Delete(action.position, action.position+len(action.content))
-		if err == nil {
-			session.rope = newRope
-			session.cursorIdx = action.position
+	if applyEdits(action.edits) {
+		if n := len(action.edits); n > 0 {
+			last := action.edits[n-1]
+			session.cursorIdx = last.position + len(last.inserted)
 		}
 	}
 
@@ -419,82 +773,268 @@ func handleRedo() {
 }
 
 // Saves the current buffer content to a file.
-func handleSave(callback func() byte) {
-	if session.filename == "[No Name]" {
+func handleSave(fd int, callback func() byte) {
+	saveBuffer(fd, callback)
+}
+
+// saveBuffer is handleSave, reporting whether the buffer was written
+func saveBuffer(fd int, callback func() byte) bool {
+	if session.scratch {
+		session.statusMessage = "Scratch buffer, not saved: :w FILE writes it to a file"
+		return false
+	}
+	if isUnnamed(session.filename) {
 		filename := editorDrawPrompt("Save as (Esc to cancel):", callback)
 		if filename == "" {
 			session.statusMessage = "Save canceled"
-			return
+			return false
+		}
+		if !confirmOverwrite(filename, callback) {
+			session.statusMessage = fmt.Sprintf("Not saved: %s exists", filename)
+			return false
 		}
+		// The copy saved under a new name is the user's own
 		session.filename = filename
+		session.readOnly = false
+	}
+	if !editable() {
+		return false
 	}
 
-	content := session.rope.String()
+	// Hooks may change the buffer (formatting), leave a note for the status
+	// line (a formatter error) or refuse the save (linting)
+	session.statusMessage = ""
+	if err := fireHooks(eventBufWritePre, session); err != nil {
+		session.statusMessage = fmt.Sprintf("Save aborted: %v", err)
+		return false
+	}
+	rope := session.rope
+	content := rope.String()
 
-	// 0644 -> the user creating the file has R/W permissions, other users have only R permissions
-	err := os.WriteFile(session.filename, []byte(content), 0644)
+	note := session.statusMessage
+
+	if err := makeParentDir(session.filename, callback); err != nil {
+		session.statusMessage = fmt.Sprintf("Error saving file: %v", err)
+		return false
+	}
+	// 0644 -> the user creating the file has R/W permissions, other users have only R permissions.
+	// An existing file is truncated and rewritten in place rather than
+	// replaced, which keeps its permissions, owner, extended attributes and
+	// hard links, and writes through a symlink to its target.
+	data := fileContent(session, content)
+	err := os.WriteFile(session.filename, []byte(data), 0644)
+	via := ""
+	if errors.Is(err, fs.ErrPermission) {
+		via, err = privilegedSave(fd, session.filename, data, callback)
+	}
 	if err != nil {
+		log.Printf("save %s: %v", session.filename, err)
 		session.statusMessage = fmt.Sprintf("Error saving file: %v", err)
-		return
+		return false
 	}
+	log.Printf("saved %d bytes to %s", len(data), session.filename)
+	session.savedRope = rope
+	session.bom = data != content
 
-	session.statusMessage = fmt.Sprintf("Saved %d bytes to %s", len(content), session.filename)
+	saved := fmt.Sprintf("Saved %d bytes to %s", len(data), session.filename)
+	if via != "" {
+		saved += " with " + via
+	}
+	if note != "" {
+		saved += " (" + note + ")"
+	}
+	session.statusMessage = saved
+	fireHooks(eventBufWritePost, session)
+	return true
 }
 
 // Draws a prompt on the status bar and waits for user input
 func editorDrawPrompt(prompt string, callback func() byte) string {
-	var input string
+	input, _ := readPrompt(prompt, nil, callback)
+	return input
+}
+
+// readPrompt draws a prompt on the status bar and returns what the user
+// entered, with ok false when they pressed Esc. Up and Down recall the
+// entries of history, when given, and Ctrl-R searches them. The input is
+// edited at its cursor, moved with Left and Right, Ctrl-Left and Ctrl-Right
+// by words, and Home and End (or Ctrl-A and Ctrl-E).
+func readPrompt(prompt string, history *promptHistory, callback func() byte) (input string, ok bool) {
+	// pos is the cursor in input, the bytes typed before it
+	pos := 0
+	// recalled is the history entry shown, len(entries) for what is typed
+	var entries []string
+	if history != nil {
+		entries = history.list()
+	}
+	recalled, typed := len(entries), ""
+	// While searching, query is what the recalled entry has to contain
+	searching, failing, query := false, false, ""
 	for {
 		// Display the prompt on the status line
 		msg := fmt.Sprintf("%s %s", prompt, input)
+		if searching {
+			label := "history search"
+			if failing {
+				label = "failing " + label
+			}
+			msg = fmt.Sprintf("(%s) `%s': %s", label, query, input)
+		}
 
 		var buf strings.Builder
-		buf.WriteString(fmt.Sprintf("\x1b[%d;1H", session.screenRows)) // Go to last line (status line)
-		buf.WriteString("\x1b[7m")                                     // Inverted colors
+		statusRow := int(session.screenRows) - statusRows() + 1
+		buf.WriteString(fmt.Sprintf("\x1b[%d;1H", statusRow)) // Go to the status line
+		buf.WriteString("\x1b[" + currentTheme.statusBar + "m")
 		buf.WriteString(msg)
 		buf.WriteString("\x1b[K") // Clear rest of line
 		buf.WriteString("\x1b[m") // Reset colors
-		// Move cursor to end of input
-		buf.WriteString(fmt.Sprintf("\x1b[%d;%dH", session.screenRows, len(msg)+1))
+		if keyHintsEnabled {
+			buf.WriteString("\r\n")
+			drawHints(&buf, promptHints, int(session.screenCols))
+		}
+		// Move cursor to its place in the input
+		buf.WriteString(fmt.Sprintf("\x1b[%d;%dH", statusRow, len(msg)-len(input)+pos+1))
 		buf.WriteString("\x1b[?25h") // Show cursor
-		fmt.Print(buf.String())
+		fmt.Fprint(output, buf.String())
+		invalidateFrame()
 
 		key := editorReadKeypress(callback)
+		c, isChar := key.char()
+
+		if searching {
+			from := -1
+			switch {
+			case key == ctrl('r'):
+				// Look for an older match
+				from = recalled
+			case key == BackspaceKey && query != "":
+				query = query[:len(query)-1]
+				from = len(entries)
+			case isChar:
+				query += string(c)
+				// The recalled entry stays if it still matches
+				from = min(recalled+1, len(entries))
+			case key == EscKey:
+				// Leave the search, keeping the entry found to edit it
+				searching = false
+				continue
+			case key != ReturnKey:
+				continue
+			}
+			if from >= 0 {
+				i := lastContaining(entries[:from], query)
+				if failing = i < 0; !failing {
+					recalled, input = i, entries[i]
+					pos = len(input)
+				}
+				continue
+			}
+		}
 
 		switch key {
-		case int(Return):
-			return input // Done
-		case int(Esc):
-			return "" // Canceled
-		case int(Backspace):
-			if len(input) > 0 {
-				input = input[:len(input)-1]
+		case ReturnKey:
+			return input, true // Done
+		case EscKey:
+			return "", false // Canceled
+		case ctrl('r'):
+			if history != nil {
+				if recalled == len(entries) {
+					typed = input
+				}
+				searching, failing, query = true, false, ""
+			}
+		case ArrowUp, ArrowDown:
+			if recalled == len(entries) {
+				typed = input
+			}
+			if key == ArrowUp {
+				recalled = max(recalled-1, 0)
+			} else {
+				recalled = min(recalled+1, len(entries))
+			}
+			if recalled < len(entries) {
+				input = entries[recalled]
+			} else {
+				input = typed
+			}
+			pos = len(input)
+		case ArrowLeft:
+			pos = max(pos-1, 0)
+		case ArrowRight:
+			pos = min(pos+1, len(input))
+		case CtrlArrowLeft:
+			pos = wordStartBefore(input, pos)
+		case CtrlArrowRight:
+			pos = wordEndAfter(input, pos)
+		case HomeKey, ctrl('a'):
+			pos = 0
+		case EndKey, ctrl('e'):
+			pos = len(input)
+		case BackspaceKey:
+			if pos > 0 {
+				input = input[:pos-1] + input[pos:]
+				pos--
 			}
-		case 0, ArrowUp, ArrowDown, ArrowLeft, ArrowRight:
-			// Ignore timeouts and arrow keys in prompt mode
+		case DeleteKey:
+			if pos < len(input) {
+				input = input[:pos] + input[pos+1:]
+			}
+		case ctrl('w'):
+			start := wordStartBefore(input, pos)
+			input = input[:start] + input[pos:]
+			pos = start
+		case ctrl('u'):
+			input = input[pos:]
+			pos = 0
+		case ctrl('k'):
+			input = input[:pos]
+		case Key{}:
+			// Ignore timeouts in prompt mode
 			continue
 		default:
-			if isRegularCharacter(byte(key)) {
-				input += string(byte(key))
+			if isChar {
+				input = input[:pos] + string(c) + input[pos:]
+				pos++
 			}
 		}
 	}
 }
 
+// lastContaining returns the index of the last entry containing query, or
+// -1 when there is none
+func lastContaining(entries []string, query string) int {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if strings.Contains(entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
 // Prompts user for search query and moves cursor to result
 func handleSearch(fd int, callback func() byte) {
 	// Save cursor position in case of cancel/not found
 	oldCursorIdx := session.cursorIdx
 
-	query := editorDrawPrompt("Search (Esc to cancel):", callback)
+	query, ok := readPrompt("Search (Esc to cancel, Up for history):", searchHistory, callback)
 
-	if query == "" {
+	if !ok {
 		// User hit Esc
 		session.statusMessage = "Search canceled"
 		return
 	}
+	if query == "" {
+		// An empty query repeats the last search
+		if entries := searchHistory.list(); len(entries) > 0 {
+			query = entries[len(entries)-1]
+		} else {
+			session.statusMessage = "No previous search"
+			return
+		}
+	}
 
 	session.lastSearchQuery = query // Save for next time
+	searchHistory.add(query)
 
 	text := session.rope.String()
 
@@ -519,10 +1059,10 @@ func handleSearch(fd int, callback func() byte) {
 
 	Timeout:
 		key := editorReadKeypress(callback) // Read kepress for Ctrl-n
-		switch {
-		case key == int(CtrlN):
+		switch _, isChar := key.char(); {
+		case key == ctrl('n'):
 			// Do nothing
-		case isRegularCharacter(byte(key)):
+		case isChar:
 			return
 		default:
 			goto Timeout
@@ -532,21 +1072,8 @@ func handleSearch(fd int, callback func() byte) {
 
 // updateCursorPosition updates row and column based on linear index
 func updateCursorPosition() {
-	text := session.rope.String()
-	row := 1
-	col := 1
-
-	for i := 0; i < session.cursorIdx && i < len(text); i++ {
-		if text[i] == '\n' {
-			row++
-			col = 1
-		} else {
-			col++
-		}
-	}
-
-	session.cursorRow = row
-	session.cursorCol = col
+	idx := min(max(session.cursorIdx, 0), session.rope.Length())
+	session.cursorRow, session.cursorCol = indexPosition(idx)
 }
 
 // getLines splits the rope content into lines
@@ -560,85 +1087,238 @@ func getLines() []string {
 
 // getLineStartIndex returns the starting index of a given row (1-indexed)
 func getLineStartIndex(row int) int {
-	lines := getLines()
-	idx := 0
-	for i := 0; i < row-1 && i < len(lines); i++ {
-		idx += len(lines[i]) + 1 // +1 for newline
+	starts := lineStarts()
+	return starts[min(max(row, 1), len(starts))-1]
+}
+
+// scrollOff is how many lines are kept visible above and below the cursor
+// when scrolling (:set scrolloff N)
+var scrollOff = 0
+
+// editorScroll adjusts rowOffset so the cursor row lies within the
+// textRows lines that are visible on screen, scrollOff lines away from
+// the edges when the buffer of lineCount lines allows it
+func editorScroll(textRows, lineCount int) {
+	if textRows < 1 {
+		textRows = 1
+	}
+	margin := min(scrollOff, (textRows-1)/2)
+	if session.cursorRow-1-margin < session.rowOffset {
+		session.rowOffset = session.cursorRow - 1 - margin
 	}
-	return idx
+	if session.cursorRow+margin > session.rowOffset+textRows {
+		// Near the end of the buffer the margin would only show '~' rows
+		session.rowOffset = min(session.cursorRow+margin-textRows,
+			max(lineCount-textRows, session.cursorRow-textRows))
+	}
+	if session.rowOffset < 0 {
+		session.rowOffset = 0
+	}
+}
+
+// scrollCursorTo scrolls so the cursor line is at the "top", "center" or
+// "bottom" of the screen, leaving the cursor where it is in the buffer.
+// The scrolloff margin is kept at the top and bottom.
+func scrollCursorTo(where string) {
+	textRows := max(int(session.screenRows)-statusRows(), 1)
+	margin := min(scrollOff, (textRows-1)/2)
+	switch where {
+	case "top":
+		session.rowOffset = session.cursorRow - 1 - margin
+	case "center":
+		session.rowOffset = session.cursorRow - 1 - (textRows-1)/2
+	case "bottom":
+		session.rowOffset = session.cursorRow - textRows + margin
+	}
+	session.rowOffset = max(session.rowOffset, 0)
+}
+
+// handleScrollTop puts the cursor line at the top of the screen (:zt)
+func handleScrollTop(fd int, args string, callback func() byte) {
+	scrollCursorTo("top")
+}
+
+// handleScrollCenter puts the cursor line in the middle of the screen (:zz)
+func handleScrollCenter(fd int, args string, callback func() byte) {
+	scrollCursorTo("center")
+}
+
+// handleScrollBottom puts the cursor line at the bottom of the screen (:zb)
+func handleScrollBottom(fd int, args string, callback func() byte) {
+	scrollCursorTo("bottom")
 }
 
 // refreshScreen redraws the entire screen
 func refreshScreen(fd int) {
+	start := time.Now()
 	var buf strings.Builder
 
-	// Hide cursor during refresh
-	buf.WriteString("\x1b[?25l")
-	// Clear screen and move cursor to top-left
-	buf.WriteString("\x1b[2J")
-	buf.WriteString("\x1b[H")
-
-	lines := getLines()
-	rows, _ := getWindowSize(fd)
-
-	// Draw content lines (leave one row for status bar)
-	for i := 0; i < int(rows)-1; i++ {
-		if i < len(lines) {
-			buf.WriteString(lines[i])
-		} else {
-			buf.WriteString("~")
+	rows, cols := getWindowSize(fd)
+	textRows := int(rows) - statusRows() - terminalRows()
+	var screenRow, screenCol int
+	if len(windows) > 0 {
+		screenRow, screenCol = drawWindows(&buf, textRows)
+	} else {
+		screenRow, screenCol = drawBufferView(&buf, textRows)
+	}
+	if termPane != nil {
+		row, col := drawTerminalPane(&buf, textRows+1)
+		if termPane.focused {
+			screenRow, screenCol = row, col
 		}
-		buf.WriteString("\x1b[K") // Clear rest of the line
-		buf.WriteString("\r\n")
 	}
 
 	// Draw status bar (inverted colors)
 	var statusMsg string
-	if session.statusMessage != "" {
+	statusSpans = nil
+	if screenReader {
+		statusMsg = screenReaderStatus()
+	} else if session.statusMessage != "" {
 		statusMsg = session.statusMessage
 		session.statusMessage = "" // Clear it after displaying once
+	} else if session.loading != nil {
+		statusMsg = loadingStatus(time.Now())
+	} else if session.hex != nil {
+		statusMsg = hexStatus()
+	} else if session.table != nil {
+		statusMsg = tableStatus()
 	} else {
-		statusMsg = fmt.Sprintf("File: %s | Row:%d Col:%d | Ctrl-Q:Quit Ctrl-S:Save Ctrl-F:Find",
-			session.filename, session.cursorRow, session.cursorCol)
+		statusMsg = statusBarText()
+	}
+
+	// The timings of the last frame go at the end, over the rest if needed
+	width := int(session.screenCols)
+	perf := ""
+	if perfStats {
+		perf = perfText()
+		width = max(width-len([]rune(perf))-1, 0)
 	}
 
 	// Truncate status if too long
-	if len(statusMsg) > int(session.screenCols) {
-		statusMsg = statusMsg[:session.screenCols]
+	if len(statusMsg) > width {
+		statusMsg = statusMsg[:width]
 	}
 
-	buf.WriteString("\x1b[7m") // Inverted colors
+	buf.WriteString("\x1b[" + currentTheme.statusBar + "m")
 	buf.WriteString(statusMsg)
 	// Pad with spaces to fill the line
-	for i := len(statusMsg); i < int(session.screenCols); i++ {
+	for i := len(statusMsg); i < width; i++ {
 		buf.WriteString(" ")
 	}
+	if perf != "" {
+		buf.WriteString(" " + perf)
+	}
 	buf.WriteString("\x1b[m") // Reset colors
+	if keyHintsEnabled {
+		buf.WriteString("\r\n")
+		drawHints(&buf, currentHints(), int(session.screenCols))
+	}
+	if showKeys {
+		drawKeyTrace(&buf, textRows)
+	}
+
+	// Write the rows that changed at once, then the cursor
+	update := titleUpdate() + mouseUpdate() + frameUpdate(buf.String(), int(rows), int(cols), screenRow, screenCol)
+	rendered := time.Now()
+	fmt.Fprint(output, update)
+	frameWritten(start, rendered, len(update))
+}
 
-	// Move cursor to correct position
-	buf.WriteString(fmt.Sprintf("\x1b[%d;%dH", session.cursorRow, session.cursorCol))
-	// Show cursor
-	buf.WriteString("\x1b[?25h")
+// drawBufferView draws the active buffer in the view it is shown with into
+// buf, on textRows rows, and returns the screen position of the cursor
+func drawBufferView(buf *strings.Builder, textRows int) (int, int) {
+	switch {
+	case session.hex != nil:
+		return drawHexView(buf, textRows)
+	case session.table != nil:
+		return drawTableView(buf, textRows)
+	case compare.shows(session):
+		return drawCompareView(buf, textRows)
+	case session.largeFile:
+		return drawLargeTextView(buf, textRows)
+	}
+	row, col := drawTextView(buf, textRows)
+	if session.fileType() == "gitcommit" {
+		drawCommitPane(buf, textRows)
+	}
+	return row, col
+}
+
+// statusFileName is the file name shown in the status bar
+func statusFileName() string {
+	name := session.filename
+	if session.bom {
+		name += " [BOM]"
+	}
+	if session.readOnly {
+		name += " [RO]"
+	}
+	return name
+}
 
-	// Write everything at once
-	fmt.Print(buf.String())
+// drawTextView draws the visible lines of the buffer into buf and returns
+// the screen position of the cursor
+func drawTextView(buf *strings.Builder, textRows int) (int, int) {
+	lines := getLines()
+	editorScroll(textRows, len(lines))
+	highlights := bufferHighlights(session.rope.String())
+	lineStart := getLineStartIndex(session.rowOffset + 1)
+
+	// The minimap and the scrollbar are drawn over the end of the lines too
+	// long to fit, the scrollbar in the last column
+	mapWidth, barWidth := minimapWidth(), scrollbarWidth()
+	var minimap, scrollbar []string
+	if mapWidth > 0 {
+		minimap = minimapRows(lines, textRows, session.rowOffset, session.rowOffset+textRows)
+	}
+	if barWidth > 0 {
+		scrollbar = scrollbarRows(len(lines), textRows, session.rowOffset)
+	}
+
+	for i := 0; i < textRows; i++ {
+		lineIdx := i + session.rowOffset
+		if lineIdx < len(lines) {
+			line := visiblePart(lines[lineIdx], int(session.screenCols))
+			buf.WriteString(renderLine(line, lineStart, highlights))
+			lineStart += len(lines[lineIdx]) + 1
+		} else {
+			buf.WriteString("~")
+		}
+		buf.WriteString("\x1b[K") // Clear rest of the line
+		if minimap != nil {
+			fmt.Fprintf(buf, "\x1b[%dG│%s", int(session.screenCols)-barWidth-mapWidth+1, minimap[i])
+		}
+		if scrollbar != nil {
+			fmt.Fprintf(buf, "\x1b[%dG%s", int(session.screenCols), scrollbar[i])
+		}
+		buf.WriteString("\r\n")
+	}
+	line := ""
+	if session.cursorRow >= 1 && session.cursorRow <= len(lines) {
+		line = lines[session.cursorRow-1]
+	}
+	col := clampColumn(visualColumn(line, session.cursorCol-1), int(session.screenCols)) + 1
+	if mapWidth+barWidth > 0 {
+		col = min(col, int(session.screenCols)-mapWidth-barWidth)
+	}
+	return session.cursorRow - session.rowOffset, col
 }
 
 // ClearScreen clears the screen
 func ClearScreen(element rune) {
-	fmt.Printf("\x1b[%cJ", element)
+	fmt.Fprintf(output, "\x1b[%cJ", element)
 }
 
 // MoveCursorTopLeft moves cursor to top left
 func MoveCursorTopLeft() {
-	fmt.Print("\x1b[H")
+	fmt.Fprint(output, "\x1b[H")
 }
 
 // DrawTildes draws tildes for empty lines
 func DrawTildes(fd int) {
 	rows, _ := getWindowSize(fd)
 	for row := uint16(1); row < rows; row++ {
-		fmt.Print("~\r\n")
+		fmt.Fprint(output, "~\r\n")
 	}
 }
 
@@ -1,8 +1,10 @@
 package editor
 
 import (
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jellexet/golang-text-editor/pkg/buffer"
 )
@@ -21,7 +23,8 @@ func makeCallback(seq []byte) func() byte {
 }
 
 func resetSessionForTest() {
-	session = Session{}
+	session = &Session{}
+	buffers = []*Session{session}
 	// provide safe defaults so functions using screenCols/Rows don't panic
 	session.screenRows = 24
 	session.screenCols = 80
@@ -34,16 +37,16 @@ func TestEditorReadKey_PrintableAndEscAndArrow(t *testing.T) {
 	t.Run("printable char", func(t *testing.T) {
 		cb := makeCallback([]byte{'a'})
 		got := editorReadKeypress(cb)
-		if got != int('a') {
-			t.Fatalf("expected %d got %d", int('a'), got)
+		if got != (Key{Rune: 'a'}) {
+			t.Fatalf("expected a got %+v", got)
 		}
 	})
 
 	t.Run("esc then timeout", func(t *testing.T) {
 		cb := makeCallback([]byte{Esc, 0})
 		got := editorReadKeypress(cb)
-		if got != int(Esc) {
-			t.Fatalf("expected Esc (%d) got %d", int(Esc), got)
+		if got != EscKey {
+			t.Fatalf("expected Esc got %+v", got)
 		}
 	})
 
@@ -51,7 +54,7 @@ func TestEditorReadKey_PrintableAndEscAndArrow(t *testing.T) {
 		cb := makeCallback([]byte{Esc, '[', 'A'})
 		got := editorReadKeypress(cb)
 		if got != ArrowUp {
-			t.Fatalf("expected ArrowUp (%d) got %d", ArrowUp, got)
+			t.Fatalf("expected ArrowUp got %+v", got)
 		}
 	})
 }
@@ -73,7 +76,7 @@ func TestInsertBackspaceUndoRedo(t *testing.T) {
 	if session.cursorIdx != len("hello world") {
 		t.Fatalf("cursorIdx after insert wrong: got %d expected %d", session.cursorIdx, len("hello world"))
 	}
-	if len(session.undoStack) == 0 || session.undoStack[len(session.undoStack)-1].actionType != "insert" {
+	if len(session.undoStack) == 0 || session.undoStack[len(session.undoStack)-1].edits[0].inserted != " world" {
 		t.Fatalf("undo stack not updated after insert")
 	}
 
@@ -87,7 +90,7 @@ func TestInsertBackspaceUndoRedo(t *testing.T) {
 	}
 	// Last undo action should be delete
 	last := session.undoStack[len(session.undoStack)-1]
-	if last.actionType != "delete" || last.content == "" {
+	if len(last.edits) != 1 || last.edits[0].removed != "d" || last.edits[0].inserted != "" {
 		t.Fatalf("undo stack did not record delete: %+v", last)
 	}
 
@@ -110,6 +113,46 @@ func TestInsertBackspaceUndoRedo(t *testing.T) {
 	}
 }
 
+func TestUndoFirstInsertInEmptyBuffer(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("")
+
+	handleInsert("a")
+	if session.rope.String() != "a" || len(session.undoStack) != 1 {
+		t.Fatalf("insert into an empty buffer: %q, %d undo steps", session.rope.String(), len(session.undoStack))
+	}
+	handleUndo()
+	if session.rope.String() != "" || session.cursorIdx != 0 {
+		t.Fatalf("undo left %q, cursor at %d", session.rope.String(), session.cursorIdx)
+	}
+	handleRedo()
+	if session.rope.String() != "a" {
+		t.Fatalf("redo gave %q", session.rope.String())
+	}
+}
+
+func TestReplaceUndoesInOneStep(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("one two three")
+	session.cursorIdx = 2
+
+	replaceText(4, 7, "2")
+	if got := session.rope.String(); got != "one 2 three" || len(session.undoStack) != 1 {
+		t.Fatalf("replace left %q with %d undo steps", got, len(session.undoStack))
+	}
+	handleUndo()
+	if got := session.rope.String(); got != "one two three" {
+		t.Fatalf("undo left %q", got)
+	}
+	if session.cursorIdx != 2 {
+		t.Errorf("undo should put the cursor back at 2, got %d", session.cursorIdx)
+	}
+	handleRedo()
+	if got := session.rope.String(); got != "one 2 three" || session.cursorIdx != 5 {
+		t.Errorf("redo left %q with the cursor at %d", got, session.cursorIdx)
+	}
+}
+
 // editorMoveCursor tests across lines and bounds
 func TestEditorMoveCursor_MultiLine(t *testing.T) {
 	resetSessionForTest()
@@ -161,6 +204,29 @@ func TestEditorMoveCursor_MultiLine(t *testing.T) {
 	}
 }
 
+func TestEditorMoveCursor_StickyColumn(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("long line\n\nab\nanother long one")
+	GoToLine(1, 7)
+
+	for _, want := range [][2]int{{2, 1}, {3, 3}, {4, 7}} {
+		editorMoveCursor(ArrowDown)
+		if session.cursorRow != want[0] || session.cursorCol != want[1] {
+			t.Fatalf("down to %d:%d, want %d:%d", session.cursorRow, session.cursorCol, want[0], want[1])
+		}
+	}
+
+	// Typing forgets the column of before
+	GoToLine(1, 7)
+	editorMoveCursor(ArrowDown)
+	editorMoveCursor(ArrowDown)
+	handleInsert("c")
+	editorMoveCursor(ArrowDown)
+	if session.cursorCol != 4 {
+		t.Errorf("down after typing went to column %d, want 4", session.cursorCol)
+	}
+}
+
 // getLines and getLineStartIndex tests
 func TestGetLinesAndStartIndex(t *testing.T) {
 	resetSessionForTest()
@@ -186,6 +252,66 @@ func TestGetLinesAndStartIndex(t *testing.T) {
 	}
 }
 
+func TestEditorScrollKeepsScrollOff(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	scrollOff = 3
+
+	// 10 visible rows of a 100 line buffer
+	for _, step := range []struct{ row, want int }{
+		{7, 0},   // far enough from the bottom edge
+		{8, 1},   // 3 lines below the cursor stay visible
+		{50, 43}, // jumping down keeps the margin too
+		{45, 41}, // and so does moving up
+		{100, 90},
+	} {
+		session.cursorRow = step.row
+		editorScroll(10, 100)
+		if session.rowOffset != step.want {
+			t.Fatalf("cursor on row %d: rowOffset %d, want %d", step.row, session.rowOffset, step.want)
+		}
+	}
+
+	// The margin shrinks when the screen is too small for it
+	scrollOff = 50
+	session.cursorRow, session.rowOffset = 20, 0
+	editorScroll(10, 100)
+	if session.rowOffset != 14 {
+		t.Fatalf("expected the cursor in the middle of the screen, rowOffset %d", session.rowOffset)
+	}
+}
+
+func TestScrollCursorTo(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	session.rope = buffer.NewRope(strings.Repeat("line\n", 100))
+	session.screenRows = 11 // 10 text rows and the status bar
+	session.cursorRow, session.rowOffset = 50, 45
+
+	for cmd, want := range map[string]int{"zt": 49, "zz": 45, "zb": 40} {
+		runCommand(0, cmd, nil)
+		if session.rowOffset != want {
+			t.Errorf("%s: rowOffset %d, want %d", cmd, session.rowOffset, want)
+		}
+		// Drawing keeps the new position
+		editorScroll(10, 100)
+		if session.rowOffset != want || session.cursorRow != 50 {
+			t.Errorf("%s: moved to rowOffset %d, cursor row %d", cmd, session.rowOffset, session.cursorRow)
+		}
+	}
+
+	scrollOff = 2
+	runCommand(0, "zt", nil)
+	if session.rowOffset != 47 {
+		t.Errorf("zt should keep the scrolloff margin, rowOffset %d", session.rowOffset)
+	}
+	session.cursorRow = 3
+	runCommand(0, "zb", nil)
+	if session.rowOffset != 0 {
+		t.Errorf("cannot scroll above the first line, rowOffset %d", session.rowOffset)
+	}
+}
+
 // handleSearch test (simulate typing "lo" then Return, then Ctrl-N to cycle)
 func TestHandleSearch_FindsAndCycles(t *testing.T) {
 	resetSessionForTest()
@@ -277,3 +403,190 @@ func TestEditorDrawPrompt_EscCancel(t *testing.T) {
 		t.Fatalf("expected empty result on Esc cancel, got %q", result)
 	}
 }
+
+// The prompt input is edited at its cursor
+func TestEditorDrawPrompt_InlineEditing(t *testing.T) {
+	resetSessionForTest()
+
+	for _, test := range []struct {
+		keys string
+		want string
+	}{
+		{"helo\x1b[Dl\r", "hello"},
+		{"world\x1b[Hhello \r", "hello world"},
+		{"ab\x01x\x05y\r", "xaby"},
+		{"one two three\x1b[1;5D\x1b[1;5D\x17\r", "two three"},
+		{"one two\x1b[1;5D\x1b[1;5D\x1b[1;5C!\r", "one! two"},
+		{"abc\x1b[D\x1b[D\x1b[3~\r", "ac"},
+		{"abc\x1b[D\x7f\r", "ac"},
+		{"keep cut\x1b[D\x1b[D\x1b[D\x0b\r", "keep "},
+		{"cut keep\x1b[D\x1b[D\x1b[D\x1b[D\x15\r", "keep"},
+	} {
+		if got := editorDrawPrompt("Prompt:", makeCallback([]byte(test.keys))); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.keys, got, test.want)
+		}
+	}
+}
+
+// LSP positions count UTF-16 units, the rope counts bytes
+func TestLSPPositionConversion(t *testing.T) {
+	resetSessionForTest()
+
+	session.rope = buffer.NewRope("package a\nvar s = \"é\" + x\n")
+	session.cursorIdx = strings.Index(session.rope.String(), "x")
+	updateCursorPosition()
+
+	pos := cursorLSPPosition()
+	if pos.Line != 1 || pos.Character != 14 {
+		t.Fatalf("unexpected position %+v", pos)
+	}
+	if idx := lspPositionToIndex(pos); idx != session.cursorIdx {
+		t.Fatalf("round trip gave %d want %d", idx, session.cursorIdx)
+	}
+}
+
+// jumping to another file opens a buffer and Ctrl-T comes back
+func TestJumpToAndBack(t *testing.T) {
+	resetSessionForTest()
+	jumpList = nil
+
+	dir := t.TempDir()
+	other := dir + "/other.txt"
+	if err := os.WriteFile(other, []byte("first\nsecond\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session.filename = "[No Name]"
+	session.rope = buffer.NewRope("hello")
+	session.cursorIdx = 3
+	updateCursorPosition()
+
+	pushJump()
+	if err := jumpTo(other, 6); err != nil {
+		t.Fatalf("jumpTo: %v", err)
+	}
+	if session.rope.String() != "first\nsecond\n" || session.cursorRow != 2 {
+		t.Fatalf("jump did not land in the other file: row %d", session.cursorRow)
+	}
+	if len(buffers) != 2 {
+		t.Fatalf("expected 2 open buffers got %d", len(buffers))
+	}
+
+	jumpBack()
+	if session.filename != "[No Name]" || session.cursorIdx != 3 {
+		t.Fatalf("jumpBack went to %s:%d", session.filename, session.cursorIdx)
+	}
+	if len(buffers) != 2 {
+		t.Fatalf("jumping back should reuse the open buffer, have %d", len(buffers))
+	}
+}
+
+// the hover box wraps long text and flips above the cursor near the bottom
+func TestOverlayBoxPlacement(t *testing.T) {
+	resetSessionForTest()
+	session.screenCols = 20
+	session.rope = buffer.NewRope(strings.Repeat("line\n", 30))
+	updateCursorPosition()
+
+	box, top, left := newTextOverlay("", "short\n"+strings.Repeat("x", 30), placeNearCursor).layout()
+	if len(box) != 5 { // border, "short", two wrapped rows, border
+		t.Fatalf("expected 5 box lines got %d: %q", len(box), box)
+	}
+	if top != 2 || left != 1 {
+		t.Fatalf("box below cursor expected at 2,1 got %d,%d", top, left)
+	}
+
+	session.cursorIdx = getLineStartIndex(22)
+	updateCursorPosition()
+	_, top, _ = newTextOverlay("", "short", placeNearCursor).layout()
+	if top+3-1 >= 22 {
+		t.Fatalf("box should be drawn above the cursor near the bottom, top=%d", top)
+	}
+}
+
+func TestEditorReadKey_Alt(t *testing.T) {
+	cb := makeCallback([]byte{Esc, 'n', 'x'})
+	if got := editorReadKeypress(cb); got != alt('n') {
+		t.Fatalf("expected Alt-n got %+v", got)
+	}
+	// the byte after the Alt sequence is left for the next read
+	if got := editorReadKeypress(cb); got != (Key{Rune: 'x'}) {
+		t.Fatalf("expected x got %+v", got)
+	}
+}
+
+func TestParseFileArgs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("odd:12", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args      []string
+		file      string
+		line, col int
+	}{
+		{[]string{"main.go"}, "main.go", 0, 0},
+		{[]string{"main.go:42"}, "main.go", 42, 0},
+		{[]string{"main.go:42:7"}, "main.go", 42, 7},
+		{[]string{"main.go:42:7:"}, "main.go", 42, 7},
+		{[]string{"+42", "main.go"}, "main.go", 42, 0},
+		{[]string{"+", "main.go"}, "main.go", -1, 0},
+		{[]string{"odd:12"}, "odd:12", 0, 0}, // an existing file is taken literally
+	}
+	for _, tt := range tests {
+		file, line, col, err := ParseFileArgs(tt.args)
+		if err != nil || file != tt.file || line != tt.line || col != tt.col {
+			t.Errorf("ParseFileArgs(%q) = %q %d %d %v, want %q %d %d",
+				tt.args, file, line, col, err, tt.file, tt.line, tt.col)
+		}
+	}
+
+	for _, args := range [][]string{{}, {"+x", "a"}, {"+0", "a"}, {"a", "b"}} {
+		if _, _, _, err := ParseFileArgs(args); err == nil {
+			t.Errorf("ParseFileArgs(%q) should fail", args)
+		}
+	}
+}
+
+func TestHandleEditAtLine(t *testing.T) {
+	resetSessionForTest()
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("a.txt", []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runCommand(0, "e a.txt:3:2", nil)
+	if session.filename != "a.txt" || session.cursorRow != 3 || session.cursorCol != 2 {
+		t.Fatalf("expected a.txt at 3:2, got %s at %d:%d", session.filename, session.cursorRow, session.cursorCol)
+	}
+	runCommand(0, "e +2 a.txt", nil)
+	if session.cursorRow != 2 || session.cursorCol != 1 {
+		t.Fatalf("expected 2:1, got %d:%d", session.cursorRow, session.cursorCol)
+	}
+	runCommand(0, "e missing.txt", nil)
+	if session.filename != "a.txt" || !strings.HasPrefix(session.statusMessage, "Edit:") {
+		t.Fatalf("opening a missing file should fail, status %q", session.statusMessage)
+	}
+}
+
+func TestKeyTimeoutWaitsForSlowSequences(t *testing.T) {
+	saveSettings(t)
+	// The arrow key arrives split over two read timeouts
+	slow := []byte{Esc, 0, '[', 0, 'A'}
+	if got := editorReadKeypress(makeCallback(slow)); got != EscKey {
+		t.Errorf("with the default timeout expected Esc, got %+v", got)
+	}
+	if err := Set("keytimeout", "300"); err != nil || keyTimeout != 300*time.Millisecond {
+		t.Fatalf("Set keytimeout: %v (%v)", err, keyTimeout)
+	}
+	if got := editorReadKeypress(makeCallback(slow)); got != ArrowUp {
+		t.Errorf("expected ArrowUp, got %+v", got)
+	}
+	if got := editorReadKeypress(makeCallback([]byte{Esc})); got != EscKey {
+		t.Errorf("expected Esc on its own, got %+v", got)
+	}
+	if err := Set("keytimeout", "10ms"); err == nil {
+		t.Error("a timeout shorter than a read should be refused")
+	}
+}
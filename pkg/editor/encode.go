@@ -0,0 +1,110 @@
+package editor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// codec turns the selected text into its encoded or decoded form
+type codec func(text string) (string, error)
+
+// encoders are the transformations of :encode, by name
+var encoders = map[string]codec{
+	"base64": func(text string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(text)), nil
+	},
+	"url": func(text string) (string, error) {
+		return url.QueryEscape(text), nil
+	},
+	"hex": func(text string) (string, error) {
+		return hexDump(text), nil
+	},
+}
+
+// decoders are the transformations of :decode, by name
+var decoders = map[string]codec{
+	"base64": decodeBase64,
+	"url":    url.QueryUnescape,
+}
+
+// hexDump formats text like the hex view: offsets, bytes in hex and their
+// printable characters, 16 bytes a line
+func hexDump(text string) string {
+	var rows []string
+	for offset := 0; offset < len(text); offset += hexBytesPerRow {
+		rows = append(rows, hexRow(text, offset))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// decodeBase64 decodes standard or URL-safe Base64, padded or not. Line
+// breaks and spaces, which wrap long values, are left out.
+func decodeBase64(text string) (string, error) {
+	text = strings.Join(strings.Fields(text), "")
+	var err error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var data []byte
+		if data, err = enc.DecodeString(text); err == nil {
+			return string(data), nil
+		}
+	}
+	return "", err
+}
+
+// handleEncode replaces the selection by its Base64, URL or hex dump
+// encoding (:encode base64|url|hex)
+func handleEncode(fd int, args string, callback func() byte) {
+	transformSelection("encode", encoders, args)
+}
+
+// handleDecode replaces the selection by its decoded Base64 or URL
+// encoding (:decode base64|url)
+func handleDecode(fd int, args string, callback func() byte) {
+	transformSelection("decode", decoders, args)
+}
+
+// transformSelection replaces the selection by what the codec called name
+// makes of it, in one undo step, and selects the result
+func transformSelection(command string, codecs map[string]codec, name string) {
+	name = strings.TrimSpace(name)
+	transform, ok := codecs[name]
+	if !ok {
+		names := make([]string, 0, len(codecs))
+		for n := range codecs {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		session.statusMessage = fmt.Sprintf("Usage: %s %s", command, strings.Join(names, "|"))
+		return
+	}
+	start, end, selected := selection()
+	if !selected {
+		session.statusMessage = fmt.Sprintf("Select the text to %s first", command)
+		return
+	}
+	text, _ := session.rope.Substring(start, end)
+	result, err := transform(text)
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("%s %s: %v", command, name, err)
+		return
+	}
+	if result == text {
+		session.statusMessage = "Nothing to " + command
+		return
+	}
+	rope := session.rope
+	replaceText(start, end, result)
+	if session.rope == rope {
+		// Refused, with the reason in the status line
+		return
+	}
+	selectRange(start, start+len(result))
+	verb := "Encoded"
+	if command == "decode" {
+		verb = "Decoded"
+	}
+	session.statusMessage = fmt.Sprintf("%s %s: %d bytes to %d", verb, name, len(text), len(result))
+}
@@ -0,0 +1,81 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodeSelection(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("app.env", "TOKEN=user:pass word\n")
+	buffers = []*Session{session}
+
+	selectRange(6, 20)
+	runCommand(0, "encode base64", nil)
+	if got := session.rope.String(); got != "TOKEN=dXNlcjpwYXNzIHdvcmQ=\n" {
+		t.Fatalf("encoded %q", got)
+	}
+	// The result stays selected, so it can be decoded right away
+	runCommand(0, "decode base64", nil)
+	if got := session.rope.String(); got != "TOKEN=user:pass word\n" || session.statusMessage != "Decoded base64: 20 bytes to 14" {
+		t.Fatalf("decoded %q, status %q", got, session.statusMessage)
+	}
+	runCommand(0, "encode url", nil)
+	if got := session.rope.String(); got != "TOKEN=user%3Apass+word\n" {
+		t.Errorf("URL encoded %q", got)
+	}
+	runCommand(0, "decode url", nil)
+
+	// Each step is undone at once
+	for range 3 {
+		handleUndo()
+	}
+	if got := session.rope.String(); got != "TOKEN=dXNlcjpwYXNzIHdvcmQ=\n" {
+		t.Errorf("after three undos %q", got)
+	}
+}
+
+func TestDecodeBase64Variants(t *testing.T) {
+	for _, in := range []string{"aGk/Pz8=", "aGk/Pz8", "aGk_Pz8=", "aGk_\n  Pz8"} {
+		if got, err := decodeBase64(in); got != "hi???" || err != nil {
+			t.Errorf("decodeBase64(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := decodeBase64("not base64!"); err == nil {
+		t.Error("invalid Base64 should be an error")
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("a.txt", "%zz\n")
+	buffers = []*Session{session}
+
+	runCommand(0, "decode url", nil)
+	if session.statusMessage != "Select the text to decode first" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+	selectRange(0, 3)
+	runCommand(0, "decode url", nil)
+	if !strings.HasPrefix(session.statusMessage, "decode url: invalid URL escape") || session.rope.String() != "%zz\n" {
+		t.Errorf("status %q, text %q", session.statusMessage, session.rope.String())
+	}
+	runCommand(0, "decode rot13", nil)
+	if session.statusMessage != "Usage: decode base64|url" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
+
+func TestEncodeHexDump(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("a.bin", "0123456789abcdef\x00\xff")
+	buffers = []*Session{session}
+
+	selectRange(0, session.rope.Length())
+	runCommand(0, "encode hex", nil)
+	want := "00000000  30 31 32 33 34 35 36 37  38 39 61 62 63 64 65 66  |0123456789abcdef|\n" +
+		"00000010  00 ff                                             |..|"
+	if got := session.rope.String(); got != want {
+		t.Errorf("hex dump\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package editor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// Keys reported by terminals with focus reporting on (\x1b[?1004h) when
+// the editor gains or loses the focus, sent as \x1b[I and \x1b[O
+var (
+	FocusIn  = Key{Special: KeyFocusIn}
+	FocusOut = Key{Special: KeyFocusOut}
+)
+
+// diskState is what a file looked like on disk when its buffer last read
+// or wrote it
+type diskState struct {
+	modTime time.Time
+	size    int64
+}
+
+func init() {
+	record := func(s *Session) error {
+		s.disk = statFile(s.filename)
+		return nil
+	}
+	addHook(eventBufOpen, record)
+	addHook(eventBufWritePost, record)
+}
+
+// statFile returns the state of filename on disk, zero when it can't be read
+func statFile(filename string) diskState {
+	if isUnnamed(filename) {
+		return diskState{}
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return diskState{}
+	}
+	return diskState{info.ModTime(), info.Size()}
+}
+
+// checkDiskChanges looks for buffers whose file another program changed
+// since it was read or written, as when the editor gets the focus back.
+// Buffers without unsaved changes are reloaded; for the others the user
+// is asked whether to lose their changes.
+func checkDiskChanges(callback func() byte) {
+	var reloaded []string
+	all := answerNo // answerAll or answerNone once given
+	for _, s := range buffers {
+		if s.disk == (diskState{}) || s.loading != nil {
+			continue
+		}
+		now := statFile(s.filename)
+		if now == s.disk || now == (diskState{}) {
+			continue
+		}
+		// Asked once per change on disk
+		s.disk = now
+		if s.modified() {
+			reply := all
+			if reply == answerNo {
+				prompt := fmt.Sprintf("%s changed on disk. Reload and lose your changes? y/n, a: reload all, Ctrl-Q: keep all", displayPath(s.filename))
+				reply = confirm(prompt, true, callback)
+			}
+			if reply == answerAll || reply == answerNone {
+				all = reply
+			}
+			if reply != answerYes && reply != answerAll {
+				continue
+			}
+		}
+		if err := reloadBuffer(s); err != nil {
+			session.statusMessage = fmt.Sprintf("Cannot reload %s: %v", s.filename, err)
+			return
+		}
+		reloaded = append(reloaded, displayPath(s.filename))
+	}
+	if len(reloaded) > 0 {
+		session.statusMessage = "Changed on disk, reloaded: " + strings.Join(reloaded, ", ")
+	}
+}
+
+// reloadBuffer reads the file of s again. The reload can be undone.
+func reloadBuffer(s *Session) error {
+	content, err := os.ReadFile(s.filename)
+	if err != nil {
+		return err
+	}
+	log.Printf("reloaded %s (%d bytes)", s.filename, len(content))
+	text, bom := stripBOM(string(content))
+	old := s.rope.String()
+	s.undoStack = append(s.undoStack, Action{
+		edits:  []textEdit{{position: 0, removed: old, inserted: text}},
+		cursor: s.cursorIdx,
+	})
+	s.redoStack = []Action{}
+	s.rope = buffer.NewRope(text)
+	s.bom = bom
+	s.savedRope = s.rope
+	// Positions in the old text mean nothing in the new one
+	s.protected = nil
+	s.selecting = false
+	s.cursorIdx = min(s.cursorIdx, s.rope.Length())
+	if s == session {
+		updateCursorPosition()
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// changeOnDisk writes content to path as another program would, later
+// than the buffer last read it
+func changeOnDisk(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFocusInReloadsChangedFiles(t *testing.T) {
+	resetSessionForTest()
+	t.Chdir(t.TempDir())
+	os.WriteFile("a.txt", []byte("old"), 0644)
+	if err := openBuffer("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	changeOnDisk(t, "a.txt", "new text")
+	ProcessKeypress(0, makeCallback([]byte("\x1b[I\x11\x11")))
+	if got := session.rope.String(); got != "new text" || session.modified() {
+		t.Fatalf("expected the buffer reloaded, got %q", got)
+	}
+	handleUndo()
+	if got := session.rope.String(); got != "old" {
+		t.Errorf("undoing the reload left %q", got)
+	}
+}
+
+func TestFocusInAsksBeforeLosingChanges(t *testing.T) {
+	resetSessionForTest()
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, []byte("old"), 0644)
+	if err := openBuffer(path); err != nil {
+		t.Fatal(err)
+	}
+	handleInsert("mine ")
+
+	changeOnDisk(t, path, "theirs")
+	checkDiskChanges(makeCallback([]byte("n")))
+	if got := session.rope.String(); got != "mine old" {
+		t.Fatalf("changes were lost: %q", got)
+	}
+	// Only asked once for a change
+	checkDiskChanges(makeCallback(nil))
+	if session.rope.String() != "mine old" {
+		t.Fatal("asked again")
+	}
+
+	changeOnDisk(t, path, "theirs again")
+	os.Chtimes(path, time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+	checkDiskChanges(makeCallback([]byte("y")))
+	if got := session.rope.String(); got != "theirs again" || !strings.Contains(session.statusMessage, "reloaded") {
+		t.Errorf("expected the buffer reloaded, got %q (%q)", got, session.statusMessage)
+	}
+}
@@ -0,0 +1,171 @@
+package editor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/jellexet/golang-text-editor/pkg/diff"
+)
+
+// formatters lists, per file extension, the commands that read a file on
+// stdin and print it formatted, in order of preference. "{file}" in an
+// argument is replaced by the buffer's file name.
+var formatters = map[string][][]string{
+	".go":   {{"goimports"}, {"gofmt"}},
+	".py":   {{"black", "--quiet", "-"}},
+	".rs":   {{"rustfmt", "--emit", "stdout"}},
+	".c":    {{"clang-format", "--assume-filename={file}"}},
+	".h":    {{"clang-format", "--assume-filename={file}"}},
+	".cpp":  {{"clang-format", "--assume-filename={file}"}},
+	".js":   {{"prettier", "--stdin-filepath", "{file}"}},
+	".ts":   {{"prettier", "--stdin-filepath", "{file}"}},
+	".json": {{"prettier", "--stdin-filepath", "{file}"}},
+	".css":  {{"prettier", "--stdin-filepath", "{file}"}},
+	".html": {{"prettier", "--stdin-filepath", "{file}"}},
+}
+
+// formatOnSave runs the formatter of the file type on every save
+var formatOnSave = true
+
+func init() {
+	addHook(eventBufWritePre, func(s *Session) error {
+		if !formatOnSave || s != session {
+			return nil
+		}
+		// A formatting error must not prevent saving, only be reported
+		if err := formatBuffer(); err != nil {
+			s.statusMessage = fmt.Sprintf("format failed: %v", err)
+		}
+		return nil
+	})
+}
+
+// formatterFor returns the first installed formatter for files of fileType
+// named filename, or nil
+func formatterFor(fileType, filename string) []string {
+	for _, candidate := range formatters["."+fileType] {
+		if _, err := exec.LookPath(candidate[0]); err == nil {
+			args := make([]string, len(candidate))
+			for i, arg := range candidate {
+				args[i] = strings.ReplaceAll(arg, "{file}", filename)
+			}
+			return args
+		}
+	}
+	return nil
+}
+
+// formatBuffer pipes the active buffer through its formatter and applies the
+// result. When the formatter fails the buffer is left untouched.
+func formatBuffer() error {
+	args := formatterFor(session.fileType(), session.filename)
+	if args == nil {
+		return nil
+	}
+	formatted, err := runFormatter(context.Background(), args, session.rope.String())
+	if err != nil {
+		return err
+	}
+	applyTextPreservingCursor(formatted)
+	return nil
+}
+
+// runFormatter returns text formatted by the formatter command args, which
+// is killed when ctx is canceled
+func runFormatter(ctx context.Context, args []string, text string) (string, error) {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", args[0], firstLine(msg))
+		}
+		return "", fmt.Errorf("%s: %v", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// applyTextPreservingCursor turns the active buffer into newText by replacing
+// only the lines that differ, and keeps the cursor on the same line of text
+func applyTextPreservingCursor(newText string) {
+	oldLines := splitLinesKeepEnds(session.rope.String())
+	newLines := splitLinesKeepEnds(newText)
+	hunks := diff.Lines(oldLines, newLines)
+	if len(hunks) == 0 {
+		return
+	}
+
+	// Find where the cursor line ends up: lines after a hunk shift by the
+	// hunk's size change, lines inside a hunk stay at the same offset in it
+	row, col := session.cursorRow-1, session.cursorCol-1
+	newRow := row
+	for _, h := range hunks {
+		if h.OldStart+h.OldLines <= row {
+			newRow += h.NewLines - h.OldLines
+		} else if h.OldStart <= row {
+			newRow = h.NewStart + min(row-h.OldStart, max(h.NewLines-1, 0))
+			break
+		}
+	}
+
+	// Replace from the bottom up so that earlier offsets stay valid
+	offsets := make([]int, len(oldLines)+1)
+	for i, line := range oldLines {
+		offsets[i+1] = offsets[i] + len(line)
+	}
+	var edits []textEdit
+	for i := len(hunks) - 1; i >= 0; i-- {
+		h := hunks[i]
+		edits = append(edits, textEdit{
+			position: offsets[h.OldStart],
+			removed:  strings.Join(oldLines[h.OldStart:h.OldStart+h.OldLines], ""),
+			inserted: strings.Join(newLines[h.NewStart:h.NewStart+h.NewLines], ""),
+		})
+	}
+	// The whole change is one undo step
+	if !editable() || protectedChange(edits) || !editText(edits...) {
+		return
+	}
+
+	lines := getLines()
+	newRow = min(max(newRow, 0), len(lines)-1)
+	session.cursorIdx = getLineStartIndex(newRow+1) + min(col, len(lines[newRow]))
+	updateCursorPosition()
+}
+
+// handleFormat formats the buffer now (:format), or turns formatting on
+// save on and off (:format on, :format off)
+func handleFormat(fd int, args string, callback func() byte) {
+	switch args {
+	case "on", "off":
+		formatOnSave = args == "on"
+		session.statusMessage = "Format on save " + args
+		return
+	}
+
+	if !editable() {
+		return
+	}
+	formatter := formatterFor(session.fileType(), session.filename)
+	if formatter == nil {
+		session.statusMessage = "No formatter installed for " + session.filename
+		return
+	}
+	// The formatter reads the text as it is now and the result replaces it
+	// in one edit, so that an interrupted format leaves the buffer as it was
+	text := session.rope.String()
+	formatted, err := interruptible("Formatting with "+formatter[0], callback, func(ctx context.Context) (string, error) {
+		return runFormatter(ctx, formatter, text)
+	})
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Format failed: %v", err)
+		return
+	}
+	applyTextPreservingCursor(formatted)
+	session.statusMessage = "Formatted"
+}
@@ -0,0 +1,66 @@
+package editor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestApplyTextPreservingCursor(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("a\n  b\nc\nkeep here\n")
+	session.cursorIdx = strings.Index(session.rope.String(), "here")
+	updateCursorPosition()
+
+	applyTextPreservingCursor("x\ny\na\nb\nc\nkeep here\n")
+	if got := session.rope.String(); got != "x\ny\na\nb\nc\nkeep here\n" {
+		t.Fatalf("unexpected text %q", got)
+	}
+	if session.cursorRow != 6 || session.cursorCol != 6 {
+		t.Fatalf("cursor should stay on \"here\", got row %d col %d", session.cursorRow, session.cursorCol)
+	}
+
+	// Every hunk is undone and redone in one step
+	handleUndo()
+	if got := session.rope.String(); got != "a\n  b\nc\nkeep here\n" || len(session.undoStack) != 0 {
+		t.Fatalf("undo left %q", got)
+	}
+	if session.cursorIdx != strings.Index(session.rope.String(), "here") {
+		t.Errorf("undo should put the cursor back, got %d", session.cursorIdx)
+	}
+	handleRedo()
+	if got := session.rope.String(); got != "x\ny\na\nb\nc\nkeep here\n" {
+		t.Fatalf("redo left %q", got)
+	}
+}
+
+func TestFormatOnSave(t *testing.T) {
+	gofmt, err := exec.LookPath("gofmt")
+	if err != nil {
+		t.Skip("gofmt not installed")
+	}
+	t.Setenv("PATH", filepath.Dir(gofmt)) // keep goimports out of the way
+	resetSessionForTest()
+	path := filepath.Join(t.TempDir(), "main.go")
+	session.filename = path
+
+	session.rope = buffer.NewRope("package main\nfunc main(){\nx:=1\n}\n")
+	handleSave(0, nil)
+	if data, _ := os.ReadFile(path); string(data) != "package main\n\nfunc main() {\n\tx := 1\n}\n" {
+		t.Fatalf("file not formatted: %q", data)
+	}
+
+	// A syntax error is reported, the buffer is saved as it is
+	session.rope = buffer.NewRope("package main\nfunc {\n")
+	handleSave(0, nil)
+	if data, _ := os.ReadFile(path); string(data) != "package main\nfunc {\n" {
+		t.Fatalf("unformattable buffer should be saved unchanged: %q", data)
+	}
+	if !strings.Contains(session.statusMessage, "format failed: gofmt") {
+		t.Fatalf("formatter error not reported: %q", session.statusMessage)
+	}
+}
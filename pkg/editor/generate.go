@@ -0,0 +1,156 @@
+package editor
+
+import (
+	"cmp"
+	"crypto/rand"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateFormat and timeFormat are the layouts :date and :time insert the
+// current date and time with, in the notation of Go's time package
+// (:set dateformat 02/01/2006) or one of the names of timeLayouts
+var (
+	dateFormat = "2006-01-02"
+	timeFormat = "15:04"
+)
+
+// timeLayouts holds the formats that can be given by name; "unix" is the
+// number of seconds since 1970
+var timeLayouts = map[string]string{
+	"iso":     time.RFC3339,
+	"rfc1123": time.RFC1123Z,
+	"kitchen": time.Kitchen,
+	"unix":    "",
+}
+
+// generators maps names to shell commands whose output :gen inserts, set
+// with :generator and in the [generators] section of the config file
+var generators = map[string]string{}
+
+// now is the clock of :date and :time, replaced by tests
+var now = time.Now
+
+// formatTime formats t with a layout or the name of one
+func formatTime(t time.Time, format string) string {
+	if layout, ok := timeLayouts[format]; ok {
+		if layout == "" {
+			return strconv.FormatInt(t.Unix(), 10)
+		}
+		format = layout
+	}
+	return t.Format(format)
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// insertGenerated puts text at the cursor, in place of the selection when
+// there is one
+func insertGenerated(text string) {
+	if !editable() {
+		return
+	}
+	insertPaste(text)
+}
+
+// handleDate inserts the current date, in the given format or dateformat
+// (:date [FORMAT])
+func handleDate(fd int, args string, callback func() byte) {
+	insertGenerated(formatTime(now(), cmp.Or(args, dateFormat)))
+}
+
+// handleTime inserts the current time, in the given format or timeformat
+// (:time [FORMAT])
+func handleTime(fd int, args string, callback func() byte) {
+	insertGenerated(formatTime(now(), cmp.Or(args, timeFormat)))
+}
+
+// handleUUID inserts a random UUID (:uuid)
+func handleUUID(fd int, args string, callback func() byte) {
+	id, err := newUUID()
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("UUID: %v", err)
+		return
+	}
+	insertGenerated(id)
+}
+
+// setGenerator makes :gen name insert the output of command
+func setGenerator(name, command string) error {
+	if name == "" || strings.ContainsAny(name, " \t") || command == "" {
+		return fmt.Errorf("usage: generator name command")
+	}
+	generators[name] = command
+	return nil
+}
+
+// handleGenerator lists the generators, shows one or sets one
+// (:generator [name [command]])
+func handleGenerator(fd int, args string, callback func() byte) {
+	name, command, _ := strings.Cut(strings.TrimSpace(args), " ")
+	command = strings.TrimSpace(command)
+	switch {
+	case name == "":
+		if len(generators) == 0 {
+			session.statusMessage = "No generators"
+			return
+		}
+		var b strings.Builder
+		for _, name := range slices.Sorted(maps.Keys(generators)) {
+			fmt.Fprintf(&b, "%-12s %s\n", name, generators[name])
+		}
+		showScratch("[Generators]", b.String())
+	case command == "":
+		if command, ok := generators[name]; ok {
+			session.statusMessage = name + ": " + command
+		} else {
+			session.statusMessage = "No generator " + name
+		}
+	default:
+		if err := setGenerator(name, command); err != nil {
+			session.statusMessage = err.Error()
+			return
+		}
+		session.statusMessage = "Generator " + name + ": " + command
+	}
+}
+
+// handleGenerate inserts the output of a generator, without its final line
+// break (:gen name)
+func handleGenerate(fd int, args string, callback func() byte) {
+	if !editable() {
+		return
+	}
+	command, ok := generators[args]
+	if !ok {
+		if args == "" {
+			session.statusMessage = "Usage: gen name"
+		} else {
+			session.statusMessage = "No generator " + args
+		}
+		return
+	}
+	result, err := runShellInterruptible(command, callback)
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("!%s: %v", command, err)
+		return
+	}
+	if result.err != nil || result.exitCode != 0 {
+		session.statusMessage = result.status(command)
+		return
+	}
+	insertGenerated(strings.TrimSuffix(result.stdout, "\n"))
+}
@@ -0,0 +1,94 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// useGenerators gives the test generators of its own and a fixed clock
+func useGenerators(t *testing.T) {
+	oldGenerators, oldNow := generators, now
+	generators = map[string]string{}
+	now = func() time.Time { return time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC) }
+	t.Cleanup(func() { generators, now = oldGenerators, oldNow })
+}
+
+func TestInsertDateAndTime(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	useGenerators(t)
+	session.rope = buffer.NewRope("")
+
+	runCommand(0, "date", nil)
+	runCommand(0, "time", nil)
+	runCommand(0, "date 02/01/2006", nil)
+	runCommand(0, "time unix", nil)
+	if got := session.rope.String(); got != "2024-03-0914:0509/03/20241709993100" {
+		t.Fatalf("inserted %q", got)
+	}
+
+	runCommand(0, "set dateformat iso", nil)
+	session.rope = buffer.NewRope("")
+	session.cursorIdx = 0
+	runCommand(0, "date", nil)
+	if got := session.rope.String(); got != "2024-03-09T14:05:00Z" {
+		t.Fatalf("dateformat iso inserted %q", got)
+	}
+
+	// The date replaces the selection
+	selectRange(0, 10)
+	runCommand(0, "date 2006", nil)
+	if got := session.rope.String(); got != "2024T14:05:00Z" {
+		t.Fatalf("date over the selection left %q", got)
+	}
+}
+
+func TestInsertUUID(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("")
+	runCommand(0, "uuid", nil)
+	runCommand(0, "uuid", nil)
+	uuid := `[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}`
+	got := session.rope.String()
+	if !regexp.MustCompile(`^` + uuid + uuid + `$`).MatchString(got) {
+		t.Fatalf("inserted %q", got)
+	}
+	if got[:36] == got[36:] {
+		t.Error("two UUIDs should differ")
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	useGenerators(t)
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("[generators]\nlorem = echo lorem ipsum\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	runCommand(0, "generator fail exit 3", nil)
+
+	session.rope = buffer.NewRope("<>")
+	session.cursorIdx = 1
+	runCommand(0, "gen lorem", nil)
+	if got := session.rope.String(); got != "<lorem ipsum>" {
+		t.Fatalf("gen inserted %q", got)
+	}
+
+	runCommand(0, "gen fail", nil)
+	if got := session.rope.String(); got != "<lorem ipsum>" || session.statusMessage != "!exit 3: exit 3" {
+		t.Errorf("a failing generator left %q, status %q", got, session.statusMessage)
+	}
+	runCommand(0, "gen missing", nil)
+	if session.statusMessage != "No generator missing" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
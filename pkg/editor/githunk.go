@@ -0,0 +1,170 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jellexet/golang-text-editor/pkg/diff"
+)
+
+// gitFile locates the active buffer inside its repository.
+// It returns the repository root and the file path relative to it.
+func gitFile() (root, rel string, err error) {
+	abs, err := filepath.Abs(session.filename)
+	if err != nil {
+		return "", "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(resolved, filepath.Base(abs))
+	}
+
+	out, err := gitOutput(filepath.Dir(abs), nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", "", err
+	}
+	root = strings.TrimSpace(out)
+	rel, err = filepath.Rel(root, abs)
+	if err != nil {
+		return "", "", err
+	}
+	return root, filepath.ToSlash(rel), nil
+}
+
+// gitOutput runs git in dir, feeding it stdin, and returns its output.
+// Errors carry the first line git printed on stderr.
+func gitOutput(dir string, stdin []byte, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", firstLine(msg))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// splitLinesKeepEnds splits text into lines that keep their trailing newline,
+// so joining them gives back text exactly
+func splitLinesKeepEnds(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// hunkAtRow returns the hunk touching the given zero-based buffer line.
+// A pure deletion touches the lines right before and after it.
+func hunkAtRow(hunks []diff.Hunk, row int) (diff.Hunk, bool) {
+	for _, h := range hunks {
+		if h.NewLines == 0 {
+			if row == h.NewStart || row == h.NewStart-1 {
+				return h, true
+			}
+		} else if row >= h.NewStart && row < h.NewStart+h.NewLines {
+			return h, true
+		}
+	}
+	return diff.Hunk{}, false
+}
+
+// cursorHunk compares the revision of the active file named by rev
+// ("" for the index, "HEAD" for the last commit) with the buffer and
+// returns the hunk under the cursor along with both sides' lines
+func cursorHunk(rev string) (h diff.Hunk, base, current []string, err error) {
+	root, rel, err := gitFile()
+	if err != nil {
+		return h, nil, nil, err
+	}
+	baseText, err := gitOutput(root, nil, "show", rev+":"+rel)
+	if err != nil {
+		return h, nil, nil, err
+	}
+
+	base = splitLinesKeepEnds(baseText)
+	current = splitLinesKeepEnds(session.rope.String())
+	h, ok := hunkAtRow(diff.Lines(base, current), session.cursorRow-1)
+	if !ok {
+		return h, nil, nil, fmt.Errorf("no change under the cursor")
+	}
+	return h, base, current, nil
+}
+
+// hunkPatch formats a single hunk as a zero-context unified diff for path
+func hunkPatch(path string, h diff.Hunk, base, current []string) string {
+	// A side with no lines is addressed by the line before it
+	oldStart, newStart := h.OldStart+1, h.NewStart+1
+	if h.OldLines == 0 {
+		oldStart--
+	}
+	if h.NewLines == 0 {
+		newStart--
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- a/%s\n+++ b/%s\n", path, path)
+	fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", oldStart, h.OldLines, newStart, h.NewLines)
+	writeLines := func(prefix string, lines []string) {
+		for _, line := range lines {
+			buf.WriteString(prefix + line)
+			if !strings.HasSuffix(line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	writeLines("-", base[h.OldStart:h.OldStart+h.OldLines])
+	writeLines("+", current[h.NewStart:h.NewStart+h.NewLines])
+	return buf.String()
+}
+
+// handleStageHunk stages the hunk under the cursor, as it is in the buffer,
+// without touching the rest of the file (:stage-hunk)
+func handleStageHunk(fd int, args string, callback func() byte) {
+	h, base, current, err := cursorHunk("")
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Stage hunk: %v", err)
+		return
+	}
+	root, rel, err := gitFile()
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Stage hunk: %v", err)
+		return
+	}
+
+	patch := hunkPatch(rel, h, base, current)
+	if _, err := gitOutput(root, []byte(patch), "apply", "--cached", "--unidiff-zero", "-"); err != nil {
+		session.statusMessage = fmt.Sprintf("Stage hunk: %v", err)
+		return
+	}
+	session.statusMessage = fmt.Sprintf("Staged -%d +%d lines", h.OldLines, h.NewLines)
+}
+
+// handleRevertHunk replaces the hunk under the cursor with its HEAD version.
+// Only the buffer changes, so the revert can be undone (:revert-hunk).
+func handleRevertHunk(fd int, args string, callback func() byte) {
+	if !editable() {
+		return
+	}
+	h, base, current, err := cursorHunk("HEAD")
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Revert hunk: %v", err)
+		return
+	}
+
+	start := len(strings.Join(current[:h.NewStart], ""))
+	end := start + len(strings.Join(current[h.NewStart:h.NewStart+h.NewLines], ""))
+	replaceText(start, end, strings.Join(base[h.OldStart:h.OldStart+h.OldLines], ""))
+	session.cursorIdx = start
+	updateCursorPosition()
+	session.statusMessage = fmt.Sprintf("Reverted -%d +%d lines to HEAD", h.NewLines, h.OldLines)
+}
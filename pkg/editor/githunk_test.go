@@ -0,0 +1,53 @@
+package editor
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestStageHunk_OnlyStagesCursorHunk(t *testing.T) {
+	dir, file := gitTestRepo(t, "a.txt", "one\ntwo\nthree\nfour\n")
+
+	resetSessionForTest()
+	session.filename = file
+	session.rope = buffer.NewRope("ONE\ntwo\nthree\nfour\nfive\n")
+	session.cursorIdx = session.rope.Length() - 2 // on "five"
+	updateCursorPosition()
+
+	handleStageHunk(0, "", nil)
+	if !strings.HasPrefix(session.statusMessage, "Staged") {
+		t.Fatalf("stage failed: %s", session.statusMessage)
+	}
+
+	out, err := exec.Command("git", "-C", dir, "diff", "--cached", "-U0").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	staged := string(out)
+	if !strings.Contains(staged, "+five") || strings.Contains(staged, "ONE") {
+		t.Fatalf("unexpected staged diff:\n%s", staged)
+	}
+}
+
+func TestRevertHunk_RestoresHeadLines(t *testing.T) {
+	_, file := gitTestRepo(t, "a.txt", "one\ntwo\nthree\n")
+
+	resetSessionForTest()
+	session.filename = file
+	session.rope = buffer.NewRope("one\n2\nthree\nfour\n")
+	session.cursorIdx = 4 // on "2"
+	updateCursorPosition()
+
+	handleRevertHunk(0, "", nil)
+	if got := session.rope.String(); got != "one\ntwo\nthree\nfour\n" {
+		t.Fatalf("revert gave %q (%s)", got, session.statusMessage)
+	}
+
+	handleUndo()
+	if got := session.rope.String(); got != "one\n2\nthree\nfour\n" {
+		t.Fatalf("undo after revert gave %q", got)
+	}
+}
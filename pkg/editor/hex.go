@@ -0,0 +1,181 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hexBytesPerRow is how many bytes a row of the hex view shows
+const hexBytesPerRow = 16
+
+// hexState is the hex editing state of a buffer
+type hexState struct {
+	lowNibble bool // the next digit typed sets the low half of the byte
+	insert    bool // digits insert new bytes instead of overwriting
+}
+
+// hexDigit returns the value of a hexadecimal digit key, or -1
+func hexDigit(key Key) int {
+	r := key.Rune
+	switch {
+	case key.Mod != 0:
+		return -1
+	case r >= '0' && r <= '9':
+		return int(r - '0')
+	case r >= 'a' && r <= 'f':
+		return int(r - 'a' + 10)
+	case r >= 'A' && r <= 'F':
+		return int(r - 'A' + 10)
+	}
+	return -1
+}
+
+// hexRow formats the bytes starting at offset as one row of the hex view:
+// the offset, the bytes in hex and their printable characters
+func hexRow(data string, offset int) string {
+	end := min(offset+hexBytesPerRow, len(data))
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%08x  ", offset)
+	for i := offset; i < offset+hexBytesPerRow; i++ {
+		if i < end {
+			fmt.Fprintf(&buf, "%02x ", data[i])
+		} else {
+			buf.WriteString("   ")
+		}
+		if i-offset == hexBytesPerRow/2-1 {
+			buf.WriteString(" ")
+		}
+	}
+	buf.WriteString(" |")
+	for i := offset; i < end; i++ {
+		if isRegularCharacter(data[i]) && data[i] != Backspace {
+			buf.WriteByte(data[i])
+		} else {
+			buf.WriteByte('.')
+		}
+	}
+	buf.WriteString("|")
+	return buf.String()
+}
+
+// drawHexView draws the visible rows of the hex view into buf and returns
+// the screen position of the cursor
+func drawHexView(buf *strings.Builder, textRows int) (int, int) {
+	data := session.rope.String()
+	textRows = max(textRows, 1)
+	cursorRow := session.cursorIdx / hexBytesPerRow
+	if cursorRow < session.rowOffset {
+		session.rowOffset = cursorRow
+	}
+	if cursorRow >= session.rowOffset+textRows {
+		session.rowOffset = cursorRow - textRows + 1
+	}
+
+	for i := 0; i < textRows; i++ {
+		offset := (session.rowOffset + i) * hexBytesPerRow
+		if offset < len(data) || offset == 0 {
+			buf.WriteString(hexRow(data, offset))
+		} else {
+			buf.WriteString("~")
+		}
+		buf.WriteString("\x1b[K")
+		buf.WriteString("\r\n")
+	}
+	return cursorRow - session.rowOffset + 1, hexCursorColumn(session.cursorIdx, session.hex.lowNibble)
+}
+
+// hexCursorColumn returns the 1-indexed screen column of the hex digit the
+// cursor is on
+func hexCursorColumn(idx int, lowNibble bool) int {
+	col := idx % hexBytesPerRow
+	screenCol := 11 + 3*col
+	if col >= hexBytesPerRow/2 {
+		screenCol++
+	}
+	if lowNibble {
+		screenCol++
+	}
+	return screenCol
+}
+
+// hexStatus describes the cursor and the editing mode for the status bar
+func hexStatus() string {
+	mode := "OVERWRITE"
+	if session.hex.insert {
+		mode = "INSERT"
+	}
+	return fmt.Sprintf("File: %s | Offset: 0x%08x (%d/%d) | HEX %s Tab:mode",
+		statusFileName(), session.cursorIdx, session.cursorIdx, session.rope.Length(), mode)
+}
+
+// hexSetByte replaces the byte at idx, which is recorded for undo
+func hexSetByte(idx int, b byte) {
+	replaceText(idx, idx+1, string([]byte{b}))
+}
+
+// handleHexKey applies a key to the hex view and reports whether it was used.
+// Keys it doesn't use (saving, undo, the command prompt) keep their usual meaning.
+func handleHexKey(key Key) bool {
+	length := session.rope.Length()
+	idx := session.cursorIdx
+
+	if d := hexDigit(key); d >= 0 {
+		switch {
+		case session.hex.lowNibble:
+			b, _ := session.rope.Index(idx)
+			hexSetByte(idx, b&0xF0|byte(d))
+			session.hex.lowNibble = false
+			session.cursorIdx = min(idx+1, session.rope.Length())
+		case session.hex.insert || idx >= length:
+			handleInsert(string([]byte{byte(d << 4)}))
+			session.hex.lowNibble = true
+			session.cursorIdx = idx
+		default:
+			b, _ := session.rope.Index(idx)
+			hexSetByte(idx, byte(d<<4)|b&0x0F)
+			session.hex.lowNibble = true
+			session.cursorIdx = idx
+		}
+		updateCursorPosition()
+		return true
+	}
+
+	switch key {
+	case ArrowLeft:
+		session.cursorIdx = max(idx-1, 0)
+	case ArrowRight:
+		session.cursorIdx = min(idx+1, length)
+	case ArrowUp:
+		if idx >= hexBytesPerRow {
+			session.cursorIdx = idx - hexBytesPerRow
+		}
+	case ArrowDown:
+		session.cursorIdx = min(idx+hexBytesPerRow, length)
+	case TabKey:
+		session.hex.insert = !session.hex.insert
+	case BackspaceKey:
+		// Backspace in the middle of a byte only forgets the first digit
+		if !session.hex.lowNibble {
+			handleBackspace()
+		}
+	default:
+		return false
+	}
+	session.hex.lowNibble = false
+	updateCursorPosition()
+	return true
+}
+
+// handleHex turns the hex view of the active buffer on and off (:hex)
+func handleHex(fd int, args string, callback func() byte) {
+	if session.hex != nil {
+		session.hex = nil
+		session.rowOffset = 0
+		session.statusMessage = "Hex mode off"
+		return
+	}
+	session.hex = &hexState{}
+	session.rowOffset = 0
+	session.statusMessage = "Hex mode on: type hex digits to edit, Tab switches insert/overwrite"
+}
@@ -0,0 +1,106 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestHexRow(t *testing.T) {
+	data := "Hello\x00\x01world, hex!\n"
+	got := hexRow(data, 0)
+	want := "00000000  48 65 6c 6c 6f 00 01 77  6f 72 6c 64 2c 20 68 65  |Hello..world, he|"
+	if got != want {
+		t.Errorf("hexRow(0) =\n%q\nwant\n%q", got, want)
+	}
+
+	got = hexRow(data, 16)
+	want = "00000010  78 21 0a                                          |x!.|"
+	if got != want {
+		t.Errorf("hexRow(16) =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestHexCursorColumn(t *testing.T) {
+	row := hexRow("0123456789abcdef", 0)
+	for idx, want := range map[int]string{0: "30", 7: "37", 8: "38", 15: "66"} {
+		col := hexCursorColumn(idx, false) - 1
+		if got := row[col : col+2]; got != want {
+			t.Errorf("byte %d: column shows %q, want %q", idx, got, want)
+		}
+		if low := hexCursorColumn(idx, true) - 1; low != col+1 {
+			t.Errorf("byte %d: low nibble column = %d, want %d", idx, low, col+1)
+		}
+	}
+}
+
+func TestHexOverwriteAndUndo(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("ABC")
+	session.hex = &hexState{}
+	session.cursorIdx = 1
+
+	handleHexKey(Key{Rune: '7'})
+	handleHexKey(Key{Rune: 'a'})
+	if got := session.rope.String(); got != "AzC" {
+		t.Fatalf("after typing 7a: %q, want %q", got, "AzC")
+	}
+	if session.cursorIdx != 2 || session.hex.lowNibble {
+		t.Errorf("cursor = %d (low nibble %v), want the next byte", session.cursorIdx, session.hex.lowNibble)
+	}
+
+	for len(session.undoStack) > 0 {
+		handleUndo()
+	}
+	if got := session.rope.String(); got != "ABC" {
+		t.Errorf("after undo: %q, want %q", got, "ABC")
+	}
+}
+
+func TestHexInsertMode(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("AB")
+	session.hex = &hexState{}
+	session.cursorIdx = 1
+
+	handleHexKey(TabKey)
+	for _, key := range "ff00" {
+		handleHexKey(Key{Rune: key})
+	}
+	if got := session.rope.String(); got != "A\xff\x00B" {
+		t.Fatalf("after inserting ff00: %q", got)
+	}
+
+	// Appending works in either mode
+	handleHexKey(TabKey)
+	session.cursorIdx = session.rope.Length()
+	handleHexKey(Key{Rune: '4'})
+	handleHexKey(Key{Rune: '3'})
+	if got := session.rope.String(); got != "A\xff\x00BC" {
+		t.Errorf("after appending 43: %q", got)
+	}
+}
+
+func TestHexBackspaceAndUnusedKeys(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("ABC")
+	session.hex = &hexState{}
+	session.cursorIdx = 2
+
+	handleHexKey(Key{Rune: '5'})
+	handleHexKey(BackspaceKey)
+	if got := session.rope.String(); got != "ABS" {
+		t.Fatalf("after typing 5: %q, want %q", got, "ABS")
+	}
+	if session.hex.lowNibble {
+		t.Error("Backspace should forget a half typed byte")
+	}
+	handleHexKey(BackspaceKey)
+	if got := session.rope.String(); got != "AS" {
+		t.Errorf("Backspace should delete the byte before the cursor, got %q", got)
+	}
+
+	if handleHexKey(Key{Rune: 'g'}) || handleHexKey(ctrl('s')) {
+		t.Error("non hex keys should be left to the main loop")
+	}
+}
@@ -0,0 +1,110 @@
+package editor
+
+import (
+	"sort"
+	"strings"
+)
+
+// highlight marks a byte range of the buffer to be drawn with an SGR style
+type highlight struct {
+	start int    // index in the rope, inclusive
+	end   int    // index in the rope, exclusive
+	style string // SGR parameters, e.g. "4" for underline
+}
+
+// bufferHighlights collects the highlights of every enabled feature
+// for the text of the active buffer
+func bufferHighlights(text string) []highlight {
+	var highlights []highlight
+	if session.largeFile {
+		return nil
+	}
+	highlights = append(highlights, conflictHighlights(text)...)
+	if session.fileType() == "gitcommit" {
+		highlights = append(highlights, commitHighlights(text)...)
+	}
+	if spellEnabled {
+		highlights = append(highlights, spellHighlights(text)...)
+	}
+	highlights = append(highlights, pagerHighlights(text)...)
+	highlights = append(highlights, dataErrorHighlights()...)
+	sort.Slice(highlights, func(i, j int) bool {
+		return highlights[i].start < highlights[j].start
+	})
+	// The selection is drawn over everything else but the other cursor
+	highlights = append(highlights, selectionHighlights()...)
+	return append(highlights, collabHighlights()...)
+}
+
+// renderLine returns line, which starts at lineStart in the buffer, with the
+// escape sequences needed to draw the highlights that overlap it.
+// Later highlights win where two of them overlap.
+func renderLine(line string, lineStart int, highlights []highlight) string {
+	lineEnd := lineStart + len(line)
+	styles := make([]string, len(line))
+	found := false
+	for _, h := range highlights {
+		if h.end <= lineStart || h.start >= lineEnd {
+			continue
+		}
+		found = true
+		for i := max(h.start, lineStart); i < min(h.end, lineEnd); i++ {
+			styles[i-lineStart] = h.style
+		}
+	}
+	if !found && !strings.Contains(line, "\t") {
+		return line
+	}
+
+	var buf strings.Builder
+	current := ""
+	col, tab := 0, session.tabWidth()
+	for i := 0; i < len(line); i++ {
+		if styles[i] != current {
+			buf.WriteString("\x1b[m")
+			if styles[i] != "" {
+				buf.WriteString("\x1b[" + styles[i] + "m")
+			}
+			current = styles[i]
+		}
+		if line[i] == '\t' {
+			width := tab - col%tab
+			buf.WriteString(strings.Repeat(" ", width))
+			col += width
+			continue
+		}
+		buf.WriteByte(line[i])
+		col++
+	}
+	if current != "" {
+		buf.WriteString("\x1b[m")
+	}
+	return buf.String()
+}
+
+// tabSize is the number of columns between tab stops of the buffers that
+// don't set their own
+var tabSize = 8
+
+// tabWidth returns the number of columns between tab stops in s
+func (s *Session) tabWidth() int {
+	if s.tabSize > 0 {
+		return s.tabSize
+	}
+	return tabSize
+}
+
+// visualColumn returns the zero-based screen column at which byte col of
+// line is drawn, expanding tabs and counting a UTF-8 character as one column
+func visualColumn(line string, col int) int {
+	visual, tab := 0, session.tabWidth()
+	for i := 0; i < min(col, len(line)); i++ {
+		switch {
+		case line[i] == '\t':
+			visual += tab - visual%tab
+		case line[i]&0xC0 != 0x80:
+			visual++
+		}
+	}
+	return visual + max(col-len(line), 0)
+}
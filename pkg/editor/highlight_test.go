@@ -0,0 +1,35 @@
+package editor
+
+import "testing"
+
+func TestRenderLine_AppliesOverlappingHighlights(t *testing.T) {
+	highlights := []highlight{
+		{start: 0, end: 3, style: "4"},   // ends before the line
+		{start: 12, end: 14, style: "4"}, // "ab" in "xxabyy"
+	}
+	got := renderLine("xxabyy", 10, highlights)
+	want := "xx\x1b[m\x1b[4mab\x1b[myy"
+	if got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	if got := renderLine("plain", 100, highlights); got != "plain" {
+		t.Fatalf("line without highlights changed: %q", got)
+	}
+}
+
+func TestRenderLineExpandsTabs(t *testing.T) {
+	old := tabSize
+	defer func() { tabSize = old }()
+	tabSize = 4
+
+	if got := renderLine("a\tb\t\tc", 0, nil); got != "a   b       c" {
+		t.Fatalf("unexpected expansion %q", got)
+	}
+	if got := visualColumn("a\tb", 2); got != 4 {
+		t.Fatalf("column after the tab should be 4, got %d", got)
+	}
+	if got := visualColumn("é\tx", 3); got != 4 {
+		t.Fatalf("a two byte character is one column, got %d", got)
+	}
+}
@@ -0,0 +1,93 @@
+package editor
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// keyHintsEnabled adds a row below the status bar listing the keys that
+// matter in the current mode, like the shortcut bar of nano
+var keyHintsEnabled bool
+
+// keyHint is a key and what it does, as shown in the hint row
+type keyHint struct {
+	key, label string
+}
+
+// Hints of the modes that don't depend on the buffer
+var (
+	editHints = []keyHint{
+		{"^S", "Save"}, {"^Q", "Quit"}, {"^F", "Find"}, {"^E", "Command"},
+		{"^Z", "Undo"}, {"^R", "Redo"}, {"^N", "Complete"}, {"^K", "Cut to end"},
+		{"Shift-Arrows", "Select"},
+	}
+	selectionHints = []keyHint{
+		{"Shift-Arrows", "Extend"}, {"Esc", "Deselect"}, {":s/a/b/", "Replace"},
+		{":align", "Align"}, {":w >>", "Append to file"}, {":protect", "Protect"},
+	}
+	promptHints = []keyHint{
+		{"Enter", "Accept"}, {"Esc", "Cancel"}, {"Left/Right", "Move"}, {"^W", "Delete word"}, {"^U", "Delete to start"},
+	}
+	hexHints = []keyHint{
+		{"0-9a-f", "Edit byte"}, {"Tab", "Insert/overwrite"}, {"^S", "Save"}, {":hex off", "Text view"},
+	}
+	tableHints = []keyHint{
+		{"Tab", "Next cell"}, {"Shift-Tab", "Previous cell"}, {"Up/Down", "Same column"}, {":table off", "Text view"},
+	}
+	compareHints = []keyHint{
+		{"Alt-O", "Other side"}, {":dnext", "Next change"}, {":dprev", "Previous change"},
+		{":dget", "Get"}, {":dput", "Put"}, {":compare off", "End"},
+	}
+	loadingHints  = []keyHint{{"Esc", "Cancel loading"}}
+	terminalHints = []keyHint{{"Alt-T", "Back to the text"}, {":term off", "Close"}}
+	pagerHints    = []keyHint{
+		{"q", "Quit"}, {"Space", "Page down"}, {"b", "Page up"}, {"/", "Search"},
+		{"n", "Next"}, {"N", "Previous"}, {"g", "Top"}, {"G", "Bottom"},
+	}
+)
+
+// statusRows is how many rows the status area takes at the bottom of the
+// screen: the status bar, and the key hints when they are on
+func statusRows() int {
+	if keyHintsEnabled {
+		return 2
+	}
+	return 1
+}
+
+// currentHints returns the key hints of what the active buffer shows
+func currentHints() []keyHint {
+	_, _, selected := selection()
+	switch {
+	case termPane != nil && termPane.focused:
+		return terminalHints
+	case session.loading != nil:
+		return loadingHints
+	case pagerMode:
+		return pagerHints
+	case session.hex != nil:
+		return hexHints
+	case session.table != nil:
+		return tableHints
+	case compare.shows(session):
+		return compareHints
+	case selected:
+		return selectionHints
+	}
+	return editHints
+}
+
+// drawHints writes the hint row: each key in the colors of the status bar
+// followed by its label, as many as fit in cols
+func drawHints(buf *strings.Builder, hints []keyHint, cols int) {
+	width := 0
+	for _, h := range hints {
+		w := utf8.RuneCountInString(h.key) + 1 + utf8.RuneCountInString(h.label) + 2
+		if width+w-2 > cols {
+			break
+		}
+		buf.WriteString("\x1b[" + currentTheme.statusBar + "m" + h.key + "\x1b[m " + h.label + "  ")
+		width += w
+	}
+	buf.WriteString("\x1b[K")
+}
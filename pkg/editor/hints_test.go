@@ -0,0 +1,35 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestKeyHints(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	if statusRows() != 1 {
+		t.Fatal("the hint row should be off by default")
+	}
+	if err := Set("keyhints", "on"); err != nil || statusRows() != 2 {
+		t.Fatalf("Set keyhints: %v, %d status rows", err, statusRows())
+	}
+
+	session.rope = buffer.NewRope("some text")
+	if hints := currentHints(); hints[0].key != "^S" {
+		t.Errorf("expected the editing hints, got %v", hints)
+	}
+	selectRange(0, 4)
+	if hints := currentHints(); hints[0].label != "Extend" {
+		t.Errorf("expected the selection hints, got %v", hints)
+	}
+
+	var buf strings.Builder
+	drawHints(&buf, promptHints, 30)
+	got := buf.String()
+	if !strings.Contains(got, "Enter\x1b[m Accept") || !strings.Contains(got, "Cancel") || strings.Contains(got, "Backspace") {
+		t.Errorf("expected the hints that fit in 30 columns, got %q", got)
+	}
+}
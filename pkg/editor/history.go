@@ -0,0 +1,133 @@
+package editor
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maxHistory bounds the number of entries kept by each prompt history
+const maxHistory = 200
+
+// promptHistory is what was entered at a prompt, oldest first. It is kept
+// in a file of the state directory across sessions.
+type promptHistory struct {
+	file    string // name of the file in the state directory
+	entries []string
+	loaded  bool
+	added   []string // entered in this session, merged into the file at exit
+}
+
+// Histories of the search and command prompts
+var (
+	searchHistory  = &promptHistory{file: "search_history"}
+	commandHistory = &promptHistory{file: "command_history"}
+)
+
+// histories are saved when the editor quits
+var histories = []*promptHistory{searchHistory, commandHistory}
+
+// path returns where h is kept
+func (h *promptHistory) path() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, h.file), nil
+}
+
+// read returns the entries of the history file, one per line, oldest first
+func (h *promptHistory) read() ([]string, error) {
+	path, err := h.path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// list returns the entries of h, oldest first, reading the file the first
+// time
+func (h *promptHistory) list() []string {
+	if !h.loaded {
+		h.loaded = true
+		saved, _ := h.read()
+		h.entries = appendHistory(saved, h.entries...)
+	}
+	return h.entries
+}
+
+// add records entry as the most recent one
+func (h *promptHistory) add(entry string) {
+	// Entries are stored one per line
+	if entry == "" || strings.Contains(entry, "\n") {
+		return
+	}
+	h.entries = appendHistory(h.list(), entry)
+	h.added = append(h.added, entry)
+}
+
+// appendHistory appends entries to history, moving those already in it to
+// the end and dropping the oldest beyond maxHistory
+func appendHistory(history []string, entries ...string) []string {
+	for _, entry := range entries {
+		history = slices.DeleteFunc(history, func(e string) bool { return e == entry })
+		history = append(history, entry)
+	}
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	return history
+}
+
+// save merges the entries added in this session into the history file,
+// which other editors may have changed meanwhile
+func (h *promptHistory) save() error {
+	if len(h.added) == 0 {
+		return nil
+	}
+	saved, err := h.read()
+	if err != nil {
+		return err
+	}
+	entries := appendHistory(saved, h.added...)
+
+	path, err := h.path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Write a temporary file first so a crash never leaves half a file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(entries, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	h.added = nil
+	return os.Rename(tmp, path)
+}
+
+// saveHistories saves every prompt history
+func saveHistories() {
+	for _, h := range histories {
+		h.save()
+	}
+}
@@ -0,0 +1,92 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// useHistory gives h an empty state directory for the test
+func useHistory(t *testing.T, h *promptHistory) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	old := *h
+	*h = promptHistory{file: old.file}
+	t.Cleanup(func() { *h = old })
+}
+
+func TestSearchHistory(t *testing.T) {
+	resetSessionForTest()
+	useHistory(t, searchHistory)
+	session.rope = buffer.NewRope("alpha beta gamma beta")
+
+	handleSearch(0, makeCallback([]byte("beta\rq")))
+	handleSearch(0, makeCallback([]byte("gamma\rq")))
+	if got := searchHistory.list(); !slices.Equal(got, []string{"beta", "gamma"}) {
+		t.Fatalf("history %q", got)
+	}
+
+	// Up recalls older queries, Down comes back to what was typed
+	input, _ := readPrompt("?", searchHistory, makeCallback([]byte("x\x1b[A\x1b[A\x1b[A\x1b[B\r")))
+	if input != "gamma" {
+		t.Errorf("recalled %q", input)
+	}
+	input, _ = readPrompt("?", searchHistory, makeCallback([]byte("x\x1b[A\x1b[B\r")))
+	if input != "x" {
+		t.Errorf("expected the typed input back, got %q", input)
+	}
+
+	// An empty query repeats the last search
+	session.cursorIdx = 0
+	handleSearch(0, makeCallback([]byte("\rq")))
+	if session.cursorIdx != 11 {
+		t.Errorf("expected the cursor on gamma, got %d", session.cursorIdx)
+	}
+
+	// The history is kept across sessions
+	saveHistories()
+	path, _ := searchHistory.path()
+	if data, err := os.ReadFile(path); err != nil || string(data) != "beta\ngamma\n" {
+		t.Fatalf("history file %q: %v", data, err)
+	}
+	*searchHistory = promptHistory{file: searchHistory.file}
+	if got := searchHistory.list(); !slices.Equal(got, []string{"beta", "gamma"}) {
+		t.Errorf("history read back %q", got)
+	}
+	if filepath.Base(path) != "search_history" {
+		t.Errorf("unexpected history file %s", path)
+	}
+}
+
+func TestCommandHistorySearch(t *testing.T) {
+	resetSessionForTest()
+	useHistory(t, commandHistory)
+	session.rope = buffer.NewRope("")
+	for _, line := range []string{"set tabsize 4", "align =", "set theme dark", "inc"} {
+		commandHistory.add(line)
+	}
+
+	// Ctrl-R finds the last command containing "set", again the one before
+	input, ok := readPrompt(":", commandHistory, makeCallback([]byte("\x12set\x12\r")))
+	if !ok || input != "set tabsize 4" {
+		t.Errorf("history search found %q", input)
+	}
+	// Esc leaves the search with the entry found, to edit it
+	input, _ = readPrompt(":", commandHistory, makeCallback([]byte("\x12ali\x1b\x00\x7f\x7f\r")))
+	if input != "align" {
+		t.Errorf("edited entry %q", input)
+	}
+	// A query without match keeps the last entry found
+	input, _ = readPrompt(":", commandHistory, makeCallback([]byte("\x12themez\r")))
+	if input != "set theme dark" {
+		t.Errorf("failing search gave %q", input)
+	}
+
+	saveSettings(t)
+	handleCommand(0, makeCallback([]byte("\x1b[A\x1b[A\r")))
+	if got := commandHistory.list(); got[len(got)-1] != "set theme dark" || currentTheme != themes["dark"] {
+		t.Errorf("expected the recalled command run and moved last, history %q", got)
+	}
+}
@@ -0,0 +1,52 @@
+package editor
+
+// event is a point in the life of a buffer that hooks can attach to
+type event int
+
+const (
+	eventBufOpen      event = iota // a file was loaded into a new buffer
+	eventBufWritePre               // the buffer is about to be written; an error aborts the save
+	eventBufWritePost              // the buffer was written
+	eventTextChanged               // the buffer content changed after a key was handled
+	eventCursorMoved               // the cursor moved after a key was handled
+)
+
+// hookFunc runs when an event fires for buffer s
+type hookFunc func(s *Session) error
+
+// hooks holds the registered hooks of each event, in registration order
+var hooks = map[event][]hookFunc{}
+
+// addHook registers fn to run every time ev fires
+func addHook(ev event, fn hookFunc) {
+	hooks[ev] = append(hooks[ev], fn)
+}
+
+// fireHooks runs the hooks of ev for s, stopping at the first error
+func fireHooks(ev event, s *Session) error {
+	for _, fn := range hooks[ev] {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fireChangeEvents fires TextChanged and CursorMoved for the active buffer
+// when its content or cursor differ from the last time it was called.
+// Ropes are never modified in place, so comparing pointers is enough.
+func fireChangeEvents() {
+	s := session
+	if s.rope != s.seenRope {
+		s.seenRope = s.rope
+		if err := fireHooks(eventTextChanged, s); err != nil {
+			s.statusMessage = err.Error()
+		}
+	}
+	if s.cursorIdx != s.seenCursorIdx {
+		s.seenCursorIdx = s.cursorIdx
+		if err := fireHooks(eventCursorMoved, s); err != nil {
+			s.statusMessage = err.Error()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// saveHooks takes the registered hooks out of the way of a test
+func saveHooks() map[event][]hookFunc {
+	saved := hooks
+	hooks = map[event][]hookFunc{}
+	return saved
+}
+
+func restoreHooks(saved map[event][]hookFunc) {
+	hooks = saved
+}
+
+func TestHooks_TextChangedAndCursorMoved(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("[No Name]", "")
+	buffers = []*Session{session}
+	defer restoreHooks(saveHooks())
+
+	var changed, moved int
+	addHook(eventTextChanged, func(s *Session) error { changed++; return nil })
+	addHook(eventCursorMoved, func(s *Session) error { moved++; return nil })
+
+	// type two characters, move left (cursor only), then quit
+	ProcessKeypress(0, makeCallback([]byte{'a', 'b', Esc, '[', 'D', CtrlQ, CtrlQ}))
+	if changed != 2 {
+		t.Fatalf("expected 2 TextChanged events got %d", changed)
+	}
+	if moved != 3 {
+		t.Fatalf("expected 3 CursorMoved events got %d", moved)
+	}
+}
+
+func TestHooks_WritePreCanAbortOrEditSave(t *testing.T) {
+	resetSessionForTest()
+	defer restoreHooks(saveHooks())
+	path := filepath.Join(t.TempDir(), "out.txt")
+	session = newSession(path, "draft")
+	buffers = []*Session{session}
+
+	addHook(eventBufWritePre, func(s *Session) error { return errors.New("lint failed") })
+	handleSave(0, nil)
+	if _, err := os.Stat(path); err == nil {
+		t.Fatalf("file should not be written when a pre-write hook fails")
+	}
+	if session.statusMessage != "Save aborted: lint failed" {
+		t.Fatalf("unexpected status %q", session.statusMessage)
+	}
+
+	hooks = map[event][]hookFunc{}
+	var written bool
+	addHook(eventBufWritePre, func(s *Session) error {
+		replaceText(0, s.rope.Length(), "final")
+		return nil
+	})
+	addHook(eventBufWritePost, func(s *Session) error { written = true; return nil })
+	handleSave(0, nil)
+	if data, _ := os.ReadFile(path); string(data) != "final" || !written {
+		t.Fatalf("expected the hook's edit on disk, got %q (post hook ran: %v)", data, written)
+	}
+}
+
+func TestHooks_BufOpen(t *testing.T) {
+	resetSessionForTest()
+	defer restoreHooks(saveHooks())
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, []byte("x"), 0644)
+
+	var opened []string
+	addHook(eventBufOpen, func(s *Session) error { opened = append(opened, s.filename); return nil })
+	openBuffer(path)
+	openBuffer(path) // already open, no event
+	if len(opened) != 1 || opened[0] != path {
+		t.Fatalf("unexpected BufOpen events %v", opened)
+	}
+}
@@ -0,0 +1,124 @@
+package editor
+
+import (
+	"math/bits"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// idleDelay is how long no key must come before the idle tasks run, so
+// that their work never delays the handling of a key being typed
+const idleDelay = 500 * time.Millisecond
+
+// idleTask is deferred work done while the user isn't typing
+type idleTask struct {
+	name string
+	run  func(now time.Time)
+	// repeat runs the task again each time reading a key times out once
+	// the round is over, for work that waits for its own interval
+	repeat bool
+}
+
+// idleTasks run in order, one each time reading a key times out, once
+// the keyboard has been quiet for idleDelay. A key stops the round; the
+// next quiet period starts it again from the first task. Until then, only
+// the repeated tasks run.
+var idleTasks = []idleTask{
+	{"rebalance", func(time.Time) { rebalanceRopes() }, false},
+	{"prune caches", func(time.Time) { pruneCaches() }, false},
+	{"blame", func(time.Time) { refreshBlame() }, false},
+	{"backups", backupBuffers, true},
+	{"histories", func(time.Time) { saveHistories() }, false},
+}
+
+// Where the main loop is in the round of idle tasks
+var (
+	lastActivity time.Time // when the last key came
+	nextIdleTask int       // index in idleTasks, len(idleTasks) when the round is over
+)
+
+// noteActivity postpones the idle tasks, and restarts their round
+func noteActivity(now time.Time) {
+	lastActivity = now
+	nextIdleTask = 0
+}
+
+// runIdleTask runs the next idle task, or the repeated ones once the round
+// is over, when the keyboard has been quiet long enough, and reports
+// whether it left a message to show
+func runIdleTask(now time.Time) bool {
+	if now.Sub(lastActivity) < idleDelay {
+		return false
+	}
+	message := session.statusMessage
+	if nextIdleTask < len(idleTasks) {
+		task := idleTasks[nextIdleTask]
+		nextIdleTask++
+		task.run(now)
+	} else {
+		for _, task := range idleTasks {
+			if task.repeat {
+				task.run(now)
+			}
+		}
+	}
+	return session.statusMessage != message
+}
+
+// ropeDepthSlack is how many levels deeper than a balanced tree edits may
+// make a rope before it is rebuilt
+const ropeDepthSlack = 16
+
+// rebalanceRopes rebuilds the ropes that edits made much deeper than they
+// need to be, which slows down every lookup in them
+func rebalanceRopes() {
+	for _, s := range buffers {
+		if s.rope == nil || s.loading != nil {
+			continue
+		}
+		balanced := bits.Len(uint(s.rope.Length()/8)) + 1
+		if s.rope.Depth() > balanced+ropeDepthSlack {
+			replaceRope(s, s.rope.Rebalance())
+		}
+	}
+}
+
+// replaceRope makes r, holding the same text, the rope of buffer s. What
+// refers to the old rope as the text it was computed for, like the saved
+// text or the line index, refers to r instead.
+func replaceRope(s *Session, r *buffer.Rope) {
+	old := s.rope
+	s.rope = r
+	for _, p := range []**buffer.Rope{&s.seenRope, &s.savedRope, &s.backupRope} {
+		if *p == old {
+			*p = r
+		}
+	}
+	if s.lineIndex != nil && s.lineIndex.rope == old {
+		s.lineIndex.rope = r
+	}
+	if s.blame != nil && s.blame.rope == old {
+		s.blame.rope = r
+	}
+	if dataErrorMark.rope == old {
+		dataErrorMark.rope = r
+	}
+	if collaboration != nil && collaboration.buffer == s && collaboration.synced == old {
+		collaboration.synced = r
+	}
+}
+
+// pruneCaches drops what was computed for text the buffers no longer
+// hold: the blame and line index of edited buffers. The status bar keeps
+// showing the blame of the active buffer until refreshBlame replaces it.
+func pruneCaches() {
+	for _, s := range buffers {
+		if s.blame != nil && s.blame.rope != s.rope && !(blameStatusEnabled && s == session) {
+			s.blame = nil
+		}
+		if s.lineIndex != nil && s.lineIndex.rope != s.rope {
+			s.lineIndex = nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package editor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestIdleTasks(t *testing.T) {
+	resetSessionForTest()
+	oldTasks := idleTasks
+	t.Cleanup(func() { idleTasks = oldTasks })
+	var ran []string
+	idleTasks = []idleTask{
+		{"first", func(time.Time) { ran = append(ran, "first") }, false},
+		{"second", func(time.Time) { ran = append(ran, "second") }, false},
+	}
+
+	start := time.Now()
+	noteActivity(start)
+	if runIdleTask(start.Add(idleDelay/2)) || len(ran) != 0 {
+		t.Fatalf("tasks ran while typing: %v", ran)
+	}
+	// One task each time reading a key times out, then none until a key
+	for i := 1; i <= 3; i++ {
+		runIdleTask(start.Add(idleDelay + time.Duration(i)*readTimeout))
+	}
+	if len(ran) != 2 || ran[0] != "first" || ran[1] != "second" {
+		t.Fatalf("ran %v", ran)
+	}
+	// A key starts the round again
+	noteActivity(start.Add(time.Second))
+	runIdleTask(start.Add(time.Second + idleDelay))
+	if len(ran) != 3 || ran[2] != "first" {
+		t.Errorf("after a key ran %v", ran)
+	}
+}
+
+func TestIdleBackupAfterInterval(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	t.Chdir(t.TempDir())
+	runCommand(0, "set backupdir "+filepath.Join(t.TempDir(), "backups"), nil)
+	session = newSession("notes.txt", "v0")
+	buffers = []*Session{session}
+
+	// An edit just after a backup, then no key for two minutes
+	start := time.Now()
+	lastBackup = start
+	session.rope = buffer.NewRope("v1")
+	noteActivity(start)
+	for at := start; at.Before(start.Add(2 * time.Minute)); at = at.Add(readTimeout) {
+		runIdleTask(at)
+	}
+	backups, err := listBackups("notes.txt")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected the edit backed up once the interval passed, got %v, %v", backups, err)
+	}
+}
+
+func TestRebalanceRopes(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("")
+	buffers = []*Session{session}
+	for i := 0; i < 200; i++ {
+		replaceText(session.rope.Length(), session.rope.Length(), "line\n")
+	}
+	session.savedRope = session.rope
+	lineStarts()
+	deep := session.rope
+
+	rebalanceRopes()
+	if session.rope == deep || session.rope.Depth() >= deep.Depth() {
+		t.Fatalf("depth %d rebalanced to %d", deep.Depth(), session.rope.Depth())
+	}
+	if session.rope.String() != deep.String() {
+		t.Fatal("rebalancing changed the text")
+	}
+	if session.savedRope != session.rope || session.lineIndex.rope != session.rope {
+		t.Error("the saved text and line index should follow the new rope")
+	}
+
+	// A balanced rope is left alone
+	balanced := session.rope
+	rebalanceRopes()
+	if session.rope != balanced {
+		t.Error("a balanced rope should not be rebuilt")
+	}
+}
@@ -0,0 +1,142 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// numberToken is a number literal of a line, as byte offsets
+type numberToken struct {
+	start, end int
+	hex        bool
+}
+
+// isHexDigit reports whether b is a hexadecimal digit
+func isHexDigit(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'f' || b >= 'A' && b <= 'F'
+}
+
+// numberAt returns the number of line under byte col or the first one after
+// it: a hex literal like 0x1F, or a decimal one with an optional minus sign
+func numberAt(line string, col int) (numberToken, bool) {
+	for i := 0; i < len(line); {
+		var tok numberToken
+		switch {
+		case strings.HasPrefix(line[i:], "0x") || strings.HasPrefix(line[i:], "0X"):
+			end := i + 2
+			for end < len(line) && isHexDigit(line[end]) {
+				end++
+			}
+			if end == i+2 {
+				i++
+				continue
+			}
+			tok = numberToken{start: i, end: end, hex: true}
+		case line[i] >= '0' && line[i] <= '9':
+			start, end := i, i
+			for end < len(line) && line[end] >= '0' && line[end] <= '9' {
+				end++
+			}
+			// A minus sign counts unless it follows a word, as in "x-1"
+			if start > 0 && line[start-1] == '-' && (start < 2 || !isIdentifierByte(line[start-2])) {
+				start--
+			}
+			tok = numberToken{start: start, end: end}
+		default:
+			i++
+			continue
+		}
+		if tok.end > col {
+			return tok, true
+		}
+		i = tok.end
+	}
+	return numberToken{}, false
+}
+
+// addToNumber returns the literal text plus delta, keeping the width of
+// zero-padded numbers and the case of hex digits
+func addToNumber(text string, hex bool, delta int64) (string, error) {
+	if hex {
+		digits := text[2:]
+		n, err := strconv.ParseUint(digits, 16, 64)
+		if err != nil {
+			return "", err
+		}
+		// Hex numbers are unsigned and wrap around, like in vim
+		result := strconv.FormatUint(n+uint64(delta), 16)
+		if strings.ToUpper(digits) == digits && strings.ToLower(digits) != digits {
+			result = strings.ToUpper(result)
+		}
+		return text[:2] + zeroPad(result, len(digits)), nil
+	}
+
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	result := n + delta
+	digits := strings.TrimPrefix(text, "-")
+	if len(digits) > 1 && digits[0] == '0' {
+		// Keep the width of "007"
+		s := zeroPad(strconv.FormatInt(max(result, -result), 10), len(digits))
+		if result < 0 {
+			s = "-" + s
+		}
+		return s, nil
+	}
+	return strconv.FormatInt(result, 10), nil
+}
+
+// zeroPad pads s with zeros on the left to width characters
+func zeroPad(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return strings.Repeat("0", width-len(s)) + s
+}
+
+// incrementNumber adds delta times the count in args to the number under
+// or after the cursor on its line
+func incrementNumber(args string, delta int64) {
+	if !editable() {
+		return
+	}
+	if args != "" {
+		count, err := strconv.ParseInt(args, 10, 64)
+		if err != nil || count < 1 {
+			session.statusMessage = "The count must be a positive number"
+			return
+		}
+		delta *= count
+	}
+
+	line, lineStart := cursorLine()
+	tok, ok := numberAt(line, session.cursorIdx-lineStart)
+	if !ok {
+		session.statusMessage = "No number under or after the cursor"
+		return
+	}
+	result, err := addToNumber(line[tok.start:tok.end], tok.hex, delta)
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Cannot change %s: %v", line[tok.start:tok.end], err)
+		return
+	}
+	replaceText(lineStart+tok.start, lineStart+tok.end, result)
+	// The cursor ends on the last digit
+	session.cursorIdx = lineStart + tok.start + len(result) - 1
+	updateCursorPosition()
+}
+
+// handleIncrement adds the count (1 by default) to the number under or
+// after the cursor (:inc [count], Alt-A)
+func handleIncrement(fd int, args string, callback func() byte) {
+	incrementNumber(args, 1)
+}
+
+// handleDecrement subtracts the count (1 by default) from the number under
+// or after the cursor (:dec [count], Alt-X)
+func handleDecrement(fd int, args string, callback func() byte) {
+	incrementNumber(args, -1)
+}
@@ -0,0 +1,77 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestAddToNumber(t *testing.T) {
+	for _, c := range []struct {
+		text  string
+		hex   bool
+		delta int64
+		want  string
+	}{
+		{"9", false, 1, "10"},
+		{"-1", false, 2, "1"},
+		{"0", false, -1, "-1"},
+		{"007", false, 1, "008"},
+		{"-010", false, 1, "-009"},
+		{"0xff", true, 1, "0x100"},
+		{"0x0F", true, 1, "0x10"},
+		{"0x00ff", true, 1, "0x0100"},
+		{"0XAB", true, -1, "0XAA"},
+		{"0x0", true, -1, "0xffffffffffffffff"},
+	} {
+		got, err := addToNumber(c.text, c.hex, c.delta)
+		if err != nil || got != c.want {
+			t.Errorf("addToNumber(%q, %d) = %q, %v, want %q", c.text, c.delta, got, err, c.want)
+		}
+	}
+}
+
+func TestNumberAt(t *testing.T) {
+	line := "x-1 = -2 + 0x1f"
+	for col, want := range map[int]string{0: "1", 2: "1", 3: "-2", 6: "-2", 8: "0x1f", 14: "0x1f"} {
+		tok, ok := numberAt(line, col)
+		if !ok || line[tok.start:tok.end] != want {
+			t.Errorf("numberAt(%d) = %q, want %q", col, line[tok.start:tok.end], want)
+		}
+	}
+	if _, ok := numberAt("no numbers 12 here", 14); ok {
+		t.Error("no number after column 14")
+	}
+}
+
+func TestIncrementCommands(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("first\nwidth: 9px\nlast")
+	session.cursorIdx = 6
+	updateCursorPosition()
+
+	runCommand(0, "inc", nil)
+	if got := session.rope.String(); got != "first\nwidth: 10px\nlast" {
+		t.Fatalf("inc gave %q", got)
+	}
+	if session.cursorIdx != 14 {
+		t.Errorf("the cursor should be on the last digit, got %d", session.cursorIdx)
+	}
+
+	runCommand(0, "dec 15", nil)
+	if got := session.rope.String(); got != "first\nwidth: -5px\nlast" {
+		t.Fatalf("dec 15 gave %q", got)
+	}
+
+	ProcessKeypress(0, makeCallback([]byte{Esc, 'a', Esc, 'a', CtrlQ, CtrlQ}))
+	if got := session.rope.String(); got != "first\nwidth: -3px\nlast" {
+		t.Fatalf("Alt-A twice gave %q", got)
+	}
+
+	session.cursorIdx = 0
+	updateCursorPosition()
+	runCommand(0, "inc", nil)
+	if session.statusMessage != "No number under or after the cursor" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+}
@@ -0,0 +1,79 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// expandTab makes Tab insert spaces up to the next tab stop in the buffers
+// opened from now on (:set expandtab on)
+var expandTab = false
+
+// fileTypeProfiles holds the local options given to the buffers of each
+// filetype when they are opened. The config file adds to these with
+// "[filetype]" sections.
+var fileTypeProfiles = map[string]map[string]string{
+	"go":   {"expandtab": "off"},
+	"py":   {"tabsize": "4", "expandtab": "on", "repl": "python3 -q"},
+	"js":   {"repl": "node"},
+	"sql":  {"repl": "psql"},
+	"yaml": {"tabsize": "2", "expandtab": "on"},
+	"yml":  {"tabsize": "2", "expandtab": "on"},
+}
+
+func init() {
+	addHook(eventBufOpen, func(s *Session) error {
+		applyFileTypeProfile(s)
+		return nil
+	})
+	// Added here as it refers to settings through applyFileTypeProfile
+	settings["filetype"] = setting{
+		getLocal: func(s *Session) string { return s.fileType() },
+		setLocal: func(s *Session, value string) error {
+			s.filetype = strings.ToLower(strings.TrimPrefix(value, "."))
+			applyFileTypeProfile(s)
+			return nil
+		},
+	}
+}
+
+// applyFileTypeProfile sets the local options of s listed for its filetype
+func applyFileTypeProfile(s *Session) {
+	for name, value := range fileTypeProfiles[s.fileType()] {
+		// Values were checked when the profile was loaded
+		settings[name].setLocal(s, value)
+	}
+}
+
+// setProfileOption records the value of a local option for the buffers of
+// fileType, checking that it is valid
+func setProfileOption(fileType, name, value string) error {
+	s, ok := settings[name]
+	if !ok {
+		return fmt.Errorf("unknown option %q", name)
+	}
+	if s.setLocal == nil || name == "filetype" {
+		return fmt.Errorf("%s cannot be set per filetype", name)
+	}
+	value = strings.TrimSpace(value)
+	if err := s.setLocal(&Session{}, value); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if fileTypeProfiles[fileType] == nil {
+		fileTypeProfiles[fileType] = map[string]string{}
+	}
+	fileTypeProfiles[fileType][name] = value
+	return nil
+}
+
+// handleTab inserts a tab, or the spaces up to the next tab stop when the
+// buffer expands tabs
+func handleTab() {
+	if !session.expandTab {
+		handleInsert("\t")
+		return
+	}
+	before, _ := session.rope.Substring(getLineStartIndex(session.cursorRow), session.cursorIdx)
+	col := visualColumn(before, len(before))
+	handleInsert(strings.Repeat(" ", session.tabWidth()-col%session.tabWidth()))
+}
@@ -0,0 +1,85 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestHandleTab(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("")
+
+	ProcessKeypress(0, makeCallback([]byte{Tab, 'x', CtrlQ, CtrlQ}))
+	if got := session.rope.String(); got != "\tx" {
+		t.Fatalf("expected a tab, got %q", got)
+	}
+
+	// With expandtab, spaces up to the next tab stop
+	session.expandTab = true
+	session.tabSize = 4
+	ProcessKeypress(0, makeCallback([]byte{Tab, 'y', Tab, CtrlQ, CtrlQ}))
+	if got := session.rope.String(); got != "\tx   y   " {
+		t.Fatalf("expected spaces to the tab stops, got %q", got)
+	}
+}
+
+func TestFileTypeProfiles(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	AddBuffer("script.py", "", 0, 0)
+	AddBuffer("main.go", "", 0, 0)
+	AddBuffer("notes.txt", "", 0, 0)
+
+	py, goFile, txt := buffers[1], buffers[2], buffers[3]
+	if py.tabWidth() != 4 || !py.expandTab {
+		t.Errorf("python buffers indent with 4 spaces: tab %d, expandtab %v", py.tabWidth(), py.expandTab)
+	}
+	if goFile.tabWidth() != 8 || goFile.expandTab {
+		t.Errorf("go buffers indent with tabs: tab %d, expandtab %v", goFile.tabWidth(), goFile.expandTab)
+	}
+	if txt.tabSize != 0 || txt.expandTab {
+		t.Error("buffers without a profile follow the global options")
+	}
+
+	// Setting the filetype applies its profile
+	switchToBuffer(txt)
+	runCommand(0, "setlocal filetype yaml", nil)
+	if txt.tabWidth() != 2 || !txt.expandTab {
+		t.Errorf("yaml profile not applied: tab %d, expandtab %v", txt.tabWidth(), txt.expandTab)
+	}
+}
+
+func TestLoadConfigProfiles(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	path := filepath.Join(t.TempDir(), "config")
+	config := "tabsize = 8\n\n[py]\ntabsize = 2\n\n[.MD]\nexpandtab = on\n"
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if tabSize != 8 {
+		t.Errorf("options before the first section are global, tabsize %d", tabSize)
+	}
+	if got := fileTypeProfiles["py"]; got["tabsize"] != "2" || got["expandtab"] != "on" {
+		t.Errorf("py profile = %v", got)
+	}
+	if got := fileTypeProfiles["md"]; got["expandtab"] != "on" {
+		t.Errorf("md profile = %v", got)
+	}
+
+	for _, bad := range []string{"[go]\ntheme = dark\n", "[go]\ntabsize = 99\n", "[go]\nfiletype = c\n"} {
+		if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), ":2:") {
+			t.Errorf("%q: expected an error on line 2, got %v", bad, err)
+		}
+	}
+}
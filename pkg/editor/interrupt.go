@@ -0,0 +1,51 @@
+package editor
+
+import (
+	"context"
+	"errors"
+)
+
+// errInterrupted is the error of a long operation stopped with Esc or Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// interruptible runs work on its own goroutine while the main loop reads
+// keys: Esc or Ctrl-C cancels the context of work and returns errInterrupted
+// once work has stopped, other keys are dropped. What runs is shown as label
+// on the status bar once it takes longer than a key read. work must leave
+// the buffers alone, its result being applied by the caller, so that an
+// interrupted operation changes nothing.
+func interruptible[T any](label string, callback func() byte, work func(ctx context.Context) (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan result, 1)
+	go func() {
+		value, err := work(ctx)
+		done <- result{value, err}
+	}()
+
+	shown := false
+	for callback != nil {
+		select {
+		case r := <-done:
+			return r.value, r.err
+		default:
+		}
+		key := editorReadKeypress(callback)
+		if key == EscKey || key == ctrl('c') {
+			cancel()
+			<-done
+			var zero T
+			return zero, errInterrupted
+		}
+		if !shown {
+			drawStatusPrompt(label + "... (Esc or Ctrl-C to interrupt)")
+			shown = true
+		}
+	}
+	r := <-done
+	return r.value, r.err
+}
@@ -0,0 +1,62 @@
+package editor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestInterruptShellCommand(t *testing.T) {
+	resetSessionForTest()
+	t.Setenv("SHELL", "/bin/sh")
+	origin := session
+	session.rope = buffer.NewRope("text")
+
+	// The whole pipeline is killed, not only the shell
+	start := time.Now()
+	runCommand(0, "!sleep 10 | cat", makeCallback([]byte{'x', Esc, 0}))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Esc took %v to interrupt the command", elapsed)
+	}
+	if session != origin || !strings.Contains(session.statusMessage, "interrupted") {
+		t.Errorf("expected no output buffer and the interruption reported, status %q", session.statusMessage)
+	}
+
+	runCommand(0, "r !sleep 10; echo late", makeCallback([]byte{CtrlC}))
+	if got := session.rope.String(); got != "text" {
+		t.Errorf("an interrupted :r inserted %q", got)
+	}
+}
+
+func TestInterruptFormat(t *testing.T) {
+	resetSessionForTest()
+	old := formatters[".txt"]
+	formatters[".txt"] = [][]string{{"sh", "-c", "sleep 10; echo formatted"}}
+	t.Cleanup(func() { formatters[".txt"] = old })
+	session.filename = "notes.txt"
+	session.rope = buffer.NewRope("as typed\n")
+
+	runCommand(0, "format", makeCallback([]byte{Esc, 0}))
+	if got := session.rope.String(); got != "as typed\n" || len(session.undoStack) != 0 {
+		t.Errorf("an interrupted format changed the buffer to %q", got)
+	}
+	if !strings.Contains(session.statusMessage, "interrupted") {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
+
+func TestReplaceAllContext(t *testing.T) {
+	got, n, err := replaceAllContext(context.Background(), "a-b-c", "-", "+")
+	if got != "a+b+c" || n != 2 || err != nil {
+		t.Errorf("got %q, %d, %v", got, n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := replaceAllContext(ctx, strings.Repeat("ab", 10000), "a", "x"); err == nil {
+		t.Error("a canceled replacement should fail")
+	}
+}
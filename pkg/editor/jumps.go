@@ -0,0 +1,229 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jumpLocation remembers where the cursor was before a jump
+type jumpLocation struct {
+	buffer    *Session
+	filename  string // used when the buffer is no longer open
+	cursorIdx int
+}
+
+// jumpList holds the origins of jumps, most recent last
+var jumpList []jumpLocation
+
+// pushJump records the current cursor position in the jump list
+func pushJump() {
+	jumpList = append(jumpList, jumpLocation{
+		buffer:    session,
+		filename:  session.filename,
+		cursorIdx: session.cursorIdx,
+	})
+}
+
+// jumpTo opens filename if needed and moves the cursor to idx
+func jumpTo(filename string, idx int) error {
+	if err := openBuffer(displayPath(filename)); err != nil {
+		return err
+	}
+	session.cursorIdx = min(max(idx, 0), session.rope.Length())
+	updateCursorPosition()
+	return nil
+}
+
+// GoToLine moves the cursor of the active buffer to a 1-indexed line and
+// column, clamped to the buffer. A line of 0 or less means the last line.
+func GoToLine(line, col int) {
+	session.cursorIdx = lineColumnIndex(session.rope.String(), line, col)
+	updateCursorPosition()
+}
+
+// lineColumnIndex returns the index of a 1-indexed line and column of text,
+// clamped to the text. A line of 0 or less means the last line.
+func lineColumnIndex(text string, line, col int) int {
+	lines := strings.Split(text, "\n")
+	if line <= 0 {
+		line = len(lines)
+	}
+	row := min(line, len(lines))
+	idx := 0
+	for _, l := range lines[:row-1] {
+		idx += len(l) + 1
+	}
+	return idx + min(max(col, 1), len(lines[row-1])+1) - 1
+}
+
+// fileLinePattern matches the "file:line" and "file:line:col" locations that
+// compilers and grep print, with an optional trailing colon
+var fileLinePattern = regexp.MustCompile(`^(.+?):(\d+)(?::(\d+))?:?$`)
+
+// ParseFileArgs reads the file to open and the line and column to start at
+// from arguments like "main.go:42", "main.go:42:7" or "+42 main.go".
+// A line of 0 means none was given; "+" alone asks for the last line.
+// A file whose name merely looks like a location is opened as named.
+func ParseFileArgs(args []string) (filename string, line, col int, err error) {
+	gotLine := false
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "+") && filename == "" && !gotLine {
+			gotLine = true
+			if arg == "+" {
+				line = -1
+				continue
+			}
+			line, err = strconv.Atoi(arg[1:])
+			if err != nil || line < 1 {
+				return "", 0, 0, fmt.Errorf("invalid line number %q", arg)
+			}
+			continue
+		}
+		if filename != "" {
+			return "", 0, 0, fmt.Errorf("unexpected argument %q", arg)
+		}
+		filename = arg
+	}
+	if filename == "" {
+		return "", 0, 0, fmt.Errorf("no file name given")
+	}
+
+	if _, statErr := os.Stat(filename); statErr == nil || gotLine || IsURL(filename) {
+		return filename, line, col, nil
+	}
+	if m := fileLinePattern.FindStringSubmatch(filename); m != nil {
+		filename = m[1]
+		line, _ = strconv.Atoi(m[2])
+		if m[3] != "" {
+			col, _ = strconv.Atoi(m[3])
+		}
+	}
+	return filename, line, col, nil
+}
+
+// handleEdit opens a file, optionally at a line (:e file[:line[:col]] or :e +line file)
+func handleEdit(fd int, args string, callback func() byte) {
+	filename, line, col, err := ParseFileArgs(strings.Fields(args))
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Edit: %v", err)
+		return
+	}
+	pushJump()
+	if err := openBuffer(filename); err != nil {
+		jumpList = jumpList[:len(jumpList)-1]
+		session.statusMessage = fmt.Sprintf("Edit: %v", err)
+		return
+	}
+	if session.loading != nil {
+		// The cursor is placed once the file is there
+		session.loading.line, session.loading.col = line, col
+	} else if line != 0 {
+		GoToLine(line, col)
+	}
+}
+
+// handleGoTo moves the cursor to a line, and optionally a column, asking
+// for it when not given (:goto line[:col])
+func handleGoTo(fd int, args string, callback func() byte) {
+	if args == "" {
+		input, ok := readPrompt("Go to line: ", nil, callback)
+		if args = strings.TrimSpace(input); !ok || args == "" {
+			return
+		}
+	}
+	lineText, colText, _ := strings.Cut(args, ":")
+	line, err := strconv.Atoi(lineText)
+	col := 1
+	if err == nil && colText != "" {
+		col, err = strconv.Atoi(colText)
+	}
+	if err != nil || line < 1 {
+		session.statusMessage = fmt.Sprintf("Go to: invalid line %q", args)
+		return
+	}
+	pushJump()
+	GoToLine(line, col)
+}
+
+// maxPickerFiles bounds the files the file picker lists, for directories
+// like the home directory
+const maxPickerFiles = 10000
+
+// handleFilePicker opens a file found under the current directory, picked
+// from a list by typing part of its name (:files). Hidden directories are
+// skipped.
+func handleFilePicker(fd int, args string, callback func() byte) {
+	var files []string
+	filepath.WalkDir(".", func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != "." && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files = append(files, path)
+		if len(files) == maxPickerFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if len(files) == 0 {
+		session.statusMessage = "No files here"
+		return
+	}
+	choice := fuzzyPickFromList(fd, "File", files, callback)
+	if choice < 0 {
+		return
+	}
+	pushJump()
+	if err := openBuffer(files[choice]); err != nil {
+		jumpList = jumpList[:len(jumpList)-1]
+		session.statusMessage = fmt.Sprintf("Open: %v", err)
+	}
+}
+
+// jumpBack returns to the position recorded by the most recent jump
+func jumpBack() {
+	if len(jumpList) == 0 {
+		session.statusMessage = "Jump list is empty"
+		return
+	}
+
+	last := jumpList[len(jumpList)-1]
+	jumpList = jumpList[:len(jumpList)-1]
+	for _, b := range buffers {
+		if b == last.buffer {
+			switchToBuffer(b)
+			session.cursorIdx = min(last.cursorIdx, session.rope.Length())
+			updateCursorPosition()
+			return
+		}
+	}
+	if err := jumpTo(last.filename, last.cursorIdx); err != nil {
+		session.statusMessage = fmt.Sprintf("Cannot jump back: %v", err)
+	}
+}
+
+// displayPath shortens path to be relative to the working directory
+// when it lives below it
+func displayPath(path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,244 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Key is a key press read from the terminal: a character or a special key,
+// with the modifiers held down. The zero Key is no key at all, what reading
+// returns when it times out.
+type Key struct {
+	Rune    rune       // the character typed, 0 for a special key
+	Special SpecialKey // the special key, when Rune is 0
+	Mod     Modifier
+}
+
+// SpecialKey is a key that doesn't type a character, or an event the
+// terminal reports like a key
+type SpecialKey int
+
+const (
+	NoSpecialKey SpecialKey = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyTab
+	KeyEnter
+	KeyBackspace
+	KeyEsc
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyInsert
+	KeyDelete
+	KeyPaste    // pasted text follows
+	KeyFocusIn  // the terminal got the focus
+	KeyFocusOut // the terminal lost the focus
+	KeyMouse    // a mouse button pressed or released, where lastMouse tells
+	KeyF1       // followed by F2 to F12: KeyF1+n-1 is Fn
+)
+
+// KeyF12 is the last function key
+const KeyF12 = KeyF1 + 11
+
+// Modifier holds the modifier keys pressed with a key, one bit each
+type Modifier uint8
+
+const (
+	ModShift Modifier = 1 << iota
+	ModAlt
+	ModCtrl
+)
+
+// Keys the editor binds
+var (
+	ArrowUp    = Key{Special: KeyUp}
+	ArrowDown  = Key{Special: KeyDown}
+	ArrowLeft  = Key{Special: KeyLeft}
+	ArrowRight = Key{Special: KeyRight}
+
+	TabKey       = Key{Special: KeyTab}
+	ReturnKey    = Key{Special: KeyEnter}
+	BackspaceKey = Key{Special: KeyBackspace}
+	EscKey       = Key{Special: KeyEsc}
+
+	HomeKey     = Key{Special: KeyHome}
+	EndKey      = Key{Special: KeyEnd}
+	PageUpKey   = Key{Special: KeyPageUp}
+	PageDownKey = Key{Special: KeyPageDown}
+	DeleteKey   = Key{Special: KeyDelete}
+)
+
+// fn returns function key Fn
+func fn(n int) Key {
+	return Key{Special: KeyF1 + SpecialKey(n-1)}
+}
+
+// ctrl returns the key r pressed with Ctrl, like ctrl('q') for Ctrl-Q
+func ctrl(r rune) Key {
+	return Key{Rune: r, Mod: ModCtrl}
+}
+
+// alt returns the key r pressed with Alt, like alt('n') for Alt-N
+func alt(r rune) Key {
+	return Key{Rune: r, Mod: ModAlt}
+}
+
+// char returns the character the key types into the text, and false for
+// special keys and keys pressed with Ctrl or Alt. The cursor moves over
+// the text a byte at a time, so only ASCII characters are typed; :unicode
+// and digraphs insert the others.
+func (k Key) char() (byte, bool) {
+	if k.Mod != 0 || k.Rune < ' ' || k.Rune >= 127 {
+		return 0, false
+	}
+	return byte(k.Rune), true
+}
+
+// specialKeyNames are the names of the special keys, as the README writes them
+var specialKeyNames = map[SpecialKey]string{
+	KeyUp: "Up", KeyDown: "Down", KeyLeft: "Left", KeyRight: "Right",
+	KeyTab: "Tab", KeyEnter: "Return", KeyBackspace: "Backspace", KeyEsc: "Esc",
+	KeyHome: "Home", KeyEnd: "End", KeyPageUp: "PageUp", KeyPageDown: "PageDown",
+	KeyInsert: "Insert", KeyDelete: "Delete", KeyPaste: "Paste",
+	KeyFocusIn: "FocusIn", KeyFocusOut: "FocusOut", KeyMouse: "Mouse",
+}
+
+// String names the key with its modifiers, like Ctrl-Q, Alt-D or Shift-Up
+func (k Key) String() string {
+	name := ""
+	switch {
+	case k == Key{}:
+		return "none"
+	case k.Rune == ' ':
+		name = "Space"
+	case k.Rune >= 'A' && k.Rune <= 'Z' && k.Mod&(ModCtrl|ModAlt) != 0:
+		// Letters with Ctrl or Alt are written in capitals, so Shift says
+		// it's one
+		name = "Shift-" + string(k.Rune)
+	case k.Rune != 0 && k.Mod&(ModCtrl|ModAlt) != 0:
+		name = strings.ToUpper(string(k.Rune))
+	case k.Rune != 0:
+		name = string(k.Rune)
+	case k.Special >= KeyF1 && k.Special <= KeyF12:
+		name = fmt.Sprintf("F%d", k.Special-KeyF1+1)
+	default:
+		name = specialKeyNames[k.Special]
+	}
+	for _, mod := range []struct {
+		mod  Modifier
+		name string
+	}{{ModShift, "Shift-"}, {ModAlt, "Alt-"}, {ModCtrl, "Ctrl-"}} {
+		if k.Mod&mod.mod != 0 {
+			name = mod.name + name
+		}
+	}
+	return name
+}
+
+// parseKey reads a key named as String names it, like Ctrl-Y, Alt-Shift-K
+// or F5. Modifiers may be written in any case.
+func parseKey(name string) (Key, error) {
+	var key Key
+	rest := name
+	for {
+		prefix, after, ok := strings.Cut(rest, "-")
+		if !ok || after == "" {
+			break
+		}
+		switch strings.ToLower(prefix) {
+		case "ctrl":
+			key.Mod |= ModCtrl
+		case "alt":
+			key.Mod |= ModAlt
+		case "shift":
+			key.Mod |= ModShift
+		default:
+			return Key{}, fmt.Errorf("unknown modifier %q in key %q", prefix, name)
+		}
+		rest = after
+	}
+
+	if r, size := utf8.DecodeRuneInString(rest); size == len(rest) && r != utf8.RuneError {
+		key.Rune = r
+		if key.Mod&(ModCtrl|ModAlt) != 0 {
+			// Letters with Ctrl or Alt are written in capitals, Shift
+			// making them one
+			key.Rune = unicode.ToLower(r)
+			if key.Mod&ModShift != 0 {
+				key.Rune, key.Mod = unicode.ToUpper(r), key.Mod&^ModShift
+			}
+		}
+		return key, nil
+	}
+	if strings.EqualFold(rest, "Space") {
+		key.Rune = ' '
+		return key, nil
+	}
+	for n := 1; n <= 12; n++ {
+		if strings.EqualFold(rest, fmt.Sprintf("F%d", n)) {
+			key.Special = fn(n).Special
+			return key, nil
+		}
+	}
+	for special, specialName := range specialKeyNames {
+		if strings.EqualFold(rest, specialName) {
+			key.Special = special
+			return key, nil
+		}
+	}
+	return Key{}, fmt.Errorf("unknown key %q", name)
+}
+
+// keyOfByte returns the key a terminal sends as the single byte b: control
+// characters are letters and a few symbols pressed with Ctrl, except for
+// Tab, Return, Backspace and Esc
+func keyOfByte(b byte) Key {
+	switch {
+	case b == Tab:
+		return TabKey
+	case b == Return:
+		return ReturnKey
+	case b == Backspace:
+		return BackspaceKey
+	case b == Esc:
+		return EscKey
+	case b >= 0x01 && b <= 0x1A:
+		return ctrl(rune('a' + b - 1))
+	case b >= 0x1C && b < ' ':
+		// Ctrl-\, Ctrl-], Ctrl-^ and Ctrl-_
+		return ctrl(rune(b + 0x40))
+	}
+	return Key{Rune: rune(b)}
+}
+
+// readRune reads the rest of the UTF-8 character starting with first, and
+// returns utf8.RuneError when it isn't valid
+func readRune(first byte, next func() byte) rune {
+	n := 0
+	switch {
+	case first&0xE0 == 0xC0:
+		n = 1
+	case first&0xF0 == 0xE0:
+		n = 2
+	case first&0xF8 == 0xF0:
+		n = 3
+	default:
+		return utf8.RuneError
+	}
+	p := []byte{first}
+	for range n {
+		b := next()
+		if b&0xC0 != 0x80 {
+			return utf8.RuneError
+		}
+		p = append(p, b)
+	}
+	r, _ := utf8.DecodeRune(p)
+	return r
+}
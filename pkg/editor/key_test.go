@@ -0,0 +1,55 @@
+package editor
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestReadKeys(t *testing.T) {
+	for input, want := range map[string]Key{
+		"x":            {Rune: 'x'},
+		"\x11":         ctrl('q'),
+		"\x1d":         ctrl(']'),
+		"\t":           TabKey,
+		"\r":           ReturnKey,
+		"\x7f":         BackspaceKey,
+		"\x1b\x0e":     {Rune: 'n', Mod: ModAlt | ModCtrl},
+		"\x1b[1;2C":    ShiftArrowRight,
+		"\x1b[Z":       ShiftTab,
+		"\x1b[200~":    BracketedPaste,
+		"é":            {Rune: 'é'},
+		"\xe2\x82\xac": {Rune: '€'},
+		"\xe2x":        {Rune: utf8.RuneError},
+	} {
+		if got := editorReadKeypress(makeCallback([]byte(input))); got != want {
+			t.Errorf("%q read as %+v, want %+v", input, got, want)
+		}
+	}
+}
+
+func TestKeyChar(t *testing.T) {
+	if c, ok := (Key{Rune: 'a'}).char(); !ok || c != 'a' {
+		t.Errorf("a types %q, %v", c, ok)
+	}
+	for _, key := range []Key{alt('a'), ctrl('a'), ArrowUp, TabKey, {Rune: 'é'}} {
+		if _, ok := key.char(); ok {
+			t.Errorf("%+v should not type a character", key)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	for _, key := range []Key{ctrl('y'), alt('k'), alt('K'), fn(5), ShiftArrowUp, CtrlHome, ReturnKey, Key{Rune: 'q'}, alt(' '), alt('-')} {
+		if got, err := parseKey(key.String()); err != nil || got != key {
+			t.Errorf("parseKey(%q) = %v, %v", key.String(), got, err)
+		}
+	}
+	if got, err := parseKey("ctrl-Y"); err != nil || got != ctrl('y') {
+		t.Errorf("modifiers in lower case: %v, %v", got, err)
+	}
+	for _, name := range []string{"Hyper-K", "Ctrl-Foo", ""} {
+		if _, err := parseKey(name); err == nil {
+			t.Errorf("%q should not be a key", name)
+		}
+	}
+}
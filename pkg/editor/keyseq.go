@@ -0,0 +1,134 @@
+package editor
+
+import (
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// xtermKeys are the escape sequences of special keys as xterm sends them,
+// which most terminals do too. Cursor keys come in both the normal and the
+// application mode.
+var xtermKeys = map[string]Key{
+	"\x1b[A": ArrowUp, "\x1b[B": ArrowDown, "\x1b[C": ArrowRight, "\x1b[D": ArrowLeft,
+	"\x1bOA": ArrowUp, "\x1bOB": ArrowDown, "\x1bOC": ArrowRight, "\x1bOD": ArrowLeft,
+	"\x1b[H": HomeKey, "\x1bOH": HomeKey, "\x1b[F": EndKey, "\x1bOF": EndKey,
+	"\x1b[1~": HomeKey, "\x1b[4~": EndKey,
+	"\x1b[2~": {Special: KeyInsert}, "\x1b[3~": DeleteKey,
+	"\x1b[5~": PageUpKey, "\x1b[6~": PageDownKey,
+	"\x1bOP": fn(1), "\x1bOQ": fn(2), "\x1bOR": fn(3), "\x1bOS": fn(4),
+	"\x1b[15~": fn(5), "\x1b[17~": fn(6), "\x1b[18~": fn(7), "\x1b[19~": fn(8),
+	"\x1b[20~": fn(9), "\x1b[21~": fn(10), "\x1b[23~": fn(11), "\x1b[24~": fn(12),
+	"\x1b[Z": ShiftTab, "\x1b[I": FocusIn, "\x1b[O": FocusOut, "\x1b[200~": BracketedPaste,
+}
+
+// termKeys are the sequences of the terminals that differ from xterm, by
+// the start of $TERM. Screen and tmux send those of xterm, or the Home and
+// End of the VT220 it accepts too.
+var termKeys = map[string]map[string]Key{
+	"linux": {
+		"\x1b[[A": fn(1), "\x1b[[B": fn(2), "\x1b[[C": fn(3), "\x1b[[D": fn(4), "\x1b[[E": fn(5),
+	},
+	"rxvt": {
+		"\x1b[7~": HomeKey, "\x1b[8~": EndKey,
+		"\x1b[11~": fn(1), "\x1b[12~": fn(2), "\x1b[13~": fn(3), "\x1b[14~": fn(4),
+		"\x1b[a": ShiftArrowUp, "\x1b[b": ShiftArrowDown, "\x1b[c": ShiftArrowRight, "\x1b[d": ShiftArrowLeft,
+		"\x1bOa": {Special: KeyUp, Mod: ModCtrl}, "\x1bOb": {Special: KeyDown, Mod: ModCtrl},
+		"\x1bOc": {Special: KeyRight, Mod: ModCtrl}, "\x1bOd": {Special: KeyLeft, Mod: ModCtrl},
+	},
+}
+
+// keySequences maps the escape sequences of the terminal the editor runs in
+// to their keys
+var keySequences = sequencesFor(os.Getenv("TERM"))
+
+// sequencesFor returns the key sequences of the terminal named term, like
+// "xterm-256color" or "rxvt-unicode"
+func sequencesFor(term string) map[string]Key {
+	keys := maps.Clone(xtermKeys)
+	for name, extra := range termKeys {
+		if term == name || strings.HasPrefix(term, name+"-") || strings.HasPrefix(term, name+".") {
+			maps.Copy(keys, extra)
+		}
+	}
+	return keys
+}
+
+// readSequence reads the rest of an escape sequence started by Esc and
+// second, '[' or 'O', and reports whether it came whole. A CSI sequence
+// (Esc [) has digits and ';' up to its final byte, an SS3 one (Esc O) a
+// single byte; the Linux console sends its first function keys as Esc [ [
+// and a letter.
+func readSequence(second byte, next func() byte) (string, bool) {
+	seq := []byte{Esc, second}
+	b := next()
+	if second == '[' && b == '[' {
+		seq = append(seq, b)
+		b = next()
+	} else if second == '[' {
+		for b >= '0' && b <= '?' {
+			seq = append(seq, b)
+			b = next()
+		}
+	}
+	if b == 0 {
+		return string(seq), false
+	}
+	return string(append(seq, b)), true
+}
+
+// decodeSequence returns the key sent as seq, which may carry modifiers:
+// xterm adds them as a parameter, Esc [ 1 ; 5 A for Ctrl-Up, and rxvt ends
+// the sequences of Shift and Ctrl keys with $ and ^ instead of ~. ok is
+// false for sequences of no key known.
+func decodeSequence(seq string) (key Key, ok bool) {
+	if key, ok := keySequences[seq]; ok {
+		return key, true
+	}
+	if decodeMouse(seq) {
+		return MouseEvent, true
+	}
+	if !strings.HasPrefix(seq, "\x1b[") || len(seq) < 4 {
+		return Key{}, false
+	}
+	params, final := seq[2:len(seq)-1], seq[len(seq)-1:]
+	switch final {
+	case "$":
+		key, ok = keySequences["\x1b["+params+"~"]
+		key.Mod |= ModShift
+		return key, ok
+	case "^":
+		key, ok = keySequences["\x1b["+params+"~"]
+		key.Mod |= ModCtrl
+		return key, ok
+	}
+
+	number, modifier, found := strings.Cut(params, ";")
+	m, err := strconv.Atoi(modifier)
+	if !found || err != nil || m < 2 {
+		return Key{}, false
+	}
+	// The keys sent without parameter, like Up as Esc [ A, or Esc O P for
+	// F1, take 1 as the number
+	bases := []string{"\x1b[" + number + final}
+	if number == "1" {
+		bases = []string{"\x1b[" + final, "\x1bO" + final}
+	}
+	for _, base := range bases {
+		if key, ok := keySequences[base]; ok {
+			m--
+			if m&1 != 0 {
+				key.Mod |= ModShift
+			}
+			if m&2 != 0 {
+				key.Mod |= ModAlt
+			}
+			if m&4 != 0 {
+				key.Mod |= ModCtrl
+			}
+			return key, true
+		}
+	}
+	return Key{}, false
+}
@@ -0,0 +1,59 @@
+package editor
+
+import "testing"
+
+func TestKeySequencesOfTerminals(t *testing.T) {
+	old := keySequences
+	t.Cleanup(func() { keySequences = old })
+
+	for term, cases := range map[string]map[string]Key{
+		"xterm-256color": {
+			"\x1b[H": HomeKey, "\x1bOF": EndKey, "\x1b[1~": HomeKey, "\x1b[5~": PageUpKey,
+			"\x1bOP": fn(1), "\x1b[24~": fn(12), "\x1b[7~": EscKey, "\x1bOA": ArrowUp,
+			"\x1b[1;5C": {Special: KeyRight, Mod: ModCtrl}, "\x1b[3;2~": {Special: KeyDelete, Mod: ModShift},
+			"\x1b[1;2P": {Special: KeyF1, Mod: ModShift}, "\x1b[15;7~": {Special: KeyF1 + 4, Mod: ModAlt | ModCtrl},
+		},
+		"linux": {"\x1b[[A": fn(1), "\x1b[[E": fn(5), "\x1b[1~": HomeKey, "\x1b[4~": EndKey},
+		"rxvt-unicode-256color": {
+			"\x1b[7~": HomeKey, "\x1b[8~": EndKey, "\x1b[11~": fn(1), "\x1b[c": ShiftArrowRight,
+			"\x1b[7$": {Special: KeyHome, Mod: ModShift}, "\x1b[3^": {Special: KeyDelete, Mod: ModCtrl},
+			"\x1bOa": {Special: KeyUp, Mod: ModCtrl},
+		},
+		"screen": {"\x1b[1~": HomeKey, "\x1b[4~": EndKey, "\x1b[1;2A": ShiftArrowUp},
+	} {
+		keySequences = sequencesFor(term)
+		for input, want := range cases {
+			if got := editorReadKeypress(makeCallback([]byte(input))); got != want {
+				t.Errorf("%s: %q read as %+v, want %+v", term, input, got, want)
+			}
+		}
+	}
+
+	// Alt-Shift-O is Esc O on its own
+	if got := editorReadKeypress(makeCallback([]byte("\x1bO"))); got != alt('O') {
+		t.Errorf("Esc O read as %+v", got)
+	}
+}
+
+func TestHomeEndPageDelete(t *testing.T) {
+	resetSessionForTest()
+	InitSession(-1, "notes.txt", "one\ntwo three\n")
+	GoToLine(2, 3)
+
+	ProcessKeypress(0, makeCallback([]byte("\x1b[F\x1b[3~\x1b[H\x1b[3~\x11\x11")))
+	if got := session.rope.String(); got != "one\nwo three" {
+		t.Errorf("text %q", got)
+	}
+	if session.cursorRow != 2 || session.cursorCol != 1 {
+		t.Errorf("cursor on %d:%d, want 2:1", session.cursorRow, session.cursorCol)
+	}
+
+	movePage(-1)
+	if session.cursorRow != 1 {
+		t.Errorf("Page Up should stop on the first line, got %d", session.cursorRow)
+	}
+	movePage(1)
+	if session.cursorRow != 2 {
+		t.Errorf("Page Down should stop on the last line, got %d", session.cursorRow)
+	}
+}
@@ -0,0 +1,97 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// largeFileThreshold is the size in bytes from which a file is opened in
+// large-file mode (:largefile SIZE changes it for files opened afterwards)
+var largeFileThreshold = 8 << 20
+
+// largeFileUndoLimit is how many actions the undo stack of a large file keeps
+const largeFileUndoLimit = 200
+
+// largeFileNotice tells the user why some features stopped working
+var largeFileNotice = fmt.Sprintf("Large file: highlighting and blame off, undo limited to %d steps", largeFileUndoLimit)
+
+// drawLargeTextView draws the visible lines of a large buffer into buf,
+// reading only those lines from the rope, and returns the screen position
+// of the cursor
+func drawLargeTextView(buf *strings.Builder, textRows int) (int, int) {
+	starts := lineStarts()
+	editorScroll(textRows, len(starts))
+	cols := int(session.screenCols)
+
+	for i := 0; i < textRows; i++ {
+		row := i + session.rowOffset
+		if row < len(starts) {
+			// Only the part of the line on screen is read
+			start, end := lineBounds(starts, row)
+			line, _ := session.rope.Substring(start, start+min(end-start, visibleBytes(cols)))
+			buf.WriteString(renderLine(visiblePart(line, cols), start, nil))
+		} else {
+			buf.WriteString("~")
+		}
+		buf.WriteString("\x1b[K")
+		buf.WriteString("\r\n")
+	}
+	// A cursor further than the bytes that fit on screen is off screen
+	start, end := lineBounds(starts, session.cursorRow-1)
+	line, _ := session.rope.Substring(start, start+min(end-start, visibleBytes(cols)))
+	col := clampColumn(visualColumn(line, session.cursorCol-1), cols)
+	return session.cursorRow - session.rowOffset, col + 1
+}
+
+// parseSize parses a byte count with an optional K, M or G suffix
+func parseSize(s string) (int, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// handleLargeFile turns large-file mode on or off for the active buffer, or
+// sets the size from which files open in it (:largefile [on|off|SIZE])
+func handleLargeFile(fd int, args string, callback func() byte) {
+	switch args {
+	case "":
+		state := "off"
+		if session.largeFile {
+			state = "on"
+		}
+		session.statusMessage = fmt.Sprintf("Large-file mode %s, threshold %d bytes", state, largeFileThreshold)
+	case "on":
+		session.largeFile = true
+		updateCursorPosition()
+		session.statusMessage = largeFileNotice
+	case "off":
+		session.largeFile = false
+		session.lineIndex = nil
+		updateCursorPosition()
+		session.statusMessage = "Large-file mode off"
+	default:
+		size, err := parseSize(args)
+		if err != nil {
+			session.statusMessage = fmt.Sprintf("Large file: %v", err)
+			return
+		}
+		largeFileThreshold = size
+		session.statusMessage = fmt.Sprintf("Files of %d bytes or more open in large-file mode", size)
+	}
+}
@@ -0,0 +1,115 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func useLargeFileThreshold(t *testing.T, size int) {
+	old := largeFileThreshold
+	largeFileThreshold = size
+	t.Cleanup(func() { largeFileThreshold = old })
+}
+
+func TestNewSessionLargeFile(t *testing.T) {
+	useLargeFileThreshold(t, 10)
+
+	if s := newSession("small.txt", "short"); s.largeFile {
+		t.Error("a small file should not be in large-file mode")
+	}
+	s := newSession("big.txt", "a much longer text")
+	if !s.largeFile {
+		t.Fatal("a file over the threshold should be in large-file mode")
+	}
+	if s.statusMessage == "" {
+		t.Error("large-file mode should be announced")
+	}
+}
+
+func TestLargeFileUndoLimit(t *testing.T) {
+	resetSessionForTest()
+	useLargeFileThreshold(t, 1)
+	session = newSession("big.txt", "x")
+	buffers = []*Session{session}
+
+	for i := 0; i < largeFileUndoLimit+50; i++ {
+		handleInsert("y")
+	}
+	if got := len(session.undoStack); got != largeFileUndoLimit {
+		t.Errorf("undo stack holds %d actions, want %d", got, largeFileUndoLimit)
+	}
+	for len(session.undoStack) > 0 {
+		handleUndo()
+	}
+	if got := session.rope.Length(); got != 51 {
+		t.Errorf("after undoing everything the buffer has %d bytes, want 51", got)
+	}
+}
+
+func TestLargeFileNavigation(t *testing.T) {
+	resetSessionForTest()
+	useLargeFileThreshold(t, 1)
+	session = newSession("big.txt", "first line\nab\nthird line\n")
+	buffers = []*Session{session}
+
+	if row, col := indexPosition(12); row != 2 || col != 2 {
+		t.Errorf("indexPosition(12) = %d,%d want 2,2", row, col)
+	}
+	if got := getLineStartIndex(3); got != 14 {
+		t.Errorf("getLineStartIndex(3) = %d, want 14", got)
+	}
+
+	session.cursorIdx = 8
+	updateCursorPosition()
+	editorMoveCursor(ArrowDown)
+	if session.cursorIdx != 13 || session.cursorRow != 2 || session.cursorCol != 3 {
+		t.Errorf("down onto a shorter line: idx %d row %d col %d, want 13 2 3",
+			session.cursorIdx, session.cursorRow, session.cursorCol)
+	}
+	editorMoveCursor(ArrowDown)
+	if session.cursorIdx != 22 {
+		t.Errorf("down past the shorter line goes back to the column: idx %d, want 22", session.cursorIdx)
+	}
+	// Moving sideways sets the column anew
+	editorMoveCursor(ArrowLeft)
+	editorMoveCursor(ArrowLeft)
+	editorMoveCursor(ArrowUp)
+	editorMoveCursor(ArrowUp)
+	editorMoveCursor(ArrowUp)
+	if session.cursorRow != 1 || session.cursorCol != 7 {
+		t.Errorf("up to the first line: row %d col %d, want 1 7", session.cursorRow, session.cursorCol)
+	}
+}
+
+func TestDrawLargeTextView(t *testing.T) {
+	resetSessionForTest()
+	useLargeFileThreshold(t, 1)
+	session = newSession("big.txt", "one\ntwo\nthree\nfour")
+	buffers = []*Session{session}
+	session.cursorIdx = session.rope.Length()
+	updateCursorPosition()
+
+	var buf strings.Builder
+	row, col := drawLargeTextView(&buf, 2)
+	lines := strings.Split(buf.String(), "\x1b[K\r\n")
+	if lines[0] != "three" || lines[1] != "four" {
+		t.Errorf("drew %q, want the last two lines", lines)
+	}
+	if row != 2 || col != 5 {
+		t.Errorf("cursor at %d,%d want 2,5", row, col)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int{"100": 100, "4k": 4096, "2M": 2 << 20, "1G": 1 << 30}
+	for in, want := range tests {
+		if got, err := parseSize(in); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "M", "-1", "ten"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) should fail", in)
+		}
+	}
+}
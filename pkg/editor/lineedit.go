@@ -0,0 +1,181 @@
+package editor
+
+import "strings"
+
+// subwordMotion makes word motions and word deletion stop at the humps of
+// camelCase and the segments of snake_case too (:set subword on)
+var subwordMotion = false
+
+// isSubwordBoundary reports whether a word of s starts at byte i, in the
+// middle of an identifier: after underscores, at a capital following a
+// lowercase letter or digit, and at the last capital of a run followed by
+// a lowercase letter, as in HTTPServer
+func isSubwordBoundary(s string, i int) bool {
+	if !subwordMotion || i <= 0 || i >= len(s) {
+		return false
+	}
+	isUpper := func(b byte) bool { return b >= 'A' && b <= 'Z' }
+	isLowerOrDigit := func(b byte) bool { return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' }
+	prev, c := s[i-1], s[i]
+	switch {
+	case prev == '_':
+		return c != '_'
+	case isLowerOrDigit(prev):
+		return isUpper(c)
+	case isUpper(prev) && isUpper(c):
+		return i+1 < len(s) && s[i+1] >= 'a' && s[i+1] <= 'z'
+	}
+	return false
+}
+
+// wordStartBefore returns where the word ending at byte i of s starts:
+// blanks before i are skipped, then a run of identifier bytes, or of other
+// symbols
+func wordStartBefore(s string, i int) int {
+	for i > 0 && (s[i-1] == ' ' || s[i-1] == '\t') {
+		i--
+	}
+	if i == 0 {
+		return 0
+	}
+	word := isIdentifierByte(s[i-1])
+	i--
+	for i > 0 && s[i-1] != ' ' && s[i-1] != '\t' && isIdentifierByte(s[i-1]) == word && !isSubwordBoundary(s, i) {
+		i--
+	}
+	return i
+}
+
+// wordEndAfter returns where the word starting at byte i of s ends, the
+// reverse of wordStartBefore
+func wordEndAfter(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	if i == len(s) {
+		return i
+	}
+	word := isIdentifierByte(s[i])
+	i++
+	for i < len(s) && s[i] != ' ' && s[i] != '\t' && isIdentifierByte(s[i]) == word && !isSubwordBoundary(s, i) {
+		i++
+	}
+	return i
+}
+
+// handleWordLeft moves the cursor to the start of the word before it, or
+// to the end of the line above at the start of a line (Ctrl-Left)
+func handleWordLeft() {
+	line, start := cursorLine()
+	if session.cursorIdx == start {
+		session.cursorIdx = max(start-1, 0)
+	} else {
+		session.cursorIdx = start + wordStartBefore(line, session.cursorIdx-start)
+	}
+	updateCursorPosition()
+}
+
+// handleWordRight moves the cursor to the end of the word after it, or to
+// the start of the line below at the end of a line (Ctrl-Right)
+func handleWordRight() {
+	line, start := cursorLine()
+	if session.cursorIdx == start+len(line) {
+		session.cursorIdx = min(session.cursorIdx+1, session.rope.Length())
+	} else {
+		session.cursorIdx = start + wordEndAfter(line, session.cursorIdx-start)
+	}
+	updateCursorPosition()
+}
+
+// handleDeleteWordBackward deletes the word before the cursor, or the line
+// break at the start of a line (Ctrl-W)
+func handleDeleteWordBackward() {
+	lineStart := getLineStartIndex(session.cursorRow)
+	if session.cursorIdx <= lineStart {
+		handleBackspace()
+		return
+	}
+	before, err := session.rope.Substring(lineStart, session.cursorIdx)
+	if err != nil {
+		return
+	}
+	deleteText(lineStart+wordStartBefore(before, len(before)), session.cursorIdx)
+}
+
+// handleDeleteToLineEnd deletes from the cursor to the end of the line, or
+// the line break when the cursor is already there (Ctrl-K)
+func handleDeleteToLineEnd() {
+	end := lineEndIndex(session.cursorIdx)
+	if end == session.cursorIdx && end < session.rope.Length() {
+		end++
+	}
+	deleteText(session.cursorIdx, end)
+}
+
+// handleHome moves the cursor to the first character of its line that
+// isn't a blank, or to the start of the line when already there (Home)
+func handleHome() {
+	line, start := cursorLine()
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	if session.cursorIdx == start+indent {
+		indent = 0
+	}
+	session.cursorIdx = start + indent
+	updateCursorPosition()
+}
+
+// handleEnd moves the cursor to the end of its line (End)
+func handleEnd() {
+	session.cursorIdx = lineEndIndex(session.cursorIdx)
+	updateCursorPosition()
+}
+
+// Ctrl+Home and Ctrl+End, which move to the start and the end of the
+// buffer, and Ctrl+Left and Ctrl+Right, which move by words
+var (
+	CtrlHome       = Key{Special: KeyHome, Mod: ModCtrl}
+	CtrlEnd        = Key{Special: KeyEnd, Mod: ModCtrl}
+	CtrlArrowLeft  = Key{Special: KeyLeft, Mod: ModCtrl}
+	CtrlArrowRight = Key{Special: KeyRight, Mod: ModCtrl}
+)
+
+// handleBufferStart moves the cursor to the start of the buffer (Ctrl-Home)
+func handleBufferStart() {
+	session.cursorIdx = 0
+	updateCursorPosition()
+}
+
+// handleBufferEnd moves the cursor to the end of the buffer (Ctrl-End)
+func handleBufferEnd() {
+	session.cursorIdx = session.rope.Length()
+	updateCursorPosition()
+}
+
+// handleDeleteForward deletes the byte under the cursor, or the line break
+// at the end of a line (Delete)
+func handleDeleteForward() {
+	if session.cursorIdx < session.rope.Length() {
+		deleteText(session.cursorIdx, session.cursorIdx+1)
+	}
+}
+
+// lineEndIndex returns the index of the line break ending the line that
+// holds idx, or the length of the rope on the last line
+func lineEndIndex(idx int) int {
+	length := session.rope.Length()
+	for idx < length {
+		if b, _ := session.rope.Index(idx); b == '\n' {
+			break
+		}
+		idx++
+	}
+	return idx
+}
+
+// cursorLine returns the line of the cursor, without its line break, and
+// where it starts in the rope
+func cursorLine() (line string, start int) {
+	start = getLineStartIndex(session.cursorRow)
+	line, _ = session.rope.Substring(start, lineEndIndex(start))
+	return line, start
+}
@@ -0,0 +1,173 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestWordStartBefore(t *testing.T) {
+	for s, want := range map[string]int{
+		"":              0,
+		"   ":           0,
+		"foo bar":       4,
+		"foo bar  ":     4,
+		"x := a.b":      7,
+		"x := a.":       6,
+		"call(arg":      5,
+		"call(":         4,
+		"naïve":         0,
+		"\tindented_id": 1,
+	} {
+		if got := wordStartBefore(s, len(s)); got != want {
+			t.Errorf("wordStartBefore(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestSubwordMotion(t *testing.T) {
+	saveSettings(t)
+	subwordMotion = true
+	for s, want := range map[string]int{
+		"parseHTTPServer": 9,
+		"parseHTTP":       5,
+		"snake_case_name": 11,
+		"version2Beta":    8,
+		"foo bar":         4,
+		"__init":          2,
+	} {
+		if got := wordStartBefore(s, len(s)); got != want {
+			t.Errorf("wordStartBefore(%q) = %d, want %d", s, got, want)
+		}
+	}
+	if got := wordEndAfter("parseHTTPServer", 0); got != 5 {
+		t.Errorf("wordEndAfter stopped at %d, want 5", got)
+	}
+	if got := wordEndAfter("parseHTTPServer", 5); got != 9 {
+		t.Errorf("wordEndAfter from the hump stopped at %d, want 9", got)
+	}
+
+	resetSessionForTest()
+	session.rope = buffer.NewRope("userName_id")
+	session.cursorIdx = session.rope.Length()
+	ProcessKeypress(0, makeCallback([]byte{CtrlW, CtrlW, CtrlQ, CtrlQ}))
+	if got := session.rope.String(); got != "user" {
+		t.Fatalf("Ctrl-W with subwords left %q", got)
+	}
+
+	subwordMotion = false
+	if got := wordStartBefore("parseHTTPServer", 15); got != 0 {
+		t.Errorf("without subword wordStartBefore = %d, want 0", got)
+	}
+}
+
+func TestWordLeftRight(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("one two\nthree")
+	session.cursorIdx = 0
+
+	// Ctrl-Right stops at word ends and steps over line breaks
+	for _, want := range []int{3, 7, 8, 13} {
+		ProcessKeypress(0, makeCallback([]byte("\x1b[1;5C\x11\x11")))
+		if session.cursorIdx != want {
+			t.Errorf("Ctrl-Right went to %d, want %d", session.cursorIdx, want)
+		}
+	}
+	for _, want := range []int{8, 7, 4, 0} {
+		ProcessKeypress(0, makeCallback([]byte("\x1b[1;5D\x11\x11")))
+		if session.cursorIdx != want {
+			t.Errorf("Ctrl-Left went to %d, want %d", session.cursorIdx, want)
+		}
+	}
+}
+
+func TestDeleteWordAndLineEnd(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("one two\nthree four")
+	session.cursorIdx = 7
+	updateCursorPosition()
+
+	ProcessKeypress(0, makeCallback([]byte{CtrlW, CtrlQ, CtrlQ}))
+	if got := session.rope.String(); got != "one \nthree four" {
+		t.Fatalf("Ctrl-W left %q", got)
+	}
+	// At the start of a line Ctrl-W joins it with the previous one
+	session.cursorIdx = 5
+	ProcessKeypress(0, makeCallback([]byte{CtrlW, CtrlQ, CtrlQ}))
+	if got := session.rope.String(); got != "one three four" {
+		t.Fatalf("Ctrl-W at line start left %q", got)
+	}
+
+	session.rope = buffer.NewRope("abc def\nghi")
+	session.cursorIdx = 4
+	ProcessKeypress(0, makeCallback([]byte{CtrlK, CtrlQ, CtrlQ}))
+	if got := session.rope.String(); got != "abc \nghi" {
+		t.Fatalf("Ctrl-K left %q", got)
+	}
+	// At the end of a line Ctrl-K joins the next one
+	ProcessKeypress(0, makeCallback([]byte{CtrlK, CtrlQ, CtrlQ}))
+	if got := session.rope.String(); got != "abc ghi" {
+		t.Fatalf("second Ctrl-K left %q", got)
+	}
+
+	// Each deletion is one undo step
+	handleUndo()
+	handleUndo()
+	if got := session.rope.String(); got != "abc def\nghi" {
+		t.Fatalf("undo gave %q", got)
+	}
+}
+
+func TestPromptDeleteWord(t *testing.T) {
+	resetSessionForTest()
+	got := editorDrawPrompt(":", makeCallback([]byte("set tabsize 4\x17\x172\r")))
+	if got != "set 2" {
+		t.Fatalf("expected Ctrl-W to delete words in the prompt, got %q", got)
+	}
+}
+
+func TestSmartHome(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("x\n\t  if ok {\n")
+	GoToLine(2, 8)
+
+	// The first press stops after the indentation, the second at the start
+	for _, want := range []int{4, 1, 4} {
+		handleHome()
+		if session.cursorRow != 2 || session.cursorCol != want {
+			t.Errorf("Home went to %d:%d, want 2:%d", session.cursorRow, session.cursorCol, want)
+		}
+	}
+
+	// A line without indentation has nowhere else to go
+	GoToLine(1, 2)
+	handleHome()
+	handleHome()
+	if session.cursorCol != 1 {
+		t.Errorf("Home on an unindented line went to column %d", session.cursorCol)
+	}
+}
+
+func TestBufferStartEndAndSelectAll(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("one\ntwo\nthree")
+	GoToLine(2, 2)
+
+	ProcessKeypress(0, makeCallback([]byte("\x1b[1;5F\x11\x11")))
+	if session.cursorRow != 3 || session.cursorCol != 6 {
+		t.Errorf("Ctrl-End went to %d:%d, want 3:6", session.cursorRow, session.cursorCol)
+	}
+	ProcessKeypress(0, makeCallback([]byte("\x1b[1;5H\x11\x11")))
+	if session.cursorIdx != 0 {
+		t.Errorf("Ctrl-Home went to %d", session.cursorIdx)
+	}
+
+	ProcessKeypress(0, makeCallback([]byte{0x01, CtrlQ, CtrlQ}))
+	if start, end, ok := selection(); !ok || start != 0 || end != session.rope.Length() {
+		t.Fatalf("Ctrl-A selected %d-%d %v", start, end, ok)
+	}
+	ProcessKeypress(0, makeCallback([]byte("\x1b[1;5H\x11\x11")))
+	if _, _, ok := selection(); ok {
+		t.Error("Ctrl-Home should end the selection")
+	}
+}
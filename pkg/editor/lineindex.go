@@ -0,0 +1,78 @@
+package editor
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// lineIndex holds where every line of a rope starts, so that the cursor
+// position and the lines of a buffer are found without scanning its text
+type lineIndex struct {
+	rope   *buffer.Rope
+	starts []int
+}
+
+// lineStarts returns the start index of every line of the active buffer.
+// Edits keep the index up to date; it is rebuilt when the rope was
+// replaced some other way.
+func lineStarts() []int {
+	if session.lineIndex != nil && session.lineIndex.rope == session.rope {
+		return session.lineIndex.starts
+	}
+	text := session.rope.String()
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	session.lineIndex = &lineIndex{rope: session.rope, starts: starts}
+	return starts
+}
+
+// editLineIndex moves the line index of old, if there is one, to rope,
+// made from old by edits. Only the lines from the first edit on change.
+func editLineIndex(old, rope *buffer.Rope, edits []textEdit) {
+	index := session.lineIndex
+	if index == nil || index.rope != old {
+		return
+	}
+	starts := index.starts
+	for _, e := range edits {
+		// Lines starting after a removed newline go, those after an
+		// inserted one come, and the ones after the edit move
+		first := sort.SearchInts(starts, e.position+1)
+		last := sort.SearchInts(starts, e.position+len(e.removed)+1)
+		var added []int
+		for i := 0; i < len(e.inserted); i++ {
+			if e.inserted[i] == '\n' {
+				added = append(added, e.position+i+1)
+			}
+		}
+		starts = slices.Replace(starts, first, last, added...)
+		shift := len(e.inserted) - len(e.removed)
+		for i := first + len(added); i < len(starts); i++ {
+			starts[i] += shift
+		}
+	}
+	session.lineIndex = &lineIndex{rope: rope, starts: starts}
+}
+
+// lineBounds returns the byte range of the zero-based line row, without its newline
+func lineBounds(starts []int, row int) (start, end int) {
+	start = starts[row]
+	end = session.rope.Length()
+	if row+1 < len(starts) {
+		end = starts[row+1] - 1
+	}
+	return start, end
+}
+
+// indexPosition returns the 1-indexed row and column of a rope index
+func indexPosition(idx int) (row, col int) {
+	starts := lineStarts()
+	row = sort.Search(len(starts), func(i int) bool { return starts[i] > idx })
+	return row, idx - starts[row-1] + 1
+}
@@ -0,0 +1,49 @@
+package editor
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestEditsKeepLineIndex(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("one\ntwo\nthree\n")
+	lineStarts()
+
+	steps := []func(){
+		func() { session.cursorIdx = 2; handleInsert("X\nY") },
+		func() { deleteText(1, 9) },
+		func() { replaceText(0, 3, "a\n\nb") },
+		func() { session.cursorIdx = session.rope.Length(); handleBackspace() },
+		handleUndo,
+		handleUndo,
+		handleRedo,
+	}
+	for i, step := range steps {
+		step()
+		if session.lineIndex == nil || session.lineIndex.rope != session.rope {
+			t.Fatalf("step %d: the line index was not kept", i)
+		}
+		got := slices.Clone(session.lineIndex.starts)
+		session.lineIndex = nil
+		if want := lineStarts(); !slices.Equal(got, want) {
+			t.Fatalf("step %d on %q: line starts %v, want %v", i, session.rope.String(), got, want)
+		}
+	}
+}
+
+func TestUpdateCursorPosition(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("ab\ncde\n")
+	for _, tt := range []struct{ idx, row, col int }{
+		{0, 1, 1}, {2, 1, 3}, {3, 2, 1}, {6, 2, 4}, {7, 3, 1}, {99, 3, 1},
+	} {
+		session.cursorIdx = tt.idx
+		updateCursorPosition()
+		if session.cursorRow != tt.row || session.cursorCol != tt.col {
+			t.Errorf("index %d at %d:%d, want %d:%d", tt.idx, session.cursorRow, session.cursorCol, tt.row, tt.col)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package editor
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// loadChunk is how many bytes are read at a time from a file loaded in the
+// background, between two checks for canceling
+const loadChunk = 1 << 20
+
+// openLoad opens a file to read in the background, which tests hold back.
+// It is read once per load, before the reading goroutine starts.
+var openLoad = os.Open
+
+// spinnerFrames are drawn in turn in the status bar while a file loads
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// fileLoad is the progress of a file read in the background. Its counters
+// are shared with the reading goroutine.
+type fileLoad struct {
+	size      int64
+	read      atomic.Int64
+	canceled  atomic.Bool
+	line, col int           // where to put the cursor once loaded, 0 to leave it
+	done      chan struct{} // closed when the reading goroutine returns
+}
+
+// LoadsInBackground reports whether filename is a file large enough (see
+// :largefile) to be read in the background rather than before editing
+func LoadsInBackground(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && info.Mode().IsRegular() && info.Size() >= int64(largeFileThreshold)
+}
+
+// InitLoadingSession is InitSession for a file read in the background
+func InitLoadingSession(fd int, filename string, line, col int) {
+	session = newLoadingSession(filename, line, col)
+	buffers = []*Session{session}
+	rows, cols := getWindowSize(fd)
+	session.screenRows = rows
+	session.screenCols = cols
+	updateCursorPosition()
+}
+
+// AddLoadingBuffer is AddBuffer for a file read in the background
+func AddLoadingBuffer(filename string, line, col int) {
+	s := newLoadingSession(filename, line, col)
+	s.screenRows = session.screenRows
+	s.screenCols = session.screenCols
+	buffers = append(buffers, s)
+}
+
+// newLoadingSession creates an empty buffer for filename and starts reading
+// the file into it. BufOpen fires once the content is there.
+func newLoadingSession(filename string, line, col int) *Session {
+	s := newSession(filename, "")
+	s.loading = &fileLoad{line: line, col: col, done: make(chan struct{})}
+	if info, err := os.Stat(filename); err == nil {
+		s.loading.size = info.Size()
+	}
+	load, open := s.loading, openLoad
+	go func() {
+		defer close(load.done)
+		content, err := readFileChunks(open, filename, load)
+		if load.canceled.Load() {
+			return
+		}
+		post(func() { finishLoading(s, content, err) })
+	}()
+	return s
+}
+
+// readFileChunks reads filename opened with open, counting the bytes read
+// in load and stopping early when the load is canceled
+func readFileChunks(open func(string) (*os.File, error), filename string, load *fileLoad) (string, error) {
+	f, err := open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var content strings.Builder
+	content.Grow(int(load.size))
+	chunk := make([]byte, loadChunk)
+	for !load.canceled.Load() {
+		n, err := f.Read(chunk)
+		content.Write(chunk[:n])
+		load.read.Add(int64(n))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return content.String(), nil
+}
+
+// finishLoading puts the content read in the background into buffer s, on
+// the main loop
+func finishLoading(s *Session, content string, err error) {
+	load := s.loading
+	if load == nil {
+		return
+	}
+	s.loading = nil
+	if err != nil {
+		log.Printf("load %s: %v", s.filename, err)
+		s.statusMessage = fmt.Sprintf("Cannot load %s: %v", s.filename, err)
+		// Saving the empty buffer would wipe the file
+		s.readOnly = true
+		return
+	}
+	log.Printf("opened %s (%d bytes)", s.filename, len(content))
+
+	loaded := newSession(s.filename, content)
+	s.rope, s.seenRope, s.backupRope, s.savedRope = loaded.rope, loaded.seenRope, loaded.backupRope, loaded.savedRope
+	s.largeFile = loaded.largeFile
+	s.bom = loaded.bom
+	s.statusMessage = loaded.statusMessage
+	fireHooks(eventBufOpen, s)
+	if load.line != 0 {
+		s.cursorIdx = lineColumnIndex(content, load.line, load.col)
+	}
+	if s == session {
+		updateCursorPosition()
+	}
+}
+
+// cancelLoading stops loading the active buffer and closes it (Esc)
+func cancelLoading() {
+	s := session
+	s.loading.canceled.Store(true)
+	s.loading = nil
+	message := fmt.Sprintf("Loading %s canceled", filepath.Base(s.filename))
+	log.Print(message)
+	if len(buffers) == 1 {
+		// The editor keeps a buffer, which must not overwrite the file
+		session = newSession("[No Name]", "")
+		session.screenRows, session.screenCols = s.screenRows, s.screenCols
+		buffers = []*Session{session}
+	} else {
+		i := indexOfBuffer(s)
+		buffers = append(buffers[:i], buffers[i+1:]...)
+		switchToBuffer(buffers[min(i, len(buffers)-1)])
+	}
+	session.statusMessage = message
+}
+
+// loadingStatus describes the progress of loading the active buffer, with
+// a spinner that turns as time passes
+func loadingStatus(now time.Time) string {
+	load := session.loading
+	frame := spinnerFrames[now.UnixMilli()/100%int64(len(spinnerFrames))]
+	progress := fmt.Sprintf("%d MB", load.read.Load()>>20)
+	if load.size > 0 {
+		progress = fmt.Sprintf("%d%%", load.read.Load()*100/load.size)
+	}
+	return fmt.Sprintf("%c Loading %s %s | Esc:Cancel", frame, filepath.Base(session.filename), progress)
+}
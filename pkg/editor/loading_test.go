@@ -0,0 +1,85 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadInBackground(t *testing.T) {
+	useLargeFileThreshold(t, 10)
+	resetSessionForTest()
+	path := filepath.Join(t.TempDir(), "big.txt")
+	content := strings.Repeat("line\n", 100)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	handleEdit(0, path+":3", nil)
+	if session.loading == nil || session.filename != path {
+		t.Fatal("expected the file to load in the background")
+	}
+	handleInsert("x")
+	if session.rope.Length() != 0 || !strings.Contains(session.statusMessage, "loading") {
+		t.Errorf("edits should wait for the file, status %q", session.statusMessage)
+	}
+	if status := loadingStatus(time.Now()); !strings.Contains(status, "Loading big.txt") {
+		t.Errorf("unexpected status %q", status)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); session.loading != nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("the file did not finish loading")
+		}
+		runPosted()
+		time.Sleep(time.Millisecond)
+	}
+	if session.rope.String() != content || !session.largeFile {
+		t.Errorf("loaded %d bytes, large file %v", session.rope.Length(), session.largeFile)
+	}
+	if session.cursorRow != 3 {
+		t.Errorf("expected the cursor on line 3, got %d", session.cursorRow)
+	}
+}
+
+func TestCancelLoading(t *testing.T) {
+	useLargeFileThreshold(t, 10)
+	resetSessionForTest()
+	first := session
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is read from a pipe that gets no data, so that it is still
+	// loading when Esc comes
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	openLoad = func(string) (*os.File, error) { return r, nil }
+	t.Cleanup(func() { openLoad = os.Open })
+
+	if err := openBuffer(path); err != nil {
+		t.Fatal(err)
+	}
+	load := session.loading
+	ProcessKeypress(0, makeCallback([]byte{Esc, 0, CtrlQ, CtrlQ}))
+	if session != first || len(buffers) != 1 {
+		t.Fatalf("expected the loading buffer closed, %d buffers left", len(buffers))
+	}
+	w.Write([]byte("late"))
+	w.Close()
+	select {
+	case <-load.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the canceled load did not stop")
+	}
+	runPosted()
+	if session.rope.Length() != 0 {
+		t.Error("a canceled load filled a buffer")
+	}
+}
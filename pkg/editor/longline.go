@@ -0,0 +1,70 @@
+package editor
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// longLineThreshold is the length in bytes from which a single line, as in
+// minified code or JSON, makes its buffer open in large-file mode: splitting
+// and highlighting such a line on every key would freeze the editor
+var longLineThreshold = 64 << 10
+
+// longLineNotice tells the user why a small file is in large-file mode
+var longLineNotice = "Long lines: " + strings.TrimPrefix(largeFileNotice, "Large file: ")
+
+// hasLongLine reports whether a line of content is longLineThreshold bytes
+// or longer
+func hasLongLine(content string) bool {
+	for len(content) >= longLineThreshold {
+		i := strings.IndexByte(content, '\n')
+		if i < 0 || i >= longLineThreshold {
+			return true
+		}
+		content = content[i+1:]
+	}
+	return false
+}
+
+// visiblePart returns the start of line that is drawn in the first cols
+// screen columns, scanning no further: the rest is off screen. It ends on
+// a character boundary. A width of 0, not known yet, keeps the whole line.
+func visiblePart(line string, cols int) string {
+	if cols <= 0 {
+		return line
+	}
+	visual, tab := 0, session.tabWidth()
+	i := 0
+	for ; i < len(line) && visual < cols; i++ {
+		switch {
+		case line[i] == '\t':
+			visual += tab - visual%tab
+		case line[i]&0xC0 != 0x80:
+			visual++
+		}
+	}
+	// Keep the continuation bytes of the last character
+	for i < len(line) && line[i]&0xC0 == 0x80 {
+		i++
+	}
+	return line[:i]
+}
+
+// visibleBytes bounds how many bytes of a line can be on a screen of cols
+// columns: every character takes at least a column, and at most
+// utf8.UTFMax bytes
+func visibleBytes(cols int) int {
+	if cols <= 0 {
+		return math.MaxInt
+	}
+	return (cols + 1) * utf8.UTFMax
+}
+
+// clampColumn keeps a zero-based screen column on a screen of cols columns
+func clampColumn(col, cols int) int {
+	if cols <= 0 {
+		return col
+	}
+	return min(col, cols-1)
+}
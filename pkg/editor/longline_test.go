@@ -0,0 +1,63 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewSessionLongLine(t *testing.T) {
+	old := longLineThreshold
+	longLineThreshold = 10
+	t.Cleanup(func() { longLineThreshold = old })
+
+	if s := newSession("short.txt", "short\nlines\n"); s.largeFile {
+		t.Error("short lines should not put a file in large-file mode")
+	}
+	s := newSession("min.js", "a\nvar a=1;var b=2;var c=3;\n")
+	if !s.largeFile {
+		t.Fatal("a line over the threshold should put the file in large-file mode")
+	}
+	if s.statusMessage != longLineNotice {
+		t.Errorf("status %q, want the long-line notice", s.statusMessage)
+	}
+}
+
+func TestVisiblePart(t *testing.T) {
+	resetSessionForTest()
+	tests := []struct {
+		line string
+		cols int
+		want string
+	}{
+		{"abcdef", 4, "abcd"},
+		{"abc", 10, "abc"},
+		{"abcdef", 0, "abcdef"},
+		{"héllo", 2, "hé"},
+		{"\tabc", 9, "\ta"},
+	}
+	for _, tt := range tests {
+		if got := visiblePart(tt.line, tt.cols); got != tt.want {
+			t.Errorf("visiblePart(%q, %d) = %q, want %q", tt.line, tt.cols, got, tt.want)
+		}
+	}
+}
+
+func TestDrawLargeTextViewLongLine(t *testing.T) {
+	resetSessionForTest()
+	useLargeFileThreshold(t, 1)
+	session = newSession("min.js", strings.Repeat("x", 10000)+"\nend")
+	buffers = []*Session{session}
+	session.screenCols = 20
+	session.cursorIdx = 5000
+	updateCursorPosition()
+
+	var buf strings.Builder
+	row, col := drawLargeTextView(&buf, 2)
+	lines := strings.Split(buf.String(), "\x1b[K\r\n")
+	if lines[0] != strings.Repeat("x", 20) || lines[1] != "end" {
+		t.Errorf("drew %q, want the first 20 columns of each line", lines)
+	}
+	if row != 1 || col != 20 {
+		t.Errorf("cursor at %d,%d want 1,20", row, col)
+	}
+}
@@ -0,0 +1,218 @@
+package editor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	"github.com/jellexet/golang-text-editor/pkg/lsp"
+)
+
+// languageServer describes how to start the server for a file type
+type languageServer struct {
+	command    []string
+	languageID string
+}
+
+// languageServers maps a file extension to its language server
+var languageServers = map[string]languageServer{
+	".go": {command: []string{"gopls"}, languageID: "go"},
+}
+
+// lspClients holds the running language servers, keyed by file extension
+var lspClients = map[string]*lsp.Client{}
+
+// lspClient returns the language server for the active buffer,
+// starting it on first use
+func lspClient() (*lsp.Client, error) {
+	ext := "." + session.fileType()
+	server, ok := languageServers[ext]
+	if !ok {
+		return nil, fmt.Errorf("no language server for %q files", ext)
+	}
+	if client, ok := lspClients[ext]; ok {
+		return client, nil
+	}
+
+	client, err := lsp.Start(server.command[0], server.command[1:]...)
+	if err != nil {
+		log.Printf("start language server %s: %v", server.command[0], err)
+		return nil, err
+	}
+	log.Printf("started language server %s", server.command[0])
+	root, err := os.Getwd()
+	if err != nil {
+		root = filepath.Dir(session.filename)
+	}
+	if err := client.Initialize(root); err != nil {
+		client.Close()
+		return nil, err
+	}
+	lspClients[ext] = client
+	return client, nil
+}
+
+// lspSync sends the current content of the active buffer to the server.
+// The server only knows what we tell it, so this runs before every request.
+func lspSync(client *lsp.Client) error {
+	text := session.rope.String()
+	session.lspVersion++
+	if session.lspVersion == 1 {
+		languageID := languageServers["."+session.fileType()].languageID
+		return client.DidOpen(session.filename, languageID, session.lspVersion, text)
+	}
+	return client.DidChange(session.filename, session.lspVersion, text)
+}
+
+// stopLanguageServers shuts down every running language server
+func stopLanguageServers() {
+	for ext, client := range lspClients {
+		client.Close()
+		delete(lspClients, ext)
+	}
+}
+
+// cursorLSPPosition returns the cursor as a zero-based line and UTF-16 offset
+func cursorLSPPosition() lsp.Position {
+	lines := getLines()
+	line := ""
+	if session.cursorRow-1 < len(lines) {
+		line = lines[session.cursorRow-1]
+	}
+	col := min(session.cursorCol-1, len(line))
+
+	character := 0
+	for _, r := range line[:col] {
+		character += utf16.RuneLen(r)
+	}
+	return lsp.Position{Line: session.cursorRow - 1, Character: character}
+}
+
+// lspPositionToIndex converts a server position into a rope index of the active buffer
+func lspPositionToIndex(pos lsp.Position) int {
+	lines := getLines()
+	if pos.Line >= len(lines) {
+		return session.rope.Length()
+	}
+
+	line := lines[pos.Line]
+	offset := len(line)
+	units := 0
+	for i, r := range line {
+		if units >= pos.Character {
+			offset = i
+			break
+		}
+		units += utf16.RuneLen(r)
+	}
+	return getLineStartIndex(pos.Line+1) + offset
+}
+
+// jumpToLSPLocation opens the file of loc and places the cursor at its start
+func jumpToLSPLocation(loc lsp.Location) error {
+	if err := openBuffer(displayPath(lsp.URIToPath(loc.URI))); err != nil {
+		return err
+	}
+	session.cursorIdx = lspPositionToIndex(loc.Range.Start)
+	updateCursorPosition()
+	return nil
+}
+
+// requestLocations syncs the active buffer and runs a definition-like request
+// for the symbol under the cursor
+func requestLocations(request func(*lsp.Client, string, lsp.Position) ([]lsp.Location, error)) ([]lsp.Location, error) {
+	client, err := lspClient()
+	if err != nil {
+		return nil, err
+	}
+	if err := lspSync(client); err != nil {
+		return nil, err
+	}
+	return request(client, session.filename, cursorLSPPosition())
+}
+
+// handleGoToDefinition jumps to the definition of the symbol under the cursor (Ctrl-]).
+// When no language server is available the tags file is used instead.
+func handleGoToDefinition(fd int, callback func() byte) {
+	locations, err := requestLocations((*lsp.Client).Definition)
+	if err != nil {
+		if name := identifierAtCursor(); name != "" && goToTag(fd, name, callback) {
+			return
+		}
+		session.statusMessage = fmt.Sprintf("Definition: %v", err)
+		return
+	}
+	if len(locations) == 0 {
+		session.statusMessage = "No definition found"
+		return
+	}
+
+	pushJump()
+	if err := jumpToLSPLocation(locations[0]); err != nil {
+		session.statusMessage = fmt.Sprintf("Definition: %v", err)
+		return
+	}
+	session.statusMessage = fmt.Sprintf("Definition at %s:%d (Ctrl-T to go back)", session.filename, session.cursorRow)
+}
+
+// handleFindReferences cycles through the references of the symbol under the cursor,
+// the same way search cycles through its matches
+func handleFindReferences(fd int, args string, callback func() byte) {
+	locations, err := requestLocations((*lsp.Client).References)
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("References: %v", err)
+		return
+	}
+	if len(locations) == 0 {
+		session.statusMessage = "No references found"
+		return
+	}
+
+	pushJump()
+	for i, loc := range locations {
+		if err := jumpToLSPLocation(loc); err != nil {
+			session.statusMessage = fmt.Sprintf("References: %v", err)
+			return
+		}
+		if i == len(locations)-1 {
+			session.statusMessage = fmt.Sprintf("Reference %d/%d (Ctrl-T to go back)", i+1, len(locations))
+			return
+		}
+
+		session.statusMessage = fmt.Sprintf("Ctrl-n to next %d/%d", i+1, len(locations))
+		refreshScreen(fd)
+
+		key := editorReadKeypress(callback)
+		for key == (Key{}) {
+			key = editorReadKeypress(callback)
+		}
+		if key != ctrl('n') {
+			return
+		}
+	}
+}
+
+// handleHover shows the documentation of the symbol under the cursor in an
+// overlay, which Up and Down scroll and any other key closes (Ctrl-G)
+func handleHover(fd int, callback func() byte) {
+	client, err := lspClient()
+	if err == nil {
+		err = lspSync(client)
+	}
+	var text string
+	if err == nil {
+		text, err = client.Hover(session.filename, cursorLSPPosition())
+	}
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Hover: %v", err)
+		return
+	}
+	if text == "" {
+		session.statusMessage = "No documentation found"
+		return
+	}
+
+	newTextOverlay("", text, placeNearCursor).run(fd, callback, nil)
+}
@@ -0,0 +1,240 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Lua plugins run in one interpreter, on the main loop like keys, through
+// the functions of the global table "editor":
+//
+//	text(), set_text(s)          the active buffer, set in one undo step
+//	insert(s)                    text typed at the cursor
+//	selection()                  the selected text, nil without one
+//	replace_selection(s)
+//	cursor(), set_cursor(l, c)   1-indexed line and byte column
+//	filename(), message(s)       message shows s in the status line
+//	prompt(question)             what the user typed, nil on Esc
+//	run(command)                 a command of the command prompt
+//	command(name, fn)            fn(args) runs for :name args
+//	bind(key, fn)                fn() runs for key, named like "Ctrl-Y"
+//	on(event, fn)                fn(filename) runs on "open", "presave",
+//	                             "save", "change" and "cursor"; an error
+//	                             in "presave" cancels the save
+
+// luaState is the interpreter of the Lua plugins, nil until one is loaded
+var luaState *lua.LState
+
+// luaPlugin is the plugin file being loaded, which bound keys are shown for
+var luaPlugin string
+
+// pluginInput is where prompts read keys while a command or key of a
+// plugin runs. Hooks have no callback, so they can't prompt.
+var pluginInput struct {
+	fd       int
+	callback func() byte
+}
+
+// luaEvents are the events Lua plugins can attach to, by name
+var luaEvents = map[string]event{
+	"open":    eventBufOpen,
+	"presave": eventBufWritePre,
+	"save":    eventBufWritePost,
+	"change":  eventTextChanged,
+	"cursor":  eventCursorMoved,
+}
+
+// luaRuntime returns the interpreter, creating it with the editor API
+func luaRuntime() *lua.LState {
+	if luaState == nil {
+		luaState = lua.NewState()
+		luaState.SetGlobal("editor", luaState.SetFuncs(luaState.NewTable(), luaAPI))
+	}
+	return luaState
+}
+
+// closeLua stops the interpreter
+func closeLua() {
+	if luaState != nil {
+		luaState.Close()
+		luaState = nil
+	}
+}
+
+// loadLuaPlugin runs the Lua file at path, which registers what it adds
+func loadLuaPlugin(path string) error {
+	L := luaRuntime()
+	luaPlugin = filepath.Base(path)
+	defer func() { luaPlugin = "" }()
+	return luaError(L.DoFile(path))
+}
+
+// callLua calls fn with args
+func callLua(fn *lua.LFunction, args ...lua.LValue) error {
+	return luaError(luaRuntime().CallByParam(lua.P{Fn: fn, Protect: true}, args...))
+}
+
+// luaError is err without the stack trace, which doesn't fit the status line
+func luaError(err error) error {
+	if apiErr, ok := err.(*lua.ApiError); ok && apiErr.Object != nil {
+		return errors.New(apiErr.Object.String())
+	}
+	return err
+}
+
+// withPluginInput runs f with prompts reading keys from callback
+func withPluginInput(fd int, callback func() byte, f func()) {
+	saved := pluginInput
+	pluginInput.fd, pluginInput.callback = fd, callback
+	defer func() { pluginInput = saved }()
+	f()
+}
+
+// luaAPI are the functions of the editor table. It is filled in init, as
+// its functions call Lua back.
+var luaAPI map[string]lua.LGFunction
+
+func init() {
+	luaAPI = map[string]lua.LGFunction{
+		"text": func(L *lua.LState) int {
+			L.Push(lua.LString(session.rope.String()))
+			return 1
+		},
+		"set_text": func(L *lua.LState) int {
+			text := L.CheckString(1)
+			ok := editable()
+			if ok && text != session.rope.String() {
+				applyTextPreservingCursor(text)
+			}
+			L.Push(lua.LBool(ok))
+			return 1
+		},
+		"insert": func(L *lua.LState) int {
+			handleInsert(L.CheckString(1))
+			return 0
+		},
+		"selection": func(L *lua.LState) int {
+			start, end, selected := selection()
+			if !selected {
+				L.Push(lua.LNil)
+				return 1
+			}
+			text, _ := session.rope.Substring(start, end)
+			L.Push(lua.LString(text))
+			return 1
+		},
+		"replace_selection": func(L *lua.LState) int {
+			text := L.CheckString(1)
+			start, end, selected := selection()
+			if !selected {
+				L.Push(lua.LFalse)
+				return 1
+			}
+			rope := session.rope
+			replaceText(start, end, text)
+			if session.rope != rope {
+				selectRange(start, start+len(text))
+			}
+			L.Push(lua.LBool(session.rope != rope))
+			return 1
+		},
+		"cursor": func(L *lua.LState) int {
+			L.Push(lua.LNumber(session.cursorRow))
+			L.Push(lua.LNumber(session.cursorIdx - getLineStartIndex(session.cursorRow) + 1))
+			return 2
+		},
+		"set_cursor": func(L *lua.LState) int {
+			GoToLine(L.CheckInt(1), L.OptInt(2, 1))
+			return 0
+		},
+		"filename": func(L *lua.LState) int {
+			L.Push(lua.LString(session.filename))
+			return 1
+		},
+		"message": func(L *lua.LState) int {
+			session.statusMessage = L.CheckString(1)
+			return 0
+		},
+		"prompt": func(L *lua.LState) int {
+			question := L.CheckString(1)
+			if pluginInput.callback == nil {
+				L.RaiseError("editor.prompt: no prompt in hooks")
+			}
+			input, ok := readPrompt(question, nil, pluginInput.callback)
+			if !ok {
+				L.Push(lua.LNil)
+				return 1
+			}
+			L.Push(lua.LString(input))
+			return 1
+		},
+		"run": func(L *lua.LState) int {
+			line := L.CheckString(1)
+			callback := pluginInput.callback
+			if callback == nil {
+				callback = declineKeys()
+			}
+			runCommand(pluginInput.fd, line, callback)
+			return 0
+		},
+		"command": func(L *lua.LState) int {
+			name, fn := L.CheckString(1), L.CheckFunction(2)
+			err := addPluginCommand(name, func(fd int, args string, callback func() byte) {
+				withPluginInput(fd, callback, func() {
+					if err := callLua(fn, lua.LString(args)); err != nil {
+						session.statusMessage = fmt.Sprintf("%s: %v", name, err)
+					}
+				})
+			})
+			if err != nil {
+				L.RaiseError("editor.command: %v", err)
+			}
+			return 0
+		},
+		"bind": func(L *lua.LState) int {
+			name, fn := L.CheckString(1), L.CheckFunction(2)
+			err := bindPluginKey(luaPlugin, name, func(fd int, callback func() byte) {
+				withPluginInput(fd, callback, func() {
+					if err := callLua(fn); err != nil {
+						session.statusMessage = fmt.Sprintf("%s: %v", name, err)
+					}
+				})
+			})
+			if err != nil {
+				L.RaiseError("editor.bind: %v", err)
+			}
+			return 0
+		},
+		"on": func(L *lua.LState) int {
+			name, fn := L.CheckString(1), L.CheckFunction(2)
+			ev, ok := luaEvents[name]
+			if !ok {
+				L.RaiseError("editor.on: unknown event %q", name)
+			}
+			pluginHooks[ev] = append(pluginHooks[ev], func(s *Session) error {
+				// The API acts on the active buffer, which s is for the hook
+				active := session
+				session = s
+				defer func() { session = active }()
+				return callLua(fn, lua.LString(s.filename))
+			})
+			return 0
+		},
+	}
+}
+
+// handleLua runs a line of Lua, with the API of plugins (:lua CODE)
+func handleLua(fd int, args string, callback func() byte) {
+	if args == "" {
+		session.statusMessage = "Usage: lua CODE"
+		return
+	}
+	withPluginInput(fd, callback, func() {
+		if err := luaError(luaRuntime().DoString(args)); err != nil {
+			session.statusMessage = fmt.Sprintf("Lua: %v", err)
+		}
+	})
+}
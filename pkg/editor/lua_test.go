@@ -0,0 +1,119 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadTestPlugin loads a Lua plugin made of code, unloaded after the test
+func loadTestPlugin(t *testing.T, code string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "test.lua"), []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := pluginDir
+	pluginDir = dir
+	t.Cleanup(func() {
+		pluginDir = oldDir
+		unloadPlugins()
+	})
+	if err := LoadPlugins(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLuaPluginCommandAndKey(t *testing.T) {
+	resetSessionForTest()
+	InitSession(-1, "notes.txt", "")
+	loadTestPlugin(t, `
+editor.command("shout", function(args)
+	editor.set_text(string.upper(editor.text()) .. args)
+	editor.message("shouted")
+end)
+editor.bind("Ctrl-Y", function()
+	local line, col = editor.cursor()
+	editor.insert("[" .. line .. ":" .. col .. "]")
+end)
+`)
+
+	runCommand(-1, "shout !", declineKeys())
+	if got := session.rope.String(); got != "!" || session.statusMessage != "shouted" {
+		t.Errorf("text %q, status %q", got, session.statusMessage)
+	}
+	playKeys(t, "ab\x19")
+	if got := session.rope.String(); got != "ab[1:3]!" {
+		t.Errorf("after Ctrl-Y: %q", got)
+	}
+	if got := keyAction(ctrl('y')); got != "plugin test.lua" {
+		t.Errorf("Ctrl-Y shown as %q", got)
+	}
+	handlePlugins(-1, "", nil)
+	if session.statusMessage != "Plugins: test.lua | commands: shout" {
+		t.Errorf("plugins: %q", session.statusMessage)
+	}
+
+	unloadPlugins()
+	if _, ok := commands["shout"]; ok {
+		t.Error("the command of the plugin is left after unloading it")
+	}
+}
+
+func TestLuaPluginErrors(t *testing.T) {
+	resetSessionForTest()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.lua"), []byte(`editor.command("w", function() end)`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := pluginDir
+	pluginDir = dir
+	defer func() {
+		pluginDir = oldDir
+		unloadPlugins()
+	}()
+	err := LoadPlugins()
+	if err == nil || !strings.HasPrefix(err.Error(), "plugin bad.lua:") || !strings.Contains(err.Error(), "command w already exists") {
+		t.Errorf("replacing a command: %v", err)
+	}
+
+	handleLua(-1, "error('boom')", nil)
+	if !strings.HasPrefix(session.statusMessage, "Lua: ") || !strings.HasSuffix(session.statusMessage, "boom") ||
+		strings.Contains(session.statusMessage, "\n") {
+		t.Errorf("status %q", session.statusMessage)
+	}
+	handleLua(-1, "editor.on('nope', print)", nil)
+	if !strings.Contains(session.statusMessage, `unknown event "nope"`) {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
+
+func TestLuaPluginPresaveHook(t *testing.T) {
+	resetSessionForTest()
+	path := filepath.Join(t.TempDir(), "out.txt")
+	InitSession(-1, path, "")
+	loadTestPlugin(t, `
+editor.on("presave", function(name)
+	if string.find(editor.text(), "TODO") then
+		error("TODO left in " .. name)
+	end
+	editor.set_text(editor.text() .. "\n")
+end)
+`)
+
+	replaceText(0, 0, "TODO")
+	handleSave(-1, nil)
+	if _, err := os.Stat(path); err == nil {
+		t.Error("the file was saved although the hook failed")
+	}
+	if !strings.HasPrefix(session.statusMessage, "Save aborted:") || !strings.HasSuffix(session.statusMessage, "TODO left in "+path) {
+		t.Errorf("status %q", session.statusMessage)
+	}
+
+	replaceText(0, session.rope.Length(), "done")
+	handleSave(-1, nil)
+	if data, err := os.ReadFile(path); err != nil || string(data) != "done\n" {
+		t.Errorf("saved %q, %v", data, err)
+	}
+}
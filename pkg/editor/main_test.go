@@ -0,0 +1,25 @@
+package editor
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain runs the tests as outside a multiplexer, whatever terminal runs
+// them, for keys to time out and colors to be drawn the same everywhere
+func TestMain(m *testing.M) {
+	multiplexer, keyTimeout, trueColor = "", readTimeout, true
+	// Templates of the user would fill the new files of the tests
+	templateDir = ""
+	pluginDir = ""
+	// Opening a file restores its cursor position and closing it remembers
+	// it, like the prompt histories: keep them away from the user's own
+	state, err := os.MkdirTemp("", "goedit-state")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_STATE_HOME", state)
+	code := m.Run()
+	os.RemoveAll(state)
+	os.Exit(code)
+}
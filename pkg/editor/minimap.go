@@ -0,0 +1,101 @@
+package editor
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minimapEnabled shows a compressed view of the buffer on the right of the
+// text (:set minimap on)
+var minimapEnabled = false
+
+const (
+	minimapCells    = 10 // columns of blocks in the minimap
+	minimapCellCols = 8  // text columns each block stands for
+	minimapMinText  = 20 // text columns left before the minimap is hidden
+)
+
+// minimapWidth returns how many screen columns the minimap takes,
+// separator included, or 0 when it isn't shown
+func minimapWidth() int {
+	if !minimapEnabled || int(session.screenCols) < minimapCells+1+minimapMinText {
+		return 0
+	}
+	return minimapCells + 1
+}
+
+// minimapLinesPerBlock returns how many lines each half of a minimap row
+// stands for so that the whole buffer fits in textRows rows
+func minimapLinesPerBlock(lineCount, textRows int) int {
+	halves := 2 * max(textRows, 1)
+	return max((lineCount+halves-1)/halves, 1)
+}
+
+// lineCells returns a bit per minimap cell, set when the cell's columns
+// of line hold something other than blanks
+func lineCells(line string) uint {
+	var cells uint
+	col, tab := 0, session.tabWidth()
+	for _, r := range line {
+		if r == '\t' {
+			col += tab - col%tab
+			continue
+		}
+		if !unicode.IsSpace(r) && col/minimapCellCols < minimapCells {
+			cells |= 1 << (col / minimapCellCols)
+		}
+		col++
+	}
+	return cells
+}
+
+// minimapRows renders textRows rows of the minimap of lines. Rows showing
+// the lines from first up to last (excluded) are marked as the viewport.
+func minimapRows(lines []string, textRows, first, last int) []string {
+	per := minimapLinesPerBlock(len(lines), textRows)
+	// cells of each half row: the union of the lines it stands for
+	halfCells := func(half int) uint {
+		var cells uint
+		for l := half * per; l < (half+1)*per && l < len(lines); l++ {
+			cells |= lineCells(lines[l])
+		}
+		return cells
+	}
+
+	rows := make([]string, textRows)
+	for row := range rows {
+		top, bottom := halfCells(2*row), halfCells(2*row+1)
+		var b strings.Builder
+		for c := 0; c < minimapCells; c++ {
+			bit := uint(1) << c
+			switch {
+			case top&bit != 0 && bottom&bit != 0:
+				b.WriteString("█")
+			case top&bit != 0:
+				b.WriteString("▀")
+			case bottom&bit != 0:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+
+		style := currentTheme.minimap
+		rowFirst, rowLast := 2*row*per, (2*row+2)*per
+		if rowFirst < len(lines) && rowFirst < last && rowLast > first {
+			style += ";" + currentTheme.minimapViewport
+		}
+		rows[row] = "\x1b[" + style + "m" + b.String() + "\x1b[m"
+	}
+	return rows
+}
+
+// minimapLine returns the line (0-based) at the top of minimap row (0-based),
+// or -1 when the row is past the end of the buffer
+func minimapLine(row, lineCount, textRows int) int {
+	line := 2 * row * minimapLinesPerBlock(lineCount, textRows)
+	if row < 0 || line >= lineCount {
+		return -1
+	}
+	return line
+}
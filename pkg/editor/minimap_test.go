@@ -0,0 +1,77 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestLineCells(t *testing.T) {
+	for line, want := range map[string]uint{
+		"":                       0,
+		"        ":               0,
+		"x":                      1,
+		"\tx":                    1 << 1,
+		"12345678        x":      1 | 1<<2,
+		strings.Repeat("x", 200): 1<<minimapCells - 1,
+	} {
+		if got := lineCells(line); got != want {
+			t.Errorf("lineCells(%q) = %b, want %b", line, got, want)
+		}
+	}
+}
+
+func TestMinimapRows(t *testing.T) {
+	resetSessionForTest()
+	// Two lines per row: the first row has a full and an empty line
+	lines := []string{"x", "", "x", "x", "", ""}
+	rows := minimapRows(lines, 4, 2, 4)
+	viewport := ";" + currentTheme.minimapViewport + "m"
+
+	for i, want := range []string{"▀", "█", " ", " "} {
+		if !strings.Contains(rows[i], want+strings.Repeat(" ", minimapCells-1)) {
+			t.Errorf("row %d = %q, want a %q block", i, rows[i], want)
+		}
+		if marked := strings.Contains(rows[i], viewport); marked != (i == 1) {
+			t.Errorf("row %d: viewport marked %v", i, marked)
+		}
+	}
+
+	// A longer buffer is compressed to fit
+	if got := minimapLinesPerBlock(100, 10); got != 5 {
+		t.Errorf("expected 5 lines per block, got %d", got)
+	}
+	if got := minimapLine(3, 100, 10); got != 30 {
+		t.Errorf("row 3 should start at line 30, got %d", got)
+	}
+	if got := minimapLine(3, 6, 4); got != -1 {
+		t.Errorf("rows past the end of the buffer have no line, got %d", got)
+	}
+}
+
+func TestDrawTextViewWithMinimap(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	session.rope = buffer.NewRope(strings.Repeat("some text\n", 10))
+
+	var plain strings.Builder
+	drawTextView(&plain, 5)
+	if strings.Contains(plain.String(), "│") {
+		t.Fatal("the minimap is off by default")
+	}
+
+	runCommand(0, "set minimap on", nil)
+	var buf strings.Builder
+	drawTextView(&buf, 5)
+	if got := strings.Count(buf.String(), "\x1b[70G│"); got != 5 {
+		t.Errorf("expected the minimap on every row at column 70, got %d rows", got)
+	}
+
+	session.screenCols = 25
+	buf.Reset()
+	drawTextView(&buf, 5)
+	if strings.Contains(buf.String(), "│") {
+		t.Error("the minimap should be hidden when the screen is too narrow")
+	}
+}
@@ -0,0 +1,105 @@
+package editor
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MouseEvent is the key read when a mouse button is pressed or released
+// with mouse reporting on; lastMouse tells where
+var MouseEvent = Key{Special: KeyMouse}
+
+// mouseState is a button press or release reported by the terminal
+type mouseState struct {
+	button  int // 0 left, 1 middle, 2 right, 64 and 65 the wheel
+	row     int // 1-indexed screen position
+	col     int
+	release bool
+}
+
+// lastMouse is the mouse event last read
+var lastMouse mouseState
+
+// mouseEnabled turns on mouse reporting (:set mouse on). It is off by
+// default, since the terminal then leaves selecting text to the editor.
+var mouseEnabled bool
+
+// mouseShown is whether the terminal was last told to report the mouse
+var mouseShown bool
+
+// mouseUpdate returns the sequence turning mouse reporting on or off, in
+// SGR mode (\x1b[?1006h) for positions past column 223, when the option
+// changed since the last frame
+func mouseUpdate() string {
+	if mouseEnabled == mouseShown {
+		return ""
+	}
+	mouseShown = mouseEnabled
+	if mouseEnabled {
+		return "\x1b[?1000h\x1b[?1006h"
+	}
+	return "\x1b[?1006l\x1b[?1000l"
+}
+
+// decodeMouse reads an SGR mouse report, \x1b[<b;col;rowM for a press and
+// with m for a release, into lastMouse
+func decodeMouse(seq string) bool {
+	params, ok := strings.CutPrefix(seq, "\x1b[<")
+	if !ok || len(params) < 1 {
+		return false
+	}
+	final := params[len(params)-1]
+	fields := strings.Split(params[:len(params)-1], ";")
+	if (final != 'M' && final != 'm') || len(fields) != 3 {
+		return false
+	}
+	var n [3]int
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return false
+		}
+		n[i] = v
+	}
+	// Bits 4, 8 and 16 of the button are Shift, Alt and Ctrl
+	lastMouse = mouseState{button: n[0] &^ 28, col: n[1], row: n[2], release: final == 'm'}
+	return true
+}
+
+// handleMouse acts on a click: on the status bar, the segment clicked
+// does what statusClicks says; on the minimap, the cursor jumps to the
+// lines of the row clicked
+func handleMouse(fd int, callback func() byte) {
+	if lastMouse.release || lastMouse.button != 0 {
+		return
+	}
+	if lastMouse.row != int(session.screenRows)-statusRows()+1 {
+		clickMinimap(lastMouse.row, lastMouse.col)
+		return
+	}
+	if click := statusClicks[statusSegmentAt(lastMouse.col)]; click != nil {
+		click(fd, callback)
+	}
+}
+
+// clickMinimap moves the cursor to the first line of the minimap row at the
+// screen position row, col, when the minimap is drawn there
+func clickMinimap(row, col int) {
+	mapWidth := minimapWidth()
+	if mapWidth == 0 || len(windows) > 0 || session.hex != nil || session.table != nil ||
+		compare.shows(session) || session.largeFile {
+		return
+	}
+	textRows := int(session.screenRows) - statusRows() - terminalRows()
+	// The first column of the minimap is its border
+	end := int(session.screenCols) - scrollbarWidth()
+	if row < 1 || row > textRows || col <= end-mapWidth+1 || col > end {
+		return
+	}
+	line := minimapLine(row-1, len(getLines()), textRows)
+	if line < 0 {
+		return
+	}
+	pushJump()
+	GoToLine(line+1, 1)
+}
@@ -0,0 +1,132 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestDecodeMouse(t *testing.T) {
+	for seq, want := range map[string]mouseState{
+		"\x1b[<0;12;3M":  {button: 0, col: 12, row: 3},
+		"\x1b[<0;12;3m":  {button: 0, col: 12, row: 3, release: true},
+		"\x1b[<18;1;40M": {button: 2, col: 1, row: 40},
+		"\x1b[<64;5;5M":  {button: 64, col: 5, row: 5},
+	} {
+		key, ok := decodeSequence(seq)
+		if !ok || key != MouseEvent || lastMouse != want {
+			t.Errorf("decodeSequence(%q) = %v %v, mouse %+v", seq, key, ok, lastMouse)
+		}
+	}
+	for _, seq := range []string{"\x1b[<0;12M", "\x1b[<a;1;1M", "\x1b[<0;1;1~"} {
+		if key, ok := decodeSequence(seq); ok && key == MouseEvent {
+			t.Errorf("%q should not be a mouse event", seq)
+		}
+	}
+}
+
+func TestMouseUpdate(t *testing.T) {
+	t.Cleanup(func() { mouseEnabled, mouseShown = false, false })
+	if mouseUpdate() != "" {
+		t.Error("nothing to send while the option is off")
+	}
+	mouseEnabled = true
+	if got := mouseUpdate(); got != "\x1b[?1000h\x1b[?1006h" || mouseUpdate() != "" {
+		t.Errorf("turning reporting on sends %q, once", got)
+	}
+	mouseEnabled = false
+	if got := mouseUpdate(); got != "\x1b[?1006l\x1b[?1000l" {
+		t.Errorf("turning reporting off sends %q", got)
+	}
+}
+
+func TestStatusSegmentAt(t *testing.T) {
+	resetSessionForTest()
+	useStatusLine(t, "file,position")
+	session.filename = "a.txt"
+	statusBarText() // File: a.txt | Row:1 Col:1
+	for col, want := range map[int]string{1: "file", 11: "file", 12: "", 15: "position", 25: "position", 26: ""} {
+		if got := statusSegmentAt(col); got != want {
+			t.Errorf("column %d is on %q, want %q", col, got, want)
+		}
+	}
+}
+
+func TestClickStatusPosition(t *testing.T) {
+	resetSessionForTest()
+	useStatusLine(t, "file,position")
+	InitSession(-1, "a.txt", strings.Repeat("line\n", 10))
+
+	// A click on Row:Col of the bottom row asks for the line to go to
+	playKeys(t, "\x1b[<0;15;24M\x1b[<0;15;24m7\r")
+	if session.cursorRow != 7 {
+		t.Errorf("cursor on row %d, want 7", session.cursorRow)
+	}
+
+	// Elsewhere it does nothing
+	playKeys(t, "\x1b[<0;15;3M\x1b[<0;15;3m")
+	if session.cursorRow != 7 {
+		t.Errorf("cursor moved to row %d", session.cursorRow)
+	}
+}
+
+func TestClickMinimap(t *testing.T) {
+	resetSessionForTest()
+	minimapEnabled = true
+	t.Cleanup(func() { minimapEnabled = false })
+	InitSession(-1, "a.txt", strings.Repeat("line\n", 100))
+
+	// Row 5 of the minimap stands for the lines from its own first one
+	want := minimapLine(4, 101, 23) + 1
+	playKeys(t, "\x1b[<0;75;5M\x1b[<0;75;5m")
+	if session.cursorRow != want {
+		t.Errorf("cursor on row %d, want %d", session.cursorRow, want)
+	}
+
+	// The border and the text are not the minimap
+	playKeys(t, "\x1b[<0;70;1M\x1b[<0;70;1m\x1b[<0;10;1M\x1b[<0;10;1m")
+	if session.cursorRow != want {
+		t.Errorf("cursor moved to row %d", session.cursorRow)
+	}
+}
+
+func TestGoTo(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("one\ntwo\nthree\n")
+
+	runCommand(0, "goto 2:3", nil)
+	if session.cursorRow != 2 || session.cursorCol != 3 {
+		t.Errorf("cursor at %d:%d, want 2:3", session.cursorRow, session.cursorCol)
+	}
+	runCommand(0, "goto", makeCallback([]byte("3\r")))
+	if session.cursorRow != 3 || session.cursorCol != 1 {
+		t.Errorf("cursor at %d:%d, want 3:1", session.cursorRow, session.cursorCol)
+	}
+	runCommand(0, "goto x", nil)
+	if !strings.HasPrefix(session.statusMessage, "Go to: invalid line") {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+}
+
+func TestFilePicker(t *testing.T) {
+	resetSessionForTest()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, name := range []string{"main.go", "docs/notes.txt", ".git/config"} {
+		os.MkdirAll(filepath.Dir(name), 0755)
+		os.WriteFile(name, []byte(name), 0644)
+	}
+
+	handleFilePicker(0, "", makeCallback([]byte{'n', 'o', 't', Return}))
+	if session.filename != filepath.Join("docs", "notes.txt") {
+		t.Errorf("opened %q", session.filename)
+	}
+	// Hidden directories are left out
+	handleFilePicker(0, "", makeCallback([]byte{'c', 'o', 'n', 'f', Return}))
+	if session.filename == filepath.Join(".git", "config") {
+		t.Error("files of hidden directories should not be listed")
+	}
+}
@@ -0,0 +1,115 @@
+package editor
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// multiplexer is the terminal multiplexer the editor runs in, "tmux" or
+// "screen", or "" when there is none. Both set $TERM to screen or tmux;
+// tmux sets $TMUX and screen $STY too.
+var multiplexer = detectMultiplexer()
+
+// detectMultiplexer finds the terminal multiplexer from the environment
+func detectMultiplexer() string {
+	term := os.Getenv("TERM")
+	switch {
+	case os.Getenv("TMUX") != "" || strings.HasPrefix(term, "tmux"):
+		return "tmux"
+	case os.Getenv("STY") != "" || strings.HasPrefix(term, "screen"):
+		return "screen"
+	}
+	return ""
+}
+
+// multiplexerKeyTimeout is the default keytimeout inside a multiplexer,
+// which passes on the bytes of the outer terminal as they come, often over
+// SSH where escape sequences arrive split
+const multiplexerKeyTimeout = 300 * time.Millisecond
+
+// defaultKeyTimeout returns how long to wait for the rest of an escape
+// sequence unless the keytimeout option says otherwise
+func defaultKeyTimeout() time.Duration {
+	if multiplexer != "" {
+		return multiplexerKeyTimeout
+	}
+	return readTimeout
+}
+
+// screenChunk is the most screen passes on in one sequence
+const screenChunk = 768
+
+// passthrough wraps seq for the multiplexer to hand it to the outer
+// terminal as it is, rather than reading it itself: tmux wants it in
+// \x1bPtmux; ... \x1b\\ with its escapes doubled, screen in \x1bP ... \x1b\\
+// pieces
+func passthrough(seq string) string {
+	switch multiplexer {
+	case "tmux":
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case "screen":
+		var b strings.Builder
+		for len(seq) > 0 {
+			n := min(len(seq), screenChunk)
+			b.WriteString("\x1bP" + seq[:n] + "\x1b\\")
+			seq = seq[n:]
+		}
+		return b.String()
+	}
+	return seq
+}
+
+// trueColor tells whether 24-bit colors reach the screen. Terminals have
+// them nowadays, but the outer terminal of a multiplexer only says so
+// through $COLORTERM, which the multiplexer keeps.
+var trueColor = multiplexer == "" || os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit"
+
+// degradeColors replaces the 24-bit colors of the SGR parameters sgr, like
+// 38;2;255;128;0, by the nearest of the 256 colors
+func degradeColors(sgr string) string {
+	params := strings.Split(sgr, ";")
+	for i := 0; i+4 < len(params); i++ {
+		if (params[i] != "38" && params[i] != "48") || params[i+1] != "2" {
+			continue
+		}
+		var rgb [3]int
+		for j := range rgb {
+			rgb[j], _ = strconv.Atoi(params[i+2+j])
+		}
+		params = append(params[:i+1], append([]string{"5", strconv.Itoa(color256(rgb[0], rgb[1], rgb[2]))}, params[i+5:]...)...)
+	}
+	return strings.Join(params, ";")
+}
+
+// cubeLevels are the intensities of the 6x6x6 color cube of the 256 colors
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// color256 returns the nearest of the 256 colors to r, g, b: a color of
+// the cube from 16, or a gray of the ramp from 232
+func color256(r, g, b int) int {
+	level := func(v int) int {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		}
+		return min((v-35)/40, 5)
+	}
+	cube := 16 + 36*level(r) + 6*level(g) + level(b)
+	cr, cg, cb := cubeLevels[level(r)], cubeLevels[level(g)], cubeLevels[level(b)]
+
+	// The grays run from 8 to 238 by 10
+	avg := (r + g + b) / 3
+	grayIndex := min(max((avg-3)/10, 0), 23)
+	gray := 8 + 10*grayIndex
+	distance := func(x, y, z int) int {
+		return (r-x)*(r-x) + (g-y)*(g-y) + (b-z)*(b-z)
+	}
+	if distance(gray, gray, gray) < distance(cr, cg, cb) {
+		return 232 + grayIndex
+	}
+	return cube
+}
@@ -0,0 +1,57 @@
+package editor
+
+import "testing"
+
+func TestDetectMultiplexer(t *testing.T) {
+	for _, env := range []struct{ term, tmux, sty, want string }{
+		{"xterm-256color", "", "", ""},
+		{"screen-256color", "/tmp/tmux-1000/default,42,0", "", "tmux"},
+		{"tmux-256color", "", "", "tmux"},
+		{"screen", "", "", "screen"},
+		{"xterm", "", "1234.pts-0.host", "screen"},
+	} {
+		t.Setenv("TERM", env.term)
+		t.Setenv("TMUX", env.tmux)
+		t.Setenv("STY", env.sty)
+		if got := detectMultiplexer(); got != env.want {
+			t.Errorf("%+v: got %q", env, got)
+		}
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	old := multiplexer
+	t.Cleanup(func() { multiplexer = old })
+	request := "\x1b]52;c;?\x07"
+
+	multiplexer = ""
+	if got := passthrough(request); got != request {
+		t.Errorf("outside a multiplexer: %q", got)
+	}
+	multiplexer = "tmux"
+	if got := passthrough(request); got != "\x1bPtmux;\x1b\x1b]52;c;?\x07\x1b\\" {
+		t.Errorf("tmux: %q", got)
+	}
+	multiplexer = "screen"
+	if got := passthrough(request); got != "\x1bP"+request+"\x1b\\" {
+		t.Errorf("screen: %q", got)
+	}
+	long := make([]byte, screenChunk+1)
+	if got := passthrough(string(long)); len(got) != len(long)+8 {
+		t.Errorf("screen should get long sequences in two pieces, got %d bytes", len(got))
+	}
+}
+
+func TestDegradeColors(t *testing.T) {
+	for sgr, want := range map[string]string{
+		"1;38;2;255;0;0":             "1;38;5;196",
+		"48;2;0;0;0;38;2;95;135;175": "48;5;16;38;5;67",
+		"38;2;128;128;128":           "38;5;244",
+		"38;5;12;4":                  "38;5;12;4",
+		"31":                         "31",
+	} {
+		if got := degradeColors(sgr); got != want {
+			t.Errorf("degradeColors(%q) = %q, want %q", sgr, got, want)
+		}
+	}
+}
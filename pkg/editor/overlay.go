@@ -0,0 +1,333 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// boxLines wraps text to maxWidth columns, keeps at most maxHeight lines and
+// surrounds the result with a border. A non-empty title is shown in the top border.
+func boxLines(text string, title string, maxWidth, maxHeight int) []string {
+	maxHeight = max(maxHeight, 1)
+	lines := wrapLines(text, maxWidth)
+	if len(lines) > maxHeight {
+		lines = append(lines[:maxHeight-1], "...")
+	}
+
+	width := utf8.RuneCountInString(title) + 2
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+
+	top := strings.Repeat("─", width+2)
+	if title != "" {
+		top = "─ " + title + " " + strings.Repeat("─", width-utf8.RuneCountInString(title)-1)
+	}
+	box := []string{"┌" + top + "┐"}
+	for _, line := range lines {
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(line))
+		box = append(box, "│ "+line+padding+" │")
+	}
+	return append(box, "└"+strings.Repeat("─", width+2)+"┘")
+}
+
+// wrapLines breaks the lines of text longer than width columns, with tabs
+// taken as four spaces
+func wrapLines(text string, width int) []string {
+	width = max(width, 1)
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		for utf8.RuneCountInString(line) > width {
+			cut := byteOffsetOfRune(line, width)
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// overlayPlacement is where an overlay is drawn on the screen
+type overlayPlacement int
+
+const (
+	// placeNearCursor draws the box below the cursor, or above it when
+	// there is no room, like the documentation of the symbol under it
+	placeNearCursor overlayPlacement = iota
+	// placeTop centers the box near the top of the screen, like lists to
+	// pick from
+	placeTop
+)
+
+// overlay is a bordered box floating over the screen. Content longer than
+// the box scrolls, and while the overlay runs it gets the keys first.
+type overlay struct {
+	title     string
+	text      string   // content to wrap to the box, when lines is nil
+	lines     []string // items, one per line, when choosing one
+	selected  int      // the item marked with '>' and kept in view
+	placement overlayPlacement
+	scroll    int // the first line shown
+}
+
+// newTextOverlay returns an overlay showing text, wrapped to the screen
+func newTextOverlay(title, text string, placement overlayPlacement) *overlay {
+	return &overlay{title: title, text: text, placement: placement}
+}
+
+// newListOverlay returns an overlay to choose one of items from, starting
+// with selected
+func newListOverlay(title string, items []string, selected int, placement overlayPlacement) *overlay {
+	return &overlay{title: title, lines: items, selected: selected, placement: placement}
+}
+
+// size returns how many columns of content and lines the box may have
+func (o *overlay) size() (width, height int) {
+	if o.placement == placeTop {
+		return int(session.screenCols) - 4, max(int(session.screenRows)/2, 1)
+	}
+	return int(session.screenCols) - 4, max((int(session.screenRows)-statusRows())/2, 1)
+}
+
+// content returns the lines of the overlay and the number of them shown,
+// making sure its scroll shows as much as fits, and the selected item
+func (o *overlay) content() (lines []string, height int) {
+	width, height := o.size()
+	if o.lines == nil {
+		lines = wrapLines(o.text, width)
+	} else {
+		lines = o.lines
+		o.selected = min(max(o.selected, 0), max(len(lines)-1, 0))
+		if o.selected < o.scroll || o.selected >= o.scroll+height {
+			o.scroll = o.selected - height/2
+		}
+	}
+	o.scroll = min(max(o.scroll, 0), max(len(lines)-height, 0))
+	return lines, height
+}
+
+// layout returns the lines of the box, border included, and the 1-indexed
+// screen position of its top-left corner
+func (o *overlay) layout() (box []string, top, left int) {
+	lines, height := o.content()
+	shown := lines[o.scroll:min(o.scroll+height, len(lines))]
+	title := o.title
+	if o.lines != nil {
+		marked := make([]string, len(shown))
+		for i, item := range shown {
+			marker := "  "
+			if o.scroll+i == o.selected {
+				marker = "> "
+			}
+			marked[i] = marker + item
+		}
+		shown = marked
+	} else if len(lines) > height {
+		title = strings.TrimSpace(fmt.Sprintf("%s %d-%d/%d", title, o.scroll+1, o.scroll+len(shown), len(lines)))
+	}
+	width, _ := o.size()
+	box = boxLines(strings.Join(shown, "\n"), title, width, height)
+	boxWidth := utf8.RuneCountInString(box[0])
+
+	if o.placement == placeTop {
+		return box, 2, max((int(session.screenCols)-boxWidth)/2+1, 1)
+	}
+	textRows := int(session.screenRows) - statusRows()
+	cursorScreenRow := session.cursorRow - session.rowOffset
+	top = cursorScreenRow + 1
+	if top+len(box)-1 > textRows {
+		top = max(cursorScreenRow-len(box), 1)
+	}
+	left = session.cursorCol
+	if left+boxWidth-1 > int(session.screenCols) {
+		left = max(int(session.screenCols)-boxWidth+1, 1)
+	}
+	return box, top, left
+}
+
+// draw redraws the screen with the overlay on top
+func (o *overlay) draw(fd int) {
+	refreshScreen(fd)
+	drawBox(o.layout())
+}
+
+// scrollBy moves the selected item, or the text, by n lines
+func (o *overlay) scrollBy(n int) {
+	if o.lines != nil {
+		o.selected = min(max(o.selected+n, 0), max(len(o.lines)-1, 0))
+	} else {
+		o.scroll += n
+	}
+}
+
+// run shows the overlay until handle returns true for a key. Up and Down
+// move the selection or scroll the text by a line, Page Up and Page Down by
+// a box; the other keys go to handle, or close the overlay when it is nil.
+func (o *overlay) run(fd int, callback func() byte, handle func(key Key) bool) {
+	for {
+		o.draw(fd)
+		key := editorReadKeypress(callback)
+		_, page := o.size()
+		switch key {
+		case Key{}:
+		case ArrowUp:
+			o.scrollBy(-1)
+		case ArrowDown:
+			o.scrollBy(1)
+		case PageUpKey:
+			o.scrollBy(-page)
+		case PageDownKey:
+			o.scrollBy(page)
+		default:
+			if handle == nil || handle(key) {
+				return
+			}
+		}
+	}
+}
+
+// drawBox paints box lines on top of the current screen at the given position
+func drawBox(box []string, top, left int) {
+	var buf strings.Builder
+	buf.WriteString("\x1b[?25l") // Hide cursor while the box is shown
+	for i, line := range box {
+		buf.WriteString(fmt.Sprintf("\x1b[%d;%dH", top+i, left))
+		buf.WriteString(line)
+	}
+	fmt.Fprint(output, buf.String())
+	invalidateFrame()
+}
+
+// pickFromList shows items in a box at the top of the screen and lets the
+// user move with the arrow keys and choose with Return.
+// It returns the chosen index, or -1 when canceled with Esc.
+func pickFromList(fd int, title string, items []string, selected int, callback func() byte) int {
+	if len(items) == 0 {
+		return -1
+	}
+	choice := -1
+	list := newListOverlay(title, items, selected, placeTop)
+	list.run(fd, callback, func(key Key) bool {
+		switch key {
+		case ReturnKey:
+			choice = list.selected
+			return true
+		case EscKey:
+			return true
+		}
+		return false
+	})
+	return choice
+}
+
+// fuzzyPickFromList works like pickFromList, but typing narrows the list
+// down to the items fuzzy matching the typed query, best matches first.
+// It returns the index of the chosen item in items, or -1 when canceled.
+func fuzzyPickFromList(fd int, title string, items []string, callback func() byte) int {
+	query := ""
+	var matches []int
+	list := newListOverlay("", nil, 0, placeTop)
+	filter := func() {
+		matches = fuzzyFilter(items, query)
+		list.title = fmt.Sprintf("%s: %s", title, query)
+		list.lines = []string{"(no match)"}
+		if len(matches) > 0 {
+			list.lines = make([]string, len(matches))
+			for i, idx := range matches {
+				list.lines[i] = items[idx]
+			}
+		}
+		list.selected = 0
+	}
+	filter()
+
+	choice := -1
+	list.run(fd, callback, func(key Key) bool {
+		c, isChar := key.char()
+		switch {
+		case key == ReturnKey:
+			if len(matches) > 0 {
+				choice = matches[list.selected]
+			}
+			return true
+		case key == EscKey:
+			return true
+		case key == BackspaceKey:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				filter()
+			}
+		case isChar:
+			query += string(c)
+			filter()
+		}
+		return false
+	})
+	return choice
+}
+
+// fuzzyFilter returns the indices of the items matching query, best first
+func fuzzyFilter(items []string, query string) []int {
+	type match struct{ idx, score int }
+	var found []match
+	for i, item := range items {
+		if score, ok := fuzzyScore(item, query); ok {
+			found = append(found, match{i, score})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].score != found[j].score {
+			return found[i].score > found[j].score
+		}
+		return len(items[found[i].idx]) < len(items[found[j].idx])
+	})
+
+	indices := make([]int, len(found))
+	for i, m := range found {
+		indices[i] = m.idx
+	}
+	return indices
+}
+
+// fuzzyScore reports whether the characters of query appear in item in
+// order, ignoring case, and scores the match: characters matched right after
+// the previous one or at the start of a word are worth more, and matching
+// the very start of the item most of all
+func fuzzyScore(item, query string) (int, bool) {
+	item, query = strings.ToLower(item), strings.ToLower(query)
+	score := 0
+	prev := -2
+	pos := 0
+	for _, q := range query {
+		idx := strings.IndexRune(item[pos:], q)
+		if idx < 0 {
+			return 0, false
+		}
+		idx += pos
+		score++
+		if idx == prev+1 {
+			score += 5
+		}
+		if idx == 0 {
+			score += 5
+		} else if strings.ContainsRune(" _-./:[", rune(item[idx-1])) {
+			score += 3
+		}
+		prev = idx
+		pos = idx + utf8.RuneLen(q)
+	}
+	return score, true
+}
+
+// byteOffsetOfRune returns the byte offset at which the n-th rune of s starts
+func byteOffsetOfRune(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
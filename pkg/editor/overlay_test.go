@@ -0,0 +1,49 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverlayScrollsText(t *testing.T) {
+	resetSessionForTest()
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, "line "+strings.Repeat("x", i%3))
+	}
+	help := newTextOverlay("Help", strings.Join(lines, "\n"), placeTop)
+
+	box, top, _ := help.layout()
+	if len(box) != 14 || top != 2 || !strings.Contains(box[0], "Help 1-12/40") {
+		t.Fatalf("expected 12 lines and where they are in the title, got %q", box)
+	}
+
+	// Page Down and Down scroll, Esc closes; the last page stays full
+	help.run(0, makeCallback([]byte("\x1b[6~\x1b[6~\x1b[6~\x1b[B\x1b")), nil)
+	if help.scroll != 28 {
+		t.Errorf("expected the last page shown, scroll %d", help.scroll)
+	}
+}
+
+func TestOverlayKeepsSelectionInView(t *testing.T) {
+	resetSessionForTest()
+	items := make([]string, 30)
+	for i := range items {
+		items[i] = strings.Repeat("i", i+1)
+	}
+	list := newListOverlay("Items", items, 25, placeTop)
+	box, _, _ := list.layout()
+	if !strings.Contains(strings.Join(box, "\n"), "> "+items[25]+" ") {
+		t.Fatalf("the selected item should be shown, got %q", box)
+	}
+
+	// Keys other than the arrows go to the handler, until it returns true
+	var handled []Key
+	list.run(0, makeCallback([]byte("\x1b[5~x\r")), func(key Key) bool {
+		handled = append(handled, key)
+		return key == ReturnKey
+	})
+	if list.selected != 13 || len(handled) != 2 || handled[0] != (Key{Rune: 'x'}) {
+		t.Errorf("selected %d, handled %+v", list.selected, handled)
+	}
+}
@@ -0,0 +1,166 @@
+package editor
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// pagerMode makes the editor a read-only pager with the keys of less
+// (--pager), for use as $PAGER
+var pagerMode bool
+
+// terminalCodes matches what programs writing to a pager use to style
+// their output: escape sequences, like the colors of git, and the
+// overstrike of man pages, a character followed by a backspace
+var terminalCodes = regexp.MustCompile("\x1b\\[[0-?]*[ -/]*[@-~]|\x1b\\][^\a\x1b]*(\a|\x1b\\\\)|.\b")
+
+// EnablePager turns the open buffers into pages to read: read-only and
+// without the terminal codes of their text, which would be drawn as is
+func EnablePager() {
+	pagerMode = true
+	// The cursor stays on the top line, which a margin would scroll away
+	scrollOff = 0
+	for _, b := range buffers {
+		b.readOnly = true
+		if b.loading != nil {
+			continue
+		}
+		if text := b.rope.String(); terminalCodes.MatchString(text) {
+			b.rope = buffer.NewRope(terminalCodes.ReplaceAllString(text, ""))
+			b.savedRope, b.seenRope = b.rope, b.rope
+			b.cursorIdx = 0
+		}
+	}
+	updateCursorPosition()
+}
+
+// handlePagerKey handles a key in pager mode. The cursor is kept at the
+// start of the top line, except on a search match.
+func handlePagerKey(fd int, key Key, callback func() byte) {
+	page := max(int(session.screenRows)-statusRows(), 1)
+	// Ctrl keys, Return and the arrows do what a letter does
+	r := key.Rune
+	switch key {
+	case ctrl('f'), ctrl('v'), PageDownKey:
+		r = 'f'
+	case ctrl('b'), PageUpKey:
+		r = 'b'
+	case ctrl('d'):
+		r = 'd'
+	case ctrl('u'):
+		r = 'u'
+	case ReturnKey, ctrl('n'), ctrl('e'), ArrowDown:
+		r = 'j'
+	case ctrl('p'), ArrowUp:
+		r = 'k'
+	case HomeKey:
+		r = 'g'
+	case EndKey:
+		r = 'G'
+	default:
+		if key.Mod != 0 {
+			return
+		}
+	}
+	switch r {
+	case ' ', 'f':
+		pagerScroll(page)
+	case 'b':
+		pagerScroll(-page)
+	case 'd':
+		pagerScroll(page / 2)
+	case 'u':
+		pagerScroll(-page / 2)
+	case 'j', 'e':
+		pagerScroll(1)
+	case 'k', 'y':
+		pagerScroll(-1)
+	case 'g', '<':
+		pagerScroll(-len(lineStarts()))
+	case 'G', '>':
+		pagerScroll(len(lineStarts()))
+	case '/', '?':
+		query, ok := readPrompt(string(r), searchHistory, callback)
+		if !ok {
+			return
+		}
+		if query == "" {
+			query = session.lastSearchQuery
+		} else {
+			session.lastSearchQuery = query
+			searchHistory.add(query)
+		}
+		pagerFind(query, r == '/')
+	case 'n', 'N':
+		pagerFind(session.lastSearchQuery, r == 'n')
+	case 'h', 'H':
+		newTextOverlay("Keys", pagerHelp, placeTop).run(fd, callback, nil)
+	}
+}
+
+// pagerHelp lists the keys of the pager, shown with h
+const pagerHelp = `Space, b   page down, up
+d, u       half a page down, up
+j, k       a line down, up
+g, G       top, bottom
+/, ?       search forward, backward
+n, N       next, previous match
+q          quit`
+
+// pagerScroll scrolls by n lines, down when positive, without going past
+// the last page
+func pagerScroll(n int) {
+	starts := lineStarts()
+	page := max(int(session.screenRows)-statusRows(), 1)
+	top := min(max(session.rowOffset+n, 0), max(len(starts)-page, 0))
+	session.rowOffset = top
+	session.cursorIdx = starts[top]
+	updateCursorPosition()
+}
+
+// pagerFind moves to the next match of query after the cursor, or the
+// previous one before it, and scrolls its line to the top
+func pagerFind(query string, forward bool) {
+	if query == "" {
+		session.statusMessage = "No previous search"
+		return
+	}
+	text := session.rope.String()
+	idx := -1
+	if forward {
+		from := min(session.cursorIdx+1, len(text))
+		if i := strings.Index(text[from:], query); i >= 0 {
+			idx = from + i
+		}
+	} else {
+		idx = strings.LastIndex(text[:session.cursorIdx], query)
+	}
+	if idx < 0 {
+		session.statusMessage = "Pattern not found: " + query
+		return
+	}
+	session.cursorIdx = idx
+	updateCursorPosition()
+	page := max(int(session.screenRows)-statusRows(), 1)
+	session.rowOffset = min(session.cursorRow-1, max(len(lineStarts())-page, 0))
+}
+
+// pagerHighlights marks the matches of the last search in pager mode
+func pagerHighlights(text string) []highlight {
+	query := session.lastSearchQuery
+	if !pagerMode || query == "" {
+		return nil
+	}
+	var highlights []highlight
+	for from := 0; ; {
+		i := strings.Index(text[from:], query)
+		if i < 0 {
+			return highlights
+		}
+		start := from + i
+		highlights = append(highlights, highlight{start, start + len(query), currentTheme.selection})
+		from = start + len(query)
+	}
+}
@@ -0,0 +1,113 @@
+package editor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// usePager starts pager mode on a buffer of n numbered lines
+func usePager(t *testing.T, n int) {
+	saveSettings(t)
+	t.Cleanup(func() { pagerMode = false })
+	var text strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&text, "line %d\n", i)
+	}
+	resetSessionForTest()
+	InitSession(-1, "[stdin]", text.String())
+	EnablePager()
+}
+
+func TestEnablePagerStripsTerminalCodes(t *testing.T) {
+	saveSettings(t)
+	t.Cleanup(func() { pagerMode = false })
+	resetSessionForTest()
+	InitSession(-1, "[stdin]", "\x1b[33mcommit 1a2b\x1b[m\n\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\ N\bNA\bAM\bME\bE _\bb_\bo_\bl_\bd")
+	EnablePager()
+	if got := session.rope.String(); got != "commit 1a2b\nlink NAME bold" {
+		t.Errorf("text %q", got)
+	}
+	if !session.readOnly || session.modified() {
+		t.Errorf("the page should be read-only and unmodified: %v %v", session.readOnly, session.modified())
+	}
+}
+
+func TestPagerKeys(t *testing.T) {
+	usePager(t, 100)
+
+	screen := playKeys(t, " ")
+	if screen.Line(0) != "line 24" {
+		t.Errorf("space should show the next page, top line %q", screen.Line(0))
+	}
+	if status := screen.Line(23); !strings.Contains(status, "q:Quit") {
+		t.Errorf("status %q", status)
+	}
+
+	screen = playKeys(t, "G")
+	if screen.Line(0) != "line 79" || screen.Line(22) != "" {
+		t.Errorf("G should show the last page, lines %q and %q", screen.Line(0), screen.Line(22))
+	}
+	screen = playKeys(t, "bbbbbjjx")
+	if screen.Line(0) != "line 3" || session.rope.Length() != 792 {
+		t.Errorf("top line %q, %d bytes", screen.Line(0), session.rope.Length())
+	}
+
+	screen = playKeys(t, "/line 5\r")
+	if screen.Line(0) != "line 5" || screen.Cell(0, 0).Style == "" {
+		t.Errorf("the match should be at the top and highlighted: %q %+v", screen.Line(0), screen.Cell(0, 0))
+	}
+	screen = playKeys(t, "n")
+	if screen.Line(0) != "line 50" {
+		t.Errorf("n should find the next match, top line %q", screen.Line(0))
+	}
+	screen = playKeys(t, "N/nothing\r")
+	if screen.Line(0) != "line 5" || !strings.Contains(screen.Line(23), "Pattern not found") {
+		t.Errorf("top line %q, status %q", screen.Line(0), screen.Line(23))
+	}
+}
+
+func TestPagerQuit(t *testing.T) {
+	usePager(t, 3)
+	output = io.Discard
+	t.Cleanup(func() { output = os.Stdout })
+	next := makeCallback([]byte("q"))
+	ProcessKeypress(-1, func() byte {
+		if b := next(); b != 0 {
+			return b
+		}
+		t.Fatal("q should quit the pager")
+		return 0
+	})
+}
+
+// Quitting the pager quits like the editor does, remembering where the
+// files were left
+func TestPagerQuitSavesPositions(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	saveSettings(t)
+	t.Cleanup(func() { pagerMode = false })
+	resetSessionForTest()
+	path := filepath.Join(t.TempDir(), "log.txt")
+	InitSession(-1, path, strings.Repeat("line\n", 100))
+	EnablePager()
+	output = io.Discard
+	t.Cleanup(func() { output = os.Stdout })
+	ProcessKeypress(-1, makeCallback([]byte("Gq")))
+
+	positions, err := loadPositions()
+	if err != nil || len(positions) != 1 || positions[0].path != path || positions[0].row == 0 {
+		t.Errorf("positions %+v, %v", positions, err)
+	}
+}
+
+func TestPagerHelp(t *testing.T) {
+	usePager(t, 100)
+	screen := playKeys(t, "h")
+	if !strings.Contains(screen.String(), "─ Keys ─") || !strings.Contains(screen.String(), "q          quit") {
+		t.Errorf("h should show the keys in a box, screen:\n%s", screen.String())
+	}
+}
@@ -0,0 +1,57 @@
+package editor
+
+import (
+	"fmt"
+	"time"
+)
+
+// perfStats shows the timings of the last frame at the end of the status
+// bar, for reports about a slow editor (:set perfstats on)
+var perfStats bool
+
+// frameStats is what the last frame cost
+type frameStats struct {
+	latency  time.Duration // from reading its first key to writing the frame
+	handling time.Duration // handling its keys: editing, moving, commands
+	render   time.Duration // composing the frame and what changed since the last one
+	bytes    int           // written to the terminal
+}
+
+// Timings of the frame in the making, and of the last one written
+var (
+	lastFrameStats  frameStats
+	pendingKeyAt    time.Time // when the first key not drawn yet was read
+	pendingHandling time.Duration
+)
+
+// keyHandled records the handling of a key read at readAt, which the next
+// frame draws. Keys read while frames are skipped add up.
+func keyHandled(readAt time.Time) {
+	if pendingKeyAt.IsZero() {
+		pendingKeyAt = readAt
+	}
+	pendingHandling += time.Since(readAt)
+}
+
+// frameWritten records a frame composed from start to rendered, then
+// written in n bytes
+func frameWritten(start, rendered time.Time, n int) {
+	lastFrameStats = frameStats{
+		handling: pendingHandling,
+		render:   rendered.Sub(start),
+		bytes:    n,
+	}
+	if !pendingKeyAt.IsZero() {
+		lastFrameStats.latency = time.Since(pendingKeyAt)
+	}
+	pendingKeyAt, pendingHandling = time.Time{}, 0
+}
+
+// perfText describes the last frame for the status bar. A frame drawn for
+// background work, without a key, has no latency or handling time.
+func perfText() string {
+	s := lastFrameStats
+	return fmt.Sprintf("key→screen %v edit %v render %v %dB",
+		s.latency.Round(time.Microsecond), s.handling.Round(time.Microsecond),
+		s.render.Round(time.Microsecond), s.bytes)
+}
@@ -0,0 +1,46 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrameWritten(t *testing.T) {
+	pendingKeyAt, pendingHandling = time.Time{}, 0
+	start := time.Now()
+	keyHandled(start.Add(-3 * time.Millisecond))
+	keyHandled(start.Add(-time.Millisecond))
+	frameWritten(start, start.Add(2*time.Millisecond), 120)
+
+	s := lastFrameStats
+	if s.latency < 3*time.Millisecond || s.handling < 4*time.Millisecond || s.render != 2*time.Millisecond || s.bytes != 120 {
+		t.Errorf("stats %+v", s)
+	}
+	if !pendingKeyAt.IsZero() || pendingHandling != 0 {
+		t.Error("the next frame should start from nothing")
+	}
+
+	// A frame without keys has no latency
+	frameWritten(start, start, 10)
+	if lastFrameStats.latency != 0 || lastFrameStats.handling != 0 {
+		t.Errorf("stats %+v", lastFrameStats)
+	}
+}
+
+func TestPerfStatsInStatusBar(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	InitSession(-1, "notes.txt", "")
+	runCommand(0, "set perfstats on", nil)
+
+	screen := playKeys(t, "hello")
+	status := screen.Line(23)
+	if !strings.HasPrefix(status, "File: notes.txt") || !strings.Contains(status, "key→screen ") ||
+		!strings.HasSuffix(status, "B") {
+		t.Errorf("status %q", status)
+	}
+	if lastFrameStats.bytes == 0 || lastFrameStats.latency < lastFrameStats.render {
+		t.Errorf("stats of the last frame %+v", lastFrameStats)
+	}
+}
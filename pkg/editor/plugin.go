@@ -0,0 +1,138 @@
+package editor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pluginDir holds the plugins loaded at startup (:set plugindir DIR in
+// the config file), in the order of their names. Empty turns them off.
+var pluginDir = defaultPluginDir()
+
+// defaultPluginDir is plugins beside the config file
+func defaultPluginDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "goedit", "plugins")
+	}
+	return ""
+}
+
+// pluginLoaders load a plugin file, by extension
+var pluginLoaders = map[string]func(path string) error{
+	".lua":  loadLuaPlugin,
+	".wasm": loadWasmPlugin,
+}
+
+// loadedPlugins are the names of the plugin files loaded
+var loadedPlugins []string
+
+// pluginKey is a key bound by a plugin
+type pluginKey struct {
+	plugin string // file of the plugin, for :showkeys
+	run    func(fd int, callback func() byte)
+}
+
+// pluginKeys are the keys bound by plugins. They win over the keys of the
+// editor.
+var pluginKeys = map[Key]pluginKey{}
+
+// pluginCommands are the names of the commands plugins added to commands
+var pluginCommands []string
+
+// pluginHooks are the hooks of plugins, run after those of the editor
+var pluginHooks = map[event][]hookFunc{}
+
+func init() {
+	for _, ev := range []event{eventBufOpen, eventBufWritePre, eventBufWritePost, eventTextChanged, eventCursorMoved} {
+		addHook(ev, func(s *Session) error {
+			for _, fn := range pluginHooks[ev] {
+				if err := fn(s); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// LoadPlugins loads the plugins of pluginDir. It stops at the first one
+// that fails, naming its file in the error.
+func LoadPlugins() error {
+	if pluginDir == "" {
+		return nil
+	}
+	dir := expandHome(pluginDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		load := pluginLoaders[filepath.Ext(entry.Name())]
+		if load == nil || entry.IsDir() {
+			continue
+		}
+		if err := load(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("plugin %s: %w", entry.Name(), err)
+		}
+		log.Printf("loaded plugin %s", entry.Name())
+		loadedPlugins = append(loadedPlugins, entry.Name())
+	}
+	return nil
+}
+
+// addPluginCommand adds a command of a plugin, which can't replace one
+// of the editor or of another plugin
+func addPluginCommand(name string, run commandFunc) error {
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid command name %q", name)
+	}
+	if _, ok := commands[name]; ok {
+		return fmt.Errorf("command %s already exists", name)
+	}
+	commands[name] = run
+	pluginCommands = append(pluginCommands, name)
+	return nil
+}
+
+// bindPluginKey binds the key called name, like Ctrl-Y, for a plugin
+func bindPluginKey(plugin, name string, run func(fd int, callback func() byte)) error {
+	key, err := parseKey(name)
+	if err != nil {
+		return err
+	}
+	pluginKeys[key] = pluginKey{plugin, run}
+	return nil
+}
+
+// unloadPlugins forgets every plugin, their commands, keys and hooks
+func unloadPlugins() {
+	for _, name := range pluginCommands {
+		delete(commands, name)
+	}
+	pluginCommands = nil
+	pluginKeys = map[Key]pluginKey{}
+	pluginHooks = map[event][]hookFunc{}
+	loadedPlugins = nil
+	closeLua()
+	closeWasm()
+}
+
+// handlePlugins lists the plugins loaded and the commands they added
+// (:plugins)
+func handlePlugins(fd int, args string, callback func() byte) {
+	if len(loadedPlugins) == 0 {
+		session.statusMessage = fmt.Sprintf("No plugins (in %s)", pluginDir)
+		return
+	}
+	names := append([]string(nil), pluginCommands...)
+	sort.Strings(names)
+	session.statusMessage = fmt.Sprintf("Plugins: %s | commands: %s",
+		strings.Join(loadedPlugins, ", "), strings.Join(names, ", "))
+}
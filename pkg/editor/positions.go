@@ -0,0 +1,166 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxRememberedPositions bounds the number of files in the positions file
+const maxRememberedPositions = 500
+
+// rememberPositions restores the cursor of files opened again
+var rememberPositions = true
+
+// filePosition is the 1-indexed cursor row and column last used in a file
+type filePosition struct {
+	path     string
+	row, col int
+}
+
+func init() {
+	addHook(eventBufOpen, func(s *Session) error {
+		if rememberPositions {
+			restoreCursorPosition(s)
+		}
+		return nil
+	})
+}
+
+// stateDir returns the directory the editor keeps its state in,
+// following the XDG base directory specification
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "goedit"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "goedit"), nil
+}
+
+// positionsPath is the file the cursor positions are remembered in
+func positionsPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "positions"), nil
+}
+
+// loadPositions reads the remembered positions, oldest first.
+// Each line holds a row, a column and an absolute path, separated by tabs.
+func loadPositions() ([]filePosition, error) {
+	path, err := positionsPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var positions []filePosition
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		row, err1 := strconv.Atoi(fields[0])
+		col, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		positions = append(positions, filePosition{path: fields[2], row: row, col: col})
+	}
+	return positions, scanner.Err()
+}
+
+// savePositions merges the cursors of the given buffers into the positions
+// file. Updated files move to the end, and the oldest entries are dropped.
+func savePositions(sessions []*Session) error {
+	positions, err := loadPositions()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if s.filename == "" || isUnnamed(s.filename) {
+			continue
+		}
+		abs, err := filepath.Abs(s.filename)
+		if err != nil {
+			continue
+		}
+		kept := positions[:0]
+		for _, p := range positions {
+			if p.path != abs {
+				kept = append(kept, p)
+			}
+		}
+		positions = append(kept, filePosition{path: abs, row: s.cursorRow, col: s.cursorCol})
+	}
+	if len(positions) > maxRememberedPositions {
+		positions = positions[len(positions)-maxRememberedPositions:]
+	}
+
+	path, err := positionsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var buf strings.Builder
+	for _, p := range positions {
+		fmt.Fprintf(&buf, "%d\t%d\t%s\n", p.row, p.col, p.path)
+	}
+	// Write a temporary file first so a crash never leaves half a file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(buf.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// saveCursorPositions remembers the cursor of every open buffer
+func saveCursorPositions() {
+	if !rememberPositions {
+		return
+	}
+	savePositions(buffers)
+}
+
+// restoreCursorPosition moves the cursor of s to where it was when the file
+// was last closed, clamped to the current content of the file
+func restoreCursorPosition(s *Session) {
+	abs, err := filepath.Abs(s.filename)
+	if err != nil {
+		return
+	}
+	positions, err := loadPositions()
+	if err != nil {
+		return
+	}
+	for i := len(positions) - 1; i >= 0; i-- {
+		if positions[i].path != abs {
+			continue
+		}
+		idx := lineColumnIndex(s.rope.String(), max(positions[i].row, 1), positions[i].col)
+		s.cursorIdx = idx
+		s.seenCursorIdx = idx
+		if s == session {
+			updateCursorPosition()
+		}
+		return
+	}
+}
@@ -0,0 +1,93 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCursorPositionRememberedAcrossSessions(t *testing.T) {
+	resetSessionForTest()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("notes.txt", []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Open the file, go to "th|ree" and quit
+	InitSession(0, "notes.txt", "one\ntwo\nthree\n")
+	session.cursorIdx = 10
+	updateCursorPosition()
+	ProcessKeypress(0, makeCallback([]byte{CtrlQ, CtrlQ}))
+
+	path, _ := positionsPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("positions file not written: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "3\t3\t") || !strings.HasSuffix(string(data), "notes.txt\n") {
+		t.Errorf("unexpected positions file %q", data)
+	}
+
+	InitSession(0, "notes.txt", "one\ntwo\nthree\n")
+	if session.cursorIdx != 10 || session.cursorRow != 3 || session.cursorCol != 3 {
+		t.Errorf("restored cursor idx %d row %d col %d, want 10 3 3",
+			session.cursorIdx, session.cursorRow, session.cursorCol)
+	}
+
+	// The file shrank in the meantime: the cursor is clamped
+	InitSession(0, "notes.txt", "one\nx")
+	if session.cursorIdx != 5 {
+		t.Errorf("clamped cursor idx %d, want 5", session.cursorIdx)
+	}
+}
+
+func TestSavePositionsKeepsMostRecent(t *testing.T) {
+	resetSessionForTest()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	var sessions []*Session
+	for i := 0; i < maxRememberedPositions+5; i++ {
+		s := newSession(filepath.Join(dir, fmt.Sprintf("f%d.txt", i)), "")
+		sessions = append(sessions, s)
+	}
+	if err := savePositions(sessions); err != nil {
+		t.Fatal(err)
+	}
+	// Saving a file again moves it to the end instead of duplicating it
+	sessions[0].cursorRow = 7
+	if err := savePositions(sessions[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	positions, err := loadPositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) > maxRememberedPositions {
+		t.Errorf("%d positions remembered, want at most %d", len(positions), maxRememberedPositions)
+	}
+	last := positions[len(positions)-1]
+	if last.path != sessions[0].filename || last.row != 7 {
+		t.Errorf("last position %+v, want %s at row 7", last, sessions[0].filename)
+	}
+	seen := map[string]bool{}
+	for _, p := range positions {
+		if seen[p.path] {
+			t.Fatalf("%s remembered twice", p.path)
+		}
+		seen[p.path] = true
+	}
+}
+
+func TestStateDirDefaultsToLocalState(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/someone")
+	dir, err := stateDir()
+	if err != nil || dir != "/home/someone/.local/state/goedit" {
+		t.Errorf("stateDir() = %q, %v", dir, err)
+	}
+}
@@ -0,0 +1,113 @@
+package editor
+
+import "fmt"
+
+// protectedRange is a read-only span [start, end) of a buffer, like
+// generated code or the prompt of a REPL, which edits may not change
+type protectedRange struct {
+	start, end int
+}
+
+// protect makes the bytes in [start, end) of s read-only. Text can still be
+// added right before or after them.
+func (s *Session) protect(start, end int) {
+	if start < end {
+		s.protected = append(s.protected, protectedRange{start, end})
+	}
+}
+
+// unprotect makes the protected ranges of s that overlap [start, end), or
+// contain start when the range is empty, editable again. It returns how
+// many there were.
+func (s *Session) unprotect(start, end int) int {
+	kept := s.protected[:0]
+	for _, r := range s.protected {
+		if !r.overlaps(start, end) && (start != end || start < r.start || start >= r.end) {
+			kept = append(kept, r)
+		}
+	}
+	n := len(s.protected) - len(kept)
+	s.protected = kept
+	return n
+}
+
+// overlaps reports whether changing [start, end) would change r. Inserting
+// (start == end) only changes it strictly inside, not at its edges.
+func (r protectedRange) overlaps(start, end int) bool {
+	if start == end {
+		return r.start < start && start < r.end
+	}
+	return start < r.end && r.start < end
+}
+
+// editableRange reports whether the bytes in [start, end) of the active
+// buffer may be replaced, telling the user why not when they may not
+func editableRange(start, end int) bool {
+	return editable() && !refuseProtected(start, end)
+}
+
+// refuseProtected reports whether changing [start, end) of the active
+// buffer would change protected text, telling the user when it would
+func refuseProtected(start, end int) bool {
+	for _, r := range session.protected {
+		if r.overlaps(start, end) {
+			session.statusMessage = fmt.Sprintf("Text %d-%d is protected (:unprotect to allow edits)", r.start, r.end)
+			return true
+		}
+	}
+	return false
+}
+
+// protectedChange reports whether making edits would change protected
+// text, telling the user when they would. An action undone or redone may
+// touch text protected after it was made.
+func protectedChange(edits []textEdit) bool {
+	// Each edit is checked against the ranges moved by the ones before
+	saved := append([]protectedRange(nil), session.protected...)
+	defer func() { session.protected = saved }()
+	for _, e := range edits {
+		if refuseProtected(e.position, e.position+len(e.removed)) {
+			return true
+		}
+		shiftProtected(e.position, len(e.removed), len(e.inserted))
+	}
+	return false
+}
+
+// shiftProtected moves the protected ranges of the active buffer after
+// removed bytes were replaced by inserted ones at pos
+func shiftProtected(pos, removed, inserted int) {
+	for i := range session.protected {
+		// Text inserted at the start of a range is not part of it
+		if r := &session.protected[i]; pos+removed <= r.start {
+			r.start += inserted - removed
+			r.end += inserted - removed
+		}
+	}
+}
+
+// handleProtect makes the selection read-only (:protect)
+func handleProtect(fd int, args string, callback func() byte) {
+	start, end, ok := selection()
+	if !ok {
+		session.statusMessage = "Select the text to protect first"
+		return
+	}
+	session.protect(start, end)
+	session.selecting = false
+	session.statusMessage = fmt.Sprintf("Protected %d bytes", end-start)
+}
+
+// handleUnprotect makes the protected ranges in the selection, or the one
+// under the cursor, editable again (:unprotect)
+func handleUnprotect(fd int, args string, callback func() byte) {
+	start, end, ok := selection()
+	if !ok {
+		start, end = session.cursorIdx, session.cursorIdx
+	}
+	if n := session.unprotect(start, end); n > 0 {
+		session.statusMessage = fmt.Sprintf("Unprotected %d ranges", n)
+	} else {
+		session.statusMessage = "No protected text here"
+	}
+}
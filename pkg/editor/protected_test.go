@@ -0,0 +1,78 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestProtectedRanges(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope(">>> print(1)")
+	session.protect(0, 4)
+
+	// The prompt can't be changed, the text after it can
+	session.cursorIdx = 2
+	handleInsert("x")
+	if got := session.rope.String(); got != ">>> print(1)" || !strings.Contains(session.statusMessage, "protected") {
+		t.Fatalf("insert inside the range: %q, status %q", got, session.statusMessage)
+	}
+	session.cursorIdx = 4
+	handleBackspace()
+	if got := session.rope.String(); got != ">>> print(1)" {
+		t.Fatalf("backspace into the range left %q", got)
+	}
+	replaceText(4, 9, "echo")
+	if got := session.rope.String(); got != ">>> echo(1)" {
+		t.Fatalf("replace after the range left %q", got)
+	}
+
+	// Text added before the range moves it
+	session.cursorIdx = 0
+	handleInsert("\n")
+	if session.protected[0] != (protectedRange{1, 5}) {
+		t.Fatalf("range did not move: %+v", session.protected[0])
+	}
+	deleteText(0, 1)
+	if session.protected[0] != (protectedRange{0, 4}) {
+		t.Fatalf("range did not move back: %+v", session.protected[0])
+	}
+
+	// Undo can't change text protected after the edit
+	session.protect(4, 8)
+	handleUndo()
+	handleUndo()
+	handleUndo()
+	if got := session.rope.String(); got != ">>> echo(1)" || len(session.undoStack) != 1 {
+		t.Fatalf("undo changed protected text: %q", got)
+	}
+
+	if n := session.unprotect(0, 8); n != 2 {
+		t.Fatalf("expected the 2 ranges unprotected, got %d", n)
+	}
+	handleUndo()
+	if got := session.rope.String(); got != ">>> print(1)" {
+		t.Fatalf("undo after unprotect left %q", got)
+	}
+}
+
+func TestProtectCommands(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("keep this")
+	selectRange(0, 4)
+	runCommand(0, "protect", nil)
+	if len(session.protected) != 1 || session.selecting {
+		t.Fatalf("expected the selection protected, got %+v", session.protected)
+	}
+	session.cursorIdx = 6
+	runCommand(0, "unprotect", nil)
+	if session.statusMessage != "No protected text here" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+	session.cursorIdx = 1
+	runCommand(0, "unprotect", nil)
+	if len(session.protected) != 0 {
+		t.Errorf("expected no protected text left, got %+v", session.protected)
+	}
+}
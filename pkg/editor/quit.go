@@ -0,0 +1,122 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// quitRequested is set by the commands that quit the editor, which the main
+// loop does after the key that ran them
+var quitRequested bool
+
+// Exit statuses of the editor, for the scripts that run it
+const (
+	ExitOK        = 0 // quit with nothing left unsaved
+	ExitError     = 1 // the editor could not start, like without a terminal
+	ExitUsage     = 2 // bad arguments, options or config file
+	ExitReadError = 3 // a file given could not be read
+	ExitUnsaved   = 4 // quit throwing away unsaved changes
+	ExitCanceled  = 5 // quit with :cq, telling the caller the edit failed
+)
+
+// exitStatus is the status the editor exits with once the main loop returns
+var exitStatus = ExitOK
+
+// discardedFiles are the buffers whose changes were thrown away on quit
+var discardedFiles []string
+
+// ExitStatus returns the status the editor should exit with, once
+// ProcessKeypress has returned
+func ExitStatus() int {
+	return exitStatus
+}
+
+// DiscardedFiles returns the names of the buffers quit with unsaved changes
+func DiscardedFiles() []string {
+	return discardedFiles
+}
+
+// quitEditor saves the cursor positions and histories, stops what runs in
+// the background and clears the screen, before the main loop returns
+func quitEditor() {
+	for _, s := range unsavedBuffers() {
+		discardedFiles = append(discardedFiles, displayPath(s.filename))
+	}
+	if len(discardedFiles) > 0 && exitStatus == ExitOK {
+		exitStatus = ExitUnsaved
+	}
+	saveCursorPositions()
+	saveHistories()
+	stopLanguageServers()
+	closeTerminal()
+	ClearScreen(Screen)
+	MoveCursorTopLeft()
+}
+
+// unsavedBuffers returns the buffers with changes that quitting would lose
+func unsavedBuffers() []*Session {
+	var unsaved []*Session
+	for _, s := range buffers {
+		if s.modified() && s.loading == nil {
+			unsaved = append(unsaved, s)
+		}
+	}
+	return unsaved
+}
+
+// handleQuitAll quits the editor, first asking for every buffer with
+// unsaved changes whether to save them, throw them away or stay (:qa and
+// Ctrl-Q). a saves every buffer left, Ctrl-Q throws away their changes.
+func handleQuitAll(fd int, args string, callback func() byte) {
+	unsaved := unsavedBuffers()
+	saveAll := false
+	for i, s := range unsaved {
+		switchToBuffer(s)
+		reply := answerAll
+		if !saveAll {
+			prompt := fmt.Sprintf("Save changes to %s (%d/%d)? y: save, n: discard, a: save all, Esc: stay, Ctrl-Q: discard all",
+				displayPath(s.filename), i+1, len(unsaved))
+			reply = confirm(prompt, true, callback)
+		}
+		switch reply {
+		case answerYes, answerAll:
+			saveAll = reply == answerAll
+			handleSave(fd, callback)
+			if s.modified() {
+				// The save failed or was canceled, which the status line tells
+				session.statusMessage = strings.TrimSpace("Quit canceled. " + session.statusMessage)
+				return
+			}
+		case answerNo:
+		case answerNone:
+			quitRequested = true
+			return
+		default:
+			session.statusMessage = "Quit canceled"
+			return
+		}
+	}
+	quitRequested = true
+}
+
+// handleWriteQuitAll saves every buffer with unsaved changes, asking for a
+// file name for those without one, and quits unless a save fails (:wqa)
+func handleWriteQuitAll(fd int, args string, callback func() byte) {
+	for _, s := range unsavedBuffers() {
+		switchToBuffer(s)
+		handleSave(fd, callback)
+		if s.modified() {
+			session.statusMessage = strings.TrimSpace("Quit canceled. " + session.statusMessage)
+			return
+		}
+	}
+	quitRequested = true
+}
+
+// handleQuitCanceled quits at once, throwing away unsaved changes, with an
+// exit status telling the program that started the editor that the edit
+// failed, so that git aborts the commit for example (:cq)
+func handleQuitCanceled(fd int, args string, callback func() byte) {
+	exitStatus = ExitCanceled
+	quitRequested = true
+}
@@ -0,0 +1,116 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// twoChangedBuffers opens a.txt and b.txt of a temporary directory with
+// unsaved changes in both
+func twoChangedBuffers(t *testing.T) (a, b string) {
+	dir := t.TempDir()
+	a, b = filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	resetSessionForTest()
+	InitSession(-1, a, "")
+	handleInsert("alpha")
+	AddBuffer(b, "", 0, 0)
+	runCommand(0, "bn", nil)
+	handleInsert("beta")
+	runCommand(0, "bn", nil)
+	return a, b
+}
+
+func TestQuitAllAsksForEachBuffer(t *testing.T) {
+	a, b := twoChangedBuffers(t)
+	t.Cleanup(func() { quitRequested = false })
+
+	// Saving the first and staying at the second
+	handleQuitAll(0, "", makeCallback([]byte("y\x1b")))
+	if quitRequested || session.statusMessage != "Quit canceled" || session.filename != b {
+		t.Errorf("quit %v, status %q on %s", quitRequested, session.statusMessage, session.filename)
+	}
+	if data, err := os.ReadFile(a); err != nil || string(data) != "alpha" {
+		t.Errorf("a.txt holds %q, %v", data, err)
+	}
+
+	handleQuitAll(0, "", makeCallback([]byte("n")))
+	if !quitRequested {
+		t.Error("discarding the last changes should quit")
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Error("b.txt should not be saved")
+	}
+}
+
+func TestQuitAllDiscardsAll(t *testing.T) {
+	twoChangedBuffers(t)
+	t.Cleanup(func() { quitRequested = false })
+	handleQuitAll(0, "", makeCallback([]byte{CtrlQ}))
+	if !quitRequested {
+		t.Error("Ctrl-Q at the question should quit without asking for the other buffers")
+	}
+}
+
+func TestQuitAllSavesAll(t *testing.T) {
+	a, b := twoChangedBuffers(t)
+	t.Cleanup(func() { quitRequested = false })
+	handleQuitAll(0, "", makeCallback([]byte("a")))
+	if !quitRequested {
+		t.Error("a at the question should save every buffer and quit")
+	}
+	for _, path := range []string{a, b} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s should be saved: %v", path, err)
+		}
+	}
+}
+
+func TestWriteQuitAll(t *testing.T) {
+	a, b := twoChangedBuffers(t)
+	t.Cleanup(func() { quitRequested = false })
+	AddBuffer("[No Name]", "", 0, 0)
+	runCommand(0, "bp", nil)
+	handleInsert("gamma")
+
+	// A buffer without a name needs one, canceling stays
+	runCommand(0, "wqa", makeCallback([]byte("\x1b")))
+	if quitRequested || !strings.HasPrefix(session.statusMessage, "Quit canceled") {
+		t.Fatalf("quit %v, status %q", quitRequested, session.statusMessage)
+	}
+	for _, path := range []string{a, b} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s should be saved: %v", path, err)
+		}
+	}
+
+	c := filepath.Join(filepath.Dir(a), "c.txt")
+	runCommand(0, "wqa", makeCallback([]byte(c+"\r")))
+	if data, _ := os.ReadFile(c); !quitRequested || string(data) != "gamma" {
+		t.Errorf("quit %v, c.txt holds %q", quitRequested, data)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	t.Cleanup(func() { exitStatus, discardedFiles = ExitOK, nil })
+	for _, tc := range []struct {
+		keys      string
+		status    int
+		discarded int
+	}{
+		{"", ExitOK, 0},
+		// Ctrl-Q at the question throws the changes away
+		{"hello", ExitUnsaved, 1},
+		{"hello\x13", ExitOK, 0},
+		{"\x05cq\r", ExitCanceled, 0},
+	} {
+		exitStatus, discardedFiles = ExitOK, nil
+		resetSessionForTest()
+		InitSession(-1, filepath.Join(t.TempDir(), "notes.txt"), "")
+		playKeys(t, tc.keys)
+		if ExitStatus() != tc.status || len(DiscardedFiles()) != tc.discarded {
+			t.Errorf("%q exits with %d, discarding %q", tc.keys, ExitStatus(), DiscardedFiles())
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package editor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRemoteSize bounds how much of a URL is downloaded
+const maxRemoteSize = 64 << 20
+
+// remoteClient fetches the content of URL buffers
+var remoteClient = &http.Client{Timeout: 30 * time.Second}
+
+// IsURL reports whether name is an http or https URL rather than a file
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// FetchURL downloads the content of an http or https URL
+func FetchURL(url string) (string, error) {
+	resp, err := remoteClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) > maxRemoteSize {
+		return "", fmt.Errorf("GET %s: larger than %d MiB", url, maxRemoteSize>>20)
+	}
+	return string(body), nil
+}
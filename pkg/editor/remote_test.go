@@ -0,0 +1,53 @@
+package editor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenURLReadOnlyThenSaveAs(t *testing.T) {
+	resetSessionForTest()
+	t.Chdir(t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/config.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("key = value\n"))
+	}))
+	defer server.Close()
+
+	url := server.URL + "/config.txt"
+	runCommand(0, "e "+url, nil)
+	if session.filename != url || session.rope.String() != "key = value\n" {
+		t.Fatalf("expected the downloaded buffer, got %s %q (%s)", session.filename, session.rope.String(), session.statusMessage)
+	}
+	handleInsert("#")
+	if session.rope.String() != "key = value\n" {
+		t.Fatal("a downloaded buffer should be read-only")
+	}
+
+	handleSave(0, makeCallback([]byte("copy.txt\r")))
+	if data, err := os.ReadFile(filepath.Join(".", "copy.txt")); err != nil || string(data) != "key = value\n" {
+		t.Fatalf("Save As wrote %q, %v", data, err)
+	}
+	if session.filename != "copy.txt" || session.readOnly {
+		t.Fatalf("the saved copy should be an ordinary buffer, got %s read-only %v", session.filename, session.readOnly)
+	}
+
+	runCommand(0, "e "+server.URL+"/missing", nil)
+	if !strings.Contains(session.statusMessage, "404") {
+		t.Fatalf("expected a 404 error, got %q", session.statusMessage)
+	}
+}
+
+func TestParseFileArgsKeepsURLs(t *testing.T) {
+	name, line, _, err := ParseFileArgs([]string{"http://localhost:8080"})
+	if err != nil || name != "http://localhost:8080" || line != 0 {
+		t.Fatalf("ParseFileArgs = %q %d %v", name, line, err)
+	}
+}
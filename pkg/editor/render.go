@@ -0,0 +1,213 @@
+package editor
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+)
+
+// frameInterval is the shortest time between two frames of the main loop,
+// which caps redrawing at 60 frames per second
+const frameInterval = time.Second / 60
+
+// Frame state of the main loop: when the screen was last drawn, and whether
+// a key was handled since without drawing it
+var (
+	lastFrame  time.Time
+	frameStale bool
+)
+
+// inputPending reports whether bytes are already waiting to be read on fd,
+// as when typing fast or pasting
+var inputPending = func(fd int) bool {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, 0)
+	return err == nil && n > 0 && fds[0].Revents&unix.POLLIN != 0
+}
+
+// requestRefresh redraws the screen after a key was handled, unless more
+// keys are waiting and the last frame is younger than frameInterval: those
+// are handled first, and drawn in one frame
+func requestRefresh(fd int, now time.Time) {
+	if now.Sub(lastFrame) < frameInterval && inputPending(fd) {
+		frameStale = true
+		return
+	}
+	drawFrame(fd, now)
+}
+
+// drawFrame redraws the screen right away
+func drawFrame(fd int, now time.Time) {
+	refreshScreen(fd)
+	lastFrame = now
+	frameStale = false
+}
+
+// cell is a character on screen with the SGR parameters it is drawn with
+type cell struct {
+	ch    rune
+	style string
+}
+
+// shownFrame is what the terminal shows since the last frame, row by row,
+// or nil when unknown: before the first frame, or after something else was
+// drawn on top of it
+var shownFrame [][]cell
+
+// invalidateFrame makes the next frame redraw the whole screen. It is called
+// by whatever draws outside of refreshScreen, like prompts and popups.
+func invalidateFrame() {
+	shownFrame = nil
+}
+
+// screenCells plays the output of a frame on a blank rows x cols screen and
+// returns the cells it leaves. It knows the sequences the editor writes:
+// cursor moves (H, G), erasing (K, J), colors (m), \r and \n.
+func screenCells(frame string, rows, cols int) [][]cell {
+	grid := make([][]cell, rows)
+	for i := range grid {
+		grid[i] = blankRow(cols)
+	}
+	row, col, style := 0, 0, ""
+	for i := 0; i < len(frame); {
+		if strings.HasPrefix(frame[i:], "\x1b[") {
+			end := i + 2
+			for end < len(frame) && (frame[end] < 0x40 || frame[end] > 0x7e) {
+				end++
+			}
+			if end == len(frame) {
+				break
+			}
+			params := frame[i+2 : end]
+			switch frame[end] {
+			case 'H':
+				r, c, _ := strings.Cut(params, ";")
+				row, col = csiParam(r)-1, csiParam(c)-1
+			case 'G':
+				col = csiParam(params) - 1
+			case 'K':
+				for c := max(col, 0); row >= 0 && row < rows && c < cols; c++ {
+					grid[row][c] = cell{' ', style}
+				}
+			case 'J':
+				if params == "2" {
+					for r := range grid {
+						grid[r] = blankRow(cols)
+					}
+				}
+			case 'm':
+				if params == "" || params == "0" {
+					style = ""
+				} else if style == "" {
+					style = params
+				} else {
+					style += ";" + params
+				}
+			}
+			i = end + 1
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(frame[i:])
+		i += size
+		switch r {
+		case '\r':
+			col = 0
+		case '\n':
+			row++
+		case '\t':
+			col += 8 - col%8
+		default:
+			if row >= 0 && row < rows && col >= 0 && col < cols {
+				grid[row][col] = cell{r, style}
+			}
+			col++
+		}
+	}
+	return grid
+}
+
+// csiParam returns the numeric parameter of a CSI sequence, 1 when missing
+func csiParam(s string) int {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// blankRow returns a row of cols blank cells
+func blankRow(cols int) []cell {
+	row := make([]cell, cols)
+	for i := range row {
+		row[i] = cell{' ', ""}
+	}
+	return row
+}
+
+// frameUpdate returns what to write to the terminal to show frame with the
+// cursor at (cursorRow, cursorCol): only the rows that differ from the shown
+// frame, or all of them after clearing the screen when it is unknown or was
+// another size
+func frameUpdate(frame string, rows, cols, cursorRow, cursorCol int) string {
+	grid := screenCells(frame, rows, cols)
+	full := len(shownFrame) != rows || rows > 0 && len(shownFrame[0]) != cols
+	var out strings.Builder
+	// Hide the cursor while it moves around
+	out.WriteString("\x1b[?25l")
+	if full {
+		out.WriteString("\x1b[2J")
+	}
+	for r, row := range grid {
+		if !full && screenReader {
+			// Only the cells that changed, for screen readers to read them alone
+			if first, last, changed := changedSpan(shownFrame[r], row); changed {
+				fmt.Fprintf(&out, "\x1b[%d;%dH", r+1, first+1)
+				writeStyled(&out, row[first:last+1])
+			}
+			continue
+		}
+		if !full && slices.Equal(row, shownFrame[r]) {
+			continue
+		}
+		fmt.Fprintf(&out, "\x1b[%d;1H", r+1)
+		writeRow(&out, row)
+	}
+	shownFrame = grid
+	fmt.Fprintf(&out, "\x1b[%d;%dH\x1b[?25h", cursorRow, cursorCol)
+	return out.String()
+}
+
+// writeRow writes the cells of row, leaving out the trailing blanks, which
+// erasing the rest of the line draws
+func writeRow(out *strings.Builder, row []cell) {
+	end := len(row)
+	for end > 0 && row[end-1] == (cell{' ', ""}) {
+		end--
+	}
+	writeStyled(out, row[:end])
+	if end < len(row) {
+		out.WriteString("\x1b[K")
+	}
+}
+
+// writeStyled writes cells with their styles, and the default style after
+func writeStyled(out *strings.Builder, cells []cell) {
+	style := ""
+	for _, c := range cells {
+		if c.style != style {
+			out.WriteString("\x1b[m")
+			if c.style != "" {
+				out.WriteString("\x1b[" + c.style + "m")
+			}
+			style = c.style
+		}
+		out.WriteRune(c.ch)
+	}
+	if style != "" {
+		out.WriteString("\x1b[m")
+	}
+}
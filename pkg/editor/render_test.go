@@ -0,0 +1,115 @@
+package editor
+
+import (
+	"github.com/jellexet/golang-text-editor/pkg/termtest"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestRefreshCoalescesFrames(t *testing.T) {
+	resetSessionForTest()
+	oldPending := inputPending
+	t.Cleanup(func() { inputPending = oldPending; frameStale = false })
+	pending := true
+	inputPending = func(fd int) bool { return pending }
+
+	start := time.Now()
+	drawFrame(0, start)
+	requestRefresh(0, start.Add(5*time.Millisecond))
+	if !frameStale || lastFrame != start {
+		t.Fatal("a key followed by more input should not be drawn right away")
+	}
+	// Input that keeps coming is still drawn 60 times a second
+	requestRefresh(0, start.Add(frameInterval))
+	if frameStale || lastFrame != start.Add(frameInterval) {
+		t.Fatal("expected a frame once frameInterval passed")
+	}
+	// The last key of a burst is drawn at once
+	pending = false
+	requestRefresh(0, start.Add(frameInterval+time.Millisecond))
+	if frameStale {
+		t.Fatal("expected a frame when no input is waiting")
+	}
+}
+
+func TestScreenCells(t *testing.T) {
+	grid := screenCells("ab\x1b[K\r\n\x1b[7mcd\x1b[m\x1b[1;4Hx\x1b[3GY\tz", 2, 12)
+	got := ""
+	for _, row := range grid {
+		for _, c := range row {
+			got += string(c.ch)
+		}
+		got += "|"
+	}
+	if want := "abYx    z   |cd          |"; got != want {
+		t.Errorf("screen %q, want %q", got, want)
+	}
+	if grid[1][0].style != "7" || grid[1][2].style != "" {
+		t.Errorf("unexpected styles %+v", grid[1][:3])
+	}
+}
+
+func TestFrameUpdateRedrawsChangedRows(t *testing.T) {
+	invalidateFrame()
+	t.Cleanup(invalidateFrame)
+
+	first := frameUpdate("one\r\ntwo\r\nthree", 3, 10, 1, 1)
+	if !strings.Contains(first, "\x1b[2J") || !strings.Contains(first, "three") {
+		t.Fatalf("the first frame should draw everything, got %q", first)
+	}
+	next := frameUpdate("one\r\n\x1b[1mTWO\x1b[m\r\nthree", 3, 10, 2, 4)
+	if want := "\x1b[?25l\x1b[2;1H\x1b[m\x1b[1mTWO\x1b[m\x1b[K\x1b[2;4H\x1b[?25h"; next != want {
+		t.Errorf("update %q, want %q", next, want)
+	}
+	if same := frameUpdate("one\r\n\x1b[1mTWO\x1b[m\r\nthree", 3, 10, 2, 4); strings.Contains(same, "TWO") {
+		t.Errorf("an unchanged frame should only move the cursor, got %q", same)
+	}
+	if resized := frameUpdate("one", 4, 10, 1, 1); !strings.Contains(resized, "\x1b[2J") {
+		t.Errorf("a resized screen should be redrawn, got %q", resized)
+	}
+}
+
+// playKeys runs the editor on an emulated terminal of 24x80, the size
+// used when fd is not a terminal, and returns the screen left by the last
+// key: the screen is unplugged before Ctrl-Q clears it
+func playKeys(t *testing.T, keys string) *termtest.Screen {
+	// Quitting saves the cursor and histories, which go to a directory of the test
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	screen := termtest.New(24, 80)
+	oldOutput := output
+	output = screen
+	t.Cleanup(func() { output = oldOutput })
+	invalidateFrame()
+	t.Cleanup(invalidateFrame)
+
+	next := makeCallback([]byte(keys))
+	ProcessKeypress(-1, func() byte {
+		if b := next(); b != 0 {
+			return b
+		}
+		output = io.Discard
+		return CtrlQ
+	})
+	return screen
+}
+
+func TestTypingEndToEnd(t *testing.T) {
+	resetSessionForTest()
+	InitSession(-1, "notes.txt", "")
+
+	screen := playKeys(t, "hello\rworld")
+	if screen.Line(0) != "hello" || !strings.HasPrefix(screen.Line(1), "world") {
+		t.Errorf("screen:\n%s", screen)
+	}
+	if row, col := screen.Cursor(); row != 1 || col != 5 || !screen.CursorVisible {
+		t.Errorf("cursor at %d:%d (visible %v), want 1:5", row, col, screen.CursorVisible)
+	}
+	if status := screen.Line(23); !strings.Contains(status, "notes.txt") || !strings.Contains(status, "Row:2 Col:6") {
+		t.Errorf("status bar %q", status)
+	}
+	if cell := screen.Cell(23, 0); cell.Style == "" {
+		t.Errorf("the status bar should be styled, got %+v", cell)
+	}
+}
@@ -0,0 +1,65 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	// Always local: each filetype has its interpreter, given by its profile
+	settings["repl"] = setting{
+		getLocal: func(s *Session) string { return s.repl },
+		setLocal: func(s *Session, value string) error {
+			s.repl = value
+			return nil
+		},
+	}
+}
+
+// replInput returns what to type into an interpreter to run text: whole
+// lines, with a blank one after an indented last line for Python, which
+// ends blocks on one
+func replInput(text, fileType string) string {
+	text = strings.TrimRight(text, "\n") + "\n"
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	last := lines[len(lines)-1]
+	if fileType == "py" && strings.TrimSpace(last) != "" && strings.TrimLeft(last, " \t") != last {
+		text += "\n"
+	}
+	return text
+}
+
+// handleSend runs the selection, or the cursor line, in the terminal pane,
+// starting the interpreter given by the repl option when no pane is open
+// (:send). The cursor moves to the next line, to send the lines one by one.
+func handleSend(fd int, args string, callback func() byte) {
+	start, end, selected := selection()
+	if !selected {
+		start, end = lineBounds(lineStarts(), session.cursorRow-1)
+	}
+	text, _ := session.rope.Substring(start, end)
+	if strings.TrimSpace(text) == "" {
+		session.statusMessage = "Nothing to send"
+		return
+	}
+
+	if termPane == nil {
+		handleTerminal(fd, session.repl, callback)
+		if termPane == nil {
+			return
+		}
+	}
+	// The keys stay with the text, for the next lines
+	termPane.focused = false
+	if _, err := termPane.pty.WriteString(replInput(text, session.fileType())); err != nil {
+		session.statusMessage = fmt.Sprintf("Send: %v", err)
+		return
+	}
+
+	if !selected && session.cursorRow < len(lineStarts()) {
+		session.cursorIdx = lineStarts()[session.cursorRow]
+		updateCursorPosition()
+	}
+	session.selecting = false
+	session.statusMessage = fmt.Sprintf("Sent %d lines to %s", strings.Count(strings.TrimRight(text, "\n"), "\n")+1, termPane.name)
+}
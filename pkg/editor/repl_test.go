@@ -0,0 +1,58 @@
+package editor
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReplInput(t *testing.T) {
+	for _, tc := range [][3]string{
+		{"x = 1", "py", "x = 1\n"},
+		{"for i in r:\n    print(i)\n", "py", "for i in r:\n    print(i)\n\n"},
+		{"function f() {\n  return 1\n}", "js", "function f() {\n  return 1\n}\n"},
+		{"  select 1;", "sql", "  select 1;\n"},
+	} {
+		if got := replInput(tc[0], tc[1]); got != tc[2] {
+			t.Errorf("replInput(%q, %q) = %q, want %q", tc[0], tc[1], got, tc[2])
+		}
+	}
+}
+
+func TestReplProfiles(t *testing.T) {
+	resetSessionForTest()
+	s := newSession("notebook.py", "")
+	fireHooks(eventBufOpen, s)
+	if s.repl != "python3 -q" {
+		t.Errorf("Python buffers should send to python3, got %q", s.repl)
+	}
+}
+
+func TestSendToRepl(t *testing.T) {
+	if _, err := os.Stat("/dev/ptmx"); err != nil {
+		t.Skip("no pseudo-terminals:", err)
+	}
+	resetSessionForTest()
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(closeTerminal)
+	session = newSession("calc.txt", "one\ntwo\nthree\n")
+	session.screenRows, session.screenCols = 24, 80
+	buffers = []*Session{session}
+	runCommand(0, `setlocal repl while read l; do echo "got $l"; done`, nil)
+
+	runCommand(0, "send", nil)
+	if termPane == nil || termPane.focused {
+		t.Fatalf("the interpreter should run in a pane without the keys, status %q", session.statusMessage)
+	}
+	if session.cursorRow != 2 || session.statusMessage != "Sent 1 lines to "+session.repl {
+		t.Errorf("cursor on line %d, status %q", session.cursorRow, session.statusMessage)
+	}
+	selectRange(4, 14)
+	runCommand(0, "send", nil)
+	waitFor(t, func() bool { return strings.Contains(termPane.screen.String(), "got three") })
+	// The terminal echoes the lines too, before or after the answers
+	got := termPane.screen.String()
+	if i, j := strings.Index(got, "got one"), strings.Index(got, "got two"); i < 0 || j < i {
+		t.Errorf("pane shows\n%s", got)
+	}
+}
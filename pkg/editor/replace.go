@@ -0,0 +1,187 @@
+package editor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// parseSubstitute splits the "/old/new/flags" argument of :s. Any character
+// may stand for '/', and a backslash before it makes it part of the text.
+// The only flag is c, to confirm each replacement.
+func parseSubstitute(args string) (from, to, flags string, err error) {
+	usage := fmt.Errorf("usage: s/old/new/[c]")
+	if args == "" || !isSubstituteDelimiter(args[0]) {
+		return "", "", "", usage
+	}
+	delim := args[0]
+	var parts []string
+	var part strings.Builder
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == '\\' && i+1 < len(args) && args[i+1] == delim:
+			part.WriteByte(delim)
+			i++
+		case args[i] == delim:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(args[i])
+		}
+	}
+	// The closing delimiter is optional, and the flags follow it
+	switch {
+	case len(parts) == 2:
+		flags = part.String()
+	case part.Len() > 0 || len(parts) == 1:
+		parts = append(parts, part.String())
+	}
+	if len(parts) != 2 || parts[0] == "" || strings.Trim(flags, "c") != "" {
+		return "", "", "", usage
+	}
+	return parts[0], parts[1], flags, nil
+}
+
+// errSubstituteCanceled is returned by replaceConfirmed when Esc is pressed
+var errSubstituteCanceled = errors.New("canceled")
+
+// isSubstituteDelimiter reports whether c can separate the texts of :s
+func isSubstituteDelimiter(c byte) bool {
+	return isRegularCharacter(c) && c != ' ' && c != '\\' && !unicode.IsLetter(rune(c)) && !unicode.IsDigit(rune(c))
+}
+
+// handleSubstitute replaces every occurrence of a text by another, in the
+// selection when there is one and in the whole buffer otherwise (:s/old/new/).
+// With the c flag each occurrence is shown and the user asked about it.
+func handleSubstitute(fd int, args string, callback func() byte) {
+	if !editable() {
+		return
+	}
+	from, to, flags, err := parseSubstitute(strings.TrimSpace(args))
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Substitute: %v", err)
+		return
+	}
+
+	start, end, selected := selection()
+	if !selected {
+		start, end = 0, session.rope.Length()
+	}
+	text, err := session.rope.Substring(start, end)
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Substitute: %v", err)
+		return
+	}
+	where := ""
+	if selected {
+		where = " in the selection"
+	}
+	type substitution struct {
+		text string
+		n    int
+	}
+	cursor := session.cursorIdx
+	var sub substitution
+	if strings.Contains(flags, "c") {
+		if !strings.Contains(text, from) {
+			session.statusMessage = fmt.Sprintf("Not found%s: %s", where, from)
+			return
+		}
+		sub.text, sub.n, err = replaceConfirmed(fd, start, text, from, to, callback)
+		if sub.n == 0 && err == nil {
+			session.statusMessage = "Nothing replaced"
+		}
+	} else {
+		sub, err = interruptible("Replacing "+from, callback, func(ctx context.Context) (substitution, error) {
+			replaced, n, err := replaceAllContext(ctx, text, from, to)
+			return substitution{replaced, n}, err
+		})
+		if sub.n == 0 && err == nil {
+			session.statusMessage = fmt.Sprintf("Not found%s: %s", where, from)
+		}
+	}
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Substitute: %v", err)
+	}
+	n, replaced := sub.n, sub.text
+	if err != nil || n == 0 {
+		// Put back what showing the occurrences moved
+		if selected {
+			selectRange(start, end)
+		} else {
+			session.selecting = false
+			session.cursorIdx = cursor
+			updateCursorPosition()
+		}
+		return
+	}
+
+	replaceText(start, end, replaced)
+	if selected {
+		// Keep the replaced text selected
+		selectRange(start, start+len(replaced))
+	} else {
+		session.selecting = false
+		session.cursorIdx = min(cursor, session.rope.Length())
+		updateCursorPosition()
+	}
+	session.statusMessage = fmt.Sprintf("Replaced %d occurrences%s", n, where)
+}
+
+// replaceConfirmed is replaceAllContext asking before each replacement, with
+// the occurrence selected in the buffer, which starts text at offset start.
+// Esc cancels them all, returning errSubstituteCanceled.
+func replaceConfirmed(fd int, start int, text, from, to string, callback func() byte) (string, int, error) {
+	var b strings.Builder
+	n, done := 0, 0
+	reply := answerNo
+	for i := strings.Index(text, from); i >= 0 && reply != answerNone; {
+		at := done + i
+		if reply != answerAll {
+			selectRange(start+at, start+at+len(from))
+			refreshScreen(fd)
+			reply = confirm(fmt.Sprintf("Replace with %q? y/n, a: all the rest, Ctrl-Q: stop, Esc: cancel", to), true, callback)
+		}
+		b.WriteString(text[done:at])
+		switch reply {
+		case answerCancel:
+			return "", 0, errSubstituteCanceled
+		case answerYes, answerAll:
+			b.WriteString(to)
+			n++
+		default:
+			b.WriteString(from)
+		}
+		done = at + len(from)
+		i = strings.Index(text[done:], from)
+	}
+	b.WriteString(text[done:])
+	return b.String(), n, nil
+}
+
+// replaceAllContext is strings.ReplaceAll that also counts the occurrences
+// replaced, and gives up when ctx is canceled
+func replaceAllContext(ctx context.Context, text, from, to string) (string, int, error) {
+	var b strings.Builder
+	n := 0
+	for {
+		i := strings.Index(text, from)
+		if i < 0 {
+			break
+		}
+		if n%1024 == 0 && ctx.Err() != nil {
+			return "", 0, ctx.Err()
+		}
+		if n == 0 {
+			b.Grow(len(text))
+		}
+		b.WriteString(text[:i])
+		b.WriteString(to)
+		text = text[i+len(from):]
+		n++
+	}
+	b.WriteString(text)
+	return b.String(), n, nil
+}
@@ -0,0 +1,110 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestParseSubstitute(t *testing.T) {
+	for args, want := range map[string][3]string{
+		"/a/b/":        {"a", "b", ""},
+		"/a/b":         {"a", "b", ""},
+		"/a//":         {"a", "", ""},
+		"|x/y|z|":      {"x/y", "z", ""},
+		`/a\/b/c/`:     {"a/b", "c", ""},
+		"#foo bar#baz": {"foo bar", "baz", ""},
+		"/a/b/c":       {"a", "b", "c"},
+	} {
+		from, to, flags, err := parseSubstitute(args)
+		if err != nil || from != want[0] || to != want[1] || flags != want[2] {
+			t.Errorf("parseSubstitute(%q) = %q, %q, %q, %v", args, from, to, flags, err)
+		}
+	}
+	for _, args := range []string{"", "/a", "//b/", "/a/b/c/", "/a/b/g", "abc"} {
+		if _, _, _, err := parseSubstitute(args); err == nil {
+			t.Errorf("parseSubstitute(%q) should fail", args)
+		}
+	}
+}
+
+func TestSubstituteWholeBuffer(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("one two one\none\n")
+
+	runCommand(0, "s/one/1/", nil)
+	if got := session.rope.String(); got != "1 two 1\n1\n" {
+		t.Fatalf("got %q", got)
+	}
+	if session.statusMessage != "Replaced 3 occurrences" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+
+	runCommand(0, "s /none/x/", nil)
+	if session.statusMessage != "Not found: none" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+}
+
+func TestSubstituteConfirmed(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("a a a a a\n")
+
+	// Yes, no, then yes to the rest
+	runCommand(0, "s/a/b/c", makeCallback([]byte("yna")))
+	if got := session.rope.String(); got != "b a b b b\n" {
+		t.Fatalf("got %q", got)
+	}
+	if session.statusMessage != "Replaced 4 occurrences" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+
+	// Ctrl-Q keeps what was replaced so far
+	runCommand(0, "s/b/c/c", makeCallback([]byte{'y', CtrlQ}))
+	if got := session.rope.String(); got != "c a b b b\n" {
+		t.Fatalf("got %q", got)
+	}
+
+	// Esc cancels them all
+	runCommand(0, "s/b/d/c", makeCallback([]byte{'y', Esc}))
+	if got := session.rope.String(); got != "c a b b b\n" {
+		t.Fatalf("got %q", got)
+	}
+	if session.statusMessage != "Substitute: canceled" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+	if _, _, ok := selection(); ok || session.cursorIdx != 0 {
+		t.Errorf("canceling should leave the cursor where it was, at %d", session.cursorIdx)
+	}
+}
+
+func TestSubstituteInSelection(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("a a\na a\na a\n")
+
+	// Select the second line with Shift+Down
+	session.cursorIdx = 4
+	updateCursorPosition()
+	ProcessKeypress(0, makeCallback([]byte{Esc, '[', '1', ';', '2', 'B', CtrlQ, CtrlQ}))
+	start, end, ok := selection()
+	if !ok || start != 4 || end != 8 {
+		t.Fatalf("expected the second line selected, got %d-%d %v", start, end, ok)
+	}
+
+	runCommand(0, "s/a/bb/", nil)
+	if got := session.rope.String(); got != "a a\nbb bb\na a\n" {
+		t.Fatalf("got %q", got)
+	}
+	if session.statusMessage != "Replaced 2 occurrences in the selection" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+	if start, end, _ := selection(); start != 4 || end != 10 {
+		t.Errorf("the replaced text should stay selected, got %d-%d", start, end)
+	}
+
+	// Moving without Shift ends the selection
+	ProcessKeypress(0, makeCallback([]byte{Esc, '[', 'A', CtrlQ, CtrlQ}))
+	if _, _, ok := selection(); ok {
+		t.Error("the selection should be gone")
+	}
+}
@@ -0,0 +1,26 @@
+package editor
+
+import "fmt"
+
+// newScratchName returns a name for a new scratch buffer: "[Scratch]",
+// then "[Scratch 2]" and on while those are open
+func newScratchName() string {
+	name := "[Scratch]"
+	for n := 2; findBuffer(name) != nil; n++ {
+		name = fmt.Sprintf("[Scratch %d]", n)
+	}
+	return name
+}
+
+// handleScratch opens an empty scratch buffer, for notes or pasted output,
+// which is thrown away on quitting unless written to a file with :w FILE
+// (:scratch)
+func handleScratch(fd int, args string, callback func() byte) {
+	pushJump()
+	s := newSession(newScratchName(), "")
+	s.scratch = true
+	s.readOnly = false
+	buffers = append(buffers, s)
+	switchToBuffer(s)
+	session.statusMessage = "Scratch buffer: never saved, :w FILE writes it to a file"
+}
@@ -0,0 +1,59 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScratchBuffer(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("main.go", "package main\n")
+	buffers = []*Session{session}
+
+	runCommand(0, "scratch", nil)
+	runCommand(0, "scratch", nil)
+	if len(buffers) != 3 || session.filename != "[Scratch 2]" || !session.scratch {
+		t.Fatalf("buffers %d, active %q", len(buffers), session.filename)
+	}
+	handleInsert("notes")
+	if session.modified() || windowTitle() != "[Scratch 2] — goedit" {
+		t.Errorf("a scratch buffer should never show unsaved changes, title %q", windowTitle())
+	}
+
+	// Ctrl-S neither prompts nor saves
+	handleSave(0, func() byte {
+		t.Fatal("saving a scratch buffer should not prompt")
+		return 0
+	})
+	if session.statusMessage != "Scratch buffer, not saved: :w FILE writes it to a file" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+
+	// Writing it to a path makes it the buffer of that file
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	runCommand(0, "w "+path, nil)
+	if got, _ := os.ReadFile(path); string(got) != "notes" {
+		t.Fatalf("file holds %q", got)
+	}
+	if session.scratch || session.filename != path {
+		t.Errorf("buffer %q, scratch %v", session.filename, session.scratch)
+	}
+	handleInsert("!")
+	if !session.modified() {
+		t.Error("the written buffer should track its changes like any file")
+	}
+
+	jumpBack()
+	if session.filename != "[Scratch]" {
+		t.Errorf("Ctrl-T should go back to the first scratch buffer, got %q", session.filename)
+	}
+}
+
+func TestOutputBuffersAreScratch(t *testing.T) {
+	resetSessionForTest()
+	showScratch("[Output: ls]", "a\nb\n")
+	if !session.scratch {
+		t.Error("command output should go to a scratch buffer")
+	}
+}
@@ -0,0 +1,56 @@
+package editor
+
+import "fmt"
+
+// scrollbarEnabled shows on the right edge of the text where the lines on
+// screen are in the buffer (:set scrollbar on)
+var scrollbarEnabled = false
+
+// scrollbarWidth returns how many screen columns the scrollbar takes, 0
+// when it isn't shown
+func scrollbarWidth() int {
+	if !scrollbarEnabled || int(session.screenCols) < 1+minimapMinText {
+		return 0
+	}
+	return 1
+}
+
+// scrollbarRows renders textRows cells of the scrollbar of a buffer of
+// lineCount lines whose lines from first on are on screen. The thumb is
+// drawn with half blocks, twice as fine as the rows; it is left out when
+// the whole buffer fits.
+func scrollbarRows(lineCount, textRows, first int) []string {
+	rows := make([]string, textRows)
+	halves := 2 * textRows
+	top, bottom := 0, 0
+	if lineCount > textRows {
+		top = first * halves / lineCount
+		bottom = min(top+max(textRows*halves/lineCount, 1), halves)
+	}
+	for row := range rows {
+		upper := 2*row >= top && 2*row < bottom
+		lower := 2*row+1 >= top && 2*row+1 < bottom
+		cell := " "
+		switch {
+		case upper && lower:
+			cell = "█"
+		case upper:
+			cell = "▀"
+		case lower:
+			cell = "▄"
+		}
+		rows[row] = fmt.Sprintf("\x1b[%sm%s\x1b[m", currentTheme.minimap, cell)
+	}
+	return rows
+}
+
+// percentSegment is how far through the buffer the cursor line is, as a
+// percentage of its lines, or of its bytes for a large file, whose lines
+// aren't indexed
+func percentSegment() string {
+	if session.largeFile {
+		return fmt.Sprintf("%d%%", session.cursorIdx*100/max(session.rope.Length(), 1))
+	}
+	lines := len(lineStarts())
+	return fmt.Sprintf("%d%%", min(session.cursorRow, lines)*100/lines)
+}
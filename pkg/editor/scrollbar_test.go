@@ -0,0 +1,67 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+// scrollbarCells returns the blocks of rows, without their colors
+func scrollbarCells(rows []string) string {
+	var b strings.Builder
+	for _, row := range rows {
+		b.WriteString(strings.TrimSuffix(strings.TrimPrefix(row, "\x1b["+currentTheme.minimap+"m"), "\x1b[m"))
+	}
+	return b.String()
+}
+
+func TestScrollbarRows(t *testing.T) {
+	resetSessionForTest()
+	for _, tc := range []struct {
+		lines, first int
+		want         string
+	}{
+		{4, 0, "    "},     // everything fits
+		{8, 0, "██  "},     // the first half
+		{8, 4, "  ██"},     // the second half
+		{16, 2, "▄▀  "},    // a quarter, half a row down
+		{400, 399, "   ▄"}, // the thumb is never less than half a row
+	} {
+		if got := scrollbarCells(scrollbarRows(tc.lines, 4, tc.first)); got != tc.want {
+			t.Errorf("%d lines from %d: scrollbar %q, want %q", tc.lines, tc.first, got, tc.want)
+		}
+	}
+}
+
+func TestDrawTextViewWithScrollbar(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	session.rope = buffer.NewRope(strings.Repeat("some text\n", 10))
+
+	runCommand(0, "set scrollbar on", nil)
+	var buf strings.Builder
+	drawTextView(&buf, 5)
+	if got := strings.Count(buf.String(), "\x1b[80G"); got != 5 {
+		t.Errorf("expected the scrollbar on every row at column 80, got %d rows", got)
+	}
+
+	// The minimap moves left of it
+	runCommand(0, "set minimap on", nil)
+	buf.Reset()
+	drawTextView(&buf, 5)
+	if got := strings.Count(buf.String(), "\x1b[69G│"); got != 5 {
+		t.Errorf("expected the minimap on every row at column 69, got %d rows", got)
+	}
+}
+
+func TestPercentSegment(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope(strings.Repeat("line\n", 3)) // 4 lines, the last empty
+	for row, want := range map[int]string{1: "25%", 2: "50%", 4: "100%"} {
+		session.cursorRow = row
+		if got := percentSegment(); got != want {
+			t.Errorf("row %d: %q, want %q", row, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package editor
+
+// Shift+arrow key constants, which extend the selection
+var (
+	ShiftArrowUp    = Key{Special: KeyUp, Mod: ModShift}
+	ShiftArrowDown  = Key{Special: KeyDown, Mod: ModShift}
+	ShiftArrowLeft  = Key{Special: KeyLeft, Mod: ModShift}
+	ShiftArrowRight = Key{Special: KeyRight, Mod: ModShift}
+)
+
+// selection returns the selected bytes [start, end) of the active buffer,
+// between the anchor and the cursor. ok is false when nothing is selected.
+func selection() (start, end int, ok bool) {
+	if !session.selecting {
+		return 0, 0, false
+	}
+	n := session.rope.Length()
+	start = min(session.selAnchor, session.cursorIdx, n)
+	end = min(max(session.selAnchor, session.cursorIdx), n)
+	return start, end, start < end
+}
+
+// selectRange selects [start, end) leaving the cursor at end
+func selectRange(start, end int) {
+	session.selecting = true
+	session.selAnchor = start
+	session.cursorIdx = end
+	updateCursorPosition()
+}
+
+// handleSelectAll selects the whole buffer, leaving the cursor at its end
+// (Ctrl-A and :selectall)
+func handleSelectAll() {
+	selectRange(0, session.rope.Length())
+}
+
+// handleDuplicate inserts a copy of the selection right after it and
+// selects the copy, or copies the cursor line below it when nothing is
+// selected (Alt-D and :duplicate)
+func handleDuplicate() {
+	before := session.rope
+	start, end, ok := selection()
+	if !ok {
+		line, lineStart := cursorLine()
+		col := session.cursorIdx - lineStart
+		replaceText(lineStart+len(line), lineStart+len(line), "\n"+line)
+		if session.rope != before {
+			session.cursorIdx = lineStart + len(line) + 1 + col
+			updateCursorPosition()
+		}
+		return
+	}
+	text, err := session.rope.Substring(start, end)
+	if err != nil {
+		return
+	}
+	replaceText(end, end, text)
+	if session.rope != before {
+		selectRange(end, end+len(text))
+	}
+}
+
+// extendSelection moves the cursor like arrowKey, starting a selection at
+// the old position when there is none
+func extendSelection(arrowKey Key) {
+	if !session.selecting {
+		session.selecting = true
+		session.selAnchor = session.cursorIdx
+	}
+	editorMoveCursor(arrowKey)
+}
+
+// clearsSelection reports whether key ends the selection: moving without
+// Shift, typing, undo, redo and Esc do
+func clearsSelection(key Key) bool {
+	switch key {
+	case ArrowUp, ArrowDown, ArrowLeft, ArrowRight, HomeKey, EndKey, CtrlHome, CtrlEnd, CtrlArrowLeft, CtrlArrowRight, PageUpKey, PageDownKey, DeleteKey,
+		EscKey, ReturnKey, BackspaceKey, ctrl('w'), ctrl('k'), ctrl('z'), ctrl('r'):
+		return true
+	}
+	_, ok := key.char()
+	return ok
+}
+
+// selectionHighlights highlights the selection, if any
+func selectionHighlights() []highlight {
+	start, end, ok := selection()
+	if !ok {
+		return nil
+	}
+	return []highlight{{start, end, currentTheme.selection}}
+}
@@ -0,0 +1,120 @@
+package editor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// shellResult is the outcome of a shell command
+type shellResult struct {
+	stdout   string
+	stderr   string
+	exitCode int
+	err      error // set when the command could not be started at all
+}
+
+// runShell runs command through the user's shell ($SHELL, or /bin/sh).
+// Canceling ctx kills the command with everything it started, like the
+// other commands of a pipeline.
+func runShell(ctx context.Context, command string) shellResult {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	cmd := exec.CommandContext(ctx, shell, "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	log.Printf("shell %q: %v", command, err)
+
+	result := shellResult{stdout: stdout.String(), stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		result.err = err
+	}
+	return result
+}
+
+// runShellInterruptible runs command until it ends or Esc or Ctrl-C
+// interrupts it
+func runShellInterruptible(command string, callback func() byte) (shellResult, error) {
+	return interruptible("Running "+command, callback, func(ctx context.Context) (shellResult, error) {
+		return runShell(ctx, command), nil
+	})
+}
+
+// status summarizes the exit status and the first line of stderr
+func (r shellResult) status(command string) string {
+	if r.err != nil {
+		return fmt.Sprintf("!%s: %v", command, r.err)
+	}
+	msg := fmt.Sprintf("!%s: exit %d", command, r.exitCode)
+	if stderr := strings.TrimSpace(r.stderr); stderr != "" {
+		msg += ": " + firstLine(stderr)
+	}
+	return msg
+}
+
+// isUnnamed reports whether filename is a placeholder like "[No Name]"
+// or a URL rather than a path on disk
+func isUnnamed(filename string) bool {
+	return strings.HasPrefix(filename, "[") && strings.HasSuffix(filename, "]") || IsURL(filename)
+}
+
+// handleShellCommand runs a shell command (:!cmd) and shows its output,
+// stdout followed by stderr, in an output buffer
+func handleShellCommand(command string, callback func() byte) {
+	if command == "" {
+		session.statusMessage = "Usage: !command"
+		return
+	}
+	result, err := runShellInterruptible(command, callback)
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("!%s: %v", command, err)
+		return
+	}
+	if result.err != nil {
+		session.statusMessage = result.status(command)
+		return
+	}
+
+	showScratch("[Output: "+command+"]", result.stdout+result.stderr)
+	session.statusMessage = result.status(command) + " (Ctrl-T to go back)"
+}
+
+// handleReadCommand inserts the standard output of a shell command at the
+// cursor (:r !cmd)
+func handleReadCommand(fd int, args string, callback func() byte) {
+	if !editable() {
+		return
+	}
+	command, ok := strings.CutPrefix(args, "!")
+	if !ok || strings.TrimSpace(command) == "" {
+		session.statusMessage = "Usage: r !command"
+		return
+	}
+	command = strings.TrimSpace(command)
+
+	result, err := runShellInterruptible(command, callback)
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("!%s: %v", command, err)
+		return
+	}
+	if result.err == nil && result.stdout != "" {
+		handleInsert(result.stdout)
+	}
+	session.statusMessage = result.status(command)
+}
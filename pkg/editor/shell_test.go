@@ -0,0 +1,47 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestShellCommand_OpensOutputBuffer(t *testing.T) {
+	resetSessionForTest()
+	t.Setenv("SHELL", "/bin/sh")
+	origin := session
+	session.rope = buffer.NewRope("text")
+
+	runCommand(0, "!echo out; echo err >&2; exit 3", nil)
+	if session == origin {
+		t.Fatalf("output should open in a new buffer")
+	}
+	if got := session.rope.String(); got != "out\nerr\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+	if !strings.Contains(session.statusMessage, "exit 3: err") {
+		t.Fatalf("status should carry exit code and stderr: %q", session.statusMessage)
+	}
+
+	jumpBack()
+	if session != origin {
+		t.Fatalf("Ctrl-T should return to the original buffer")
+	}
+}
+
+func TestReadCommand_InsertsStdoutAtCursor(t *testing.T) {
+	resetSessionForTest()
+	t.Setenv("SHELL", "/bin/sh")
+	session.rope = buffer.NewRope("a\nb")
+	session.cursorIdx = 2
+	updateCursorPosition()
+
+	runCommand(0, "r !printf 'x\\n'", nil)
+	if got := session.rope.String(); got != "a\nx\nb" {
+		t.Fatalf("unexpected buffer %q (%s)", got, session.statusMessage)
+	}
+	if session.statusMessage != "!printf 'x\\n': exit 0" {
+		t.Fatalf("unexpected status %q", session.statusMessage)
+	}
+}
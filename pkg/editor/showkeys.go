@@ -0,0 +1,147 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// showKeys draws a box listing the last keys read, the bytes the terminal
+// sent for them and what they did, for finding out why a key does nothing
+// or the wrong thing (:showkeys)
+var showKeys bool
+
+// keyTraceLength is how many keys the box lists
+const keyTraceLength = 8
+
+// keyTraceEntry is a key read, or a command run, as :showkeys lists it
+type keyTraceEntry struct {
+	bytes  string // what the terminal sent, "" for a command
+	key    string
+	action string
+}
+
+// keyTrace holds the last keys read, oldest first
+var keyTrace []keyTraceEntry
+
+// keyTraceBytes collects the bytes of the key being read
+var keyTraceBytes []byte
+
+// keyActions names what the keys of the main loop do
+var keyActions = map[Key]string{
+	ArrowUp: "move up", ArrowDown: "move down", ArrowLeft: "move left", ArrowRight: "move right",
+	HomeKey: "line start", EndKey: "line end", CtrlArrowLeft: "word left", CtrlArrowRight: "word right",
+	CtrlHome: "buffer start", CtrlEnd: "buffer end", PageUpKey: "page up", PageDownKey: "page down",
+	ShiftArrowUp: "select up", ShiftArrowDown: "select down", ShiftArrowLeft: "select left", ShiftArrowRight: "select right",
+	DeleteKey: "delete", BackspaceKey: "backspace", TabKey: "indent", ReturnKey: "new line",
+	EscKey: "deselect", BracketedPaste: "paste", FocusIn: "check files on disk", MouseEvent: "mouse",
+	ctrl('a'): "selectall", ctrl('q'): "quit", ctrl('n'): "complete", ctrl('e'): "command prompt",
+	ctrl('f'): "search", ctrl('g'): "hover", ctrl('l'): "zz", ctrl(']'): "definition", ctrl('t'): "back",
+	ctrl('r'): "redo", ctrl('s'): "save", ctrl('z'): "undo", ctrl('w'): "delete word",
+	ctrl('k'): "delete to line end", ctrl('v'): "unicode",
+	alt('n'): "cnext", alt('p'): "cprev", alt('o'): "other side", alt('d'): "duplicate",
+	alt('('): "outer-start", alt(')'): "outer-end", alt('b'): "inside-brackets", alt('q'): "inside-quotes",
+	alt('a'): "inc", alt('x'): "dec", alt('v'): "paste", alt('l'): "speak", alt('t'): "terminal focus",
+	alt('w'): "next window",
+}
+
+// readTracedKey reads a key like editorReadKeypress, keeping the bytes it
+// is made of while :showkeys is on
+func readTracedKey(callback func() byte) Key {
+	if !showKeys {
+		return editorReadKeypress(callback)
+	}
+	return editorReadKeypress(func() byte {
+		b := callback()
+		if b != 0 {
+			keyTraceBytes = append(keyTraceBytes, b)
+		}
+		return b
+	})
+}
+
+// keyAction names what key does in the state the editor is in before
+// handling it
+func keyAction(key Key) string {
+	switch {
+	case pagerMode:
+		return "pager"
+	case termPane != nil && termPane.focused && key != alt('t'):
+		return "terminal pane"
+	case pluginKeys[key].run != nil:
+		return "plugin " + pluginKeys[key].plugin
+	case session.hex != nil:
+		return "hex view"
+	case session.table != nil:
+		return "table view"
+	}
+	if action, ok := keyActions[key]; ok {
+		return action
+	}
+	if c, ok := key.char(); ok {
+		return "insert " + strconv.QuoteRune(rune(c))
+	}
+	return "unbound"
+}
+
+// traceKey adds key, read from keyTraceBytes, to the keys :showkeys lists
+func traceKey(key Key) {
+	if showKeys {
+		addKeyTrace(keyTraceEntry{strconv.Quote(string(keyTraceBytes)), key.String(), keyAction(key)})
+	}
+}
+
+// traceCommand adds a command run from the prompt to the keys :showkeys
+// lists, as the prompt key only opened the prompt
+func traceCommand(line string) {
+	if showKeys {
+		addKeyTrace(keyTraceEntry{action: ":" + line})
+	}
+}
+
+// addKeyTrace adds entry to keyTrace, dropping the oldest beyond
+// keyTraceLength
+func addKeyTrace(entry keyTraceEntry) {
+	keyTrace = append(keyTrace, entry)
+	if len(keyTrace) > keyTraceLength {
+		keyTrace = keyTrace[len(keyTrace)-keyTraceLength:]
+	}
+}
+
+// drawKeyTrace writes the box of :showkeys into buf, in the bottom right
+// corner of the textRows rows of text
+func drawKeyTrace(buf *strings.Builder, textRows int) {
+	lines := make([]string, 0, keyTraceLength)
+	for _, entry := range keyTrace {
+		lines = append(lines, fmt.Sprintf("%-16s %-12s %s", entry.bytes, entry.key, entry.action))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "Press a key")
+	}
+	box := boxLines(strings.Join(lines, "\n"), "Keys", int(session.screenCols)-4, keyTraceLength)
+	width := len([]rune(box[0]))
+	top := max(textRows-len(box)+1, 1)
+	left := max(int(session.screenCols)-width+1, 1)
+	for i, line := range box {
+		fmt.Fprintf(buf, "\x1b[%d;%dH%s", top+i, left, line)
+	}
+}
+
+// handleShowKeys toggles the box listing the keys read and what they did,
+// or turns it on or off (:showkeys [on|off])
+func handleShowKeys(fd int, args string, callback func() byte) {
+	on := !showKeys
+	if args != "" {
+		b, err := parseBool(args)
+		if err != nil {
+			session.statusMessage = "Usage: showkeys [on|off]"
+			return
+		}
+		on = b
+	}
+	showKeys = on
+	keyTrace = nil
+	if showKeys {
+		session.statusMessage = "Showing keys (:showkeys off to stop)"
+	}
+}
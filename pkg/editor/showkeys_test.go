@@ -0,0 +1,63 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyString(t *testing.T) {
+	for key, want := range map[Key]string{
+		{Rune: 'x'}:                        "x",
+		{Rune: ' '}:                        "Space",
+		ctrl('q'):                          "Ctrl-Q",
+		alt('d'):                           "Alt-D",
+		alt('D'):                           "Alt-Shift-D",
+		ShiftArrowUp:                       "Shift-Up",
+		CtrlArrowLeft:                      "Ctrl-Left",
+		{Rune: 'n', Mod: ModAlt | ModCtrl}: "Ctrl-Alt-N",
+		fn(5):                              "F5",
+		ReturnKey:                          "Return",
+	} {
+		if got := key.String(); got != want {
+			t.Errorf("%+v is named %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestShowKeys(t *testing.T) {
+	resetSessionForTest()
+	t.Cleanup(func() { showKeys, keyTrace = false, nil })
+	InitSession(-1, "notes.txt", "")
+
+	screen := playKeys(t, "\x05showkeys\ra\x1b[1;5D\x1b[1;3P\x05stats\r")
+	var box []string
+	for row := range 24 {
+		if line := screen.Line(row); strings.Contains(line, "│") {
+			_, inside, _ := strings.Cut(line, "│")
+			box = append(box, strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(inside), "│")))
+		}
+	}
+	want := []string{
+		`"a"              a            insert 'a'`,
+		`"\x1b[1;5D"      Ctrl-Left    word left`,
+		`"\x1b[1;3P"      Alt-F1       unbound`,
+		`"\x05"           Ctrl-E       command prompt`,
+		`:stats`,
+	}
+	if len(box) != len(want) {
+		t.Fatalf("the box shows %q", box)
+	}
+	for i := range want {
+		if box[i] != want[i] {
+			t.Errorf("line %d of the box is %q, want %q", i, box[i], want[i])
+		}
+	}
+
+	// Off, the box goes away
+	resetSessionForTest()
+	InitSession(-1, "notes.txt", "")
+	screen = playKeys(t, "\x05showkeys off\r")
+	if strings.Contains(screen.String(), "Keys") {
+		t.Errorf("the box is still shown:\n%s", screen)
+	}
+}
@@ -0,0 +1,357 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// spellChecker holds the words considered correctly spelled
+type spellChecker struct {
+	words        map[string]bool
+	personalPath string // file that words added by the user are appended to
+}
+
+// dictionaryPaths are tried in order to find a system word list.
+// Hunspell .dic files work too, their affix flags are ignored.
+var dictionaryPaths = []string{
+	"/usr/share/hunspell/en_US.dic",
+	"/usr/share/myspell/en_US.dic",
+	"/usr/share/myspell/dicts/en_US.dic",
+	"/usr/share/dict/words",
+}
+
+// proseExtensions are spell checked entirely, other files only in comments and strings
+var proseExtensions = map[string]bool{
+	"": true, ".txt": true, ".md": true, ".markdown": true, ".rst": true, ".tex": true,
+}
+
+// spell is the loaded checker, nil until spell checking is first enabled
+var spell *spellChecker
+
+// spellEnabled tells the renderer to underline misspelled words
+var spellEnabled bool
+
+// loadSpellChecker reads the system dictionary and the personal one
+func loadSpellChecker() (*spellChecker, error) {
+	sc := &spellChecker{words: make(map[string]bool)}
+
+	loaded := false
+	for _, path := range dictionaryPaths {
+		if err := sc.loadWords(path); err == nil {
+			loaded = true
+			break
+		}
+	}
+	if !loaded {
+		return nil, fmt.Errorf("no dictionary found (install hunspell-en-us or a words file)")
+	}
+
+	if dir, err := os.UserConfigDir(); err == nil {
+		sc.personalPath = filepath.Join(dir, "goedit", "spell.txt")
+		sc.loadWords(sc.personalPath)
+	}
+	return sc, nil
+}
+
+// loadWords adds every word of a word list to the dictionary
+func (sc *spellChecker) loadWords(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Hunspell lines look like "word/FLAGS"; the first line is a count
+		word, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), "/")
+		if word != "" {
+			sc.words[word] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// known reports whether word is spelled correctly.
+// Capitalized forms of dictionary words are accepted.
+func (sc *spellChecker) known(word string) bool {
+	return sc.words[word] || sc.words[strings.ToLower(word)]
+}
+
+// addWord accepts word from now on and appends it to the personal dictionary
+func (sc *spellChecker) addWord(word string) error {
+	sc.words[word] = true
+	if sc.personalPath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(sc.personalPath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(sc.personalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, word)
+	return err
+}
+
+// suggest returns up to limit dictionary words closest to word,
+// nearest first, considering only words within an edit distance of 2
+func (sc *spellChecker) suggest(word string, limit int) []string {
+	type candidate struct {
+		word     string
+		distance int
+	}
+	lower := strings.ToLower(word)
+
+	var candidates []candidate
+	for w := range sc.words {
+		if abs(len(w)-len(lower)) > 2 {
+			continue
+		}
+		if d := editDistance(lower, strings.ToLower(w)); d <= 2 {
+			candidates = append(candidates, candidate{w, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].word < candidates[j].word
+	})
+
+	var suggestions []string
+	for _, c := range candidates {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestion := c.word
+		// Keep the capitalization the user typed
+		if r, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(r) {
+			first, size := utf8.DecodeRuneInString(suggestion)
+			suggestion = string(unicode.ToUpper(first)) + suggestion[size:]
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// spellRegions returns the byte ranges of text that should be spell checked:
+// everything in prose files, only comments and string literals in code
+func spellRegions(fileType, text string) [][2]int {
+	ext := "." + fileType
+	if proseExtensions[ext] {
+		return [][2]int{{0, len(text)}}
+	}
+
+	var regions [][2]int
+	for _, l := range literals(fileType, text) {
+		regions = append(regions, [2]int{l.start, l.end})
+	}
+	return regions
+}
+
+// spellWords calls fn with every word of text[start:end] worth checking.
+// Words with digits or inner capitals are identifiers, not prose.
+func spellWords(text string, start, end int, fn func(wordStart, wordEnd int)) {
+	i := start
+	for i < end {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !unicode.IsLetter(r) {
+			i += size
+			continue
+		}
+
+		wordStart := i
+		identifier := false
+		for i < end {
+			r, size = utf8.DecodeRuneInString(text[i:])
+			if unicode.IsLetter(r) || (r == '\'' && i+size < end && isLetterAt(text, i+size)) {
+				if unicode.IsUpper(r) && i > wordStart {
+					identifier = true
+				}
+			} else if unicode.IsDigit(r) || r == '_' {
+				identifier = true
+			} else {
+				break
+			}
+			i += size
+		}
+
+		if !identifier && utf8.RuneCountInString(text[wordStart:i]) > 1 {
+			fn(wordStart, i)
+		}
+	}
+}
+
+func isLetterAt(text string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return unicode.IsLetter(r)
+}
+
+// spellHighlights underlines the misspelled words of the active buffer
+func spellHighlights(text string) []highlight {
+	if spell == nil {
+		return nil
+	}
+	var highlights []highlight
+	for _, region := range spellRegions(session.fileType(), text) {
+		spellWords(text, region[0], region[1], func(start, end int) {
+			if !spell.known(text[start:end]) {
+				highlights = append(highlights, highlight{start: start, end: end, style: currentTheme.misspelled})
+			}
+		})
+	}
+	return highlights
+}
+
+// wordAtCursor returns the bounds of the word under or just before the cursor
+func wordAtCursor() (start, end int) {
+	text := session.rope.String()
+	start, end = session.cursorIdx, session.cursorIdx
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:start])
+		if !unicode.IsLetter(r) && r != '\'' {
+			break
+		}
+		start -= size
+	}
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsLetter(r) && r != '\'' {
+			break
+		}
+		end += size
+	}
+	return start, end
+}
+
+// ensureSpellChecker loads the dictionary the first time it is needed
+func ensureSpellChecker() bool {
+	if spell != nil {
+		return true
+	}
+	sc, err := loadSpellChecker()
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Spell: %v", err)
+		return false
+	}
+	spell = sc
+	return true
+}
+
+// handleSpellToggle turns misspelling underlines on and off (:spell)
+func handleSpellToggle(fd int, args string, callback func() byte) {
+	if !spellEnabled && !ensureSpellChecker() {
+		return
+	}
+	spellEnabled = !spellEnabled
+	if spellEnabled {
+		session.statusMessage = "Spell checking on"
+	} else {
+		session.statusMessage = "Spell checking off"
+	}
+}
+
+// handleSpellSuggest cycles through corrections for the word under the cursor
+// and replaces it with the one accepted by Return (:suggest)
+func handleSpellSuggest(fd int, args string, callback func() byte) {
+	if !editable() {
+		return
+	}
+	if !ensureSpellChecker() {
+		return
+	}
+	start, end := wordAtCursor()
+	word := session.rope.String()[start:end]
+	if word == "" {
+		session.statusMessage = "No word under the cursor"
+		return
+	}
+	if spell.known(word) {
+		session.statusMessage = fmt.Sprintf("%q is spelled correctly", word)
+		return
+	}
+
+	suggestions := spell.suggest(word, 10)
+	for i, suggestion := range suggestions {
+		session.statusMessage = fmt.Sprintf("%s -> %s (%d/%d) Ctrl-n:next Return:accept Esc:cancel",
+			word, suggestion, i+1, len(suggestions))
+		refreshScreen(fd)
+
+		key := editorReadKeypress(callback)
+		for key == (Key{}) {
+			key = editorReadKeypress(callback)
+		}
+		switch key {
+		case ctrl('n'):
+			continue
+		case ReturnKey:
+			replaceText(start, end, suggestion)
+			session.statusMessage = fmt.Sprintf("Replaced %q with %q", word, suggestion)
+			return
+		default:
+			session.statusMessage = "Suggestions canceled"
+			return
+		}
+	}
+	session.statusMessage = fmt.Sprintf("No suggestions for %q", word)
+}
+
+// handleSpellAdd adds the word under the cursor (or the argument) to the
+// personal dictionary (:spelladd [word])
+func handleSpellAdd(fd int, args string, callback func() byte) {
+	if !ensureSpellChecker() {
+		return
+	}
+	word := args
+	if word == "" {
+		start, end := wordAtCursor()
+		word = session.rope.String()[start:end]
+	}
+	if word == "" {
+		session.statusMessage = "No word under the cursor"
+		return
+	}
+	if err := spell.addWord(word); err != nil {
+		session.statusMessage = fmt.Sprintf("Spell: %v", err)
+		return
+	}
+	session.statusMessage = fmt.Sprintf("Added %q to the personal dictionary", word)
+}
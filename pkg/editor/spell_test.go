@@ -0,0 +1,89 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func testSpellChecker(t *testing.T, words ...string) *spellChecker {
+	sc := &spellChecker{words: map[string]bool{}, personalPath: filepath.Join(t.TempDir(), "spell.txt")}
+	for _, w := range words {
+		sc.words[w] = true
+	}
+	return sc
+}
+
+func TestSpellRegions_CodeOnlyCommentsAndStrings(t *testing.T) {
+	text := "x := \"helo\" // a coment\n/* blk */ y"
+	regions := spellRegions("go", text)
+	var got []string
+	for _, r := range regions {
+		got = append(got, text[r[0]:r[1]])
+	}
+	want := []string{"\"helo\"", "// a coment", "/* blk */"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	if regions := spellRegions("txt", text); len(regions) != 1 || regions[0] != [2]int{0, len(text)} {
+		t.Fatalf("prose files should be checked entirely: %v", regions)
+	}
+}
+
+func TestSpellHighlights_SkipsIdentifiers(t *testing.T) {
+	resetSessionForTest()
+	spell = testSpellChecker(t, "the", "cat", "sat")
+	defer func() { spell = nil }()
+
+	session.filename = "notes.txt"
+	text := "The cat szt on myVar and x2 don't"
+	var got []string
+	for _, h := range spellHighlights(text) {
+		got = append(got, text[h.start:h.end])
+	}
+	want := []string{"szt", "on", "and", "don't"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestSpellSuggestAndAdd(t *testing.T) {
+	sc := testSpellChecker(t, "hello", "help", "world")
+	if got := sc.suggest("Helo", 2); !reflect.DeepEqual(got, []string{"Hello", "Help"}) {
+		t.Fatalf("unexpected suggestions %q", got)
+	}
+
+	if err := sc.addWord("gopher"); err != nil {
+		t.Fatalf("addWord: %v", err)
+	}
+	if !sc.known("gopher") {
+		t.Fatalf("added word should be known")
+	}
+	data, _ := os.ReadFile(sc.personalPath)
+	if string(data) != "gopher\n" {
+		t.Fatalf("personal dictionary has %q", data)
+	}
+}
+
+func TestSpellSuggest_ReplacesWord(t *testing.T) {
+	resetSessionForTest()
+	spell = testSpellChecker(t, "hello", "world")
+	defer func() { spell = nil }()
+
+	session.rope = buffer.NewRope("helo world")
+	session.cursorIdx = 2
+	updateCursorPosition()
+
+	handleSpellSuggest(0, "", makeCallback([]byte{Return}))
+	if session.rope.String() != "hello world" {
+		t.Fatalf("suggestion not applied: %q", session.rope.String())
+	}
+	handleUndo()
+	if session.rope.String() != "helo world" {
+		t.Fatalf("undo did not restore the word: %q", session.rope.String())
+	}
+}
@@ -0,0 +1,91 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// textStats are the counts :stats reports
+type textStats struct {
+	lines, words, chars int
+	minLine, maxLine    int // shortest and longest line, in characters
+	avgLine             float64
+	numeric             bool // every non-blank line is a number
+	sum, mean           float64
+}
+
+// computeStats counts the lines, words and characters of text and measures
+// its lines. A final line break doesn't start another line.
+func computeStats(text string) textStats {
+	st := textStats{
+		words:   len(strings.Fields(text)),
+		chars:   utf8.RuneCountInString(text),
+		numeric: true,
+	}
+	if text == "" {
+		st.numeric = false
+		return st
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	st.lines = len(lines)
+	st.minLine = math.MaxInt
+	total, numbers := 0, 0
+	for _, line := range lines {
+		n := utf8.RuneCountInString(line)
+		total += n
+		st.minLine = min(st.minLine, n)
+		st.maxLine = max(st.maxLine, n)
+
+		field := strings.TrimSpace(line)
+		if field == "" || !st.numeric {
+			continue
+		}
+		if x, err := strconv.ParseFloat(field, 64); err == nil {
+			st.sum += x
+			numbers++
+		} else {
+			st.numeric = false
+		}
+	}
+	st.avgLine = float64(total) / float64(st.lines)
+	if numbers == 0 {
+		st.numeric = false
+	} else {
+		st.mean = st.sum / float64(numbers)
+	}
+	return st
+}
+
+// formatNumber writes x without the noise of floating point sums, like
+// 0.30000000000000004
+func formatNumber(x float64) string {
+	return strconv.FormatFloat(math.Round(x*1e6)/1e6, 'f', -1, 64)
+}
+
+// String is the one line summary shown in the status bar
+func (st textStats) String() string {
+	s := fmt.Sprintf("%d lines, %d words, %d chars", st.lines, st.words, st.chars)
+	if st.lines > 0 {
+		s += fmt.Sprintf("; line length %d-%d, avg %.1f", st.minLine, st.maxLine, st.avgLine)
+	}
+	if st.numeric {
+		s += fmt.Sprintf("; sum %s, mean %s", formatNumber(st.sum), formatNumber(st.mean))
+	}
+	return s
+}
+
+// handleStats shows the statistics of the selection, or of the whole
+// buffer when nothing is selected (:stats). When every line holds a
+// number, their sum and mean are shown too.
+func handleStats(fd int, args string, callback func() byte) {
+	what := "Buffer"
+	text := session.rope.String()
+	if start, end, selected := selection(); selected {
+		what = "Selection"
+		text, _ = session.rope.Substring(start, end)
+	}
+	session.statusMessage = what + ": " + computeStats(text).String()
+}
@@ -0,0 +1,37 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestComputeStats(t *testing.T) {
+	for text, want := range map[string]string{
+		"":                  "0 lines, 0 words, 0 chars",
+		"one two\nthree\n":  "2 lines, 3 words, 14 chars; line length 5-7, avg 6.0",
+		"naïve\n":           "1 lines, 1 words, 6 chars; line length 5-5, avg 5.0",
+		"0.1\n0.2\n\n -3\n": "4 lines, 3 words, 13 chars; line length 0-3, avg 2.2; sum -2.7, mean -0.9",
+		"1\n2\nthree\n":     "3 lines, 3 words, 10 chars; line length 1-5, avg 2.3",
+		"10\n20\n5":         "3 lines, 3 words, 7 chars; line length 1-2, avg 1.7; sum 35, mean 11.666667",
+	} {
+		if got := computeStats(text).String(); got != want {
+			t.Errorf("stats of %q:\n got %q\nwant %q", text, got, want)
+		}
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("price\n3\n4.5\n")
+	runCommand(0, "stats", nil)
+	if want := "Buffer: 3 lines, 3 words, 12 chars; line length 1-5, avg 3.0"; session.statusMessage != want {
+		t.Errorf("status %q, want %q", session.statusMessage, want)
+	}
+
+	selectRange(6, 12)
+	runCommand(0, "stats", nil)
+	if want := "Selection: 2 lines, 2 words, 6 chars; line length 1-3, avg 2.0; sum 7.5, mean 3.75"; session.statusMessage != want {
+		t.Errorf("status %q, want %q", session.statusMessage, want)
+	}
+}
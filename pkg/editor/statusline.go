@@ -0,0 +1,255 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// statusSegment returns a piece of the status bar, or "" to be left out
+type statusSegment func() string
+
+// statusSegments holds the segments the statusline option can show, by
+// name. A feature with state worth seeing adds its segment here.
+var statusSegments = map[string]statusSegment{
+	"file":        func() string { return "File: " + statusFileName() },
+	"position":    func() string { return fmt.Sprintf("Row:%d Col:%d", session.cursorRow, session.cursorCol) },
+	"percent":     percentSegment,
+	"offset":      offsetSegment,
+	"size":        sizeSegment,
+	"keys":        keysSegment,
+	"blame":       blameSegment,
+	"branch":      branchSegment,
+	"lsp":         lspSegment,
+	"diagnostics": diagnosticsSegment,
+	"clock":       func() string { return time.Now().Format("15:04") },
+	"battery":     batterySegment,
+}
+
+// statusLine lists the segments of the status bar, in order
+// (:set statusline file,position,branch,clock)
+var statusLine = []string{"file", "position", "percent", "blame", "keys"}
+
+// statusSpan is where a segment is shown on the status bar, from column
+// start to end, 1-indexed and excluded
+type statusSpan struct {
+	name       string
+	start, end int
+}
+
+// statusSpans are the segments of the status bar last drawn, for clicks
+// to find; nil while it shows a message
+var statusSpans []statusSpan
+
+// statusClicks holds what clicking a segment of the status bar does
+var statusClicks = map[string]func(fd int, callback func() byte){
+	"file":     func(fd int, callback func() byte) { handleFilePicker(fd, "", callback) },
+	"position": func(fd int, callback func() byte) { handleGoTo(fd, "", callback) },
+}
+
+// statusSegmentAt returns the name of the segment shown at column col of
+// the status bar, "" for none
+func statusSegmentAt(col int) string {
+	for _, span := range statusSpans {
+		if col >= span.start && col < span.end {
+			return span.name
+		}
+	}
+	return ""
+}
+
+// statusDrawnAt is when the status bar was last composed, so that the
+// clock is redrawn when the minute changes
+var statusDrawnAt time.Time
+
+// statusBarText joins the segments of the status bar that have something
+// to show, recording where each goes in statusSpans
+func statusBarText() string {
+	statusDrawnAt = time.Now()
+	var parts []string
+	col := 1
+	statusSpans = statusSpans[:0]
+	for _, name := range statusLine {
+		if text := statusSegments[name](); text != "" {
+			if len(parts) > 0 {
+				col += len(" | ")
+			}
+			width := utf8.RuneCountInString(text)
+			statusSpans = append(statusSpans, statusSpan{name, col, col + width})
+			col += width
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// statusOutdated reports whether the status bar shows a time older than now
+func statusOutdated(now time.Time) bool {
+	timed := slices.Contains(statusLine, "clock") || slices.Contains(statusLine, "battery")
+	return timed && !now.Truncate(time.Minute).Equal(statusDrawnAt.Truncate(time.Minute))
+}
+
+// setStatusLine checks and sets the statusline option: segment names
+// separated by commas
+func setStatusLine(value string) error {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := statusSegments[name]; !ok {
+			known := make([]string, 0, len(statusSegments))
+			for k := range statusSegments {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown segment %q (one of %s)", name, strings.Join(known, ", "))
+		}
+		names = append(names, name)
+	}
+	statusLine = names
+	return nil
+}
+
+// keysSegment reminds the main keys, unless the blame of the cursor line
+// takes their place
+func keysSegment() string {
+	if pagerMode {
+		return "q:Quit Space/b:Page /:Search n/N:Next h:Help"
+	}
+	if slices.Contains(statusLine, "blame") && blameSegment() != "" {
+		return ""
+	}
+	return "Ctrl-Q:Quit Ctrl-S:Save Ctrl-F:Find Ctrl-E:Cmd"
+}
+
+// blameSegment is the blame of the cursor line, when :blame on asked for it
+func blameSegment() string {
+	if !blameStatusEnabled || session.largeFile {
+		return ""
+	}
+	return blameStatusSegment()
+}
+
+// branchSegment is the git branch of the directory of the active buffer,
+// read from the HEAD file rather than by running git on every frame
+func branchSegment() string {
+	dir, err := filepath.Abs(filepath.Dir(session.filename))
+	if err != nil || isUnnamed(session.filename) {
+		dir, _ = os.Getwd()
+	}
+	if branch := gitBranch(dir); branch != "" {
+		return "Branch: " + branch
+	}
+	return ""
+}
+
+// gitBranch returns the branch checked out in the repository holding dir,
+// the start of the commit hash when none is, and "" outside of one
+func gitBranch(dir string) string {
+	for {
+		gitDir := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitDir); err == nil {
+			if !info.IsDir() {
+				// Worktrees and submodules point to their git directory
+				data, err := os.ReadFile(gitDir)
+				target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+				if err != nil || !ok {
+					return ""
+				}
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(dir, target)
+				}
+				gitDir = target
+			}
+			data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+			if err != nil {
+				return ""
+			}
+			head := strings.TrimSpace(string(data))
+			if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+				return strings.TrimPrefix(ref, "refs/heads/")
+			}
+			return head[:min(len(head), 7)]
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// lspSegment names the language server of the active buffer once it runs
+func lspSegment() string {
+	ext := "." + session.fileType()
+	if _, ok := lspClients[ext]; !ok {
+		return ""
+	}
+	return "LSP: " + languageServers[ext].command[0]
+}
+
+// diagnosticsSegment counts the problems the language server found in the
+// active buffer
+func diagnosticsSegment() string {
+	client, ok := lspClients["."+session.fileType()]
+	if !ok {
+		return ""
+	}
+	switch n := client.Diagnostics(session.filename); n {
+	case 0:
+		return ""
+	case 1:
+		return "1 problem"
+	default:
+		return fmt.Sprintf("%d problems", n)
+	}
+}
+
+// fileOffset is where byte i of the buffer is in its file, which also
+// holds the byte order mark kept out of the buffer
+func fileOffset(i int) int {
+	if session.bom {
+		return i + len(utf8BOM)
+	}
+	return i
+}
+
+// offsetSegment is the offset in the file of the byte under the cursor,
+// counted from 0 as in hex dumps and the error messages of parsers
+func offsetSegment() string {
+	offset := fileOffset(session.cursorIdx)
+	return fmt.Sprintf("Offset: %d (0x%x)", offset, offset)
+}
+
+// sizeSegment is the size of the file the buffer saves to
+func sizeSegment() string {
+	return fmt.Sprintf("%d bytes", fileOffset(session.rope.Length()))
+}
+
+// powerSupplyDir is where Linux describes the batteries
+var powerSupplyDir = "/sys/class/power_supply"
+
+// batterySegment is the charge of the first battery, marked while it
+// charges, or "" on machines without one
+func batterySegment() string {
+	batteries, _ := filepath.Glob(filepath.Join(powerSupplyDir, "BAT*"))
+	if len(batteries) == 0 {
+		return ""
+	}
+	capacity, err := os.ReadFile(filepath.Join(batteries[0], "capacity"))
+	if err != nil {
+		return ""
+	}
+	text := "Battery: " + strings.TrimSpace(string(capacity)) + "%"
+	if status, _ := os.ReadFile(filepath.Join(batteries[0], "status")); strings.TrimSpace(string(status)) == "Charging" {
+		text += "+"
+	}
+	return text
+}
@@ -0,0 +1,111 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func useStatusLine(t *testing.T, value string) {
+	old := statusLine
+	t.Cleanup(func() { statusLine = old })
+	if err := Set("statusline", value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusBarText(t *testing.T) {
+	resetSessionForTest()
+	session.filename = "notes.txt"
+	session.cursorRow, session.cursorCol = 3, 7
+
+	want := "File: notes.txt | Row:3 Col:7 | 100% | Ctrl-Q:Quit Ctrl-S:Save Ctrl-F:Find Ctrl-E:Cmd"
+	if got := statusBarText(); got != want {
+		t.Errorf("default status %q, want %q", got, want)
+	}
+
+	// Segments with nothing to show are left out with their separator
+	useStatusLine(t, "position, lsp, file")
+	if got := statusBarText(); got != "Row:3 Col:7 | File: notes.txt" {
+		t.Errorf("custom status %q", got)
+	}
+	if err := Set("statusline", "file,weather"); err == nil || !strings.Contains(err.Error(), "weather") {
+		t.Errorf("an unknown segment should be refused, got %v", err)
+	}
+	if got := statusLine; len(got) != 3 {
+		t.Errorf("a refused value should keep the segments, got %v", got)
+	}
+}
+
+func TestOffsetSegments(t *testing.T) {
+	resetSessionForTest()
+	useStatusLine(t, "offset,size")
+	session.rope = buffer.NewRope("héllo\nworld")
+	session.cursorIdx = 9
+	if got := statusBarText(); got != "Offset: 9 (0x9) | 12 bytes" {
+		t.Errorf("status %q", got)
+	}
+
+	// The byte order mark is in the file, though not in the buffer
+	session.bom = true
+	if got := statusBarText(); got != "Offset: 12 (0xc) | 15 bytes" {
+		t.Errorf("status with a byte order mark %q", got)
+	}
+}
+
+func TestGitBranch(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+	os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("ref: refs/heads/feature/x\n"), 0644)
+	sub := filepath.Join(root, "pkg", "a")
+	os.MkdirAll(sub, 0755)
+
+	if got := gitBranch(sub); got != "feature/x" {
+		t.Errorf("branch %q, want feature/x", got)
+	}
+
+	// A worktree points to its git directory, which may be detached
+	worktree := filepath.Join(root, "wt")
+	os.MkdirAll(filepath.Join(root, ".git", "worktrees", "wt"), 0755)
+	os.WriteFile(filepath.Join(root, ".git", "worktrees", "wt", "HEAD"), []byte("0123456789abcdef\n"), 0644)
+	os.MkdirAll(worktree, 0755)
+	os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: ../.git/worktrees/wt\n"), 0644)
+	if got := gitBranch(worktree); got != "0123456" {
+		t.Errorf("detached worktree branch %q, want the short hash", got)
+	}
+}
+
+func TestBatterySegment(t *testing.T) {
+	old := powerSupplyDir
+	powerSupplyDir = t.TempDir()
+	t.Cleanup(func() { powerSupplyDir = old })
+
+	if got := batterySegment(); got != "" {
+		t.Errorf("no battery should show nothing, got %q", got)
+	}
+	bat := filepath.Join(powerSupplyDir, "BAT0")
+	os.MkdirAll(bat, 0755)
+	os.WriteFile(filepath.Join(bat, "capacity"), []byte("83\n"), 0644)
+	os.WriteFile(filepath.Join(bat, "status"), []byte("Charging\n"), 0644)
+	if got := batterySegment(); got != "Battery: 83%+" {
+		t.Errorf("battery %q", got)
+	}
+}
+
+func TestStatusOutdated(t *testing.T) {
+	resetSessionForTest()
+	statusBarText()
+	later := statusDrawnAt.Add(time.Minute)
+	if statusOutdated(later) {
+		t.Error("without a clock the status bar never gets old")
+	}
+	useStatusLine(t, "file,clock")
+	statusBarText()
+	if statusOutdated(statusDrawnAt) || !statusOutdated(statusDrawnAt.Add(time.Minute)) {
+		t.Error("the clock should be redrawn when the minute changes")
+	}
+}
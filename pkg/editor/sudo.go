@@ -0,0 +1,79 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// privilegedWriters are the commands that can write stdin to a file the
+// user has no permission for, in order of preference; the file name is
+// appended to them
+var privilegedWriters = [][]string{
+	{"sudo", "tee", "--"},
+	{"pkexec", "tee", "--"},
+}
+
+// errSaveCanceled is returned when the user declines a privileged save
+var errSaveCanceled = errors.New("permission denied")
+
+// privilegedSave offers to write content to filename with elevated
+// privileges after a normal save was denied. It returns the tool used.
+func privilegedSave(fd int, filename, content string, callback func() byte) (string, error) {
+	var writer []string
+	for _, w := range privilegedWriters {
+		if _, err := exec.LookPath(w[0]); err == nil {
+			writer = w
+			break
+		}
+	}
+	if writer == nil {
+		return "", errSaveCanceled
+	}
+
+	prompt := fmt.Sprintf("Permission denied. Save with %s? y/n", writer[0])
+	if confirm(prompt, false, callback) != answerYes {
+		return "", errSaveCanceled
+	}
+
+	cmd := exec.Command(writer[0], append(writer[1:], filename)...)
+	cmd.Stdin = strings.NewReader(content)
+	// tee copies its input to stdout, which is discarded; password prompts
+	// and errors go to the terminal
+	cmd.Stderr = os.Stderr
+	var err error
+	withCookedTerminal(fd, func() {
+		fmt.Fprint(output, "\x1b[2J\x1b[H")
+		fmt.Fprintf(output, "Saving %s with %s\n", filename, writer[0])
+		err = cmd.Run()
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", writer[0], err)
+	}
+	return writer[0], nil
+}
+
+// withCookedTerminal runs fn with the terminal back in its normal line
+// mode, so that programs like sudo can read a password, and puts it back
+// in raw mode afterwards
+func withCookedTerminal(fd int, fn func()) {
+	// Whatever runs may draw anywhere on the screen
+	defer invalidateFrame()
+	raw, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		// Not a terminal (tests, pipes): nothing to switch
+		fn()
+		return
+	}
+	cooked := *raw
+	cooked.Lflag |= unix.ECHO | unix.ICANON | unix.ISIG | unix.IEXTEN
+	cooked.Iflag |= unix.ICRNL
+	cooked.Oflag |= unix.OPOST
+	unix.IoctlSetTermios(fd, unix.TCSETS, &cooked)
+	defer unix.IoctlSetTermios(fd, unix.TCSETS, raw)
+	fn()
+}
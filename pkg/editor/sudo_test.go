@@ -0,0 +1,60 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// usePrivilegedWriters replaces sudo with plain commands for a test
+func usePrivilegedWriters(t *testing.T, writers [][]string) {
+	old := privilegedWriters
+	privilegedWriters = writers
+	t.Cleanup(func() { privilegedWriters = old })
+}
+
+func TestPrivilegedSave(t *testing.T) {
+	resetSessionForTest()
+	usePrivilegedWriters(t, [][]string{{"no-such-sudo"}, {"tee", "--"}})
+	path := filepath.Join(t.TempDir(), "system.conf")
+
+	via, err := privilegedSave(0, path, "option=1\n", makeCallback([]byte{'n'}))
+	if !errors.Is(err, errSaveCanceled) {
+		t.Fatalf("declining should cancel, got %q %v", via, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("nothing should be written when declined")
+	}
+
+	via, err = privilegedSave(0, path, "option=1\n", makeCallback([]byte{'y'}))
+	if err != nil || via != "tee" {
+		t.Fatalf("privilegedSave = %q, %v", via, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "option=1\n" {
+		t.Fatalf("wrote %q", data)
+	}
+}
+
+func TestSaveOffersPrivilegedWrite(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write read-only files")
+	}
+	resetSessionForTest()
+	usePrivilegedWriters(t, [][]string{{"sh", "-c", `chmod u+w "$0" && cat > "$0"`}})
+	path := filepath.Join(t.TempDir(), "locked.txt")
+	if err := os.WriteFile(path, []byte("old"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	session = newSession(path, "new")
+	buffers = []*Session{session}
+
+	handleSave(0, makeCallback([]byte{'y'}))
+	if !strings.HasSuffix(session.statusMessage, "with sh") {
+		t.Fatalf("unexpected status %q", session.statusMessage)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Fatalf("file holds %q", data)
+	}
+}
@@ -0,0 +1,47 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+)
+
+// Sequences turning off, and back on, the terminal modes the editor runs
+// with: the alternate screen, bracketed paste and focus reporting. Mouse
+// reporting comes back with the next frame, when on.
+const (
+	leaveTerminalModes = "\x1b[?1006l\x1b[?1000l\x1b[?1004l\x1b[?2004l\x1b[?1049l"
+	enterTerminalModes = "\x1b[?1049h\x1b[?2004h\x1b[?1004h"
+)
+
+// stopProcess stops the editor as Ctrl-Z does in a shell, and returns once
+// it is continued (fg). SIGTSTP goes to the whole process group, so that
+// the shell sees the job stopped.
+var stopProcess = func() {
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, unix.SIGCONT)
+	defer signal.Stop(cont)
+	unix.Kill(0, unix.SIGTSTP)
+	<-cont
+}
+
+// handleSuspend hands the terminal back to the shell and stops the editor,
+// which redraws in raw mode once resumed (:suspend and :stop). Raw mode
+// turns off the terminal's own Ctrl-Z, which undoes instead.
+func handleSuspend(fd int, args string, callback func() byte) {
+	if _, err := unix.IoctlGetTermios(fd, unix.TCGETS); err != nil {
+		session.statusMessage = "Cannot suspend: not running in a terminal"
+		return
+	}
+
+	ClearScreen(Screen)
+	MoveCursorTopLeft()
+	fmt.Fprint(output, leaveTerminalModes+"\x1b[?25h")
+	withCookedTerminal(fd, stopProcess)
+	fmt.Fprint(output, enterTerminalModes)
+	mouseShown = false
+	// The terminal may have been resized in the meantime
+	session.screenRows, session.screenCols = getWindowSize(fd)
+}
@@ -0,0 +1,67 @@
+package editor
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSuspendRestoresTerminal(t *testing.T) {
+	if _, err := os.Stat("/dev/ptmx"); err != nil {
+		t.Skip("no pseudo-terminals:", err)
+	}
+	resetSessionForTest()
+	ptmx, tty, err := openPTY(24, 80)
+	if err != nil {
+		t.Skip("no pseudo-terminals:", err)
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+	fd := int(tty.Fd())
+	if _, err := EnableRawMode(fd); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	output = &out
+	t.Cleanup(func() { output = os.Stdout })
+	oldStop := stopProcess
+	t.Cleanup(func() { stopProcess = oldStop })
+	stopped := false
+	stopProcess = func() {
+		stopped = true
+		state, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+		if err != nil || state.Lflag&unix.ICANON == 0 || state.Lflag&unix.ISIG == 0 {
+			t.Error("the shell should get the terminal back in cooked mode")
+		}
+		if !strings.HasSuffix(out.String(), leaveTerminalModes+"\x1b[?25h") {
+			t.Errorf("the alternate screen should be left, output %q", out.String())
+		}
+	}
+
+	runCommand(fd, "suspend", nil)
+	if !stopped {
+		t.Fatalf("not stopped, status %q", session.statusMessage)
+	}
+	state, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil || state.Lflag&unix.ICANON != 0 {
+		t.Error("raw mode should be back once resumed")
+	}
+	if !strings.HasSuffix(out.String(), enterTerminalModes) {
+		t.Errorf("the alternate screen should be back, output %q", out.String())
+	}
+}
+
+func TestSuspendWithoutTerminal(t *testing.T) {
+	resetSessionForTest()
+	oldStop := stopProcess
+	t.Cleanup(func() { stopProcess = oldStop })
+	stopProcess = func() { t.Error("stopped without a terminal") }
+
+	runCommand(-1, "stop", nil)
+	if !strings.Contains(session.statusMessage, "Cannot suspend") {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
@@ -0,0 +1,62 @@
+package editor
+
+import "strings"
+
+// hashCommentExtensions use '#' line comments, everything else uses C-style comments
+var hashCommentExtensions = map[string]bool{
+	".py": true, ".sh": true, ".bash": true, ".rb": true, ".pl": true,
+	".yaml": true, ".yml": true, ".toml": true, ".conf": true, ".mk": true,
+}
+
+// literal is a comment or a string literal of source code, text[start:end]
+type literal struct {
+	start, end int
+	quote      byte // the quote of a string, 0 for a comment
+}
+
+// literals finds the comments and the string literals of text, source
+// code of fileType, in order. Strings end with their line, but for
+// backquoted ones, and an unterminated one at the end of text.
+func literals(fileType, text string) []literal {
+	var found []literal
+	hashComments := hashCommentExtensions["."+fileType]
+	for i := 0; i < len(text); i++ {
+		end := -1
+		var quote byte
+		switch {
+		case hashComments && text[i] == '#',
+			!hashComments && strings.HasPrefix(text[i:], "//"):
+			end = strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += i
+			}
+		case !hashComments && strings.HasPrefix(text[i:], "/*"):
+			end = strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += i + 4
+			}
+		case text[i] == '"' || text[i] == '\'' || text[i] == '`':
+			quote = text[i]
+			end = i + 1
+			for end < len(text) && text[end] != quote {
+				if quote != '`' && text[end] == '\n' {
+					break
+				}
+				if quote != '`' && text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(text))
+		}
+		if end >= 0 {
+			found = append(found, literal{i, end, quote})
+			i = end - 1
+		}
+	}
+	return found
+}
@@ -0,0 +1,326 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShiftTab is the key reported for Shift-Tab, which terminals send as \x1b[Z
+var ShiftTab = Key{Special: KeyTab, Mod: ModShift}
+
+// tableSeparator is drawn between the columns of the table view
+const tableSeparator = " │ "
+
+// tableState is the aligned view of a buffer of delimiter-separated values.
+// Only the drawing changes: the text keeps its delimiters and is edited as is.
+type tableState struct {
+	delim     byte
+	colOffset int // number of columns scrolled off the left of the screen
+}
+
+// tableDelimiters are the delimiters looked for, in order of preference
+var tableDelimiters = []byte{',', '\t', ';', '|'}
+
+func init() {
+	addHook(eventBufOpen, func(s *Session) error {
+		if s.largeFile || s.loading != nil {
+			return nil
+		}
+		if _, ok := detectDelimiter(s.rope.String(), s.fileType()); ok && s.statusMessage == "" {
+			s.statusMessage = "Delimited columns found, :table shows them aligned"
+		}
+		return nil
+	})
+}
+
+// detectDelimiter returns the delimiter of text when it looks like rows of
+// delimited values: every one of its first lines has the same number of
+// fields, at least two. Files without a csv or tsv extension need three
+// such lines, so that prose with commas isn't taken for a table.
+func detectDelimiter(text, fileType string) (byte, bool) {
+	minLines := 3
+	switch fileType {
+	case "tsv":
+		return '\t', true
+	case "csv":
+		minLines = 1
+	case "", "txt", "psv", "dat":
+	default:
+		return 0, false
+	}
+
+	var lines []string
+	for _, line := range strings.SplitN(text, "\n", 21) {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > 20 {
+		// The last one is the rest of the text
+		lines = lines[:20]
+	}
+	if len(lines) < minLines {
+		return 0, false
+	}
+	for _, delim := range tableDelimiters {
+		fields := len(splitFields(lines[0], delim))
+		if fields < 2 {
+			continue
+		}
+		same := true
+		for _, line := range lines[1:] {
+			if len(splitFields(line, delim)) != fields {
+				same = false
+				break
+			}
+		}
+		if same {
+			return delim, true
+		}
+	}
+	return 0, false
+}
+
+// splitFields returns the start and end of the fields of line. A field
+// starting with a double quote runs to the closing quote, so it may hold
+// the delimiter; a doubled quote inside it is a quote.
+func splitFields(line string, delim byte) [][2]int {
+	var fields [][2]int
+	start := 0
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"' && i == start:
+			quoted = true
+		case line[i] == '"' && quoted:
+			if i+1 < len(line) && line[i+1] == '"' {
+				i++
+			} else {
+				quoted = false
+			}
+		case line[i] == delim && !quoted:
+			fields = append(fields, [2]int{start, i})
+			start = i + 1
+		}
+	}
+	return append(fields, [2]int{start, len(line)})
+}
+
+// tableWidths returns the width of each column: that of its widest field
+func tableWidths(lines []string, delim byte) []int {
+	var widths []int
+	for _, line := range lines {
+		for c, f := range splitFields(line, delim) {
+			if c == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[c] = max(widths[c], visualColumn(line[f[0]:f[1]], f[1]-f[0]))
+		}
+	}
+	return widths
+}
+
+// fieldAt returns the index of the field of fields holding col, a byte
+// offset in the line. The delimiter ending a field belongs to it.
+func fieldAt(fields [][2]int, col int) int {
+	for c := len(fields) - 1; c > 0; c-- {
+		if col >= fields[c][0] {
+			return c
+		}
+	}
+	return 0
+}
+
+// tableColumnX returns the screen column where column c of the table
+// starts, counting from column from
+func tableColumnX(widths []int, from, c int) int {
+	x := 0
+	for i := from; i < c; i++ {
+		x += widths[i] + len([]rune(tableSeparator))
+	}
+	return x
+}
+
+// drawTableView draws the visible rows of the buffer as an aligned table
+// into buf, with the first line pinned at the top as the header, and
+// returns the screen position of the cursor
+func drawTableView(buf *strings.Builder, textRows int) (int, int) {
+	lines := getLines()
+	starts := lineStarts()
+	delim := session.table.delim
+	widths := tableWidths(lines, delim)
+	highlights := bufferHighlights(session.rope.String())
+	row := session.cursorRow - 1
+	cols := int(session.screenCols)
+
+	// The header takes the first screen row, the body scrolls below it
+	textRows = max(textRows, 2)
+	bodyRows := textRows - 1
+	session.rowOffset = max(session.rowOffset, 1)
+	if row >= 1 && row < session.rowOffset {
+		session.rowOffset = row
+	}
+	if row >= session.rowOffset+bodyRows {
+		session.rowOffset = row - bodyRows + 1
+	}
+
+	// Scroll sideways to keep the cell of the cursor on screen
+	fields := splitFields(lines[row], delim)
+	cell := fieldAt(fields, session.cursorCol-1)
+	cellWidth := 0
+	if cell < len(widths) {
+		cellWidth = widths[cell]
+	}
+	t := session.table
+	t.colOffset = min(t.colOffset, cell)
+	for t.colOffset < cell && tableColumnX(widths, t.colOffset, cell)+cellWidth >= cols {
+		t.colOffset++
+	}
+
+	drawRow := func(i int, style string, highlights []highlight) {
+		line := lines[i]
+		for c, f := range splitFields(line, delim) {
+			if c < t.colOffset {
+				continue
+			}
+			if style != "" {
+				buf.WriteString("\x1b[" + style + "m")
+			}
+			if c > t.colOffset {
+				buf.WriteString(tableSeparator)
+			}
+			text := line[f[0]:f[1]]
+			buf.WriteString(renderLine(text, starts[i]+f[0], highlights))
+			if style != "" {
+				buf.WriteString("\x1b[" + style + "m")
+			}
+			buf.WriteString(strings.Repeat(" ", widths[c]-visualColumn(text, len(text))))
+			if style != "" {
+				buf.WriteString("\x1b[m")
+			}
+		}
+		buf.WriteString("\x1b[K\r\n")
+	}
+
+	// The header style goes first, for the other highlights to show over it
+	header := highlight{starts[0], starts[0] + len(lines[0]), currentTheme.tableHeader}
+	drawRow(0, header.style, append([]highlight{header}, highlights...))
+	for i := 0; i < bodyRows; i++ {
+		if lineIdx := session.rowOffset + i; lineIdx < len(lines) {
+			drawRow(lineIdx, "", highlights)
+		} else {
+			buf.WriteString("~\x1b[K\r\n")
+		}
+	}
+
+	screenRow := 1
+	if row > 0 {
+		screenRow = row - session.rowOffset + 2
+	}
+	f := fields[cell]
+	inCell := visualColumn(lines[row][f[0]:], session.cursorCol-1-f[0])
+	return screenRow, clampColumn(tableColumnX(widths, t.colOffset, cell)+inCell, cols) + 1
+}
+
+// tableStatus describes the cell of the cursor for the status bar: its row,
+// its column and the name the header gives that column
+func tableStatus() string {
+	lines := getLines()
+	row := session.cursorRow - 1
+	fields := splitFields(lines[row], session.table.delim)
+	cell := fieldAt(fields, session.cursorCol-1)
+	name := ""
+	if header := splitFields(lines[0], session.table.delim); cell < len(header) {
+		name = " " + strings.TrimSpace(lines[0][header[cell][0]:header[cell][1]])
+	}
+	return fmt.Sprintf("File: %s | Row %d/%d | Column %d/%d%s | TABLE Tab:next cell",
+		statusFileName(), session.cursorRow, len(lines), cell+1, len(fields), name)
+}
+
+// handleTableKey moves the cursor between cells and reports whether the
+// key was used: Tab and Shift-Tab go to the next and previous cell, up
+// and down stay in the same column. Other keys edit the text as usual.
+func handleTableKey(key Key) bool {
+	starts := lineStarts()
+	row := session.cursorRow - 1
+	line := func(r int) string {
+		start, end := lineBounds(starts, r)
+		text, _ := session.rope.Substring(start, end)
+		return text
+	}
+	fields := splitFields(line(row), session.table.delim)
+	cell := fieldAt(fields, session.cursorCol-1)
+
+	switch key {
+	case TabKey:
+		if cell+1 < len(fields) {
+			session.cursorIdx = starts[row] + fields[cell+1][0]
+		} else if row+1 < len(starts) {
+			session.cursorIdx = starts[row+1]
+		}
+	case ShiftTab:
+		if cell > 0 {
+			session.cursorIdx = starts[row] + fields[cell-1][0]
+		} else if row > 0 {
+			prev := splitFields(line(row-1), session.table.delim)
+			session.cursorIdx = starts[row-1] + prev[len(prev)-1][0]
+		}
+	case ArrowUp, ArrowDown:
+		target := row - 1
+		if key == ArrowDown {
+			target = row + 1
+		}
+		if target < 0 || target >= len(starts) {
+			return true
+		}
+		offset := session.cursorCol - 1 - fields[cell][0]
+		other := splitFields(line(target), session.table.delim)
+		f := other[min(cell, len(other)-1)]
+		session.cursorIdx = starts[target] + min(f[0]+offset, f[1])
+	default:
+		return false
+	}
+	updateCursorPosition()
+	return true
+}
+
+// handleTable shows the buffer as an aligned table, or the text again
+// (:table [off|DELIM]). The delimiter is detected unless given, as one
+// character or "tab".
+func handleTable(fd int, args string, callback func() byte) {
+	args = strings.TrimSpace(args)
+	if args == "off" || (args == "" && session.table != nil) {
+		session.table = nil
+		session.rowOffset = 0
+		session.statusMessage = "Table view off"
+		return
+	}
+	if session.hex != nil || session.largeFile {
+		session.statusMessage = "No table view in hex or large-file mode"
+		return
+	}
+
+	var delim byte
+	switch {
+	case args == "tab":
+		delim = '\t'
+	case len(args) == 1:
+		delim = args[0]
+	case args == "":
+		var ok bool
+		if delim, ok = detectDelimiter(session.rope.String(), "csv"); !ok {
+			session.statusMessage = "No delimiter found, give one: table ;"
+			return
+		}
+	default:
+		session.statusMessage = "Usage: table [off|DELIM|tab]"
+		return
+	}
+	session.table = &tableState{delim: delim}
+	session.rowOffset = 0
+	name := string(delim)
+	if delim == '\t' {
+		name = "tab"
+	}
+	session.statusMessage = fmt.Sprintf("Table view on, columns split at %q: Tab/Shift-Tab move between cells", name)
+}
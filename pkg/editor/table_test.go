@@ -0,0 +1,103 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectDelimiter(t *testing.T) {
+	for _, tc := range []struct {
+		text, fileType string
+		want           byte
+		ok             bool
+	}{
+		{"name,age\nann,31\n", "csv", ',', true},
+		{"name;age\nann;31\n", "csv", ';', true},
+		{"a,b\tc\nd,e\tf\n", "tsv", '\t', true},
+		{"id|name\n1|a\n2|b\n", "", '|', true},
+		{"\"last, first\",age\n\"doe, jane\",31\n", "csv", ',', true},
+		// Prose with commas isn't a table, nor are two lines of a text file
+		{"Hello, world.\nNo commas here\n", "txt", 0, false},
+		{"a,b\nc,d\n", "txt", 0, false},
+		{"a,b\nc,d\ne,f\n", "go", 0, false},
+	} {
+		if got, ok := detectDelimiter(tc.text, tc.fileType); got != tc.want || ok != tc.ok {
+			t.Errorf("detectDelimiter(%q, %q) = %q, %v, want %q, %v", tc.text, tc.fileType, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	line := `1,"say ""hi"", then go",,end`
+	var got []string
+	for _, f := range splitFields(line, ',') {
+		got = append(got, line[f[0]:f[1]])
+	}
+	if want := []string{"1", `"say ""hi"", then go"`, "", "end"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("fields %q, want %q", got, want)
+	}
+}
+
+func TestTableView(t *testing.T) {
+	resetSessionForTest()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	InitSession(-1, "people.csv", "name,age,city\nann,31,Oslo\nbartholomew,7,Rome\n")
+	if !strings.Contains(session.statusMessage, ":table") {
+		t.Errorf("opening a CSV file should offer the table view, status %q", session.statusMessage)
+	}
+	runCommand(0, "table", nil)
+
+	// Tab goes to the age of ann, down stays in that column, typing edits
+	// the text
+	screen := playKeys(t, "\x1b[B\t\x1b[B9")
+	want := []string{
+		"name        │ age │ city",
+		"ann         │ 31  │ Oslo",
+		"bartholomew │ 97  │ Rome",
+	}
+	for i, line := range want {
+		if got := screen.Line(i); got != line {
+			t.Errorf("row %d = %q, want %q", i, got, line)
+		}
+	}
+	if got := session.rope.String(); got != "name,age,city\nann,31,Oslo\nbartholomew,97,Rome\n" {
+		t.Errorf("text %q", got)
+	}
+	if row, col := screen.Cursor(); row != 2 || col != 15 {
+		t.Errorf("cursor at %d:%d, want 2:15", row, col)
+	}
+	if status := screen.Line(23); !strings.Contains(status, "Row 3/4 | Column 2/3 age") {
+		t.Errorf("status %q", status)
+	}
+
+	// Shift-Tab goes back a cell, then to the end of the row above
+	handleTableKey(ShiftTab)
+	handleTableKey(ShiftTab)
+	if session.cursorRow != 2 || session.cursorCol != 8 {
+		t.Errorf("cursor on %d:%d after Shift-Tab, want the city of ann", session.cursorRow, session.cursorCol)
+	}
+
+	runCommand(0, "table", nil)
+	if session.table != nil {
+		t.Error("table should toggle off")
+	}
+}
+
+func TestTableHeaderPinned(t *testing.T) {
+	resetSessionForTest()
+	text := "n;square\n"
+	for i := 1; i <= 40; i++ {
+		text += strings.Repeat("x", i%3) + ";" + strings.Repeat("y", i%5) + "\n"
+	}
+	InitSession(-1, "squares.txt", text)
+	runCommand(0, "table ;", nil)
+	GoToLine(35, 1)
+
+	screen := playKeys(t, "")
+	if got := screen.Line(0); got != "n  │ square" {
+		t.Errorf("header %q", got)
+	}
+	if row, _ := screen.Cursor(); screen.Line(row) != "x  │ yyyy" {
+		t.Errorf("cursor row %d shows %q", row, screen.Line(row))
+	}
+}
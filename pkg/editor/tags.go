@@ -0,0 +1,265 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// tag is one entry of a ctags file
+type tag struct {
+	name    string
+	file    string // relative to the working directory
+	address string // line number or /^pattern$/ search
+	kind    string
+}
+
+// tagsCache avoids parsing the tags file again until it changes on disk
+var tagsCache struct {
+	path    string
+	modTime time.Time
+	tags    []tag
+}
+
+// findTagsFile looks for a "tags" file in the working directory and its parents
+func findTagsFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, "tags")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no tags file found (generate one with :maketags)")
+		}
+		dir = parent
+	}
+}
+
+// loadTags returns the tags of the nearest tags file, parsing it only when
+// it changed since the last call
+func loadTags() ([]tag, error) {
+	path, err := findTagsFile()
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if tagsCache.path == path && tagsCache.modTime.Equal(info.ModTime()) {
+		return tagsCache.tags, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tags, err := parseTags(f, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	tagsCache.path = path
+	tagsCache.modTime = info.ModTime()
+	tagsCache.tags = tags
+	return tags, nil
+}
+
+// parseTags reads the lines of a tags file:
+//
+//	name<TAB>file<TAB>address;"<TAB>kind<TAB>...
+//
+// File names are relative to dir, the directory of the tags file.
+func parseTags(f *os.File, dir string) ([]tag, error) {
+	var tags []tag
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "!_TAG_") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			continue
+		}
+
+		address, extra, _ := strings.Cut(strings.Join(fields[2:], "\t"), ";\"")
+		t := tag{name: fields[0], file: fields[1], address: address}
+		if !filepath.IsAbs(t.file) {
+			t.file = displayPath(filepath.Join(dir, t.file))
+		}
+		for _, field := range strings.Split(strings.TrimSpace(extra), "\t") {
+			if kind, ok := strings.CutPrefix(field, "kind:"); ok {
+				t.kind = kind
+			} else if len(field) == 1 {
+				t.kind = field
+			}
+		}
+		tags = append(tags, t)
+	}
+	return tags, scanner.Err()
+}
+
+// identifierAtCursor returns the identifier under or just before the cursor
+func identifierAtCursor() string {
+	text := session.rope.String()
+	isIdent := func(b byte) bool {
+		return b == '_' || b >= 0x80 || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+	}
+	start, end := session.cursorIdx, session.cursorIdx
+	for start > 0 && isIdent(text[start-1]) {
+		start--
+	}
+	for end < len(text) && isIdent(text[end]) {
+		end++
+	}
+	return text[start:end]
+}
+
+// tagLocation finds the rope index a tag points to in the active buffer
+func tagLocation(t tag) int {
+	lines := getLines()
+	if n, err := strconv.Atoi(t.address); err == nil {
+		return getLineStartIndex(min(max(n, 1), len(lines)))
+	}
+
+	// A search pattern like /^func main() {$/ matches a whole line literally
+	pattern := strings.Trim(t.address, "/?")
+	anchoredEnd := strings.HasSuffix(pattern, "$") && !strings.HasSuffix(pattern, "\\$")
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+	pattern = strings.NewReplacer(`\/`, "/", `\\`, `\`, `\$`, "$").Replace(pattern)
+
+	for i, line := range lines {
+		if line == pattern || (!anchoredEnd && strings.HasPrefix(line, pattern)) {
+			col := max(strings.Index(line, t.name), 0)
+			return getLineStartIndex(i+1) + col
+		}
+	}
+	return 0
+}
+
+// jumpToTag opens the file of a tag and moves the cursor to its definition
+func jumpToTag(t tag) error {
+	if err := openBuffer(t.file); err != nil {
+		return err
+	}
+	session.cursorIdx = tagLocation(t)
+	updateCursorPosition()
+	return nil
+}
+
+// tagItem describes a tag in a picker list
+func tagItem(t tag) string {
+	if t.kind != "" {
+		return fmt.Sprintf("%s [%s] %s", t.name, t.kind, t.file)
+	}
+	return fmt.Sprintf("%s %s", t.name, t.file)
+}
+
+// goToTag jumps to the definition of name, asking which one when the tags
+// file has several. It returns false when the name has no tag.
+func goToTag(fd int, name string, callback func() byte) bool {
+	tags, err := loadTags()
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Tags: %v", err)
+		return false
+	}
+
+	var matches []tag
+	for _, t := range tags {
+		if t.name == name {
+			matches = append(matches, t)
+		}
+	}
+	if len(matches) == 0 {
+		session.statusMessage = "No tag for " + name
+		return false
+	}
+
+	choice := 0
+	if len(matches) > 1 {
+		items := make([]string, len(matches))
+		for i, t := range matches {
+			items[i] = tagItem(t)
+		}
+		if choice = pickFromList(fd, "Tag "+name, items, 0, callback); choice < 0 {
+			return true
+		}
+	}
+
+	pushJump()
+	if err := jumpToTag(matches[choice]); err != nil {
+		session.statusMessage = fmt.Sprintf("Tags: %v", err)
+		return true
+	}
+	session.statusMessage = fmt.Sprintf("Tag %s at %s:%d (Ctrl-T to go back)", name, session.filename, session.cursorRow)
+	return true
+}
+
+// handleTag jumps to the definition of the given symbol, or of the one
+// under the cursor (:tag [name])
+func handleTag(fd int, args string, callback func() byte) {
+	name := args
+	if name == "" {
+		name = identifierAtCursor()
+	}
+	if name == "" {
+		session.statusMessage = "No identifier under the cursor"
+		return
+	}
+	goToTag(fd, name, callback)
+}
+
+// handleTagPicker lets the user fuzzy find any symbol of the tags file (:tags)
+func handleTagPicker(fd int, args string, callback func() byte) {
+	tags, err := loadTags()
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Tags: %v", err)
+		return
+	}
+	items := make([]string, len(tags))
+	for i, t := range tags {
+		items[i] = tagItem(t)
+	}
+
+	choice := fuzzyPickFromList(fd, "Symbol", items, callback)
+	if choice < 0 {
+		return
+	}
+	pushJump()
+	if err := jumpToTag(tags[choice]); err != nil {
+		session.statusMessage = fmt.Sprintf("Tags: %v", err)
+	}
+}
+
+// handleMakeTags generates the tags file of the working directory with
+// ctags, or gotags when only that is installed (:maketags)
+func handleMakeTags(fd int, args string, callback func() byte) {
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("ctags"); err == nil {
+		cmd = exec.Command("ctags", "-R", ".")
+	} else if _, err := exec.LookPath("gotags"); err == nil {
+		cmd = exec.Command("gotags", "-R", "-f", "tags", ".")
+	} else {
+		session.statusMessage = "Tags: neither ctags nor gotags is installed"
+		return
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		session.statusMessage = fmt.Sprintf("Tags: %v %s", err, firstLine(string(out)))
+		return
+	}
+	session.statusMessage = "Generated tags"
+}
@@ -0,0 +1,78 @@
+package editor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func writeTagsFixture(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("main.go", []byte("package main\n\nfunc helper() {}\n\nfunc main() {\n\thelper()\n}\n"), 0644)
+	os.WriteFile("tags", []byte("!_TAG_FILE_FORMAT\t2\t/extended format/\n"+
+		"helper\tmain.go\t/^func helper() {}$/;\"\tf\n"+
+		"main\tmain.go\t5;\"\tkind:func\n"), 0644)
+	tagsCache.path = ""
+}
+
+func TestLoadTags(t *testing.T) {
+	writeTagsFixture(t)
+	tags, err := loadTags()
+	if err != nil {
+		t.Fatalf("loadTags: %v", err)
+	}
+	want := []tag{
+		{name: "helper", file: "main.go", address: "/^func helper() {}$/", kind: "f"},
+		{name: "main", file: "main.go", address: "5", kind: "func"},
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Fatalf("got %+v", tags)
+	}
+}
+
+func TestTag_JumpsToSymbolUnderCursor(t *testing.T) {
+	writeTagsFixture(t)
+	resetSessionForTest()
+	jumpList = nil
+	session.filename = "other.txt"
+	session.rope = buffer.NewRope("call helper here")
+	session.cursorIdx = 7
+	updateCursorPosition()
+
+	handleTag(0, "", nil)
+	if session.filename != "main.go" || session.cursorRow != 3 || session.cursorCol != 6 {
+		t.Fatalf("expected main.go:3:6 got %s:%d:%d (%s)", session.filename, session.cursorRow, session.cursorCol, session.statusMessage)
+	}
+
+	handleTag(0, "main", nil)
+	if session.cursorRow != 5 {
+		t.Fatalf("line number address should land on row 5, got %d", session.cursorRow)
+	}
+	if len(jumpList) != 2 {
+		t.Fatalf("each tag jump should be recorded, have %d", len(jumpList))
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	items := []string{"readMessage", "parseTags", "main", "mkdirAll"}
+	got := fuzzyFilter(items, "ma")
+	if len(got) != 3 || items[got[0]] != "main" {
+		t.Fatalf("unexpected order %v", got)
+	}
+	if got := fuzzyFilter(items, "pt"); !reflect.DeepEqual(got, []int{1}) {
+		t.Fatalf("expected parseTags only, got %v", got)
+	}
+}
+
+func TestTagPicker_FiltersAndJumps(t *testing.T) {
+	writeTagsFixture(t)
+	resetSessionForTest()
+	session.rope = buffer.NewRope("")
+
+	handleTagPicker(0, "", makeCallback([]byte{'m', 'a', 'i', Return}))
+	if session.filename != "main.go" || session.cursorRow != 5 {
+		t.Fatalf("expected main.go:5 got %s:%d", session.filename, session.cursorRow)
+	}
+}
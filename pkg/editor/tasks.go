@@ -0,0 +1,53 @@
+package editor
+
+import (
+	"slices"
+	"sync"
+)
+
+// Buffers and the session belong to the main loop, which is the only
+// goroutine that may touch them. Background work reads immutable ropes and
+// hands its results over with post; the loop runs them between keys, at the
+// latest when reading a key times out.
+
+var (
+	postedMu sync.Mutex
+	posted   []func()
+)
+
+// post queues f to run on the main loop. It may be called from any goroutine
+// and never blocks.
+func post(f func()) {
+	postedMu.Lock()
+	posted = append(posted, f)
+	postedMu.Unlock()
+}
+
+// runPosted runs the functions queued by post, in order, and reports
+// whether there were any
+func runPosted() bool {
+	postedMu.Lock()
+	tasks := posted
+	posted = nil
+	postedMu.Unlock()
+	for _, f := range tasks {
+		f()
+	}
+	return len(tasks) > 0
+}
+
+// background runs work on its own goroutine, then apply with its result on
+// the main loop. The result is dropped when buffer s was edited or closed
+// meanwhile, as it would be stale. work must only use what it captured, like
+// the rope of s: ropes are never modified in place, so reading them is safe.
+func background[T any](s *Session, work func() T, apply func(T)) {
+	rope := s.rope
+	go func() {
+		result := work()
+		post(func() {
+			if s.rope == rope && slices.Contains(buffers, s) {
+				apply(result)
+			}
+		})
+	}()
+}
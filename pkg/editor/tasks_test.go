@@ -0,0 +1,72 @@
+package editor
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestPostFromGoroutines(t *testing.T) {
+	resetSessionForTest()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				post(func() { session.lspVersion++ })
+			}
+		}()
+	}
+	wg.Wait()
+	if !runPosted() || session.lspVersion != 800 {
+		t.Fatalf("expected the 800 posted functions to run, got %d", session.lspVersion)
+	}
+	if runPosted() {
+		t.Error("the queue should be empty once run")
+	}
+}
+
+func TestBackgroundDropsStaleResults(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("hello")
+	rope := session.rope
+
+	done := make(chan bool)
+	background(session, func() string { defer close(done); return strings.ToUpper(rope.String()) }, func(s string) {
+		session.statusMessage = s
+	})
+	<-done
+	waitPosted(t)
+	if session.statusMessage != "HELLO" {
+		t.Errorf("expected the result applied, got %q", session.statusMessage)
+	}
+
+	session.statusMessage = ""
+	done = make(chan bool)
+	background(session, func() string { defer close(done); return strings.ToUpper(rope.String()) }, func(s string) {
+		session.statusMessage = s
+	})
+	session.rope = buffer.NewRope("edited")
+	<-done
+	waitPosted(t)
+	if session.statusMessage != "" {
+		t.Errorf("a result for old text was applied: %q", session.statusMessage)
+	}
+}
+
+// waitPosted runs the posted functions once the background goroutine that
+// just finished its work has queued them
+func waitPosted(t *testing.T) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if runPosted() {
+			return
+		}
+		runtime.Gosched()
+	}
+	t.Fatal("nothing was posted")
+}
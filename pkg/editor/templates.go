@@ -0,0 +1,96 @@
+package editor
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// templateDir holds the templates of new files (:set templatedir DIR), one
+// file per file type named after it ("go", "sh", "py") or per file name
+// ("Makefile"). Empty turns templates off.
+var templateDir = defaultTemplateDir()
+
+// defaultTemplateDir is templates beside the config file
+func defaultTemplateDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "goedit", "templates")
+	}
+	return ""
+}
+
+// templateCursor marks where the cursor goes in a template
+const templateCursor = "{{cursor}}"
+
+func init() {
+	addHook(eventBufOpen, func(s *Session) error {
+		if s.rope.Length() == 0 && !s.scratch && !isUnnamed(s.filename) && !IsURL(s.filename) {
+			if _, err := os.Stat(s.filename); errors.Is(err, fs.ErrNotExist) {
+				applyTemplate(s)
+			}
+		}
+		return nil
+	})
+}
+
+// findTemplate returns the template for a new file called filename of
+// fileType, "" when there is none
+func findTemplate(filename, fileType string) string {
+	if templateDir == "" {
+		return ""
+	}
+	for _, name := range []string{filepath.Base(filename), fileType} {
+		if name == "" {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(expandHome(templateDir), name)); err == nil {
+			return string(data)
+		}
+	}
+	return ""
+}
+
+// expandTemplate fills in the placeholders of a template for filename:
+// {{name}} (the file name without extension), {{dir}} (the name of its
+// directory, a Go package name), {{year}} and {{date}}
+func expandTemplate(template, filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		abs = filename
+	}
+	now := time.Now()
+	return strings.NewReplacer(
+		"{{name}}", strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)),
+		"{{dir}}", filepath.Base(filepath.Dir(abs)),
+		"{{year}}", now.Format("2006"),
+		"{{date}}", now.Format("2006-01-02"),
+	).Replace(template)
+}
+
+// applyTemplate inserts the template of the new file of s, as an edit that
+// undo takes back, with the cursor at {{cursor}} or the end
+func applyTemplate(s *Session) {
+	template := findTemplate(s.filename, s.fileType())
+	if template == "" {
+		return
+	}
+	text := expandTemplate(template, s.filename)
+	cursor := strings.Index(text, templateCursor)
+	text = strings.Replace(text, templateCursor, "", 1)
+	if cursor < 0 {
+		cursor = len(text)
+	}
+
+	active := session
+	session = s
+	defer func() { session = active }()
+	if editText(textEdit{position: 0, inserted: text}) {
+		session.cursorIdx = cursor
+		updateCursorPosition()
+		log.Printf("new file %s from a template", s.filename)
+	}
+}
@@ -0,0 +1,52 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewFileTemplate(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	templates := t.TempDir()
+	os.WriteFile(filepath.Join(templates, "go"), []byte("// Copyright {{year}}\n\npackage {{dir}}\n\n{{cursor}}\n"), 0644)
+	os.WriteFile(filepath.Join(templates, "Makefile"), []byte("all:\n"), 0644)
+	runCommand(0, "set templatedir "+templates, nil)
+
+	dir := filepath.Join(t.TempDir(), "widgets")
+	os.Mkdir(dir, 0755)
+	InitSession(-1, filepath.Join(dir, "widget.go"), "")
+	want := "// Copyright " + time.Now().Format("2006") + "\n\npackage widgets\n\n\n"
+	if got := session.rope.String(); got != want {
+		t.Fatalf("new file holds %q, want %q", got, want)
+	}
+	if session.cursorRow != 5 || session.cursorCol != 1 {
+		t.Errorf("cursor on %d:%d, want 5:1", session.cursorRow, session.cursorCol)
+	}
+	// The template is one undo step
+	handleUndo()
+	if session.rope.String() != "" {
+		t.Errorf("undo left %q", session.rope.String())
+	}
+
+	// A template named after the file wins
+	AddBuffer(filepath.Join(dir, "Makefile"), "", 0, 0)
+	if got := buffers[1].rope.String(); got != "all:\n" {
+		t.Errorf("Makefile holds %q", got)
+	}
+
+	// Existing files, even empty ones, are left alone
+	existing := filepath.Join(dir, "empty.go")
+	os.WriteFile(existing, nil, 0644)
+	InitSession(-1, existing, "")
+	if session.rope.Length() != 0 {
+		t.Errorf("an existing file got %q", session.rope.String())
+	}
+	InitSession(-1, filepath.Join(dir, "notes.txt"), "")
+	if session.rope.Length() != 0 || !strings.HasSuffix(session.filename, "notes.txt") {
+		t.Errorf("a file type without template got %q", session.rope.String())
+	}
+}
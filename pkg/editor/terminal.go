@@ -0,0 +1,289 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/jellexet/golang-text-editor/pkg/vt"
+	"golang.org/x/sys/unix"
+)
+
+// terminalPane is a shell running in a pseudo-terminal, drawn in a pane at
+// the bottom of the screen. Its output is played on an emulated screen.
+type terminalPane struct {
+	cmd     *exec.Cmd
+	pty     *os.File
+	screen  *vt.Screen
+	rows    int
+	name    string
+	focused bool // keys go to the shell rather than the editor
+}
+
+// termPane is the open terminal pane, or nil
+var termPane *terminalPane
+
+// openPTY opens a pseudo-terminal pair of rows x cols
+func openPTY(rows, cols int) (ptmx, tty *os.File, err error) {
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	ptmx = os.NewFile(uintptr(fd), "/dev/ptmx")
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+	ws := &unix.Winsize{Row: uint16(rows), Col: uint16(cols)}
+	if err := unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, ws); err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+	tty, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		ptmx.Close()
+		return nil, nil, err
+	}
+	return ptmx, tty, nil
+}
+
+// startTerminal runs command, or the user's shell ($SHELL, or /bin/sh), in
+// a pseudo-terminal of rows x cols. Its output is played on the pane's
+// screen from the main loop.
+func startTerminal(command string, rows, cols int) (*terminalPane, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell)
+	name := shell
+	if command != "" {
+		cmd = exec.Command(shell, "-c", command)
+		name = command
+	}
+	ptmx, tty, err := openPTY(rows, cols)
+	if err != nil {
+		return nil, err
+	}
+	defer tty.Close()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	cmd.Env = append(os.Environ(), "TERM=vt100")
+	// The shell leads a session of its own, with the pane as its terminal
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if err := cmd.Start(); err != nil {
+		ptmx.Close()
+		return nil, err
+	}
+	log.Printf("terminal %q started, pid %d", name, cmd.Process.Pid)
+
+	pane := &terminalPane{cmd: cmd, pty: ptmx, screen: vt.New(rows, cols), rows: rows, name: name}
+	go pane.readOutput()
+	return pane, nil
+}
+
+// readOutput hands what the shell writes to the main loop until it exits,
+// then closes the pane
+func (p *terminalPane) readOutput() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := p.pty.Read(buf)
+		if n > 0 {
+			data := append([]byte(nil), buf[:n]...)
+			post(func() { p.screen.Write(data) })
+		}
+		if err != nil {
+			break
+		}
+	}
+	err := p.cmd.Wait()
+	post(func() {
+		if termPane != p {
+			return
+		}
+		closeTerminal()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			session.statusMessage = fmt.Sprintf("Terminal exited: %v", exitErr)
+		} else {
+			session.statusMessage = "Terminal exited"
+		}
+	})
+}
+
+// closeTerminal ends the shell of the pane, if any, and closes the pane
+func closeTerminal() {
+	p := termPane
+	if p == nil {
+		return
+	}
+	termPane = nil
+	p.cmd.Process.Signal(syscall.SIGHUP)
+	p.pty.Close()
+	invalidateFrame()
+}
+
+// terminalSequences are what xterm sends for special keys, which the pane
+// passes on to the shell
+var terminalSequences = map[SpecialKey]string{
+	KeyUp: "\x1b[A", KeyDown: "\x1b[B", KeyRight: "\x1b[C", KeyLeft: "\x1b[D",
+	KeyHome: "\x1b[H", KeyEnd: "\x1b[F", KeyInsert: "\x1b[2~", KeyDelete: "\x1b[3~",
+	KeyPageUp: "\x1b[5~", KeyPageDown: "\x1b[6~",
+	KeyF1: "\x1bOP", KeyF1 + 1: "\x1bOQ", KeyF1 + 2: "\x1bOR", KeyF1 + 3: "\x1bOS",
+	KeyF1 + 4: "\x1b[15~", KeyF1 + 5: "\x1b[17~", KeyF1 + 6: "\x1b[18~", KeyF1 + 7: "\x1b[19~",
+	KeyF1 + 8: "\x1b[20~", KeyF1 + 9: "\x1b[21~", KeyF1 + 10: "\x1b[23~", KeyF12: "\x1b[24~",
+}
+
+// terminalKeyBytes returns what a terminal sends for key, to pass it on to
+// the shell
+func terminalKeyBytes(key Key) string {
+	var data string
+	switch key.Special {
+	case KeyUp, KeyDown, KeyRight, KeyLeft:
+		if key.Mod&ModShift != 0 {
+			return "\x1b[1;2" + terminalSequences[key.Special][2:]
+		}
+		data = terminalSequences[key.Special]
+	case KeyTab:
+		if key.Mod&ModShift != 0 {
+			return "\x1b[Z"
+		}
+		data = "\t"
+	case KeyEnter:
+		data = "\r"
+	case KeyBackspace:
+		data = string(rune(Backspace))
+	case KeyEsc:
+		data = "\x1b"
+	case NoSpecialKey:
+		data = string(key.Rune)
+		if key.Mod&ModCtrl != 0 {
+			data = string(rune(key.Rune & 0x1F))
+		}
+	default:
+		// Pastes and focus changes are not keys to the shell
+		var ok bool
+		if data, ok = terminalSequences[key.Special]; !ok {
+			return ""
+		}
+	}
+	if key.Mod&ModAlt != 0 {
+		data = "\x1b" + data
+	}
+	return data
+}
+
+// handleTerminalKey passes key on to the shell of the focused pane
+func handleTerminalKey(key Key, callback func() byte) {
+	data := terminalKeyBytes(key)
+	if key == BracketedPaste {
+		data = readPaste(callback)
+	}
+	if data == "" {
+		return
+	}
+	if _, err := termPane.pty.WriteString(data); err != nil {
+		session.statusMessage = fmt.Sprintf("Terminal: %v", err)
+	}
+}
+
+// toggleTerminalFocus moves the keys between the editor and the terminal
+// pane (Alt-T), opening one when there is none
+func toggleTerminalFocus() {
+	if termPane == nil {
+		handleTerminal(-1, "", nil)
+		return
+	}
+	termPane.focused = !termPane.focused
+}
+
+// terminalRows returns how many rows of the text area the pane takes with
+// its title row, none when there is no pane
+func terminalRows() int {
+	if termPane == nil {
+		return 0
+	}
+	return termPane.rows + 1
+}
+
+// drawTerminalPane draws the title row of the pane and the screen of its
+// shell into buf, from screen row top, and returns the screen position of
+// the shell's cursor
+func drawTerminalPane(buf *strings.Builder, top int) (int, int) {
+	p := termPane
+	cols := int(session.screenCols)
+	title := " Terminal: " + p.name + " (Alt-T: "
+	if p.focused {
+		title += "back to the text)"
+	} else {
+		title += "focus)"
+	}
+	fmt.Fprintf(buf, "\x1b[%d;1H\x1b[%sm%s\x1b[K\x1b[m\r\n", top, currentTheme.statusBar, title)
+
+	_, screenCols := p.screen.Size()
+	for r := 0; r < p.rows; r++ {
+		style := ""
+		for c := 0; c < min(cols, screenCols); c++ {
+			cell := p.screen.Cell(r, c)
+			if cell.Style != style {
+				buf.WriteString("\x1b[m")
+				if cell.Style != "" && !trueColor {
+					// Programs in the pane may use colors the terminal lacks
+					buf.WriteString("\x1b[" + degradeColors(cell.Style) + "m")
+				} else if cell.Style != "" {
+					buf.WriteString("\x1b[" + cell.Style + "m")
+				}
+				style = cell.Style
+			}
+			buf.WriteRune(cell.Rune)
+		}
+		if style != "" {
+			buf.WriteString("\x1b[m")
+		}
+		buf.WriteString("\x1b[K\r\n")
+	}
+	row, col := p.screen.Cursor()
+	return top + 1 + row, col + 1
+}
+
+// handleTerminal opens a pane running the user's shell, or command, below
+// the text and gives it the keys; :term off closes it (:term [off|command])
+func handleTerminal(fd int, args string, callback func() byte) {
+	args = strings.TrimSpace(args)
+	if args == "off" {
+		if termPane == nil {
+			session.statusMessage = "No terminal open"
+			return
+		}
+		closeTerminal()
+		session.statusMessage = "Terminal closed"
+		return
+	}
+	if termPane != nil {
+		termPane.focused = true
+		return
+	}
+	// The pane takes the lower half of the text area
+	textRows := int(session.screenRows) - statusRows()
+	rows := max(textRows/2-1, 1)
+	if textRows < 6 {
+		session.statusMessage = "Terminal: the screen is too small"
+		return
+	}
+	pane, err := startTerminal(args, rows, int(session.screenCols))
+	if err != nil {
+		session.statusMessage = fmt.Sprintf("Terminal: %v", err)
+		return
+	}
+	pane.focused = true
+	termPane = pane
+	session.statusMessage = "Terminal open: Alt-T switches between it and the text"
+}
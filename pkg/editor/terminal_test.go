@@ -0,0 +1,71 @@
+package editor
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/termtest"
+)
+
+func TestTerminalKeyBytes(t *testing.T) {
+	for key, want := range map[Key]string{
+		{Rune: 'a'}: "a", ctrl('d'): "\x04", ReturnKey: "\r", ArrowUp: "\x1b[A", ArrowLeft: "\x1b[D",
+		ShiftArrowLeft: "\x1b[1;2D", ShiftTab: "\x1b[Z", alt('b'): "\x1bb", ctrl(']'): "\x1d", FocusIn: "",
+		HomeKey: "\x1b[H", DeleteKey: "\x1b[3~", fn(5): "\x1b[15~", alt('x'): "\x1bx",
+	} {
+		if got := terminalKeyBytes(key); got != want {
+			t.Errorf("terminalKeyBytes(%+v) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestTerminalPane(t *testing.T) {
+	if _, err := os.Stat("/dev/ptmx"); err != nil {
+		t.Skip("no pseudo-terminals:", err)
+	}
+	resetSessionForTest()
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(closeTerminal)
+
+	runCommand(0, `term printf 'name? '; read name; echo "hello $name"; read wait`, nil)
+	if termPane == nil || !termPane.focused {
+		t.Fatalf("no focused pane, status %q", session.statusMessage)
+	}
+	waitFor(t, func() bool { return strings.Contains(termPane.screen.Line(0), "name?") })
+	for _, b := range []byte("ann\r") {
+		handleTerminalKey(keyOfByte(b), nil)
+	}
+	waitFor(t, func() bool { return strings.Contains(termPane.screen.String(), "hello ann") })
+
+	// The pane takes the lower half of the screen, below the text
+	screen := termtest.New(24, 80)
+	output = screen
+	t.Cleanup(func() { output = os.Stdout })
+	invalidateFrame()
+	t.Cleanup(invalidateFrame)
+	refreshScreen(-1)
+	if got := screen.Line(12); !strings.HasPrefix(got, " Terminal: printf") {
+		t.Errorf("pane title row %q\n%s", got, screen)
+	}
+	if got := screen.Line(14); got != "hello ann" {
+		t.Errorf("pane shows %q\n%s", got, screen)
+	}
+	if row, _ := screen.Cursor(); row != 15 {
+		t.Errorf("the cursor should be in the pane, on row %d", row)
+	}
+
+	// Alt-T gives the keys back to the text
+	toggleTerminalFocus()
+	refreshScreen(-1)
+	if row, col := screen.Cursor(); row != 0 || col != 0 {
+		t.Errorf("cursor at %d:%d, want the text", row, col)
+	}
+
+	// The pane closes when the shell exits
+	handleTerminalKey(ReturnKey, nil)
+	waitFor(t, func() bool { return termPane == nil })
+	if session.statusMessage != "Terminal exited" {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
@@ -0,0 +1,105 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalStatePath is the file the settings of the terminal on fd are
+// saved in while the editor runs, one per terminal, like
+// ~/.local/state/goedit/terminal/dev-pts-3
+func terminalStatePath(fd int) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	name := "tty"
+	if tty, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd)); err == nil && strings.HasPrefix(tty, "/dev/") {
+		name = strings.ReplaceAll(strings.TrimPrefix(tty, "/"), "/", "-")
+	}
+	return filepath.Join(dir, "terminal", name), nil
+}
+
+// SaveTerminalState records the settings the terminal on fd had before
+// the editor changed them, for --repair-terminal to put back when the
+// editor is killed without restoring them
+func SaveTerminalState(fd int, state *unix.Termios) error {
+	path, err := terminalStatePath(fd)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.NativeEndian, state); err != nil {
+		return err
+	}
+	// Write a temporary file first so a crash never leaves half a file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RemoveTerminalState forgets the settings saved for the terminal on fd,
+// once the editor has restored them itself
+func RemoveTerminalState(fd int) {
+	if path, err := terminalStatePath(fd); err == nil {
+		os.Remove(path)
+	}
+}
+
+// loadTerminalState reads the settings saved for the terminal on fd
+func loadTerminalState(fd int) (*unix.Termios, error) {
+	path, err := terminalStatePath(fd)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state unix.Termios
+	if err := binary.Read(bytes.NewReader(data), binary.NativeEndian, &state); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &state, nil
+}
+
+// RepairTerminal puts the terminal on fd back the way it was before an
+// editor that didn't exit cleanly changed it: the saved settings, or
+// without them the usual line editing and echo, and the main screen with
+// the modes the editor turns on off. It returns what it did.
+func RepairTerminal(fd int) (string, error) {
+	state, err := loadTerminalState(fd)
+	restored := "Restored the saved terminal settings"
+	if errors.Is(err, os.ErrNotExist) {
+		if state, err = unix.IoctlGetTermios(fd, unix.TCGETS); err != nil {
+			return "", err
+		}
+		// Undo what EnableRawMode does
+		state.Lflag |= unix.ECHO | unix.ICANON | unix.ISIG | unix.IEXTEN
+		state.Iflag |= unix.IXON | unix.BRKINT | unix.ICRNL
+		state.Oflag |= unix.OPOST
+		state.Cc[unix.VMIN] = 1
+		state.Cc[unix.VTIME] = 0
+		restored = "No saved terminal settings, turned line editing and echo back on"
+	} else if err != nil {
+		return "", err
+	}
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, state); err != nil {
+		return "", err
+	}
+	fmt.Fprint(output, leaveTerminalModes+"\x1b[?25h\x1b[m")
+	RemoveTerminalState(fd)
+	return restored, nil
+}
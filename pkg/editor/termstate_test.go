@@ -0,0 +1,65 @@
+package editor
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestRepairTerminal(t *testing.T) {
+	if _, err := os.Stat("/dev/ptmx"); err != nil {
+		t.Skip("no pseudo-terminals:", err)
+	}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	ptmx, tty, err := openPTY(24, 80)
+	if err != nil {
+		t.Skip("no pseudo-terminals:", err)
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+	fd := int(tty.Fd())
+	var out strings.Builder
+	output = &out
+	t.Cleanup(func() { output = os.Stdout })
+
+	// An editor killed in raw mode leaves its saved settings behind
+	original, err := EnableRawMode(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveTerminalState(fd, original); err != nil {
+		t.Fatal(err)
+	}
+	path, _ := terminalStatePath(fd)
+	if !strings.Contains(path, "dev-pts-") {
+		t.Errorf("the state of a pseudo-terminal is saved in %s", path)
+	}
+
+	msg, err := RepairTerminal(fd)
+	if err != nil || msg != "Restored the saved terminal settings" {
+		t.Fatalf("repair: %q, %v", msg, err)
+	}
+	if state, err := unix.IoctlGetTermios(fd, unix.TCGETS); err != nil || *state != *original {
+		t.Errorf("the terminal is not back as it was: %+v, %v", state, err)
+	}
+	if !strings.HasPrefix(out.String(), leaveTerminalModes) {
+		t.Errorf("the alternate screen should be left, output %q", out.String())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("the saved settings should be removed once restored")
+	}
+
+	// Without saved settings line editing and echo come back
+	if _, err := EnableRawMode(fd); err != nil {
+		t.Fatal(err)
+	}
+	msg, err = RepairTerminal(fd)
+	if err != nil || !strings.HasPrefix(msg, "No saved terminal settings") {
+		t.Fatalf("repair: %q, %v", msg, err)
+	}
+	if state, err := unix.IoctlGetTermios(fd, unix.TCGETS); err != nil || state.Lflag&unix.ICANON == 0 || state.Lflag&unix.ECHO == 0 {
+		t.Errorf("the terminal should be in cooked mode: %+v, %v", state, err)
+	}
+}
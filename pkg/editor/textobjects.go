@@ -0,0 +1,153 @@
+package editor
+
+import "sort"
+
+// closingBracket maps the opening brackets to their closing ones
+var closingBracket = map[byte]byte{'(': ')', '[': ']', '{': '}'}
+
+// isClosingBracket reports whether c closes a bracket pair
+func isClosingBracket(c byte) bool {
+	return c == ')' || c == ']' || c == '}'
+}
+
+// literalAt returns the index in lits of the literal holding text[i], -1
+// for none
+func literalAt(lits []literal, i int) int {
+	n := sort.Search(len(lits), func(n int) bool { return lits[n].end > i })
+	if n < len(lits) && lits[n].start <= i {
+		return n
+	}
+	return -1
+}
+
+// enclosingString returns the string literal holding pos, its quotes
+// included: text[open] and text[close-1] are the quotes
+func enclosingString(lits []literal, text string, pos int) (open, close int, ok bool) {
+	n := literalAt(lits, pos)
+	if n < 0 {
+		return 0, 0, false
+	}
+	l := lits[n]
+	if l.quote == 0 || l.end-l.start < 2 || text[l.end-1] != l.quote {
+		return 0, 0, false
+	}
+	return l.start, l.end, true
+}
+
+// enclosingBrackets returns the innermost pair of brackets around pos, or
+// on it, with text[open] the opening bracket and text[close-1] the closing
+// one. Brackets in comments and strings are left out, but for a pair
+// inside the one holding pos.
+func enclosingBrackets(lits []literal, text string, pos int) (open, close int, ok bool) {
+	if n := literalAt(lits, pos); n >= 0 {
+		l := lits[n]
+		if open, close, ok := matchBrackets(text, l.start, l.end, pos, func(int) bool { return false }); ok {
+			return open, close, true
+		}
+	}
+	return matchBrackets(text, 0, len(text), pos, func(i int) bool { return literalAt(lits, i) >= 0 })
+}
+
+// matchBrackets is enclosingBrackets looking only at text[lo:hi], and
+// leaving out the bytes skip says
+func matchBrackets(text string, lo, hi, pos int, skip func(int) bool) (open, close int, ok bool) {
+	// Back to the opening bracket not closed before pos
+	var closers []byte
+	i := min(pos, hi-1)
+	if i == pos && isClosingBracket(text[i]) {
+		i-- // on a closing bracket, its pair encloses pos
+	}
+	open = -1
+	for ; i >= lo && open < 0; i-- {
+		if skip(i) {
+			continue
+		}
+		switch c := text[i]; {
+		case isClosingBracket(c):
+			closers = append(closers, c)
+		case closingBracket[c] != 0:
+			if len(closers) == 0 {
+				open = i
+			} else if closers[len(closers)-1] == closingBracket[c] {
+				closers = closers[:len(closers)-1]
+			}
+		}
+	}
+	if open < 0 {
+		return 0, 0, false
+	}
+
+	// Forward to the bracket closing it
+	depth := 0
+	for j := open + 1; j < hi; j++ {
+		if skip(j) {
+			continue
+		}
+		switch text[j] {
+		case text[open]:
+			depth++
+		case closingBracket[text[open]]:
+			if depth == 0 {
+				return open, j + 1, true
+			}
+			depth--
+		}
+	}
+	return 0, 0, false
+}
+
+// enclosing returns the innermost string or pair of brackets around the
+// cursor, of kind "string", "brackets" or "" for either
+func enclosing(kind string) (open, close int, ok bool) {
+	text := session.rope.String()
+	lits := literals(session.fileType(), text)
+	pos := session.cursorIdx
+	if kind != "brackets" {
+		open, close, ok = enclosingString(lits, text, pos)
+	}
+	if kind != "string" {
+		// Brackets inside the string are closer to the cursor
+		if bo, bc, found := enclosingBrackets(lits, text, pos); found && (!ok || bo > open) {
+			open, close, ok = bo, bc, true
+		}
+	}
+	return open, close, ok
+}
+
+// handleEnclosingJump moves the cursor to the opening delimiter of the
+// string or bracket pair around it, or to the closing one when toEnd is
+// set (Alt-( and Alt-), :outer-start and :outer-end)
+func handleEnclosingJump(toEnd bool) {
+	open, close, ok := enclosing("")
+	if !ok {
+		session.statusMessage = "Not inside a string or brackets"
+		return
+	}
+	pushJump()
+	session.cursorIdx = open
+	if toEnd {
+		session.cursorIdx = close - 1
+	}
+	updateCursorPosition()
+}
+
+// handleSelectInside selects the text inside the innermost pair of
+// brackets, or string, around the cursor: kind is "brackets" or "string"
+// (Alt-B and Alt-Q, :inside-brackets and :inside-quotes). When that is
+// already selected, the delimiters are added to the selection.
+func handleSelectInside(kind string) {
+	open, close, ok := enclosing(kind)
+	if !ok {
+		what := "brackets"
+		if kind == "string" {
+			what = "a string"
+		}
+		session.statusMessage = "Not inside " + what
+		return
+	}
+	if start, end, selected := selection(); selected && start == open+1 && end == close-1 {
+		selectRange(open, close)
+		return
+	}
+	selectRange(open+1, close-1)
+}
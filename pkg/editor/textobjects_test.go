@@ -0,0 +1,93 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestEnclosingBrackets(t *testing.T) {
+	text := `f(a, g[b], "c)", {d}) // e)`
+	lits := literals("go", text)
+	for _, tc := range []struct {
+		at   string // the cursor goes on the first byte of it
+		want string
+	}{
+		{"a,", `(a, g[b], "c)", {d})`},
+		{"b]", "[b]"},
+		{"]", "[b]"},
+		{"[b", "[b]"},
+		{"d}", "{d}"},
+		{"c)", `(a, g[b], "c)", {d})`}, // the ) of the string counts in it only
+		{") //", `(a, g[b], "c)", {d})`},
+		{"f(", ""},
+		{"e)", ""},
+	} {
+		open, close, ok := enclosingBrackets(lits, text, strings.Index(text, tc.at))
+		got := ""
+		if ok {
+			got = text[open:close]
+		}
+		if got != tc.want {
+			t.Errorf("brackets around %q: %q, want %q", tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestSelectInside(t *testing.T) {
+	resetSessionForTest()
+	session.filename = "main.go"
+	session.rope = buffer.NewRope(`call(x, "say (hi)")`)
+	session.cursorIdx = strings.Index(session.rope.String(), "hi")
+	updateCursorPosition()
+
+	selected := func() string {
+		start, end, _ := selection()
+		text, _ := session.rope.Substring(start, end)
+		return text
+	}
+	// Brackets inside the string are closer than the string
+	ProcessKeypress(0, makeCallback([]byte("\x1bb\x11\x11")))
+	if got := selected(); got != "hi" {
+		t.Errorf("inside brackets selected %q", got)
+	}
+	// Again, the brackets are added
+	ProcessKeypress(0, makeCallback([]byte("\x1bb\x11\x11")))
+	if got := selected(); got != "(hi)" {
+		t.Errorf("around brackets selected %q", got)
+	}
+
+	ProcessKeypress(0, makeCallback([]byte("\x1bq\x11\x11")))
+	if got := selected(); got != "say (hi)" {
+		t.Errorf("inside quotes selected %q", got)
+	}
+
+	session.selecting = false
+	session.cursorIdx = 1
+	runCommand(0, "inside-quotes", nil)
+	if session.statusMessage != "Not inside a string" {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+}
+
+func TestEnclosingJump(t *testing.T) {
+	resetSessionForTest()
+	session.filename = "main.go"
+	session.rope = buffer.NewRope("if ok {\n\treturn f(1)\n}\n")
+	session.cursorIdx = strings.Index(session.rope.String(), "return")
+	updateCursorPosition()
+
+	ProcessKeypress(0, makeCallback([]byte("\x1b)\x11\x11")))
+	if session.cursorRow != 3 || session.cursorCol != 1 {
+		t.Errorf("end of the block at %d:%d, want 3:1", session.cursorRow, session.cursorCol)
+	}
+	runCommand(0, "outer-start", nil)
+	if session.cursorRow != 1 || session.cursorCol != 7 {
+		t.Errorf("start of the block at %d:%d, want 1:7", session.cursorRow, session.cursorCol)
+	}
+	jumpBack()
+	if session.cursorRow != 3 {
+		t.Errorf("jumping back went to row %d", session.cursorRow)
+	}
+}
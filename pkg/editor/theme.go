@@ -0,0 +1,92 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// theme holds the SGR parameters used to draw each part of the screen
+type theme struct {
+	statusBar  string // status bar and prompts
+	selection  string // selected text
+	misspelled string // words the spell checker doesn't know
+	diffOld    string // changed lines on the left of a comparison
+	diffNew    string // changed lines on the right of a comparison
+	diffFiller string // padding where one side of a comparison has no lines
+
+	conflictMarker string // <<<<<<<, ||||||| , ======= and >>>>>>> lines
+	conflictOurs   string // our side of a merge conflict
+	conflictBase   string // the common ancestor of a diff3 style conflict
+	conflictTheirs string // their side of a merge conflict
+
+	minimap         string // blocks of the minimap
+	minimapViewport string // minimap rows of the lines on screen
+
+	commitComment  string // comment lines of a commit message
+	commitOverflow string // text past 50 columns on the summary line, 72 on the others
+
+	peerCursor string // the cursor of the other editor in a collaboration
+	parseError string // the line of a JSON or YAML syntax error
+
+	tableHeader string // the pinned first row of the table view
+}
+
+// themes are the color schemes that can be chosen by name
+var themes = map[string]theme{
+	"default": {statusBar: "7", selection: "7", misspelled: "4", diffOld: "31", diffNew: "32", diffFiller: "2",
+		conflictMarker: "1", conflictOurs: "32", conflictBase: "2", conflictTheirs: "34",
+		minimap: "2", minimapViewport: "7",
+		commitComment: "36", commitOverflow: "41", peerCursor: "45", parseError: "41", tableHeader: "1;4"},
+	"dark": {statusBar: "48;5;238;97", selection: "48;5;24", misspelled: "4;91",
+		diffOld: "48;5;52", diffNew: "48;5;22", diffFiller: "38;5;240",
+		conflictMarker: "1;38;5;244", conflictOurs: "48;5;22", conflictBase: "48;5;236", conflictTheirs: "48;5;18",
+		minimap: "38;5;244", minimapViewport: "48;5;238",
+		commitComment: "38;5;109", commitOverflow: "48;5;52", peerCursor: "48;5;127", parseError: "48;5;52", tableHeader: "1;48;5;236"},
+	"light": {statusBar: "48;5;252;30", selection: "48;5;153", misspelled: "4;31",
+		diffOld: "48;5;224", diffNew: "48;5;194", diffFiller: "38;5;250",
+		conflictMarker: "1;38;5;242", conflictOurs: "48;5;194", conflictBase: "48;5;254", conflictTheirs: "48;5;189",
+		minimap: "38;5;245", minimapViewport: "48;5;252",
+		commitComment: "38;5;30", commitOverflow: "48;5;224", peerCursor: "48;5;218", parseError: "48;5;224", tableHeader: "1;48;5;254"},
+	// Bright colors on black and white only, bold where it helps
+	"high-contrast": {statusBar: "1;30;107", selection: "1;30;103", misspelled: "1;4;91",
+		diffOld: "1;97;41", diffNew: "1;30;102", diffFiller: "97",
+		conflictMarker: "1;30;107", conflictOurs: "1;30;102", conflictBase: "97;40", conflictTheirs: "1;30;106",
+		minimap: "97", minimapViewport: "1;30;107",
+		commitComment: "1;96", commitOverflow: "1;97;41", peerCursor: "1;30;105", parseError: "1;97;41", tableHeader: "1;30;107"},
+	// No color at all: every cue is bold, dim, underlined or inverse, for
+	// monochrome terminals, color-blind users and NO_COLOR
+	"mono": {statusBar: "7", selection: "7", misspelled: "4", diffOld: "2;4", diffNew: "1", diffFiller: "2",
+		conflictMarker: "1;7", conflictOurs: "1", conflictBase: "2", conflictTheirs: "4",
+		minimap: "2", minimapViewport: "7",
+		commitComment: "2", commitOverflow: "4", peerCursor: "1;4", parseError: "4;7", tableHeader: "1;4"},
+}
+
+// currentTheme is the theme the screen is drawn with
+var currentTheme = themes[defaultThemeName()]
+
+// defaultThemeName is the theme the editor starts with: mono when the
+// NO_COLOR environment variable asks for no color (https://no-color.org),
+// which a theme set in the config or on the command line still overrides
+func defaultThemeName() string {
+	if os.Getenv("NO_COLOR") != "" {
+		return "mono"
+	}
+	return "default"
+}
+
+// setTheme switches to the theme called name
+func setTheme(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		names := make([]string, 0, len(themes))
+		for n := range themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	currentTheme = t
+	return nil
+}
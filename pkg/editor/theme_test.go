@@ -0,0 +1,29 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNoColorTheme(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if got := defaultThemeName(); got != "default" {
+		t.Errorf("an empty NO_COLOR should be ignored, got %q", got)
+	}
+	t.Setenv("NO_COLOR", "1")
+	if got := defaultThemeName(); got != "mono" {
+		t.Errorf("NO_COLOR should start in the mono theme, got %q", got)
+	}
+
+	// Only the attributes of SGR are used, no color
+	mono := themes["mono"]
+	for _, style := range []string{mono.statusBar, mono.selection, mono.misspelled, mono.diffOld, mono.diffNew,
+		mono.diffFiller, mono.conflictMarker, mono.conflictOurs, mono.conflictBase, mono.conflictTheirs,
+		mono.minimap, mono.minimapViewport, mono.commitComment, mono.commitOverflow} {
+		for _, param := range strings.Split(style, ";") {
+			if len(param) > 1 {
+				t.Errorf("mono theme uses color %q", style)
+			}
+		}
+	}
+}
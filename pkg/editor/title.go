@@ -0,0 +1,50 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// shownTitle is the terminal title last set, to only send it when it changes
+var shownTitle string
+
+// modified reports whether the content of s changed since it was loaded or
+// saved, which scratch buffers never are. Content hashes tell an edit
+// undone from a real change without comparing the text.
+func (s *Session) modified() bool {
+	if s.rope == s.savedRope || s.scratch {
+		return false
+	}
+	return s.rope.Hash() != s.savedRope.Hash() || s.rope.Length() != s.savedRope.Length()
+}
+
+// windowTitle is the terminal title for the active buffer: its file name,
+// marked when it has unsaved changes
+func windowTitle() string {
+	name := filepath.Base(session.filename)
+	if isUnnamed(session.filename) {
+		name = session.filename
+	}
+	if session.modified() {
+		name += " [+]"
+	}
+	// Control characters in a file name would end the sequence early
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '?'
+		}
+		return r
+	}, name)
+	return name + " — goedit"
+}
+
+// titleUpdate returns the OSC 0 sequence setting the terminal title (of
+// the window and the tab) when it changed since the last frame
+func titleUpdate() string {
+	title := windowTitle()
+	if title == shownTitle {
+		return ""
+	}
+	shownTitle = title
+	return "\x1b]0;" + title + "\x07"
+}
@@ -0,0 +1,44 @@
+package editor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestTerminalTitle(t *testing.T) {
+	resetSessionForTest()
+	session = newSession("/tmp/dir/notes.txt", "text")
+	buffers = []*Session{session}
+	t.Cleanup(func() { shownTitle = "" })
+
+	if got := titleUpdate(); got != "\x1b]0;notes.txt — goedit\x07" {
+		t.Errorf("title %q", got)
+	}
+	if got := titleUpdate(); got != "" {
+		t.Errorf("an unchanged title should not be sent again, got %q", got)
+	}
+	session.rope = buffer.NewRope("changed")
+	if got := windowTitle(); got != "notes.txt [+] — goedit" {
+		t.Errorf("title of a modified buffer %q", got)
+	}
+	session.filename = filepath.Join(t.TempDir(), "notes.txt")
+	handleSave(0, nil)
+	if session.modified() {
+		t.Errorf("a saved buffer is not modified (status %q)", session.statusMessage)
+	}
+	session.rope, _ = session.rope.Insert(0, "un")
+	if !session.modified() {
+		t.Error("an edit should mark the buffer modified")
+	}
+	session.rope, _ = session.rope.Delete(0, 2)
+	if session.modified() {
+		t.Error("an edit undone should leave the buffer unmodified")
+	}
+
+	session = newSession("[No Name]", "")
+	if got := windowTitle(); got != "[No Name] — goedit" {
+		t.Errorf("title of an unnamed buffer %q", got)
+	}
+}
@@ -0,0 +1,144 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// digraph is a character that can be entered by a two-key combination or
+// by its Unicode name
+type digraph struct {
+	keys string
+	name string
+	r    rune
+}
+
+// digraphs are the combinations of common symbols, mostly those of RFC 1345
+// that vim uses too. Go has no table of Unicode names: only these names are
+// known.
+var digraphs = []digraph{
+	{"a'", "LATIN SMALL LETTER A WITH ACUTE", 'á'},
+	{"e'", "LATIN SMALL LETTER E WITH ACUTE", 'é'},
+	{"i'", "LATIN SMALL LETTER I WITH ACUTE", 'í'},
+	{"o'", "LATIN SMALL LETTER O WITH ACUTE", 'ó'},
+	{"u'", "LATIN SMALL LETTER U WITH ACUTE", 'ú'},
+	{"E'", "LATIN CAPITAL LETTER E WITH ACUTE", 'É'},
+	{"a!", "LATIN SMALL LETTER A WITH GRAVE", 'à'},
+	{"e!", "LATIN SMALL LETTER E WITH GRAVE", 'è'},
+	{"e>", "LATIN SMALL LETTER E WITH CIRCUMFLEX", 'ê'},
+	{"a:", "LATIN SMALL LETTER A WITH DIAERESIS", 'ä'},
+	{"o:", "LATIN SMALL LETTER O WITH DIAERESIS", 'ö'},
+	{"u:", "LATIN SMALL LETTER U WITH DIAERESIS", 'ü'},
+	{"A:", "LATIN CAPITAL LETTER A WITH DIAERESIS", 'Ä'},
+	{"O:", "LATIN CAPITAL LETTER O WITH DIAERESIS", 'Ö'},
+	{"U:", "LATIN CAPITAL LETTER U WITH DIAERESIS", 'Ü'},
+	{"n?", "LATIN SMALL LETTER N WITH TILDE", 'ñ'},
+	{"c,", "LATIN SMALL LETTER C WITH CEDILLA", 'ç'},
+	{"ss", "LATIN SMALL LETTER SHARP S", 'ß'},
+	{"ae", "LATIN SMALL LETTER AE", 'æ'},
+	{"o/", "LATIN SMALL LETTER O WITH STROKE", 'ø'},
+	{"aa", "LATIN SMALL LETTER A WITH RING ABOVE", 'å'},
+	{"a*", "GREEK SMALL LETTER ALPHA", 'α'},
+	{"b*", "GREEK SMALL LETTER BETA", 'β'},
+	{"l*", "GREEK SMALL LETTER LAMDA", 'λ'},
+	{"m*", "GREEK SMALL LETTER MU", 'μ'},
+	{"p*", "GREEK SMALL LETTER PI", 'π'},
+	{"D*", "GREEK CAPITAL LETTER DELTA", 'Δ'},
+	{"W*", "GREEK CAPITAL LETTER OMEGA", 'Ω'},
+	{"Eu", "EURO SIGN", '€'},
+	{"Pd", "POUND SIGN", '£'},
+	{"Ye", "YEN SIGN", '¥'},
+	{"Ct", "CENT SIGN", '¢'},
+	{"Co", "COPYRIGHT SIGN", '©'},
+	{"Rg", "REGISTERED SIGN", '®'},
+	{"TM", "TRADE MARK SIGN", '™'},
+	{"SE", "SECTION SIGN", '§'},
+	{"PI", "PILCROW SIGN", '¶'},
+	{"DG", "DEGREE SIGN", '°'},
+	{"+-", "PLUS-MINUS SIGN", '±'},
+	{"*X", "MULTIPLICATION SIGN", '×'},
+	{"-:", "DIVISION SIGN", '÷'},
+	{"!=", "NOT EQUAL TO", '≠'},
+	{"=<", "LESS-THAN OR EQUAL TO", '≤'},
+	{">=", "GREATER-THAN OR EQUAL TO", '≥'},
+	{"?2", "ALMOST EQUAL TO", '≈'},
+	{"00", "INFINITY", '∞'},
+	{"RT", "SQUARE ROOT", '√'},
+	{"->", "RIGHTWARDS ARROW", '→'},
+	{"<-", "LEFTWARDS ARROW", '←'},
+	{"-!", "UPWARDS ARROW", '↑'},
+	{"-v", "DOWNWARDS ARROW", '↓'},
+	{"=>", "RIGHTWARDS DOUBLE ARROW", '⇒'},
+	{"..", "HORIZONTAL ELLIPSIS", '…'},
+	{"-N", "EN DASH", '–'},
+	{"-M", "EM DASH", '—'},
+	{"'6", "LEFT SINGLE QUOTATION MARK", '‘'},
+	{"'9", "RIGHT SINGLE QUOTATION MARK", '’'},
+	{"\"6", "LEFT DOUBLE QUOTATION MARK", '“'},
+	{"\"9", "RIGHT DOUBLE QUOTATION MARK", '”'},
+	{"<<", "LEFT-POINTING DOUBLE ANGLE QUOTATION MARK", '«'},
+	{">>", "RIGHT-POINTING DOUBLE ANGLE QUOTATION MARK", '»'},
+	{"Sb", "BULLET", '•'},
+	{"NS", "NO-BREAK SPACE", ' '},
+	{"OK", "CHECK MARK", '✓'},
+	{"XX", "BALLOT X", '✗'},
+}
+
+// parseCharacter returns the character described by s: a hex code point
+// (e9, U+00E9, 0xE9), a digraph (e') or a Unicode name of the digraph table
+func parseCharacter(s string) (rune, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("nothing to insert")
+	}
+	// Digraphs come first: "00" is one
+	for _, d := range digraphs {
+		if d.keys == s || strings.EqualFold(d.name, s) {
+			return d.r, nil
+		}
+	}
+	hex := s
+	for _, prefix := range []string{"U+", "u+", "0x", "0X", "\\u", "\\U"} {
+		hex = strings.TrimPrefix(hex, prefix)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a hex code point, a digraph or a known name", s)
+	}
+	if r := rune(n); utf8.ValidRune(r) {
+		return r, nil
+	}
+	return 0, fmt.Errorf("U+%04X is not a valid character", n)
+}
+
+// handleInsertUnicode inserts a character given by its code point, digraph
+// or name (:unicode [CHAR], Ctrl-V), prompting for it when not given
+func handleInsertUnicode(fd int, args string, callback func() byte) {
+	if !editable() {
+		return
+	}
+	if args == "" {
+		args = editorDrawPrompt("Character (hex code point, digraph or name):", callback)
+		if args == "" {
+			return
+		}
+	}
+	r, err := parseCharacter(args)
+	if err != nil {
+		session.statusMessage = "Insert character: " + err.Error()
+		return
+	}
+	handleInsert(string(r))
+	session.statusMessage = fmt.Sprintf("Inserted U+%04X", r)
+}
+
+// handleDigraphs lists the digraph table in a scratch buffer (:digraphs)
+func handleDigraphs(fd int, args string, callback func() byte) {
+	var b strings.Builder
+	for _, d := range digraphs {
+		fmt.Fprintf(&b, "%-3s %c  U+%04X  %s\n", d.keys, d.r, d.r, d.name)
+	}
+	showScratch("[Digraphs]", b.String())
+}
@@ -0,0 +1,69 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestParseCharacter(t *testing.T) {
+	for in, want := range map[string]rune{
+		"e9":          'é',
+		"U+1F600":     '😀',
+		"0x263a":      '☺',
+		"e'":          'é',
+		"->":          '→',
+		"euro sign":   '€',
+		"Degree Sign": '°',
+		"ae":          'æ',
+		"U+AE":        '®',
+	} {
+		if got, err := parseCharacter(in); err != nil || got != want {
+			t.Errorf("parseCharacter(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "zz", "U+D800", "110000"} {
+		if _, err := parseCharacter(in); err == nil {
+			t.Errorf("parseCharacter(%q) should fail", in)
+		}
+	}
+}
+
+func TestInsertUnicode(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("ab")
+	session.cursorIdx = 1
+
+	ProcessKeypress(0, makeCallback([]byte("\x16Eu\r\x11\x11")))
+	if got := session.rope.String(); got != "a€b" || session.cursorIdx != 4 {
+		t.Errorf("Ctrl-V left %q, cursor %d", got, session.cursorIdx)
+	}
+	runCommand(0, "unicode nope", nil)
+	if got := session.rope.String(); got != "a€b" || session.statusMessage == "" {
+		t.Errorf("an unknown character changed the buffer: %q", got)
+	}
+}
+
+func TestCharInfo(t *testing.T) {
+	resetSessionForTest()
+	session.rope = buffer.NewRope("a€\x01\xff")
+
+	for idx, want := range map[int]string{
+		0: "'a' U+0061, decimal 97, UTF-8 61",
+		// In the middle of the euro sign
+		2: "'€' U+20AC, decimal 8364, UTF-8 E2 82 AC, EURO SIGN (digraph Eu)",
+		4: "'^A' U+0001, decimal 1, UTF-8 01",
+		5: "Invalid UTF-8 byte 0xFF",
+	} {
+		session.cursorIdx = idx
+		runCommand(0, "ga", nil)
+		if session.statusMessage != want {
+			t.Errorf("at %d: %q, want %q", idx, session.statusMessage, want)
+		}
+	}
+	session.cursorIdx = 6
+	runCommand(0, "char", nil)
+	if session.statusMessage != "No character under the cursor" {
+		t.Errorf("at the end: %q", session.statusMessage)
+	}
+}
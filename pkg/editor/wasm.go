@@ -0,0 +1,316 @@
+package editor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// WASM plugins run in wazero, in any language compiling to WebAssembly. A
+// plugin sees nothing of the machine: WASI is there for the runtimes of
+// languages that need it, with no files, environment or network, and
+// memory and the time of each call are capped. It reaches the editor
+// through the functions it imports from the module "goedit", each one
+// needing a capability granted to the plugin in the config file:
+//
+//	[plugins]
+//	wordcount.wasm = read, commands
+//
+// The functions are, by capability:
+//
+//	(none)     message(ptr, len)                the status line
+//	read       text(), selection(), filename()  strings, 0 for no selection
+//	           cursor()                         line<<32 | col, from 1
+//	edit       set_text(ptr, len) i32           1 when the buffer changed
+//	           insert(ptr, len)                 text typed at the cursor
+//	           replace_selection(ptr, len) i32  1 when there was one
+//	           set_cursor(line, col)
+//	commands   register_command(ptr, len) i32   1 when the name is free
+//
+// Strings go to the editor as a pointer and length in the memory of the
+// plugin, and come back as an i64 of pointer<<32 | length, written in
+// memory the plugin gives with its export alloc(len) ptr and then owns.
+// After its start function (_initialize, for reactors), the editor calls
+// its export init(), where commands are registered; :name args calls
+// command(name_ptr, name_len, args_ptr, args_len) for them.
+
+// wasmHostModule is the module plugins import the editor's functions from
+const wasmHostModule = "goedit"
+
+// wasmMemoryPages caps the memory of a plugin, in pages of 64 KiB
+const wasmMemoryPages = 1024
+
+// wasmTimeout is how long a call into a plugin may run. A plugin that
+// takes longer is stopped for good.
+const wasmTimeout = time.Second
+
+// wasmCapabilities are the capabilities that can be granted to plugins
+var wasmCapabilities = []string{"read", "edit", "commands"}
+
+// wasmGrants are the capabilities granted to each plugin file, from the
+// [plugins] section of the config file
+var wasmGrants = map[string][]string{}
+
+// wasmState is the runtime of the WASM plugins, nil until one is loaded
+var wasmState wazero.Runtime
+
+// wasmHostFunc is a function of the editor plugins can import
+type wasmHostFunc struct {
+	capability string // what the plugin must be granted, "" for none
+	fn         any    // passed to wazero's WithFunc
+}
+
+// wasmHost are the functions of the module goedit, by name. It is filled
+// in init, as register_command adds commands.
+var wasmHost map[string]wasmHostFunc
+
+// setPluginGrant grants the capabilities listed in value, separated by
+// commas, to the plugin file name
+func setPluginGrant(name, value string) error {
+	var grants []string
+	for _, c := range strings.Split(value, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !slices.Contains(wasmCapabilities, c) {
+			return fmt.Errorf("unknown capability %q (%s)", c, strings.Join(wasmCapabilities, ", "))
+		}
+		grants = append(grants, c)
+	}
+	wasmGrants[name] = grants
+	return nil
+}
+
+// wasmRuntime returns the runtime, creating it with the host modules
+func wasmRuntime() (wazero.Runtime, error) {
+	if wasmState != nil {
+		return wasmState, nil
+	}
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(wasmMemoryPages).
+		WithCloseOnContextDone(true))
+	host := r.NewHostModuleBuilder(wasmHostModule)
+	names := make([]string, 0, len(wasmHost))
+	for name := range wasmHost {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		host.NewFunctionBuilder().WithFunc(wasmHost[name].fn).Export(name)
+	}
+	if _, err := host.Instantiate(ctx); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	wasmState = r
+	return r, nil
+}
+
+// closeWasm stops the runtime and every plugin in it
+func closeWasm() {
+	if wasmState != nil {
+		wasmState.Close(context.Background())
+		wasmState = nil
+	}
+}
+
+// wasmError is the first line of err, without the stack of the plugin
+func wasmError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	return errors.New(strings.TrimSuffix(msg, " (recovered by wazero)"))
+}
+
+// loadWasmPlugin checks that the WASM module at path only imports what
+// it was granted, then runs its start function and init
+func loadWasmPlugin(path string) error {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	r, err := wasmRuntime()
+	if err != nil {
+		return err
+	}
+	// Compiling runs no code of the plugin, and takes a while for big ones
+	compiled, err := r.CompileModule(context.Background(), code)
+	if err != nil {
+		return wasmError(err)
+	}
+	name := filepath.Base(path)
+	for _, def := range compiled.ImportedFunctions() {
+		module, fn, _ := def.Import()
+		switch {
+		case module == wasi_snapshot_preview1.ModuleName:
+			// Sandboxed: no files, environment or network
+		case module != wasmHostModule:
+			return fmt.Errorf("imports %s.%s, which the editor doesn't provide", module, fn)
+		case wasmHost[fn].fn == nil:
+			return fmt.Errorf("imports unknown function %s.%s", module, fn)
+		case wasmHost[fn].capability != "" && !slices.Contains(wasmGrants[name], wasmHost[fn].capability):
+			return fmt.Errorf("%s.%s needs the %s capability ([plugins] %s = %[3]s in the config file)",
+				module, fn, wasmHost[fn].capability, name)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wasmTimeout)
+	defer cancel()
+	m, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().
+		WithName(name).
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return wasmError(err)
+	}
+	if init := m.ExportedFunction("init"); init != nil {
+		if _, err := init.Call(ctx); err != nil {
+			return wasmError(err)
+		}
+	}
+	return nil
+}
+
+// wasmMemory is the memory of m, which strings go through
+func wasmMemory(m api.Module) api.Memory {
+	mem := m.Memory()
+	if mem == nil {
+		panic(errors.New("plugin exports no memory"))
+	}
+	return mem
+}
+
+// wasmString reads the string at ptr in the memory of m. Out of bounds,
+// the call of the plugin fails.
+func wasmString(m api.Module, ptr, size uint32) string {
+	b, ok := wasmMemory(m).Read(ptr, size)
+	if !ok {
+		panic(fmt.Errorf("string at %d+%d out of memory", ptr, size))
+	}
+	return string(b)
+}
+
+// wasmPut copies s into memory m allocates, returning pointer<<32 | length
+func wasmPut(ctx context.Context, m api.Module, s string) uint64 {
+	alloc := m.ExportedFunction("alloc")
+	if alloc == nil {
+		panic(errors.New("plugin exports no alloc function"))
+	}
+	res, err := alloc.Call(ctx, uint64(len(s)))
+	if err != nil {
+		panic(err)
+	}
+	ptr := uint32(res[0])
+	if !wasmMemory(m).WriteString(ptr, s) {
+		panic(fmt.Errorf("alloc returned %d, out of memory", ptr))
+	}
+	return uint64(ptr)<<32 | uint64(len(s))
+}
+
+// wasmBool is b as an i32
+func wasmBool(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// runWasmCommand runs the command name of plugin m, through its export
+// command
+func runWasmCommand(m api.Module, name, args string) error {
+	command := m.ExportedFunction("command")
+	if command == nil {
+		return errors.New("plugin exports no command function")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wasmTimeout)
+	defer cancel()
+	var err error
+	func() {
+		// Strings are put in the plugin's memory by calling it, which may fail
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		namePut, argsPut := wasmPut(ctx, m, name), wasmPut(ctx, m, args)
+		_, err = command.Call(ctx, namePut>>32, namePut&0xffffffff, argsPut>>32, argsPut&0xffffffff)
+	}()
+	return wasmError(err)
+}
+
+func init() {
+	wasmHost = map[string]wasmHostFunc{
+		"message": {"", func(_ context.Context, m api.Module, ptr, size uint32) {
+			session.statusMessage = wasmString(m, ptr, size)
+		}},
+		"text": {"read", func(ctx context.Context, m api.Module) uint64 {
+			return wasmPut(ctx, m, session.rope.String())
+		}},
+		"selection": {"read", func(ctx context.Context, m api.Module) uint64 {
+			start, end, selected := selection()
+			if !selected {
+				return 0
+			}
+			text, _ := session.rope.Substring(start, end)
+			return wasmPut(ctx, m, text)
+		}},
+		"filename": {"read", func(ctx context.Context, m api.Module) uint64 {
+			return wasmPut(ctx, m, session.filename)
+		}},
+		"cursor": {"read", func() uint64 {
+			col := session.cursorIdx - getLineStartIndex(session.cursorRow) + 1
+			return uint64(session.cursorRow)<<32 | uint64(col)
+		}},
+		"set_text": {"edit", func(_ context.Context, m api.Module, ptr, size uint32) uint32 {
+			text := wasmString(m, ptr, size)
+			if !editable() || text == session.rope.String() {
+				return 0
+			}
+			applyTextPreservingCursor(text)
+			return 1
+		}},
+		"insert": {"edit", func(_ context.Context, m api.Module, ptr, size uint32) {
+			handleInsert(wasmString(m, ptr, size))
+		}},
+		"replace_selection": {"edit", func(_ context.Context, m api.Module, ptr, size uint32) uint32 {
+			text := wasmString(m, ptr, size)
+			start, end, selected := selection()
+			if !selected {
+				return 0
+			}
+			rope := session.rope
+			replaceText(start, end, text)
+			if session.rope != rope {
+				selectRange(start, start+len(text))
+			}
+			return wasmBool(session.rope != rope)
+		}},
+		"set_cursor": {"edit", func(line, col uint32) {
+			GoToLine(int(line), int(col))
+		}},
+		"register_command": {"commands", func(_ context.Context, m api.Module, ptr, size uint32) uint32 {
+			name := wasmString(m, ptr, size)
+			err := addPluginCommand(name, func(fd int, args string, callback func() byte) {
+				if err := runWasmCommand(m, name, args); err != nil {
+					session.statusMessage = fmt.Sprintf("%s: %v", name, err)
+				}
+			})
+			return wasmBool(err == nil)
+		}},
+	}
+}
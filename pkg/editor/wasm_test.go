@@ -0,0 +1,178 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wasmVec encodes items as a WASM vector: their count, then them
+func wasmVec(items ...[]byte) []byte {
+	out := wasmUint(uint32(len(items)))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// wasmUint encodes n in unsigned LEB128
+func wasmUint(n uint32) []byte {
+	var out []byte
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// wasmName encodes a name of an import or export
+func wasmName(s string) []byte {
+	return append(wasmUint(uint32(len(s))), s...)
+}
+
+// wasmSection encodes a section with its id and size
+func wasmSection(id byte, content []byte) []byte {
+	return append(append([]byte{id}, wasmUint(uint32(len(content)))...), content...)
+}
+
+// wasmFuncType encodes a function type of i32 parameters returning results
+func wasmFuncType(params int, results ...byte) []byte {
+	out := []byte{0x60}
+	out = append(out, wasmUint(uint32(params))...)
+	for range params {
+		out = append(out, 0x7f)
+	}
+	return append(append(out, wasmUint(uint32(len(results)))...), results...)
+}
+
+const (
+	wasmI32 = 0x7f
+	wasmI64 = 0x7e
+)
+
+// echoPlugin is a WASM plugin adding :echo TEXT, which shows the text of
+// the buffer in the status line and replaces it with TEXT
+func echoPlugin() []byte {
+	types := wasmSection(1, wasmVec(
+		wasmFuncType(2, wasmI32), // 0: register_command, set_text
+		wasmFuncType(0, wasmI64), // 1: text
+		wasmFuncType(2),          // 2: message
+		wasmFuncType(0),          // 3: init
+		wasmFuncType(1, wasmI32), // 4: alloc
+		wasmFuncType(4),          // 5: command
+	))
+	importFunc := func(name string, typ byte) []byte {
+		return append(append(wasmName("goedit"), wasmName(name)...), 0x00, typ)
+	}
+	imports := wasmSection(2, wasmVec(
+		importFunc("register_command", 0), // func 0
+		importFunc("text", 1),             // func 1
+		importFunc("set_text", 0),         // func 2
+		importFunc("message", 2),          // func 3
+	))
+	funcs := wasmSection(3, wasmVec([]byte{3}, []byte{4}, []byte{5}))
+	memory := wasmSection(5, wasmVec([]byte{0x00, 0x01}))
+	// The next free byte for alloc
+	globals := wasmSection(6, wasmVec([]byte{wasmI32, 0x01, 0x41, 0x80, 0x08, 0x0b}))
+	export := func(name string, kind, index byte) []byte {
+		return append(wasmName(name), kind, index)
+	}
+	exports := wasmSection(7, wasmVec(
+		export("memory", 0x02, 0),
+		export("init", 0x00, 4),
+		export("alloc", 0x00, 5),
+		export("command", 0x00, 6),
+	))
+	body := func(locals []byte, code ...byte) []byte {
+		b := append(locals, code...)
+		return append(wasmUint(uint32(len(b))), b...)
+	}
+	code := wasmSection(10, wasmVec(
+		// init: register_command("echo")
+		body([]byte{0}, 0x41, 0, 0x41, 4, 0x10, 0, 0x1a, 0x0b),
+		// alloc: the next free byte, moved size bytes further
+		body([]byte{0}, 0x23, 0, 0x23, 0, 0x20, 0, 0x6a, 0x24, 0, 0x0b),
+		// command: t = text(); message(t >> 32, t); set_text(args)
+		body([]byte{1, 1, wasmI64},
+			0x10, 1, 0x21, 4,
+			0x20, 4, 0x42, 32, 0x88, 0xa7, 0x20, 4, 0xa7, 0x10, 3,
+			0x20, 2, 0x20, 3, 0x10, 2, 0x1a,
+			0x0b),
+	))
+	data := wasmSection(11, wasmVec(append([]byte{0x00, 0x41, 0, 0x0b}, wasmName("echo")...)))
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	for _, section := range [][]byte{types, imports, funcs, memory, globals, exports, code, data} {
+		module = append(module, section...)
+	}
+	return module
+}
+
+// loadWasmTestPlugin loads a WASM plugin named name, unloaded after the test
+func loadWasmTestPlugin(t *testing.T, name string, code []byte) error {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), code, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := pluginDir
+	pluginDir = dir
+	t.Cleanup(func() {
+		pluginDir = oldDir
+		unloadPlugins()
+	})
+	return LoadPlugins()
+}
+
+func TestWasmPlugin(t *testing.T) {
+	resetSessionForTest()
+	InitSession(-1, "notes.txt", "")
+	replaceText(0, 0, "old text")
+	t.Cleanup(func() { wasmGrants = map[string][]string{} })
+	if err := setPluginGrant("echo.wasm", "read, edit, commands"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadWasmTestPlugin(t, "echo.wasm", echoPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	runCommand(-1, "echo new text", declineKeys())
+	if got := session.rope.String(); got != "new text" || session.statusMessage != "old text" {
+		t.Errorf("text %q, status %q", got, session.statusMessage)
+	}
+	handlePlugins(-1, "", nil)
+	if session.statusMessage != "Plugins: echo.wasm | commands: echo" {
+		t.Errorf("plugins: %q", session.statusMessage)
+	}
+}
+
+func TestWasmPluginCapabilities(t *testing.T) {
+	resetSessionForTest()
+	t.Cleanup(func() { wasmGrants = map[string][]string{} })
+	if err := setPluginGrant("echo.wasm", "read, commands"); err != nil {
+		t.Fatal(err)
+	}
+	err := loadWasmTestPlugin(t, "echo.wasm", echoPlugin())
+	if err == nil || err.Error() != "plugin echo.wasm: goedit.set_text needs the edit capability ([plugins] echo.wasm = edit in the config file)" {
+		t.Errorf("loaded without the edit capability: %v", err)
+	}
+	if _, ok := commands["echo"]; ok {
+		t.Error("the plugin refused registered its command")
+	}
+
+	// Nothing but goedit and WASI can be imported
+	host := []byte("\x00asm\x01\x00\x00\x00")
+	host = append(host, wasmSection(1, wasmVec(wasmFuncType(0)))...)
+	host = append(host, wasmSection(2, wasmVec(append(append(wasmName("env"), wasmName("system")...), 0x00, 0)))...)
+	err = loadWasmTestPlugin(t, "host.wasm", host)
+	if err == nil || !strings.HasSuffix(err.Error(), "imports env.system, which the editor doesn't provide") {
+		t.Errorf("loaded importing env.system: %v", err)
+	}
+
+	if err := setPluginGrant("echo.wasm", "read, files"); err == nil || !strings.Contains(err.Error(), `unknown capability "files"`) {
+		t.Errorf("granting an unknown capability: %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// viewState is what a window shows of its buffer: where the cursor is,
+// what is selected and how far the text is scrolled. The active window's
+// lives in its buffer, where editing uses it; the other windows keep
+// theirs until they become active.
+type viewState struct {
+	cursorIdx int
+	rowOffset int
+	selecting bool
+	selAnchor int
+}
+
+// window is a part of the screen showing a buffer, which other windows
+// may show too
+type window struct {
+	buf  *Session
+	view viewState // while the window isn't active
+
+	// The buffer and scrolling the window was last drawn with, to scroll
+	// the others as much with scrollbind
+	drawnBuf    *Session
+	drawnOffset int
+}
+
+// The windows on screen in order, top to bottom or left to right, with
+// the active one at activeWindow. Without splits there are none and the
+// active buffer takes the whole screen.
+var (
+	windows       []*window
+	activeWindow  int
+	verticalSplit bool // the windows are side by side
+)
+
+// scrollBind scrolls every window as much as the active one, to read two
+// versions or translations of a text side by side (:set scrollbind on)
+var scrollBind bool
+
+// saveView returns the view state held by s
+func saveView(s *Session) viewState {
+	return viewState{s.cursorIdx, s.rowOffset, s.selecting, s.selAnchor}
+}
+
+// loadView makes v the view state of s, within the text it now holds
+func loadView(s *Session, v viewState) {
+	length := s.rope.Length()
+	s.cursorIdx = min(v.cursorIdx, length)
+	s.rowOffset = v.rowOffset
+	s.selecting = v.selecting
+	s.selAnchor = min(v.selAnchor, length)
+}
+
+// shiftWindows moves the cursors and selections of the other windows on
+// the active buffer after removed bytes were replaced by inserted ones at
+// pos, like the protected ranges
+func shiftWindows(pos, removed, inserted int) {
+	shift := func(idx int) int {
+		switch {
+		case idx >= pos+removed:
+			return idx + inserted - removed
+		case idx > pos:
+			return pos
+		}
+		return idx
+	}
+	for i, w := range windows {
+		if i != activeWindow && w.buf == session {
+			w.view.cursorIdx = shift(w.view.cursorIdx)
+			w.view.selAnchor = shift(w.view.selAnchor)
+		}
+	}
+}
+
+// splitWindow splits the active window in two, side by side when vertical,
+// both showing the active buffer with its view. The new window, after the
+// old one, becomes active.
+func splitWindow(vertical bool) error {
+	if len(windows) > 1 && vertical != verticalSplit {
+		return fmt.Errorf("the windows are already split the other way, :only first")
+	}
+	if len(windows) == 0 {
+		windows = []*window{{buf: session, drawnBuf: session, drawnOffset: session.rowOffset}}
+		activeWindow = 0
+	}
+	if len(windows) >= 8 {
+		return fmt.Errorf("too many windows")
+	}
+	verticalSplit = vertical
+	windows[activeWindow].view = saveView(session)
+	w := &window{buf: session, drawnBuf: session, drawnOffset: session.rowOffset}
+	windows = append(windows[:activeWindow+1], append([]*window{w}, windows[activeWindow+1:]...)...)
+	activeWindow++
+	invalidateFrame()
+	return nil
+}
+
+// focusWindow makes the window at index i active, with its buffer and view
+func focusWindow(i int) {
+	if i == activeWindow || i < 0 || i >= len(windows) {
+		return
+	}
+	windows[activeWindow].view = saveView(session)
+	activeWindow = i
+	w := windows[i]
+	w.buf.screenRows, w.buf.screenCols = session.screenRows, session.screenCols
+	session = w.buf
+	loadView(session, w.view)
+	updateCursorPosition()
+}
+
+// closeWindow closes the window at index i, leaving the whole screen to
+// the last one
+func closeWindow(i int) {
+	if i == activeWindow {
+		next := i + 1
+		if next == len(windows) {
+			next = i - 1
+		}
+		focusWindow(next)
+	}
+	windows = append(windows[:i], windows[i+1:]...)
+	if i < activeWindow {
+		activeWindow--
+	}
+	if len(windows) == 1 {
+		windows = nil
+		activeWindow = 0
+	}
+	invalidateFrame()
+}
+
+// windowRegion returns the first screen row and column (1-indexed) and the
+// size of window i, out of textRows x cols. Stacked windows have a title
+// row below them, except the last one, which the status bar describes;
+// side by side windows are parted by a line.
+func windowRegion(i, textRows, cols int) (top, left, rows, width int) {
+	if verticalSplit {
+		start, length := windowSpan(i, cols)
+		return 1, start + 1, textRows, length
+	}
+	start, length := windowSpan(i, textRows)
+	return start + 1, 1, length, cols
+}
+
+// windowSpan shares size rows or columns between the windows, one of each
+// pair parted by a title row or a line, and returns the offset and length
+// of window i. The first windows get what doesn't divide evenly.
+func windowSpan(i, size int) (start, length int) {
+	n := len(windows)
+	size -= n - 1
+	for j := 0; j <= i; j++ {
+		start += length
+		if j > 0 {
+			start++
+		}
+		length = size / n
+		if j < size%n {
+			length++
+		}
+	}
+	return start, length
+}
+
+// followScroll scrolls the active buffer's view by delta lines for
+// scrollbind, within its lineCount lines, and moves the cursor along when
+// it would leave the rows of the window
+func followScroll(delta, rows, lineCount int) {
+	session.rowOffset = min(max(session.rowOffset+delta, 0), max(lineCount-1, 0))
+	margin := min(scrollOff, (rows-1)/2)
+	row := min(max(session.cursorRow, session.rowOffset+1+margin), session.rowOffset+rows-margin)
+	if row != session.cursorRow {
+		GoToLine(min(row, lineCount), session.cursorCol)
+	}
+}
+
+// drawWindows draws every window in its part of the text area into buf,
+// and returns the screen position of the cursor of the active one
+func drawWindows(buf *strings.Builder, textRows int) (int, int) {
+	active := session
+	screenRows, screenCols := active.screenRows, active.screenCols
+	cols := int(screenCols)
+	windows[activeWindow].view = saveView(active)
+	var cursorRow, cursorCol int
+
+	// The active window is drawn first, so that with scrollbind the others
+	// follow how far it scrolled
+	order := []int{activeWindow}
+	for i := range windows {
+		if i != activeWindow {
+			order = append(order, i)
+		}
+	}
+	scrolled := 0
+	for _, i := range order {
+		w := windows[i]
+		top, left, rows, width := windowRegion(i, textRows, cols)
+		// Each window is drawn with its view into the buffer, whose own
+		// view, the active window's, is put back after
+		kept := saveView(w.buf)
+		loadView(w.buf, w.view)
+		session = w.buf
+		w.buf.screenRows, w.buf.screenCols = uint16(rows+statusRows()), uint16(width)
+		updateCursorPosition()
+		if scrollBind && i != activeWindow && scrolled != 0 && w.drawnBuf == w.buf {
+			followScroll(scrolled, rows, len(lineStarts()))
+		}
+		var view strings.Builder
+		row, col := drawBufferView(&view, rows)
+		w.view = saveView(w.buf)
+		loadView(w.buf, kept)
+		// A window showing another buffer than last time hasn't scrolled
+		if i == activeWindow && w.drawnBuf == w.buf {
+			scrolled = w.view.rowOffset - w.drawnOffset
+		}
+		w.drawnBuf, w.drawnOffset = w.buf, w.view.rowOffset
+
+		for r, cells := range screenCells(view.String(), rows, width) {
+			fmt.Fprintf(buf, "\x1b[%d;%dH", top+r, left)
+			writeStyled(buf, cells)
+		}
+		if i == activeWindow {
+			cursorRow, cursorCol = top+row-1, left+col-1
+		}
+		if i == len(windows)-1 {
+			continue
+		}
+		if verticalSplit {
+			for r := 0; r < rows; r++ {
+				fmt.Fprintf(buf, "\x1b[%d;%dH│", top+r, left+width)
+			}
+		} else {
+			title := " " + statusFileName()
+			if w.buf.modified() {
+				title += " [+]"
+			}
+			fmt.Fprintf(buf, "\x1b[%d;1H\x1b[%sm%s\x1b[K\x1b[m", top+rows, currentTheme.statusBar, title)
+		}
+	}
+
+	session = active
+	active.screenRows, active.screenCols = screenRows, screenCols
+	loadView(active, windows[activeWindow].view)
+	updateCursorPosition()
+	// The status bar is drawn from where the text area ends
+	fmt.Fprintf(buf, "\x1b[%d;1H", textRows+1)
+	return cursorRow, cursorCol
+}
+
+// handleSplit splits the active window, one above the other, showing the
+// active buffer or FILE in the new one (:split [FILE])
+func handleSplit(fd int, args string, callback func() byte) {
+	splitWith(false, args)
+}
+
+// handleVSplit splits the active window side by side (:vsplit [FILE])
+func handleVSplit(fd int, args string, callback func() byte) {
+	splitWith(true, args)
+}
+
+// splitWith splits the active window and opens file in the new one
+func splitWith(vertical bool, file string) {
+	if err := splitWindow(vertical); err != nil {
+		session.statusMessage = "Split: " + err.Error()
+		return
+	}
+	if file = strings.TrimSpace(file); file != "" {
+		if err := openBuffer(expandHome(file)); err != nil {
+			closeWindow(activeWindow)
+			session.statusMessage = fmt.Sprintf("Split: %v", err)
+			return
+		}
+	}
+	session.statusMessage = fmt.Sprintf("Window %d/%d (Alt-W: next window, :close, :only)", activeWindow+1, len(windows))
+}
+
+// handleNextWindow makes the next window active, after the last the first
+// (Alt-W)
+func handleNextWindow() {
+	if len(windows) == 0 {
+		session.statusMessage = "No other window (:split, :vsplit)"
+		return
+	}
+	focusWindow((activeWindow + 1) % len(windows))
+}
+
+// handleCloseWindow closes the active window (:close)
+func handleCloseWindow(fd int, args string, callback func() byte) {
+	if len(windows) == 0 {
+		session.statusMessage = "Cannot close the last window"
+		return
+	}
+	closeWindow(activeWindow)
+}
+
+// handleOnlyWindow closes every window but the active one (:only)
+func handleOnlyWindow(fd int, args string, callback func() byte) {
+	if len(windows) == 0 {
+		return
+	}
+	windows = nil
+	activeWindow = 0
+	invalidateFrame()
+}
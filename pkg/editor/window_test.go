@@ -0,0 +1,122 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWindowSpan(t *testing.T) {
+	windows = make([]*window, 3)
+	t.Cleanup(func() { windows = nil })
+	// 23 rows: two title rows and 21 for the text, 7 each
+	for i, want := range [][2]int{{0, 7}, {8, 7}, {16, 7}} {
+		if start, length := windowSpan(i, 23); start != want[0] || length != want[1] {
+			t.Errorf("window %d at %d, %d rows, want %v", i, start, length, want)
+		}
+	}
+	// 80 columns: two lines and 78 for the text
+	for i, want := range [][2]int{{0, 26}, {27, 26}, {54, 26}} {
+		if start, length := windowSpan(i, 80); start != want[0] || length != want[1] {
+			t.Errorf("window %d at %d, %d columns, want %v", i, start, length, want)
+		}
+	}
+	if start, length := windowSpan(2, 25); start != 18 || length != 7 {
+		t.Errorf("the first windows should take the rows left over, got %d, %d", start, length)
+	}
+}
+
+func TestWindowsKeepTheirView(t *testing.T) {
+	resetSessionForTest()
+	t.Cleanup(func() { windows, activeWindow = nil, 0 })
+	InitSession(-1, "notes.txt", "one\ntwo\nthree\nfour\n")
+	GoToLine(2, 1)
+
+	runCommand(0, "split", nil)
+	if len(windows) != 2 || activeWindow != 1 || session.cursorRow != 2 {
+		t.Fatalf("windows %d, active %d, cursor on %d", len(windows), activeWindow, session.cursorRow)
+	}
+	GoToLine(4, 3)
+	handleNextWindow()
+	if activeWindow != 0 || session.cursorRow != 2 || session.cursorCol != 1 {
+		t.Errorf("the first window should keep its cursor on 2:1, got %d:%d", session.cursorRow, session.cursorCol)
+	}
+
+	// Text typed in one window moves the cursor of the other along
+	handleInsert("zero\n")
+	handleNextWindow()
+	if session.cursorRow != 5 || session.cursorCol != 3 {
+		t.Errorf("cursor of the second window on %d:%d, want 5:3", session.cursorRow, session.cursorCol)
+	}
+
+	runCommand(0, "close", nil)
+	if windows != nil || session.cursorRow != 3 {
+		t.Errorf("closing should leave the first window alone, with its cursor: %d windows, row %d", len(windows), session.cursorRow)
+	}
+}
+
+func TestDrawWindows(t *testing.T) {
+	resetSessionForTest()
+	t.Cleanup(func() { windows, activeWindow = nil, 0 })
+	InitSession(-1, "a.txt", "alpha\n")
+	AddBuffer("b.txt", "beta\n", 0, 0)
+
+	runCommand(0, "split", nil)
+	runCommand(0, "bn", nil)
+	screen := playKeys(t, "")
+	if screen.Line(0) != "alpha" || !strings.HasPrefix(screen.Line(11), " a.txt") || screen.Line(12) != "beta" {
+		t.Errorf("stacked windows:\n%s", screen)
+	}
+	if row, col := screen.Cursor(); row != 12 || col != 0 {
+		t.Errorf("cursor at %d:%d, want the second window", row, col)
+	}
+
+	runCommand(0, "only", nil)
+	runCommand(0, "vsplit", nil)
+	handleNextWindow()
+	handleInsert("x")
+	screen = playKeys(t, "")
+	if got := screen.Line(0); got != "xbeta"+strings.Repeat(" ", 35)+"│xbeta" {
+		t.Errorf("side by side windows: %q", got)
+	}
+	if row, col := screen.Cursor(); row != 0 || col != 1 {
+		t.Errorf("cursor at %d:%d, want the first window", row, col)
+	}
+}
+
+func TestScrollBind(t *testing.T) {
+	resetSessionForTest()
+	saveSettings(t)
+	t.Cleanup(func() { windows, activeWindow = nil, 0 })
+	var en, fr strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&en, "line %d\n", i)
+		fmt.Fprintf(&fr, "ligne %d\n", i)
+	}
+	InitSession(-1, "en.txt", en.String())
+	AddBuffer("fr.txt", fr.String(), 0, 0)
+	runCommand(0, "vsplit fr.txt", nil)
+	playKeys(t, "")
+
+	runCommand(0, "set scrollbind on", nil)
+	GoToLine(50, 1)
+	screen := playKeys(t, "")
+	left, right, _ := strings.Cut(screen.Line(0), "│")
+	left = strings.TrimSpace(left)
+	if left == "line 1" || strings.TrimPrefix(left, "line ") != strings.TrimPrefix(right, "ligne ") {
+		t.Errorf("the windows should scroll together:\n%s", screen)
+	}
+	// The other window's cursor stays in view
+	handleNextWindow()
+	if session.filename != "en.txt" || session.cursorRow <= session.rowOffset || session.rowOffset == 0 {
+		t.Errorf("cursor of the other window on line %d, scrolled by %d", session.cursorRow, session.rowOffset)
+	}
+
+	runCommand(0, "set scrollbind off", nil)
+	GoToLine(1, 1)
+	screen = playKeys(t, "")
+	left, right, _ = strings.Cut(screen.Line(0), "│")
+	if strings.TrimSpace(left) != "line 1" || right == "ligne 1" {
+		t.Errorf("without scrollbind only the active window scrolls:\n%s", screen)
+	}
+}
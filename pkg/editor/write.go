@@ -0,0 +1,116 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// makeParentDir offers to create the directory of filename, and those
+// above it, when it doesn't exist yet, as mkdir -p does
+func makeParentDir(filename string, callback func() byte) error {
+	dir := filepath.Dir(filename)
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if confirm(fmt.Sprintf("%s does not exist. Create it? y/n", dir), false, callback) != answerYes {
+		return fmt.Errorf("no such directory %s", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	log.Printf("created %s", dir)
+	return nil
+}
+
+// appendToFile adds text at the end of the existing file filename
+func appendToFile(filename, text string) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// confirmOverwrite asks before the buffer is saved over filename, an
+// existing file other than its own, and reports whether to go on
+func confirmOverwrite(filename string, callback func() byte) bool {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return true
+	}
+	if own, err := os.Stat(session.filename); err == nil && os.SameFile(info, own) {
+		return true
+	}
+	return confirm(fmt.Sprintf("%s exists. Overwrite? y/n", filename), false, callback) == answerYes
+}
+
+// handleWrite saves the buffer (:w) or saves it under a new name (:w FILE),
+// which makes a scratch buffer the buffer of that file. It also appends
+// the selection, or the whole buffer when nothing is selected, to an
+// existing file (:w >> FILE).
+func handleWrite(fd int, args string, callback func() byte) {
+	writeBuffer(fd, args, false, callback)
+}
+
+// handleForceWrite is :w FILE without asking before overwriting FILE (:w!)
+func handleForceWrite(fd int, args string, callback func() byte) {
+	writeBuffer(fd, args, true, callback)
+}
+
+// writeBuffer runs :w with args, or :w! when force
+func writeBuffer(fd int, args string, force bool, callback func() byte) {
+	if args == "" {
+		handleSave(fd, callback)
+		return
+	}
+	target, ok := strings.CutPrefix(args, ">>")
+	target = expandHome(strings.TrimSpace(target))
+	if target == "" {
+		session.statusMessage = "Usage: w [FILE | >> FILE]"
+		return
+	}
+	if !ok {
+		if !force && !confirmOverwrite(target, callback) {
+			session.statusMessage = fmt.Sprintf("Not saved: %s exists (:w! FILE overwrites it)", target)
+			return
+		}
+		// Like the name given at the Save as prompt, the copy is the user's
+		// own. The buffer keeps its name when it couldn't be written there.
+		filename, scratch, readOnly := session.filename, session.scratch, session.readOnly
+		session.filename = target
+		session.scratch = false
+		session.readOnly = false
+		if !saveBuffer(fd, callback) {
+			session.filename, session.scratch, session.readOnly = filename, scratch, readOnly
+		}
+		return
+	}
+
+	what := "the buffer"
+	text := session.rope.String()
+	if start, end, selected := selection(); selected {
+		what = "the selection"
+		text, _ = session.rope.Substring(start, end)
+	}
+	// Whole lines are appended, so that the next append starts on its own line
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	if err := appendToFile(target, text); err != nil {
+		log.Printf("append to %s: %v", target, err)
+		session.statusMessage = fmt.Sprintf("Append: %v", err)
+		return
+	}
+	log.Printf("appended %d bytes to %s", len(text), target)
+	session.statusMessage = fmt.Sprintf("Appended %s (%d bytes) to %s", what, len(text), target)
+}
@@ -0,0 +1,154 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jellexet/golang-text-editor/pkg/buffer"
+)
+
+func TestWriteAppend(t *testing.T) {
+	resetSessionForTest()
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session = newSession(filepath.Join(dir, "buf.txt"), "one\ntwo")
+	buffers = []*Session{session}
+
+	runCommand(0, "w >> "+notes, nil)
+	if session.statusMessage != "Appended the buffer (8 bytes) to "+notes {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+
+	selectRange(4, 7)
+	runCommand(0, "w >>"+notes, nil)
+	if got, _ := os.ReadFile(notes); string(got) != "first\none\ntwo\ntwo\n" {
+		t.Fatalf("notes hold %q", got)
+	}
+	if _, err := os.Stat(session.filename); !os.IsNotExist(err) {
+		t.Error("appending should not save the buffer itself")
+	}
+
+	runCommand(0, "w >> "+filepath.Join(dir, "missing.txt"), nil)
+	if !strings.HasPrefix(session.statusMessage, "Append:") {
+		t.Errorf("appending to a missing file should fail, status %q", session.statusMessage)
+	}
+
+	session.rope = buffer.NewRope("saved")
+	runCommand(0, "w", nil)
+	if got, _ := os.ReadFile(session.filename); string(got) != "saved" {
+		t.Errorf(":w should save the buffer, file holds %q", got)
+	}
+}
+
+func TestWriteAsExistingFile(t *testing.T) {
+	resetSessionForTest()
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.txt")
+	if err := os.WriteFile(other, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session = newSession(filepath.Join(dir, "buf.txt"), "new")
+	buffers = []*Session{session}
+
+	runCommand(0, "w "+other, makeCallback([]byte("n")))
+	if got, _ := os.ReadFile(other); string(got) != "keep" || session.filename == other {
+		t.Fatalf("answering no overwrote %q with %q", other, got)
+	}
+	if !strings.HasPrefix(session.statusMessage, "Not saved") {
+		t.Errorf("status %q", session.statusMessage)
+	}
+
+	runCommand(0, "w "+other, makeCallback([]byte("y")))
+	if got, _ := os.ReadFile(other); string(got) != "new" || session.filename != other {
+		t.Fatalf("answering yes left %q, buffer %q", got, session.filename)
+	}
+
+	// The buffer's own file, and :w!, need no answer
+	session.rope = buffer.NewRope("again")
+	runCommand(0, "w "+other, nil)
+	session = newSession(filepath.Join(dir, "forced.txt"), "forced")
+	buffers = []*Session{session}
+	runCommand(0, "w! "+other, nil)
+	if got, _ := os.ReadFile(other); string(got) != "forced" {
+		t.Errorf(":w! left %q", got)
+	}
+}
+
+func TestWriteFailureKeepsName(t *testing.T) {
+	resetSessionForTest()
+	dir := t.TempDir()
+	session = newSession(filepath.Join(dir, "buf.txt"), "text")
+	session.readOnly = true
+	buffers = []*Session{session}
+
+	// Declining to create the directory fails the save
+	runCommand(0, "w "+filepath.Join(dir, "missing", "copy.txt"), makeCallback([]byte("n")))
+	if session.filename != filepath.Join(dir, "buf.txt") || !session.readOnly {
+		t.Errorf("a failed :w FILE left the buffer as %q, read-only %v", session.filename, session.readOnly)
+	}
+	if !strings.HasPrefix(session.statusMessage, "Error saving file") {
+		t.Errorf("status %q", session.statusMessage)
+	}
+}
+
+func TestSaveKeepsFileMetadata(t *testing.T) {
+	resetSessionForTest()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(script, []byte("old"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.sh")
+	if err := os.Symlink("run.sh", link); err != nil {
+		t.Fatal(err)
+	}
+	hard := filepath.Join(dir, "hard.sh")
+	if err := os.Link(script, hard); err != nil {
+		t.Fatal(err)
+	}
+
+	session = newSession(link, "new")
+	buffers = []*Session{session}
+	handleSave(0, nil)
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("the symlink should stay a symlink: %v", err)
+	}
+	info, err := os.Stat(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("permissions changed to %v", info.Mode().Perm())
+	}
+	for _, name := range []string{script, hard} {
+		if got, _ := os.ReadFile(name); string(got) != "new" {
+			t.Errorf("%s holds %q", name, got)
+		}
+	}
+}
+
+func TestSaveCreatesDirectories(t *testing.T) {
+	resetSessionForTest()
+	path := filepath.Join(t.TempDir(), "new", "dir", "notes.txt")
+	session = newSession(path, "text")
+	buffers = []*Session{session}
+
+	handleSave(0, makeCallback([]byte("n")))
+	if session.statusMessage != "Error saving file: no such directory "+filepath.Dir(path) {
+		t.Errorf("unexpected status %q", session.statusMessage)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Error("declining should create nothing")
+	}
+
+	handleSave(0, makeCallback([]byte("y")))
+	if data, err := os.ReadFile(path); err != nil || string(data) != "text" {
+		t.Errorf("file holds %q, %v", data, err)
+	}
+}
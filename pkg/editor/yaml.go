@@ -0,0 +1,170 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML is read and written with gopkg.in/yaml.v3, through its node tree:
+// scalars keep the quoting they were written with and comments stay
+// attached to the nodes they were next to, so formatting changes the
+// layout and nothing else. Documents after the first are refused.
+
+// dataError is a syntax error at a 1-indexed line of the text
+type dataError struct {
+	line int
+	msg  string
+}
+
+func (e *dataError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+// yamlErrorLine matches the line yaml.v3 gives in its errors
+var yamlErrorLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// yamlError turns an error of yaml.v3 into a dataError when it tells the line
+func yamlError(err error) error {
+	msg := err.Error()
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	m := yamlErrorLine.FindStringSubmatch(msg)
+	if m == nil {
+		// Some errors tell no line
+		return errors.New(strings.TrimPrefix(msg, "yaml: "))
+	}
+	line, _ := strconv.Atoi(m[1])
+	return &dataError{line, m[2]}
+}
+
+// parseYAML reads a YAML document. It returns nil for a document without
+// content, like one of comments only.
+func parseYAML(text string) (*yaml.Node, error) {
+	dec := yaml.NewDecoder(strings.NewReader(text))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, yamlError(err)
+	}
+	// Decoding the values finds what the node tree allows, like a key twice
+	var value any
+	if err := doc.Decode(&value); err != nil {
+		return nil, yamlError(err)
+	}
+	var next yaml.Node
+	if err := dec.Decode(&next); err == nil {
+		return nil, &dataError{next.Line, "several documents are not supported"}
+	} else if err != io.EOF {
+		return nil, yamlError(err)
+	}
+	return &doc, nil
+}
+
+// encodeYAML writes node with indent spaces per level
+func encodeYAML(node *yaml.Node, indent int) (string, error) {
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(indent)
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// formatYAML writes node in block style, indent spaces per level. Empty
+// collections stay {} and [].
+func formatYAML(node *yaml.Node, indent int) (string, error) {
+	setYAMLStyle(node, func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+			n.Style &^= yaml.FlowStyle
+		}
+	})
+	return encodeYAML(node, indent)
+}
+
+// yamlFlow writes node on one line in flow style, like JSON. Block
+// scalars become quoted strings and nulls are spelled out, which flow
+// collections need. Comments can't be kept on one line: callers check
+// there are none.
+func yamlFlow(node *yaml.Node) (string, error) {
+	setYAMLStyle(node, func(n *yaml.Node) {
+		n.HeadComment, n.LineComment, n.FootComment = "", "", ""
+		switch {
+		case n.Kind != yaml.ScalarNode:
+			n.Style |= yaml.FlowStyle
+		case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+			n.Style = yaml.DoubleQuotedStyle
+		case n.ShortTag() == "!!null" && n.Value == "":
+			n.Value = "null"
+		}
+	})
+	out, err := encodeYAML(node, 2)
+	return strings.TrimSuffix(out, "\n"), err
+}
+
+// setYAMLStyle calls set on node and every node under it
+func setYAMLStyle(node *yaml.Node, set func(*yaml.Node)) {
+	set(node)
+	for _, child := range node.Content {
+		setYAMLStyle(child, set)
+	}
+}
+
+// yamlCommentLines counts the lines of text holding a comment. Formatted
+// text must have as many, or comments were lost on the way.
+func yamlCommentLines(text string) int {
+	count := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimLeft(line, " ")
+		if stripYAMLComment(line) != line {
+			count++
+		}
+	}
+	return count
+}
+
+// quotedEnd returns the index after the quoted string s starts with, or -1
+// when it is not closed
+func quotedEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment cuts the comment off a line: a # at its start or after
+// a space, outside of a quoted string
+func stripYAMLComment(s string) string {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" [{,:-", rune(s[i-1]))):
+			if end := quotedEnd(s[i:]); end > 0 {
+				i += end - 1
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatYAML(t *testing.T) {
+	text := `# service
+name:   web   # the name
+ports: [80, "443"]
+env:
+- {name: A, value: "1"}
+-   name: B
+    value: two words
+      continued
+empty:
+nested:
+      deep: {a: [1, 2], b: {}}
+script: |
+    echo hi
+
+    exit 0
+url: http://example.com:8080/x
+`
+	node, err := parseYAML(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# service
+name: web # the name
+ports:
+  - 80
+  - "443"
+env:
+  - name: A
+    value: "1"
+  - name: B
+    value: two words continued
+empty:
+nested:
+  deep:
+    a:
+      - 1
+      - 2
+    b: {}
+script: |
+  echo hi
+
+  exit 0
+url: http://example.com:8080/x
+`
+	got, err := formatYAML(node, 2)
+	if err != nil || got != want {
+		t.Errorf("formatted:\n%s\nwant:\n%s", got, want)
+	}
+	if yamlCommentLines(got) != yamlCommentLines(text) {
+		t.Errorf("comments lost:\n%s", got)
+	}
+	// Formatting again changes nothing
+	again, err := parseYAML(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := formatYAML(again, 2); got != want {
+		t.Errorf("formatting is not stable:\n%s", got)
+	}
+
+	flow, err := yamlFlow(node)
+	if err != nil || !strings.HasPrefix(flow, `{name: web, ports: [80, "443"], env: [{name: A, value: "1"}`) ||
+		!strings.Contains(flow, `empty: null`) || !strings.Contains(flow, `script: "echo hi\n\nexit 0\n"`) ||
+		strings.Contains(flow, "#") {
+		t.Errorf("flow %s, %v", flow, err)
+	}
+}
+
+func TestParseJSONAsYAML(t *testing.T) {
+	node, err := parseYAML("{\n\t\"a\": [1, true],\n\t\"b\": {\"c\": null}\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := formatYAML(node, 2); got != "\"a\":\n  - 1\n  - true\n\"b\":\n  \"c\": null\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for text, line := range map[string]int{
+		"a: 1\n  b: 2\n":       2,
+		"a: 1\na: 2\n":         2,
+		"a:\n\t- x\n":          2,
+		"a: 1\n---\nb: 2\n":    2,
+		"x: 1\n\n\nbad line\n": 4,
+	} {
+		_, err := parseYAML(text)
+		e, ok := err.(*dataError)
+		if !ok || e.line != line {
+			t.Errorf("%q: got %v, want an error on line %d", text, err, line)
+		}
+	}
+	// yaml.v3 tells no line for some errors
+	if _, err := parseYAML("a: {x: 1]}\n"); err == nil || strings.HasPrefix(err.Error(), "yaml:") {
+		t.Errorf("unclosed flow mapping: %v", err)
+	}
+	if node, err := parseYAML("# only a comment\n"); node != nil || err != nil {
+		t.Errorf("a document of comments is empty: %v %v", node, err)
+	}
+}
@@ -0,0 +1,454 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Position is a zero-based line and UTF-16 character offset
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span between two positions (end exclusive)
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range inside a document identified by its URI
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// message is a JSON-RPC 2.0 request, response or notification
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  interface{}      `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// response is what a pending request receives from the reader goroutine
+type response struct {
+	result json.RawMessage
+	err    error
+}
+
+// Client is a minimal Language Server Protocol client.
+// It speaks JSON-RPC over the stdin/stdout of the server process.
+type Client struct {
+	cmd     *exec.Cmd
+	w       io.WriteCloser
+	r       *bufio.Reader
+	writeMu sync.Mutex // serializes writes to the server
+	mu      sync.Mutex // guards nextID, pending, closed and diagnostics
+	nextID  int
+	pending map[int]chan response
+	closed  error
+
+	// diagnostics counts the problems last published for each document URI
+	diagnostics map[string]int
+
+	// Timeout bounds how long a request waits for its response
+	Timeout time.Duration
+}
+
+// Start launches the language server command and returns a connected client.
+// Initialize must be called before any other request.
+func Start(command string, args ...string) (*Client, error) {
+	cmd := exec.Command(command, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := NewClient(stdout, stdin)
+	c.cmd = cmd
+	return c, nil
+}
+
+// NewClient creates a client over an already connected stream pair
+func NewClient(r io.Reader, w io.WriteCloser) *Client {
+	c := &Client{
+		w:       w,
+		r:       bufio.NewReader(r),
+		pending: make(map[int]chan response),
+		Timeout: 5 * time.Second,
+
+		diagnostics: make(map[string]int),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Initialize performs the initialize handshake for the given workspace root
+func (c *Client) Initialize(rootDir string) error {
+	params := map[string]interface{}{
+		"processId": nil,
+		"rootUri":   PathToURI(rootDir),
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"hover": map[string]interface{}{
+					"contentFormat": []string{"plaintext"},
+				},
+			},
+		},
+	}
+	if _, err := c.call("initialize", params); err != nil {
+		return err
+	}
+	return c.notify("initialized", map[string]interface{}{})
+}
+
+// DidOpen tells the server that a document is now managed by the editor
+func (c *Client) DidOpen(path, languageID string, version int, text string) error {
+	return c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri":        PathToURI(path),
+			"languageId": languageID,
+			"version":    version,
+			"text":       text,
+		},
+	})
+}
+
+// DidChange sends the full new content of an open document
+func (c *Client) DidChange(path string, version int, text string) error {
+	return c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri":     PathToURI(path),
+			"version": version,
+		},
+		"contentChanges": []map[string]interface{}{
+			{"text": text},
+		},
+	})
+}
+
+// Definition asks where the symbol at pos is defined
+func (c *Client) Definition(path string, pos Position) ([]Location, error) {
+	result, err := c.call("textDocument/definition", positionParams(path, pos))
+	if err != nil {
+		return nil, err
+	}
+	return parseLocations(result)
+}
+
+// References asks for every use of the symbol at pos, including its declaration
+func (c *Client) References(path string, pos Position) ([]Location, error) {
+	params := positionParams(path, pos)
+	params["context"] = map[string]interface{}{"includeDeclaration": true}
+	result, err := c.call("textDocument/references", params)
+	if err != nil {
+		return nil, err
+	}
+	return parseLocations(result)
+}
+
+// Hover asks for the documentation of the symbol at pos.
+// It returns an empty string when the server has nothing to show.
+func (c *Client) Hover(path string, pos Position) (string, error) {
+	result, err := c.call("textDocument/hover", positionParams(path, pos))
+	if err != nil {
+		return "", err
+	}
+	return parseHover(result)
+}
+
+// Close shuts the server down politely and waits for the process to exit
+func (c *Client) Close() error {
+	c.call("shutdown", nil)
+	c.notify("exit", nil)
+	c.w.Close()
+	if c.cmd != nil {
+		return c.cmd.Wait()
+	}
+	return nil
+}
+
+func positionParams(path string, pos Position) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": PathToURI(path)},
+		"position":     pos,
+	}
+}
+
+// parseLocations accepts every shape a definition-like request may return:
+// null, a single Location, a list of Locations or a list of LocationLinks.
+func parseLocations(raw json.RawMessage) ([]Location, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return nil, nil
+	}
+
+	type locationOrLink struct {
+		Location
+		TargetURI            string `json:"targetUri"`
+		TargetSelectionRange Range  `json:"targetSelectionRange"`
+	}
+
+	var items []locationOrLink
+	if strings.HasPrefix(trimmed, "{") {
+		var item locationOrLink
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	} else if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	locations := make([]Location, 0, len(items))
+	for _, item := range items {
+		if item.TargetURI != "" {
+			locations = append(locations, Location{URI: item.TargetURI, Range: item.TargetSelectionRange})
+		} else {
+			locations = append(locations, item.Location)
+		}
+	}
+	return locations, nil
+}
+
+// parseHover extracts the text of a hover result, whose contents may be
+// MarkupContent, a MarkedString or a list of MarkedStrings
+func parseHover(raw json.RawMessage) (string, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return "", nil
+	}
+
+	var hover struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := json.Unmarshal(raw, &hover); err != nil {
+		return "", err
+	}
+
+	var parts []json.RawMessage
+	if strings.HasPrefix(strings.TrimSpace(string(hover.Contents)), "[") {
+		if err := json.Unmarshal(hover.Contents, &parts); err != nil {
+			return "", err
+		}
+	} else {
+		parts = []json.RawMessage{hover.Contents}
+	}
+
+	var texts []string
+	for _, part := range parts {
+		var s string
+		if err := json.Unmarshal(part, &s); err == nil {
+			texts = append(texts, s)
+			continue
+		}
+		var markup struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(part, &markup); err != nil {
+			return "", err
+		}
+		texts = append(texts, markup.Value)
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n\n")), nil
+}
+
+// call sends a request and waits for its response
+func (c *Client) call(method string, params interface{}) (json.RawMessage, error) {
+	c.mu.Lock()
+	if c.closed != nil {
+		c.mu.Unlock()
+		return nil, c.closed
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan response, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	rawID := json.RawMessage(strconv.Itoa(id))
+	if err := c.write(message{JSONRPC: "2.0", ID: &rawID, Method: method, Params: params}); err != nil {
+		c.forget(id)
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp.result, resp.err
+	case <-time.After(c.Timeout):
+		c.forget(id)
+		return nil, fmt.Errorf("%s: timed out after %v", method, c.Timeout)
+	}
+}
+
+// notify sends a notification, which has no response
+func (c *Client) notify(method string, params interface{}) error {
+	return c.write(message{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *Client) forget(id int) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// write frames a message with the Content-Length header the protocol requires
+func (c *Client) write(msg message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// readLoop dispatches server messages until the stream ends
+func (c *Client) readLoop() {
+	for {
+		msg, err := readMessage(c.r)
+		if err != nil {
+			c.mu.Lock()
+			c.closed = fmt.Errorf("language server closed the connection: %v", err)
+			for id, ch := range c.pending {
+				ch <- response{err: c.closed}
+				delete(c.pending, id)
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			// A request from the server (e.g. window/workDoneProgress/create).
+			// We support none of them, but answering keeps the server happy.
+			c.write(message{JSONRPC: "2.0", ID: msg.ID, Result: json.RawMessage("null")})
+		case msg.Method == "textDocument/publishDiagnostics":
+			c.recordDiagnostics(msg.Params)
+		case msg.Method != "":
+			// Other notifications (log messages, progress) are ignored
+		case msg.ID != nil:
+			id, err := strconv.Atoi(string(*msg.ID))
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if !ok {
+				continue
+			}
+			if msg.Error != nil {
+				ch <- response{err: fmt.Errorf("%s (code %d)", msg.Error.Message, msg.Error.Code)}
+			} else {
+				ch <- response{result: msg.Result}
+			}
+		}
+	}
+}
+
+// recordDiagnostics keeps how many diagnostics the server published for a
+// document, which replace the ones it published before
+func (c *Client) recordDiagnostics(params interface{}) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	var published struct {
+		URI         string            `json:"uri"`
+		Diagnostics []json.RawMessage `json:"diagnostics"`
+	}
+	if json.Unmarshal(raw, &published) != nil {
+		return
+	}
+	c.mu.Lock()
+	c.diagnostics[published.URI] = len(published.Diagnostics)
+	c.mu.Unlock()
+}
+
+// Diagnostics returns how many problems the server last reported in the
+// file at path
+func (c *Client) Diagnostics(path string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.diagnostics[PathToURI(path)]
+}
+
+// readMessage reads one Content-Length framed message
+func readMessage(r *bufio.Reader) (message, error) {
+	var msg message
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return msg, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return msg, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return msg, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return msg, err
+	}
+	err := json.Unmarshal(body, &msg)
+	return msg, err
+}
+
+// PathToURI converts a file path into a file:// URI
+func PathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// URIToPath converts a file:// URI back into a file path
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -0,0 +1,176 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// fakeServer answers requests on the other end of a pipe pair.
+// handler returns the JSON result for a request; notifications are recorded.
+func fakeServer(t *testing.T, handler func(method string, params json.RawMessage) string) (*Client, func() []string) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	var mu sync.Mutex
+	var notifications []string
+
+	go func() {
+		r := bufio.NewReader(serverR)
+		for {
+			msg, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			if msg.ID == nil {
+				mu.Lock()
+				notifications = append(notifications, msg.Method)
+				mu.Unlock()
+				continue
+			}
+			params, _ := json.Marshal(msg.Params)
+			body := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`, *msg.ID, handler(msg.Method, params))
+			fmt.Fprintf(serverW, "Content-Length: %d\r\n\r\n%s", len(body), body)
+		}
+	}()
+
+	return NewClient(clientR, clientW), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), notifications...)
+	}
+}
+
+func TestClient_DefinitionShapes(t *testing.T) {
+	cases := map[string]string{
+		"single location": `{"uri":"file:///tmp/a.go","range":{"start":{"line":3,"character":5},"end":{"line":3,"character":9}}}`,
+		"location list":   `[{"uri":"file:///tmp/a.go","range":{"start":{"line":3,"character":5},"end":{"line":3,"character":9}}}]`,
+		"location links":  `[{"targetUri":"file:///tmp/a.go","targetSelectionRange":{"start":{"line":3,"character":5},"end":{"line":3,"character":9}}}]`,
+	}
+
+	for name, result := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, _ := fakeServer(t, func(method string, params json.RawMessage) string {
+				if method == "textDocument/definition" {
+					return result
+				}
+				return "null"
+			})
+			if err := client.Initialize("/tmp"); err != nil {
+				t.Fatalf("initialize: %v", err)
+			}
+
+			locs, err := client.Definition("/tmp/b.go", Position{Line: 1, Character: 2})
+			if err != nil {
+				t.Fatalf("definition: %v", err)
+			}
+			if len(locs) != 1 || locs[0].URI != "file:///tmp/a.go" || locs[0].Range.Start != (Position{3, 5}) {
+				t.Fatalf("unexpected locations: %+v", locs)
+			}
+		})
+	}
+}
+
+func TestClient_ReferencesAndNull(t *testing.T) {
+	var gotParams string
+	client, notifications := fakeServer(t, func(method string, params json.RawMessage) string {
+		if method == "textDocument/references" {
+			gotParams = string(params)
+			return `[{"uri":"file:///x.go","range":{}},{"uri":"file:///y.go","range":{}}]`
+		}
+		return "null"
+	})
+
+	if err := client.DidOpen("/tmp/a.go", "go", 1, "package a"); err != nil {
+		t.Fatalf("didOpen: %v", err)
+	}
+	locs, err := client.References("/tmp/a.go", Position{})
+	if err != nil || len(locs) != 2 {
+		t.Fatalf("references: %v %+v", err, locs)
+	}
+	if want := `"includeDeclaration":true`; !strings.Contains(gotParams, want) {
+		t.Fatalf("references params %s missing %s", gotParams, want)
+	}
+	if got := notifications(); len(got) == 0 || got[0] != "textDocument/didOpen" {
+		t.Fatalf("didOpen notification not received: %v", got)
+	}
+
+	locs, err = client.Definition("/tmp/a.go", Position{})
+	if err != nil || locs != nil {
+		t.Fatalf("null result should give no locations: %v %+v", err, locs)
+	}
+}
+
+func TestClient_ServerGone(t *testing.T) {
+	clientR, serverW := io.Pipe()
+	client := NewClient(clientR, nopWriteCloser{io.Discard})
+	serverW.Close()
+
+	if _, err := client.Definition("/tmp/a.go", Position{}); err == nil {
+		t.Fatalf("expected an error once the server is gone")
+	}
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	path := "/tmp/dir with space/main.go"
+	uri := PathToURI(path)
+	if uri != "file:///tmp/dir%20with%20space/main.go" {
+		t.Fatalf("unexpected uri %q", uri)
+	}
+	if got := URIToPath(uri); got != path {
+		t.Fatalf("round trip gave %q", got)
+	}
+}
+
+func TestClient_HoverShapes(t *testing.T) {
+	cases := map[string]string{
+		"markup content": `{"contents":{"kind":"plaintext","value":"func Foo()"}}`,
+		"marked string":  `{"contents":"func Foo()"}`,
+		"marked list":    `{"contents":[{"language":"go","value":"func Foo()"}]}`,
+		"no information": `null`,
+	}
+
+	for name, result := range cases {
+		t.Run(name, func(t *testing.T) {
+			client, _ := fakeServer(t, func(method string, params json.RawMessage) string {
+				return result
+			})
+			text, err := client.Hover("/tmp/a.go", Position{})
+			if err != nil {
+				t.Fatalf("hover: %v", err)
+			}
+			want := "func Foo()"
+			if result == "null" {
+				want = ""
+			}
+			if text != want {
+				t.Fatalf("got %q want %q", text, want)
+			}
+		})
+	}
+}
+
+func TestClient_Diagnostics(t *testing.T) {
+	clientR, serverW := io.Pipe()
+	client := NewClient(clientR, nopWriteCloser{io.Discard})
+
+	body := `{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///tmp/a.go","diagnostics":[{"message":"x"},{"message":"y"}]}}`
+	fmt.Fprintf(serverW, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	serverW.Close()
+	// The notification is read before the end of the stream fails the request
+	client.Definition("/tmp/a.go", Position{})
+
+	if n := client.Diagnostics("/tmp/a.go"); n != 2 {
+		t.Errorf("got %d diagnostics for a.go, want 2", n)
+	}
+	if n := client.Diagnostics("/tmp/b.go"); n != 0 {
+		t.Errorf("got %d diagnostics for b.go, want 0", n)
+	}
+}
@@ -0,0 +1,18 @@
+// Package termtest is the terminal of end-to-end tests: a program draws on
+// an in-memory screen emulated by pkg/vt, so what it drew with escape
+// sequences can be checked without a real terminal.
+package termtest
+
+import "github.com/jellexet/golang-text-editor/pkg/vt"
+
+// Screen is the screen a test plays the output of a program on
+type Screen = vt.Screen
+
+// Cell is a character on a Screen with its style
+type Cell = vt.Cell
+
+// New returns a blank screen of rows x cols cells with the cursor at the
+// top left
+func New(rows, cols int) *Screen {
+	return vt.New(rows, cols)
+}
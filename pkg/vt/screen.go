@@ -0,0 +1,272 @@
+// Package vt emulates a VT100 terminal in memory. The editor plays the
+// output of the shell of its terminal pane on it, and pkg/termtest builds
+// the screens of end-to-end tests on it.
+package vt
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Cell is a character on screen with the SGR parameters it is drawn with,
+// like "1;7" for bold inverse, or "" for the default style
+type Cell struct {
+	Rune  rune
+	Style string
+}
+
+// blank is an empty cell in the default style
+var blank = Cell{' ', ""}
+
+// Screen is an in-memory terminal of fixed size. Writing to it plays the
+// output of a program: text, control characters and the escape sequences
+// of a VT100 (cursor moves, erasing, SGR styles), plus the window title.
+// Other sequences are read and ignored.
+type Screen struct {
+	rows, cols int
+	cells      [][]Cell
+	row, col   int
+	style      string
+	pending    []byte // incomplete sequence or character at the end of a write
+
+	// CursorVisible is false while the program hides the cursor (CSI ?25l)
+	CursorVisible bool
+	// Title is the last window title set with OSC 0 or OSC 2
+	Title string
+}
+
+// New returns a blank screen of rows x cols cells with the cursor at the
+// top left
+func New(rows, cols int) *Screen {
+	s := &Screen{rows: rows, cols: cols, CursorVisible: true}
+	s.cells = make([][]Cell, rows)
+	for r := range s.cells {
+		s.cells[r] = blankRow(cols)
+	}
+	return s
+}
+
+// Write plays p on the screen. It never fails; a sequence cut at the end
+// of p is completed by the next write.
+func (s *Screen) Write(p []byte) (int, error) {
+	data := append(s.pending, p...)
+	s.pending = nil
+	for i := 0; i < len(data); {
+		n := s.step(data[i:])
+		if n == 0 {
+			s.pending = append([]byte(nil), data[i:]...)
+			break
+		}
+		i += n
+	}
+	return len(p), nil
+}
+
+// step plays the control, sequence or character at the start of data and
+// returns its length, or 0 when data ends before it does
+func (s *Screen) step(data []byte) int {
+	switch data[0] {
+	case '\x1b':
+		return s.escape(data)
+	case '\r':
+		s.col = 0
+	case '\n':
+		s.lineFeed()
+	case '\b':
+		s.col = max(s.col-1, 0)
+	case '\t':
+		s.col = min(s.col+8-s.col%8, s.cols-1)
+	case '\a':
+	default:
+		if data[0] < 0x20 {
+			return 1
+		}
+		if !utf8.FullRune(data) {
+			return 0
+		}
+		r, size := utf8.DecodeRune(data)
+		s.put(r)
+		return size
+	}
+	return 1
+}
+
+// put writes r at the cursor, wrapping at the end of the row
+func (s *Screen) put(r rune) {
+	if s.col >= s.cols {
+		s.col = 0
+		s.lineFeed()
+	}
+	s.cells[s.row][s.col] = Cell{r, s.style}
+	s.col++
+}
+
+// lineFeed moves the cursor down, scrolling the screen up at the bottom
+func (s *Screen) lineFeed() {
+	if s.row < s.rows-1 {
+		s.row++
+		return
+	}
+	copy(s.cells, s.cells[1:])
+	s.cells[s.rows-1] = blankRow(s.cols)
+}
+
+// escape plays the escape sequence at the start of data
+func (s *Screen) escape(data []byte) int {
+	if len(data) < 2 {
+		return 0
+	}
+	switch data[1] {
+	case '[':
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				s.csi(string(data[2:i]), data[i])
+				return i + 1
+			}
+		}
+		return 0
+	case ']':
+		// Operating system commands end with BEL or ST (ESC \)
+		for i := 2; i < len(data); i++ {
+			if data[i] == '\a' {
+				s.osc(string(data[2:i]))
+				return i + 1
+			}
+			if data[i] == '\x1b' && i+1 < len(data) && data[i+1] == '\\' {
+				s.osc(string(data[2:i]))
+				return i + 2
+			}
+		}
+		return 0
+	}
+	return 2
+}
+
+// csi plays a control sequence with its parameters and final byte
+func (s *Screen) csi(params string, final byte) {
+	if strings.HasPrefix(params, "?") {
+		if params == "?25" && (final == 'h' || final == 'l') {
+			s.CursorVisible = final == 'h'
+		}
+		return
+	}
+	switch final {
+	case 'H', 'f':
+		r, c, _ := strings.Cut(params, ";")
+		s.row = min(max(param(r, 1), 1), s.rows) - 1
+		s.col = min(max(param(c, 1), 1), s.cols) - 1
+	case 'A':
+		s.row = max(s.row-param(params, 1), 0)
+	case 'B':
+		s.row = min(s.row+param(params, 1), s.rows-1)
+	case 'C':
+		s.col = min(s.col+param(params, 1), s.cols-1)
+	case 'D':
+		s.col = max(min(s.col, s.cols-1)-param(params, 1), 0)
+	case 'G':
+		s.col = min(max(param(params, 1), 1), s.cols) - 1
+	case 'K':
+		from, to := s.col, s.cols
+		switch param(params, 0) {
+		case 1:
+			from, to = 0, s.col+1
+		case 2:
+			from = 0
+		}
+		s.erase(s.row, min(from, s.cols), min(to, s.cols))
+	case 'J':
+		switch param(params, 0) {
+		case 0:
+			s.erase(s.row, min(s.col, s.cols), s.cols)
+			for r := s.row + 1; r < s.rows; r++ {
+				s.erase(r, 0, s.cols)
+			}
+		case 1:
+			for r := 0; r < s.row; r++ {
+				s.erase(r, 0, s.cols)
+			}
+			s.erase(s.row, 0, min(s.col+1, s.cols))
+		case 2, 3:
+			for r := range s.cells {
+				s.erase(r, 0, s.cols)
+			}
+		}
+	case 'm':
+		if params == "" || params == "0" {
+			s.style = ""
+		} else if s.style == "" {
+			s.style = params
+		} else {
+			s.style += ";" + params
+		}
+	}
+}
+
+// osc plays an operating system command: only titles are kept
+func (s *Screen) osc(command string) {
+	if title, ok := strings.CutPrefix(command, "0;"); ok {
+		s.Title = title
+	} else if title, ok := strings.CutPrefix(command, "2;"); ok {
+		s.Title = title
+	}
+}
+
+// erase blanks the cells [from, to) of row r. Erased cells take the
+// current background, which the default style stands for.
+func (s *Screen) erase(r, from, to int) {
+	for c := from; c < to; c++ {
+		s.cells[r][c] = blank
+	}
+}
+
+// Size returns the number of rows and columns of the screen
+func (s *Screen) Size() (rows, cols int) {
+	return s.rows, s.cols
+}
+
+// Cursor returns the zero-based row and column of the cursor
+func (s *Screen) Cursor() (row, col int) {
+	return s.row, min(s.col, s.cols-1)
+}
+
+// Cell returns the cell at the zero-based row and column
+func (s *Screen) Cell(row, col int) Cell {
+	return s.cells[row][col]
+}
+
+// Line returns the text of the zero-based row, without trailing blanks
+func (s *Screen) Line(row int) string {
+	var b strings.Builder
+	for _, c := range s.cells[row] {
+		b.WriteRune(c.Rune)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// String returns the text of every row, one per line
+func (s *Screen) String() string {
+	lines := make([]string, s.rows)
+	for r := range lines {
+		lines[r] = s.Line(r)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// param returns the numeric parameter of a control sequence, def when it
+// is missing
+func param(p string, def int) int {
+	if n, err := strconv.Atoi(p); err == nil {
+		return n
+	}
+	return def
+}
+
+// blankRow returns a row of cols blank cells
+func blankRow(cols int) []Cell {
+	row := make([]Cell, cols)
+	for i := range row {
+		row[i] = blank
+	}
+	return row
+}
@@ -0,0 +1,55 @@
+package vt
+
+import "testing"
+
+func TestWriteText(t *testing.T) {
+	s := New(3, 5)
+	s.Write([]byte("ab\r\ncdefgh"))
+	if s.Line(0) != "ab" || s.Line(1) != "cdefg" || s.Line(2) != "h" {
+		t.Errorf("lines %q", s.String())
+	}
+	if row, col := s.Cursor(); row != 2 || col != 1 {
+		t.Errorf("cursor at %d:%d, want 2:1", row, col)
+	}
+
+	// A line feed on the last row scrolls
+	s.Write([]byte("\r\nxy"))
+	if s.String() != "cdefg\nh\nxy" {
+		t.Errorf("after scrolling %q", s.String())
+	}
+}
+
+func TestWriteSequences(t *testing.T) {
+	s := New(4, 10)
+	s.Write([]byte("\x1b]2;notes.txt\a\x1b[?25l0123456789\x1b[2;3Hab\x1b[1;7mcd\x1b[m\x1b[1;5H\x1b[K"))
+	if s.Title != "notes.txt" || s.CursorVisible {
+		t.Errorf("title %q, cursor visible %v", s.Title, s.CursorVisible)
+	}
+	if s.Line(0) != "0123" || s.Line(1) != "  abcd" {
+		t.Errorf("lines %q", s.String())
+	}
+	if got := s.Cell(1, 4); got != (Cell{'c', "1;7"}) {
+		t.Errorf("styled cell %+v", got)
+	}
+	if got := s.Cell(1, 3); got.Style != "" {
+		t.Errorf("unstyled cell %+v", got)
+	}
+
+	s.Write([]byte("\x1b[2J\x1b[?25h\x1b[3;2Hx\x1b[2Dy"))
+	if s.String() != "\n\nyx\n" || !s.CursorVisible {
+		t.Errorf("after clearing %q", s.String())
+	}
+	if row, col := s.Cursor(); row != 2 || col != 1 {
+		t.Errorf("cursor at %d:%d, want 2:1", row, col)
+	}
+}
+
+func TestWriteSplitSequences(t *testing.T) {
+	s := New(2, 10)
+	for _, part := range []string{"\x1b", "[2;", "4H", "\xc3", "\xa9t\xe2\x82", "\xac"} {
+		s.Write([]byte(part))
+	}
+	if s.Line(1) != "   ét€" {
+		t.Errorf("line %q", s.Line(1))
+	}
+}